@@ -2,15 +2,18 @@
 package baidupcs
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"strconv"
 
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/diskcache"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/expires/cachemap"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/internal/panhome"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/tokenbucket"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester"
 )
@@ -145,7 +148,13 @@ type (
 		panUA       string
 		isSetPanUA  bool
 		ph          *panhome.PanHome
-		cacheOpMap  cachemap.CacheOpMap
+		cacheOpMap  *cachemap.CacheOpMap
+		diskCache   *diskcache.Cache               // 磁盘缓存
+		offline     bool                           // 离线模式
+		noCache     bool                           // 不读取磁盘缓存, 请求结果仍然更新缓存
+		ctx         context.Context                // 请求使用的 context, 为空则不可取消
+		retryPolicy *requester.RetryPolicy         // 请求级别的重试策略, 为空则使用默认策略
+		rateLimits  map[string]*tokenbucket.Bucket // 各类接口的限速器
 	}
 
 	userInfoJSON struct {
@@ -264,6 +273,29 @@ func (pcs *BaiduPCS) lazyInit() {
 	if !pcs.isSetPanUA {
 		pcs.panUA = NetdiskUA
 	}
+	if pcs.cacheOpMap == nil {
+		pcs.cacheOpMap = &cachemap.CacheOpMap{}
+	}
+}
+
+// WithContext 返回使用 ctx 发起请求的 BaiduPCS 浅拷贝, 与原对象共享 http 客户端和缓存,
+// ctx 取消或超时后, 所有进行中的 API 请求都会中断, 并返回网络错误
+func (pcs *BaiduPCS) WithContext(ctx context.Context) *BaiduPCS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	pcs.lazyInit()
+	pcs2 := *pcs
+	pcs2.ctx = ctx
+	return &pcs2
+}
+
+// Context 返回请求使用的 context, 未设置则返回 context.Background()
+func (pcs *BaiduPCS) Context() context.Context {
+	if pcs.ctx == nil {
+		return context.Background()
+	}
+	return pcs.ctx
 }
 
 // GetClient 获取当前的http client
@@ -9,6 +9,8 @@ import (
 
 // deleteCache 删除含有 dirs 的缓存
 func (pcs *BaiduPCS) deleteCache(dirs []string) {
+	pcs.lazyInit()
+	pcs.invalidateDiskCacheDirs(dirs)
 	cache := pcs.cacheOpMap.LazyInitCachePoolOp(OperationFilesDirectoriesList)
 	for _, v := range dirs {
 		key := v + "_" + defaultOrderOptionsStr
@@ -21,6 +23,7 @@ func (pcs *BaiduPCS) deleteCache(dirs []string) {
 
 // CacheFilesDirectoriesList 缓存获取
 func (pcs *BaiduPCS) CacheFilesDirectoriesList(path string, options *OrderOptions) (fdl FileDirectoryList, pcsError pcserror.Error) {
+	pcs.lazyInit()
 	data := pcs.cacheOpMap.CacheOperation(OperationFilesDirectoriesList, path+"_"+fmt.Sprint(options), func() expires.DataExpires {
 		fdl, pcsError = pcs.FilesDirectoriesList(path, options)
 		if pcsError != nil {
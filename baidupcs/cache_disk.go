@@ -0,0 +1,176 @@
+package baidupcs
+
+import (
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/diskcache"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"path"
+)
+
+const (
+	// DiskCacheOpList 目录列表的磁盘缓存
+	DiskCacheOpList = "list"
+	// DiskCacheOpMeta 文件/目录元信息的磁盘缓存
+	DiskCacheOpMeta = "meta"
+)
+
+var (
+	// ErrOffline 离线模式, 不发起网络请求
+	ErrOffline = errors.New("离线模式, 无法访问网络")
+	// ErrOfflineCacheMiss 离线模式下没有找到缓存
+	ErrOfflineCacheMiss = errors.New("离线模式, 没有找到缓存")
+
+	// 所有可能的排序方式, 用于删除目录的全部列表缓存
+	allOrderBy = []OrderBy{OrderByName, OrderByTime, OrderBySize, ""}
+	allOrder   = []Order{OrderAsc, OrderDesc, ""}
+)
+
+// SetDiskCache 设置磁盘缓存, 为 nil 则不使用磁盘缓存
+func (pcs *BaiduPCS) SetDiskCache(dc *diskcache.Cache) {
+	pcs.diskCache = dc
+}
+
+// DiskCache 返回磁盘缓存
+func (pcs *BaiduPCS) DiskCache() *diskcache.Cache {
+	return pcs.diskCache
+}
+
+// SetOffline 设置离线模式, 离线模式下只读取磁盘缓存 (包括已过期的), 不发起网络请求
+func (pcs *BaiduPCS) SetOffline(offline bool) {
+	pcs.offline = offline
+}
+
+// IsOffline 是否为离线模式
+func (pcs *BaiduPCS) IsOffline() bool {
+	return pcs.offline
+}
+
+// WithoutCache 返回不读取磁盘缓存的 BaiduPCS 浅拷贝, 请求结果仍然更新缓存,
+// 用于下载, 上传, 复制/移动等需要准确的大小, md5 和 fs_id 的操作
+func (pcs *BaiduPCS) WithoutCache() *BaiduPCS {
+	pcs2 := *pcs
+	pcs2.noCache = true
+	return &pcs2
+}
+
+func listCacheKey(pcspath string, options *OrderOptions) string {
+	return pcspath + "?by=" + string(options.By) + "&order=" + string(options.Order)
+}
+
+// stripFileDirectory 去掉父目录和子目录信息, 用于保存到缓存
+func stripFileDirectory(fd *FileDirectory) *FileDirectory {
+	fd2 := *fd
+	fd2.Parent = nil
+	fd2.Children = nil
+	return &fd2
+}
+
+func (pcs *BaiduPCS) offlineCacheMissError(op string) pcserror.Error {
+	return &pcserror.PCSErrInfo{
+		Operation: op,
+		ErrType:   pcserror.ErrTypeOthers,
+		Err:       ErrOfflineCacheMiss,
+	}
+}
+
+// loadListCache 读取目录列表缓存
+func (pcs *BaiduPCS) loadListCache(pcspath string, options *OrderOptions) (fdl FileDirectoryList, ok bool) {
+	if pcs.noCache || !pcs.offline && !pcs.diskCache.Enabled(DiskCacheOpList) {
+		return nil, false
+	}
+	ok, _ = pcs.diskCache.Get(DiskCacheOpList, listCacheKey(pcspath, options), &fdl, pcs.offline)
+	return
+}
+
+// saveListCache 保存目录列表缓存, 并更新其中文件/目录的元信息缓存
+func (pcs *BaiduPCS) saveListCache(pcspath string, options *OrderOptions, fdl FileDirectoryList) {
+	if !pcs.diskCache.Enabled(DiskCacheOpList) {
+		return
+	}
+	stripped := make(FileDirectoryList, 0, len(fdl))
+	for _, fd := range fdl {
+		stripped = append(stripped, stripFileDirectory(fd))
+	}
+	err := pcs.diskCache.Put(DiskCacheOpList, listCacheKey(pcspath, options), stripped)
+	if err != nil {
+		baiduPCSVerbose.Warnf("save list cache error: %s\n", err)
+	}
+	for _, fd := range stripped {
+		pcs.saveMetaCache(fd)
+	}
+}
+
+// loadMetaCache 读取文件/目录元信息缓存
+func (pcs *BaiduPCS) loadMetaCache(pcspath string) (fd *FileDirectory, ok bool) {
+	if pcs.noCache || !pcs.offline && !pcs.diskCache.Enabled(DiskCacheOpMeta) {
+		return nil, false
+	}
+	ok, _ = pcs.diskCache.Get(DiskCacheOpMeta, pcspath, &fd, pcs.offline)
+	return fd, ok && fd != nil
+}
+
+// saveMetaCache 保存文件/目录元信息缓存
+func (pcs *BaiduPCS) saveMetaCache(fd *FileDirectory) {
+	if !pcs.diskCache.Enabled(DiskCacheOpMeta) || fd == nil || fd.Path == "" {
+		return
+	}
+	err := pcs.diskCache.Put(DiskCacheOpMeta, fd.Path, stripFileDirectory(fd))
+	if err != nil {
+		baiduPCSVerbose.Warnf("save meta cache error: %s\n", err)
+	}
+}
+
+// cachedListing 读取目录任意一种排序方式的列表缓存, 包括已过期的
+func (pcs *BaiduPCS) cachedListing(dir string) (fdl FileDirectoryList) {
+	for _, by := range allOrderBy {
+		for _, order := range allOrder {
+			ok, _ := pcs.diskCache.Get(DiskCacheOpList, listCacheKey(dir, &OrderOptions{By: by, Order: order}), &fdl, true)
+			if ok {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+// deleteListCache 删除目录的全部列表缓存
+func (pcs *BaiduPCS) deleteListCache(dir string) {
+	for _, by := range allOrderBy {
+		for _, order := range allOrder {
+			pcs.diskCache.Delete(DiskCacheOpList, listCacheKey(dir, &OrderOptions{By: by, Order: order}))
+		}
+	}
+}
+
+// invalidateDiskCacheDirs 目录的内容发生变化, 删除目录及其中文件/目录的缓存
+func (pcs *BaiduPCS) invalidateDiskCacheDirs(dirs []string) {
+	if pcs.diskCache == nil {
+		return
+	}
+	for _, dir := range dirs {
+		for _, fd := range pcs.cachedListing(dir) {
+			pcs.diskCache.Delete(DiskCacheOpMeta, fd.Path)
+		}
+		pcs.deleteListCache(dir)
+		pcs.diskCache.Delete(DiskCacheOpMeta, dir)
+	}
+}
+
+// invalidateDiskCacheTree 文件/目录被删除或移动, 删除其自身及所有已缓存的子文件/子目录的缓存
+func (pcs *BaiduPCS) invalidateDiskCacheTree(pcspaths ...string) {
+	if pcs.diskCache == nil {
+		return
+	}
+	for _, pcspath := range pcspaths {
+		pcspath = path.Clean(pcspath)
+		for _, fd := range pcs.cachedListing(pcspath) {
+			if fd.Isdir {
+				pcs.invalidateDiskCacheTree(fd.Path)
+				continue
+			}
+			pcs.diskCache.Delete(DiskCacheOpMeta, fd.Path)
+		}
+		pcs.deleteListCache(pcspath)
+		pcs.diskCache.Delete(DiskCacheOpMeta, pcspath)
+	}
+}
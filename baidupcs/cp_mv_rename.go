@@ -24,8 +24,8 @@ func (pcs *BaiduPCS) Move(cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error) {
 }
 
 func (pcs *BaiduPCS) cpmvOp(op string, cpmvJSON ...*CpMvJSON) (pcsError pcserror.Error) {
-	dataReadCloser, err := pcs.prepareCpMvOp(op, cpmvJSON...)
-	if err != nil {
+	dataReadCloser, pcsError := pcs.prepareCpMvOp(op, cpmvJSON...)
+	if pcsError != nil {
 		return
 	}
 
@@ -38,5 +38,11 @@ func (pcs *BaiduPCS) cpmvOp(op string, cpmvJSON ...*CpMvJSON) (pcsError pcserror
 
 	// 更新缓存
 	pcs.deleteCache((*CpMvJSONList)(unsafe.Pointer(&cpmvJSON)).AllRelatedDir())
+	for _, cj := range cpmvJSON {
+		if op != OperationCopy {
+			pcs.invalidateDiskCacheTree(cj.From)
+		}
+		pcs.invalidateDiskCacheTree(cj.To)
+	}
 	return nil
 }
@@ -0,0 +1,238 @@
+// Package diskcache 持久化在磁盘上的元信息缓存, 每个缓存项保存为一个文件, 进程重启后仍然有效
+package diskcache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Cache 磁盘缓存, 按操作分目录储存
+	Cache struct {
+		dir  string
+		ttls map[string]time.Duration
+		mu   sync.Mutex
+
+		hits   int64
+		misses int64
+		stales int64
+	}
+
+	// OpStat 单个操作的缓存统计
+	OpStat struct {
+		Op      string
+		TTL     time.Duration
+		Entries int   // 缓存项数量
+		Expired int   // 已过期的缓存项数量
+		Size    int64 // 占用的磁盘空间
+	}
+
+	// Stats 缓存统计
+	Stats struct {
+		Dir    string
+		Ops    []OpStat
+		Hits   int64 // 本进程命中次数
+		Misses int64 // 本进程未命中次数
+		Stales int64 // 本进程使用过期数据的次数 (离线模式)
+	}
+
+	entry struct {
+		Key       string          `json:"key"`
+		SavedAt   int64           `json:"saved_at"`
+		ExpiresAt int64           `json:"expires_at"`
+		Data      json.RawMessage `json:"data"`
+	}
+)
+
+// New 初始化磁盘缓存, dir 为缓存目录, ttls 为各个操作的有效期, 有效期不大于 0 的操作不缓存
+func New(dir string, ttls map[string]time.Duration) *Cache {
+	return &Cache{
+		dir:  dir,
+		ttls: ttls,
+	}
+}
+
+// Dir 返回缓存目录
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Enabled 操作是否启用缓存
+func (c *Cache) Enabled(op string) bool {
+	return c != nil && c.ttls[op] > 0
+}
+
+func (c *Cache) entryPath(op, key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, op, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get 读取缓存到 v, allowExpired 为 true 时也返回已过期的数据.
+// ok 表示是否读取成功, expired 表示读取到的数据是否已过期
+func (c *Cache) Get(op, key string, v interface{}, allowExpired bool) (ok, expired bool) {
+	if c == nil {
+		return false, false
+	}
+
+	data, err := os.ReadFile(c.entryPath(op, key))
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return false, false
+	}
+
+	e := entry{}
+	if err = json.Unmarshal(data, &e); err != nil || e.Key != key {
+		atomic.AddInt64(&c.misses, 1)
+		return false, false
+	}
+
+	expired = time.Now().Unix() >= e.ExpiresAt
+	if expired && !allowExpired {
+		atomic.AddInt64(&c.misses, 1)
+		return false, true
+	}
+
+	if err = json.Unmarshal(e.Data, v); err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return false, expired
+	}
+
+	if expired {
+		atomic.AddInt64(&c.stales, 1)
+	} else {
+		atomic.AddInt64(&c.hits, 1)
+	}
+	return true, expired
+}
+
+// Put 写入缓存, 未启用缓存的操作直接忽略
+func (c *Cache) Put(op, key string, v interface{}) error {
+	if !c.Enabled(op) {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	data, err = json.Marshal(&entry{
+		Key:       key,
+		SavedAt:   now.Unix(),
+		ExpiresAt: now.Add(c.ttls[op]).Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	entryPath := c.entryPath(op, key)
+	err = os.MkdirAll(filepath.Dir(entryPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名, 避免读取到不完整的数据
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tmpPath := entryPath + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, entryPath)
+}
+
+// Delete 删除缓存项
+func (c *Cache) Delete(op, key string) {
+	if c == nil {
+		return
+	}
+	os.Remove(c.entryPath(op, key))
+}
+
+// Clear 清空所有缓存
+func (c *Cache) Clear() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return os.RemoveAll(c.dir)
+}
+
+// Stats 统计缓存
+func (c *Cache) Stats() (stats *Stats, err error) {
+	stats = &Stats{
+		Dir:    c.dir,
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Stales: atomic.LoadInt64(&c.stales),
+	}
+
+	ops := map[string]*OpStat{}
+	for op, ttl := range c.ttls {
+		ops[op] = &OpStat{
+			Op:  op,
+			TTL: ttl,
+		}
+	}
+
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		op := d.Name()
+		opStat, ok := ops[op]
+		if !ok {
+			opStat = &OpStat{
+				Op: op,
+			}
+			ops[op] = opStat
+		}
+
+		files, err := os.ReadDir(filepath.Join(c.dir, op))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			opStat.Entries++
+			opStat.Size += info.Size()
+
+			e := entry{}
+			data, err := os.ReadFile(filepath.Join(c.dir, op, f.Name()))
+			if err != nil || json.Unmarshal(data, &e) != nil || now >= e.ExpiresAt {
+				opStat.Expired++
+			}
+		}
+	}
+
+	for _, opStat := range ops {
+		stats.Ops = append(stats.Ops, *opStat)
+	}
+	sort.Slice(stats.Ops, func(i, j int) bool {
+		return stats.Ops[i].Op < stats.Ops[j].Op
+	})
+	return stats, nil
+}
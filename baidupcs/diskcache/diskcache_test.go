@@ -0,0 +1,43 @@
+package diskcache_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/diskcache"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := diskcache.New(t.TempDir(), map[string]time.Duration{
+		"list": time.Minute,
+		"meta": -time.Second,
+	})
+
+	if err := c.Put("list", "/a", []string{"b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	var v []string
+	ok, expired := c.Get("list", "/a", &v, false)
+	if !ok || expired || len(v) != 2 {
+		t.Fatalf("get: ok=%v expired=%v v=%v", ok, expired, v)
+	}
+
+	// 未启用的操作不写入
+	c.Put("meta", "/a", "x")
+	var s string
+	if ok, _ = c.Get("meta", "/a", &s, true); ok {
+		t.Fatal("disabled op should not be cached")
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Ops) != 2 || stats.Ops[0].Op != "list" || stats.Ops[0].Entries != 1 || stats.Hits != 1 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	c.Delete("list", "/a")
+	if ok, _ = c.Get("list", "/a", &v, true); ok {
+		t.Fatal("deleted entry should miss")
+	}
+}
@@ -42,7 +42,7 @@ var (
 	ErrGetRapidUploadInfoCrc32NotEqual    = errors.New("x-bs-meta-crc32 不匹配")
 	ErrGetRapidUploadInfoSliceMD5NotEqual = errors.New("slice-md5 不匹配")
 
-	ErrFileTooLarge = errors.New("文件大于20GB, 无法秒传")
+	ErrFileTooLarge = pcserror.WithKind(pcserror.ErrFileTooLarge, "文件大于20GB, 无法秒传")
 )
 
 func (pcs *BaiduPCS) getLocateDownloadLink(pcspath string) (link string, pcsError pcserror.Error) {
@@ -140,7 +140,7 @@ func (pcs *BaiduPCS) GetRapidUploadInfoByLink(link string, compareRInfo *RapidUp
 		header["Range"] = "bytes=0-" + strconv.FormatInt(SliceMD5Size-1, 10)
 	}
 
-	resp, err := pcs.client.Req(http.MethodGet, link, nil, header)
+	resp, err := pcs.client.ReqWithContext(pcs.Context(), http.MethodGet, link, nil, header)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
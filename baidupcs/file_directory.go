@@ -40,18 +40,20 @@ type (
 
 	// FileDirectory 文件或目录的元信息
 	FileDirectory struct {
-		FsID     int64  // fs_id
-		AppID    int64  // app_id
-		Path     string // 路径
-		Filename string // 文件名 或 目录名
-		Ctime    int64  // 创建日期
-		Mtime    int64  // 修改日期
-		MD5      string // md5 值
+		FsID       int64  // fs_id
+		AppID      int64  // app_id
+		Path       string // 路径
+		Filename   string // 文件名 或 目录名
+		Ctime      int64  // 创建日期
+		Mtime      int64  // 修改日期
+		LocalMtime int64  // 上传时本地文件的修改日期, 未记录时为0
+		LocalCtime int64  // 上传时本地文件的创建日期, 未记录时为0
+		MD5        string // md5 值
 		BlockListJSON
-		Size        int64 // 文件大小 (目录为0)
-		Isdir       bool  // 是否为目录
-		Ifhassubdir bool  // 是否含有子目录 (只对目录有效)
-		PreBase   string  // 真正的base目录
+		Size        int64  // 文件大小 (目录为0)
+		Isdir       bool   // 是否为目录
+		Ifhassubdir bool   // 是否含有子目录 (只对目录有效)
+		PreBase     string // 真正的base目录
 
 		Parent   *FileDirectory    // 父目录信息
 		Children FileDirectoryList // 子目录信息
@@ -62,13 +64,15 @@ type (
 
 	// fdJSON 用于解析远程JSON数据
 	fdJSON struct {
-		FsID     int64  `json:"fs_id"` // fs_id
-		AppID    int64  `json:"app_id"`
-		Path     string `json:"path"`            // 路径
-		Filename string `json:"server_filename"` // 文件名 或 目录名
-		Ctime    int64  `json:"ctime"`           // 创建日期
-		Mtime    int64  `json:"mtime"`           // 修改日期
-		MD5      string `json:"md5"`             // md5 值
+		FsID       int64  `json:"fs_id"` // fs_id
+		AppID      int64  `json:"app_id"`
+		Path       string `json:"path"`            // 路径
+		Filename   string `json:"server_filename"` // 文件名 或 目录名
+		Ctime      int64  `json:"ctime"`           // 创建日期
+		Mtime      int64  `json:"mtime"`           // 修改日期
+		LocalMtime int64  `json:"local_mtime"`     // 上传时本地文件的修改日期
+		LocalCtime int64  `json:"local_ctime"`     // 上传时本地文件的创建日期
+		MD5        string `json:"md5"`             // md5 值
 		BlockListJSON
 		Size           int64 `json:"size"` // 文件大小 (目录为0)
 		IsdirInt       int8  `json:"isdir"`
@@ -112,6 +116,13 @@ func (pcs *BaiduPCS) FilesDirectoriesMeta(path string) (data *FileDirectory, pcs
 		path = PathSeparator
 	}
 
+	if fd, ok := pcs.loadMetaCache(path); ok {
+		return fd, nil
+	}
+	if pcs.offline {
+		return nil, pcs.offlineCacheMissError(OperationFilesDirectoriesMeta)
+	}
+
 	fds, err := pcs.FilesDirectoriesBatchMeta(path)
 	if err != nil {
 		return nil, err
@@ -125,6 +136,7 @@ func (pcs *BaiduPCS) FilesDirectoriesMeta(path string) (data *FileDirectory, pcs
 			Err:       errors.New("未知返回数据"),
 		}
 	}
+	pcs.saveMetaCache(fds[0])
 	return fds[0], nil
 }
 
@@ -157,6 +169,19 @@ func (pcs *BaiduPCS) FilesDirectoriesBatchMeta(paths ...string) (data FileDirect
 
 // FilesDirectoriesList 获取目录下的文件和目录列表
 func (pcs *BaiduPCS) FilesDirectoriesList(path string, options *OrderOptions) (data FileDirectoryList, pcsError pcserror.Error) {
+	if options == nil {
+		options = DefaultOrderOptions
+	}
+	if path == "" {
+		path = PathSeparator
+	}
+	if fdl, ok := pcs.loadListCache(path, options); ok {
+		return fdl, nil
+	}
+	if pcs.offline {
+		return nil, pcs.offlineCacheMissError(OperationFilesDirectoriesList)
+	}
+
 	dataReadCloser, pcsError := pcs.PrepareFilesDirectoriesList(path, options)
 	if pcsError != nil {
 		return nil, pcsError
@@ -177,6 +202,7 @@ func (pcs *BaiduPCS) FilesDirectoriesList(path string, options *OrderOptions) (d
 	jsonData.List.fixMD5()
 
 	data = jsonData.List
+	pcs.saveListCache(path, options, data)
 	return
 }
 
@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/expires"
 	"time"
 )
@@ -13,10 +14,10 @@ func (ph *PanHome) SetSignExpires() {
 }
 
 // CacheSignature 在有效期内返回缓存结果
-func (ph *PanHome) CacheSignature() (sign SignRes, err error) {
+func (ph *PanHome) CacheSignature(ctx context.Context) (sign SignRes, err error) {
 	if ph.signExpires == nil || ph.signExpires.IsExpires() {
 		// 先签名再设置有效期
-		ph.signRes, err = ph.Signature()
+		ph.signRes, err = ph.Signature(ctx)
 		if err != nil { // 空指针与空接口不等价
 			return nil, err
 		}
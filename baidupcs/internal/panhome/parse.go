@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -16,13 +17,13 @@ var (
 	ErrMatchPanHome    = errors.New("网盘首页数据匹配出错")
 )
 
-func (ph *PanHome) getSignInfo() error {
+func (ph *PanHome) getSignInfo(ctx context.Context) error {
 	ph.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
 	u := *panBaiduComURL
 	u.Path = "/disk/home"
-	resp, err := ph.client.Req(http.MethodGet, u.String(), nil, map[string]string{
+	resp, err := ph.client.ReqWithContext(ctx, http.MethodGet, u.String(), nil, map[string]string{
 		"User-Agent": PanHomeUserAgent,
 	})
 	if resp != nil {
@@ -1,6 +1,7 @@
 package panhome
 
 import (
+	"context"
 	"github.com/qjfoidnh/Baidu-Login/bdcrypto"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/netdisksign"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
@@ -26,8 +27,9 @@ func (sr *signRes) Timestamp() string {
 	return sr.timestamp
 }
 
-func (ph *PanHome) Signature() (sign SignRes, err error) {
-	err = ph.getSignInfo()
+// Signature 从网盘首页获取签名, ctx 取消后中断请求
+func (ph *PanHome) Signature(ctx context.Context) (sign SignRes, err error) {
+	err = ph.getSignInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
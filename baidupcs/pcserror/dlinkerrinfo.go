@@ -64,6 +64,11 @@ func (dle *DlinkErrInfo) GetError() error {
 	return dle.Err
 }
 
+// Unwrap 返回原始错误, 用于 errors.Is 和 errors.As
+func (dle *DlinkErrInfo) Unwrap() error {
+	return dle.Err
+}
+
 func (dle *DlinkErrInfo) Error() string {
 	if dle.Operation == "" {
 		if dle.Err != nil {
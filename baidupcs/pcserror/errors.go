@@ -0,0 +1,137 @@
+package pcserror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+)
+
+var (
+	// ErrNotFound 文件或目录不存在
+	ErrNotFound = errors.New("文件或目录不存在")
+	// ErrAlreadyExists 文件或目录已存在
+	ErrAlreadyExists = errors.New("文件或目录已存在")
+	// ErrQuotaExceeded 网盘容量已满
+	ErrQuotaExceeded = errors.New("超出配额, 网盘容量已满")
+	// ErrAuthExpired 登录状态过期或帐号无效
+	ErrAuthExpired = errors.New("登录状态过期, 请尝试重新登录")
+	// ErrRateLimited 请求过于频繁
+	ErrRateLimited = errors.New("请求过于频繁")
+	// ErrFileTooLarge 文件过大
+	ErrFileTooLarge = errors.New("文件过大")
+
+	// permanentErrors 重试也不会成功的错误
+	permanentErrors = []error{ErrNotFound, ErrAlreadyExists, ErrQuotaExceeded, ErrAuthExpired, ErrFileTooLarge}
+
+	// pcsErrKinds PCS 错误代码的分类
+	pcsErrKinds = map[int]error{
+		31045: ErrAuthExpired, // user not exists
+		110:   ErrAuthExpired, // access token invalid or no longer valid
+		111:   ErrAuthExpired, // access token expired
+		31061: ErrAlreadyExists,
+		31066: ErrNotFound,
+		31202: ErrNotFound,
+		31297: ErrNotFound,
+		31112: ErrQuotaExceeded,
+		31034: ErrRateLimited, // hit frequence limit
+	}
+
+	// panErrKinds 网盘网页 api 错误代码的分类
+	panErrKinds = map[int]error{
+		-3:    ErrNotFound,
+		-9:    ErrNotFound,
+		-8:    ErrAlreadyExists,
+		-30:   ErrAlreadyExists,
+		-4:    ErrAuthExpired,
+		-6:    ErrAuthExpired,
+		3:     ErrAuthExpired,
+		9019:  ErrAuthExpired,
+		31034: ErrRateLimited,
+	}
+)
+
+type (
+	kindError struct {
+		kind error
+		msg  string
+	}
+)
+
+// WithKind 返回属于 kind 分类的错误, 错误信息为 msg, 可用 errors.Is(err, kind) 判断
+func WithKind(kind error, msg string) error {
+	return &kindError{
+		kind: kind,
+		msg:  msg,
+	}
+}
+
+func (ke *kindError) Error() string {
+	return ke.msg
+}
+
+func (ke *kindError) Unwrap() error {
+	return ke.kind
+}
+
+// isKind 远端服务器错误代码是否属于 target 分类
+func isKind(target error, errType ErrType, code int, kinds map[int]error) bool {
+	if errType != ErrTypeRemoteError {
+		return false
+	}
+	kind, ok := kinds[code]
+	return ok && kind == target
+}
+
+// IsAuthError 是否为登录状态过期或帐号无效的错误
+func IsAuthError(err error) bool {
+	return errors.Is(err, ErrAuthExpired)
+}
+
+// IsRetryable 判断错误是否值得重试.
+// 网络错误, json 解析错误 (通常是服务器返回了错误页面) 和未知的远端服务器错误可以重试;
+// 文件不存在, 已存在, 配额已满, 登录过期, 文件过大等错误, 以及被取消的操作不重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	for _, kind := range permanentErrors {
+		if errors.Is(err, kind) {
+			return false
+		}
+	}
+
+	var pcsError Error
+	if errors.As(err, &pcsError) {
+		switch pcsError.GetErrType() {
+		case ErrTypeNetError, ErrTypeJSONParseError, ErrTypeRemoteError:
+			return true
+		default:
+			// 内部错误和其他错误, 只有原始错误是临时性的才重试
+			return isTransient(pcsError.GetError())
+		}
+	}
+
+	// 本地文件系统的错误, 可能是权限问题
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return false
+	}
+	return true
+}
+
+// isTransient 是否为临时性的错误
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrRateLimited)
+}
@@ -0,0 +1,35 @@
+package pcserror_test
+
+import (
+	"context"
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	notFound := &pcserror.PCSErrInfo{Operation: "test", ErrType: pcserror.ErrTypeRemoteError, ErrCode: 31066}
+	if !errors.Is(notFound, pcserror.ErrNotFound) || pcserror.IsRetryable(notFound) {
+		t.Fatal("31066 should be a non-retryable ErrNotFound")
+	}
+
+	auth := &pcserror.PanErrorInfo{Operation: "test", ErrType: pcserror.ErrTypeRemoteError, ErrNo: -6}
+	if !pcserror.IsAuthError(auth) || pcserror.IsRetryable(auth) {
+		t.Fatal("-6 should be a non-retryable auth error")
+	}
+
+	limited := &pcserror.PCSErrInfo{Operation: "test", ErrType: pcserror.ErrTypeRemoteError, ErrCode: 31034}
+	if !errors.Is(limited, pcserror.ErrRateLimited) || !pcserror.IsRetryable(limited) {
+		t.Fatal("31034 should be a retryable ErrRateLimited")
+	}
+
+	netErr := &pcserror.PCSErrInfo{Operation: "test", ErrType: pcserror.ErrTypeNetError, Err: context.Canceled}
+	if !errors.Is(netErr, context.Canceled) || pcserror.IsRetryable(netErr) {
+		t.Fatal("canceled request should unwrap and not be retried")
+	}
+
+	tooLarge := &pcserror.PCSErrInfo{Operation: "test", ErrType: pcserror.ErrTypeOthers, Err: pcserror.WithKind(pcserror.ErrFileTooLarge, "too large")}
+	if !errors.Is(tooLarge, pcserror.ErrFileTooLarge) || pcserror.IsRetryable(tooLarge) {
+		t.Fatal("wrapped ErrFileTooLarge should not be retried")
+	}
+}
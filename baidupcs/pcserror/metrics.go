@@ -0,0 +1,10 @@
+package pcserror
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+)
+
+var (
+	// APIErrors 各个操作的出错次数, code 为远程错误代码, 网络错误为 net, json 解析错误为 json
+	APIErrors = metrics.NewCounterVec("baidupcs_api_errors_total", "Number of API errors by operation and error code.", "operation", "code")
+)
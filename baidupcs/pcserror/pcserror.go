@@ -4,6 +4,7 @@ package pcserror
 import (
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/jsonhelper"
 	"io"
+	"strconv"
 )
 
 type (
@@ -83,12 +84,14 @@ func HandleJSONParse(op string, data io.Reader, info interface{}) (pcsError Erro
 
 	if err != nil {
 		errInfo.SetJSONError(err)
+		APIErrors.Inc(op, "json")
 		return errInfo
 	}
 
 	// 设置出错类型为远程错误
 	if errInfo.GetRemoteErrCode() != 0 {
 		errInfo.SetRemoteError()
+		APIErrors.Inc(op, strconv.Itoa(errInfo.GetRemoteErrCode()))
 		return errInfo
 	}
 
@@ -66,6 +66,16 @@ func (pcse *PCSErrInfo) GetError() error {
 	return pcse.Err
 }
 
+// Unwrap 返回原始错误, 用于 errors.Is 和 errors.As
+func (pcse *PCSErrInfo) Unwrap() error {
+	return pcse.Err
+}
+
+// Is 判断远端服务器返回的错误是否属于 target 分类, 例如 pcserror.ErrNotFound
+func (pcse *PCSErrInfo) Is(target error) bool {
+	return isKind(target, pcse.ErrType, pcse.ErrCode, pcsErrKinds)
+}
+
 func (pcse *PCSErrInfo) Error() string {
 	if pcse.Operation == "" {
 		if pcse.Err != nil {
@@ -65,6 +65,16 @@ func (pane *XPanErrorInfo) GetError() error {
 	return pane.Err
 }
 
+// Unwrap 返回原始错误, 用于 errors.Is 和 errors.As
+func (pane *XPanErrorInfo) Unwrap() error {
+	return pane.Err
+}
+
+// Is 判断远端服务器返回的错误是否属于 target 分类, 例如 pcserror.ErrNotFound
+func (pane *XPanErrorInfo) Is(target error) bool {
+	return isKind(target, pane.ErrType, pane.ErrNo, panErrKinds)
+}
+
 func (pane *XPanErrorInfo) Error() string {
 	if pane.Operation == "" {
 		if pane.Err != nil {
@@ -14,6 +14,7 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/netdisksign"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/multipartreader"
 	"github.com/qjfoidnh/baidu-tools/tieba"
 )
@@ -27,6 +28,11 @@ const (
 	reqTypePan
 )
 
+var (
+	apiRequests = metrics.NewCounterVec("baidupcs_api_requests_total", "Number of API requests by operation and http status.", "operation", "status")
+	apiLatency  = metrics.NewHistogramVec("baidupcs_api_request_duration_seconds", "API request latency by operation.", nil, "operation")
+)
+
 func handleRespClose(resp *http.Response) error {
 	if resp != nil {
 		return resp.Body.Close()
@@ -67,8 +73,17 @@ func (pcs *BaiduPCS) sendReqReturnResp(rt reqType, op, method, urlStr string, po
 		}
 	}
 
-	resp, err := pcs.client.Req(method, urlStr, post, header)
+	var (
+		err error
+	)
+	if pcs.offline {
+		err = ErrOffline
+		apiRequests.Inc(op, "error")
+	} else {
+		resp, err = pcs.reqWithRetry(op, method, urlStr, post, header)
+	}
 	if err != nil {
+		pcserror.APIErrors.Inc(op, "net")
 		handleRespClose(resp)
 		switch rt {
 		case reqTypePCS:
@@ -191,7 +206,7 @@ func (pcs *BaiduPCS) PrepareBDStoken() (dataReadCloser io.ReadCloser, pcsError p
 	pcs.lazyInit()
 	pcsURL := pcs.generatePanURL("gettemplatevariable", map[string]string{
 		"clienttype": "0",
-		"app_id": string(pcs.appID),
+		"app_id":     strconv.Itoa(pcs.appID),
 		"fields":     `["bdstoken"]`,
 	})
 	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePCS, OperationGetBDSToken, http.MethodGet, pcsURL.String(), nil, nil)
@@ -327,7 +342,7 @@ func (pcs *BaiduPCS) prepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32
 }
 
 // prepareRapidUploadV2 秒传文件接口2, 不进行文件夹检查
-func (pcs *BaiduPCS) prepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+func (pcs *BaiduPCS) prepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64, fileTime *LocalFileTime) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcsURL := pcs.generatePanURL("precreate", nil)
 	post := map[string]string{
 		"path":       targetPath,
@@ -348,6 +363,7 @@ func (pcs *BaiduPCS) prepareRapidUploadV2(targetPath, contentMD5, sliceMD5, data
 		"block_list": mergeStringList(contentMD5),
 		"mode":       "1",
 	}
+	fileTime.setParams(post)
 	baiduPCSVerbose.Infof("%s URL: %s, Post: %v\n", OperationRapidUpload, pcsURL, post)
 
 	dataReadCloser, pcsError = pcs.sendReqReturnReadCloser(reqTypePan, OperationRapidUpload, http.MethodPost, pcsURL.String(), post, map[string]string{
@@ -370,13 +386,13 @@ func (pcs *BaiduPCS) PrepareRapidUpload(targetPath, contentMD5, sliceMD5, crc32
 }
 
 // PrepareRapidUploadV2 秒传文件新接口, 只返回服务器响应数据和错误信息
-func (pcs *BaiduPCS) PrepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+func (pcs *BaiduPCS) PrepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64, fileTime *LocalFileTime) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
 	pcsError = pcs.CheckIsdir(OperationRapidUpload, targetPath, "", totalSize)
 	if pcsError != nil {
 		return nil, pcsError
 	}
-	return pcs.prepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32, offset, length, totalSize, dataTime)
+	return pcs.prepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32, offset, length, totalSize, dataTime, fileTime)
 }
 
 // PrepareLocateDownload 获取下载链接, 只返回服务器响应数据和错误信息
@@ -428,7 +444,7 @@ func (pcs *BaiduPCS) PrepareLocatePanAPIDownload(fidList ...int64) (dataReadClos
 	pcs.lazyInit()
 	// 初始化
 	var (
-		sign, err = pcs.ph.CacheSignature()
+		sign, err = pcs.ph.CacheSignature(pcs.Context())
 	)
 	if err != nil {
 		return nil, &pcserror.PanErrorInfo{
@@ -452,13 +468,15 @@ func (pcs *BaiduPCS) PrepareLocatePanAPIDownload(fidList ...int64) (dataReadClos
 }
 
 // PrepareUpload 上传单个文件, 只返回服务器响应数据和错误信息（分片上传中的预上传部分）
-func (pcs *BaiduPCS) PrepareUpload(policy string, targetPath string, uploadFunc UploadFunc) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+func (pcs *BaiduPCS) PrepareUpload(policy string, targetPath string, fileTime *LocalFileTime, uploadFunc UploadFunc) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
 
-	pcsURL := pcs.generatePCSURL("file", "upload", map[string]string{
+	params := map[string]string{
 		"path":  targetPath,
 		"ondup": strings.Replace(policy, "rsync", "overwrite", -1),
-	})
+	}
+	fileTime.setParams(params)
+	pcsURL := pcs.generatePCSURL("file", "upload", params)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUpload, pcsURL)
 
 	resp, err := uploadFunc(pcsURL.String(), pcs.client.Jar)
@@ -506,7 +524,7 @@ func (pcs *BaiduPCS) PrepareUploadTmpFile(uploadFunc UploadFunc) (dataReadCloser
 }
 
 // PrepareUploadCreateSuperFile 分片上传—合并分片文件, 只返回服务器响应数据和错误信息
-func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(policy string, checkDir bool, targetPath string, blockList ...string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
+func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(policy string, checkDir bool, targetPath string, fileTime *LocalFileTime, blockList ...string) (dataReadCloser io.ReadCloser, pcsError pcserror.Error) {
 	pcs.lazyInit()
 
 	if checkDir {
@@ -526,10 +544,12 @@ func (pcs *BaiduPCS) PrepareUploadCreateSuperFile(policy string, checkDir bool,
 		panic(err)
 	}
 
-	pcsURL := pcs.generatePCSURL("file", "createsuperfile", map[string]string{
+	params := map[string]string{
 		"path":  targetPath,
 		"ondup": strings.Replace(policy, "rsync", "overwrite", -1),
-	})
+	}
+	fileTime.setParams(params)
+	pcsURL := pcs.generatePCSURL("file", "createsuperfile", params)
 	baiduPCSVerbose.Infof("%s URL: %s\n", OperationUploadCreateSuperFile, pcsURL)
 
 	// 表单上传
@@ -0,0 +1,120 @@
+package baidupcs
+
+import (
+	"context"
+	"math"
+
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/tokenbucket"
+)
+
+const (
+	// APIClassMeta 目录列表, 元信息, 搜索等查询类接口
+	APIClassMeta = "meta"
+	// APIClassFile 删除, 创建目录, 移动, 拷贝, 秒传等文件操作接口
+	APIClassFile = "file"
+	// APIClassLocate 获取下载链接的接口
+	APIClassLocate = "locate"
+	// APIClassShare 分享相关的接口
+	APIClassShare = "share"
+)
+
+var (
+	// APIClasses 所有可限速的接口类别
+	APIClasses = []string{APIClassMeta, APIClassFile, APIClassLocate, APIClassShare}
+
+	// operationAPIClasses 各个操作所属的接口类别, 不在其中的操作不限速
+	operationAPIClasses = map[string]string{
+		OperationGetUK:                 APIClassMeta,
+		OperationGetBDSToken:           APIClassMeta,
+		OperationGetCursorDiff:         APIClassMeta,
+		OperationQuotaInfo:             APIClassMeta,
+		OperationFilesDirectoriesMeta:  APIClassMeta,
+		OperationFilesDirectoriesList:  APIClassMeta,
+		OperationSearch:                APIClassMeta,
+		OperationRecycleList:           APIClassMeta,
+		OperationCloudDlQueryTask:      APIClassMeta,
+		OperationCloudDlListTask:       APIClassMeta,
+		OperationRemove:                APIClassFile,
+		OperationMkdir:                 APIClassFile,
+		OperationRename:                APIClassFile,
+		OperationCopy:                  APIClassFile,
+		OperationMove:                  APIClassFile,
+		OperationRapidUpload:           APIClassFile,
+		OperationUploadCreateSuperFile: APIClassFile,
+		OperationUploadPrecreate:       APIClassFile,
+		OperationRecycleRestore:        APIClassFile,
+		OperationRecycleDelete:         APIClassFile,
+		OperationRecycleClear:          APIClassFile,
+		OperationCloudDlAddTask:        APIClassFile,
+		OperationCloudDlCancelTask:     APIClassFile,
+		OperationCloudDlDeleteTask:     APIClassFile,
+		OperationCloudDlClearTask:      APIClassFile,
+		OperationLocateDownload:        APIClassLocate,
+		OperationLocatePanAPIDownload:  APIClassLocate,
+		OperationGetRapidUploadInfo:    APIClassLocate,
+		OperationShareSet:              APIClassShare,
+		OperationShareCancel:           APIClassShare,
+		OperationShareList:             APIClassShare,
+		OperationShareSURLInfo:         APIClassShare,
+		OperationShareFileSavetoLocal:  APIClassShare,
+		OperationRapidLinkSavetoLocal:  APIClassShare,
+	}
+
+	apiRateLimitWait = metrics.NewCounterVec("baidupcs_api_ratelimit_wait_seconds_total", "Time spent waiting for the client-side API rate limiter by class.", "class")
+	apiRateLimitHits = metrics.NewCounterVec("baidupcs_api_ratelimit_penalties_total", "Number of server rate-limit responses that slowed the client-side limiter, by class.", "class")
+)
+
+// APIClassOf 返回操作所属的接口类别, 不限速的操作返回空字符串
+func APIClassOf(op string) string {
+	return operationAPIClasses[op]
+}
+
+// SetAPIRateLimits 设置各类接口每秒最多的请求数, 不在 limits 中或不大于 0 的类别不限速
+func (pcs *BaiduPCS) SetAPIRateLimits(limits map[string]float64) {
+	buckets := make(map[string]*tokenbucket.Bucket, len(limits))
+	for class, rate := range limits {
+		if rate <= 0 {
+			continue
+		}
+		buckets[class] = tokenbucket.New(rate, int(math.Ceil(rate)))
+	}
+	pcs.rateLimits = buckets
+}
+
+// APIRateLimits 返回各类接口设定的速率和当前 (自适应降速后) 的速率
+func (pcs *BaiduPCS) APIRateLimits() (rates, currents map[string]float64) {
+	rates, currents = map[string]float64{}, map[string]float64{}
+	for class, bucket := range pcs.rateLimits {
+		rates[class], currents[class] = bucket.Rate()
+	}
+	return
+}
+
+// waitAPIRateLimit 请求前等待限速器的令牌
+func (pcs *BaiduPCS) waitAPIRateLimit(ctx context.Context, op string) error {
+	class := APIClassOf(op)
+	bucket := pcs.rateLimits[class]
+	if bucket == nil {
+		return nil
+	}
+	waited, err := bucket.Wait(ctx)
+	if waited > 0 {
+		apiRateLimitWait.Add(waited.Seconds(), class)
+		baiduPCSVerbose.Infof("%s: API 限速 (%s), 等待 %s\n", op, class, waited)
+	}
+	return err
+}
+
+// penalizeAPIRateLimit 服务器返回请求过于频繁, 降低该类接口的速率
+func (pcs *BaiduPCS) penalizeAPIRateLimit(op string) {
+	class := APIClassOf(op)
+	bucket := pcs.rateLimits[class]
+	if bucket == nil {
+		return
+	}
+	bucket.Penalize()
+	apiRateLimitHits.Inc(class)
+	_, current := bucket.Rate()
+	baiduPCSVerbose.Infof("%s: 服务器返回请求过于频繁, %s 类接口降速至 %.2f 次/秒\n", op, class, current)
+}
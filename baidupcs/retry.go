@@ -0,0 +1,197 @@
+package baidupcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester"
+)
+
+const (
+	// maxPeekBodySize 检查错误代码时, 最多读取的响应体大小
+	maxPeekBodySize = 64 * 1024
+)
+
+var (
+	// idempotentOperations 幂等的操作, 网络错误, 服务器临时错误和请求过于频繁时可以重试.
+	// 其他操作 (例如移动, 删除, 上传) 只在请求确定没有发出时才重试
+	idempotentOperations = map[string]bool{
+		OperationGetUK:                true,
+		OperationGetBDSToken:          true,
+		OperationGetCursorDiff:        true,
+		OperationQuotaInfo:            true,
+		OperationFilesDirectoriesMeta: true,
+		OperationFilesDirectoriesList: true,
+		OperationSearch:               true,
+		OperationLocateDownload:       true,
+		OperationLocatePanAPIDownload: true,
+		OperationCloudDlQueryTask:     true,
+		OperationCloudDlListTask:      true,
+		OperationShareList:            true,
+		OperationShareSURLInfo:        true,
+		OperationRecycleList:          true,
+		OperationGetRapidUploadInfo:   true,
+	}
+
+	// operationRetryBudgets 各个操作的最大重试次数, 不能超过全局设置
+	operationRetryBudgets = map[string]int{
+		OperationSearch:               1, // 递归搜索耗时较长
+		OperationLocateDownload:       2, // 下载任务自身也会重试
+		OperationLocatePanAPIDownload: 2,
+	}
+
+	apiRetries = metrics.NewCounterVec("baidupcs_api_retries_total", "Number of retried API requests by operation and reason.", "operation", "reason")
+)
+
+type (
+	errCodeJSON struct {
+		ErrorCode int `json:"error_code"`
+		Errno     int `json:"errno"`
+	}
+)
+
+// SetRetryPolicy 设置请求级别的重试策略, 为 nil 则使用 requester.DefaultRetryPolicy
+func (pcs *BaiduPCS) SetRetryPolicy(policy *requester.RetryPolicy) {
+	pcs.retryPolicy = policy
+}
+
+// RetryPolicy 返回操作 op 的重试策略
+func (pcs *BaiduPCS) RetryPolicy(op string) requester.RetryPolicy {
+	policy := requester.DefaultRetryPolicy
+	if pcs.retryPolicy != nil {
+		policy = *pcs.retryPolicy
+	}
+	if budget, ok := operationRetryBudgets[op]; ok && budget < policy.MaxRetry {
+		policy.MaxRetry = budget
+	}
+	return policy
+}
+
+// IsIdempotentOperation 操作是否为幂等的, 可以安全地重试
+func IsIdempotentOperation(op string) bool {
+	return idempotentOperations[op]
+}
+
+// reqWithRetry 发送请求, 按照重试策略重试临时性的错误
+func (pcs *BaiduPCS) reqWithRetry(op, method, urlStr string, post interface{}, header map[string]string) (resp *http.Response, err error) {
+	var (
+		ctx        = pcs.Context()
+		policy     = pcs.RetryPolicy(op)
+		idempotent = IsIdempotentOperation(op)
+		begin      = time.Now()
+	)
+	if requester.RewindPost(post) != nil {
+		// 请求体不能重复发送, 不重试
+		policy.MaxRetry = 0
+	}
+	for retry := 0; ; retry++ {
+		if err = pcs.waitAPIRateLimit(ctx, op); err != nil {
+			return nil, err
+		}
+
+		startTime := time.Now()
+		resp, err = pcs.client.ReqWithContext(ctx, method, urlStr, post, header)
+		apiLatency.Observe(time.Since(startTime).Seconds(), op)
+		if err != nil {
+			apiRequests.Inc(op, "error")
+		} else {
+			apiRequests.Inc(op, strconv.Itoa(resp.StatusCode))
+		}
+
+		var remoteErr pcserror.Error
+		if err == nil {
+			remoteErr = peekRemoteError(op, resp)
+		}
+		rateLimited := err == nil && (resp.StatusCode == http.StatusTooManyRequests || errors.Is(remoteErr, pcserror.ErrRateLimited))
+		if rateLimited {
+			pcs.penalizeAPIRateLimit(op)
+		}
+
+		if retry >= policy.MaxRetry || ctx.Err() != nil {
+			return
+		}
+
+		var reason string
+		switch {
+		case err != nil && requester.IsRequestNotSent(err):
+			reason = "not_sent"
+		case !idempotent:
+			return
+		case err != nil:
+			reason = "net"
+		case rateLimited:
+			reason = "rate_limited"
+		case requester.IsTransientStatus(resp.StatusCode):
+			reason = strconv.Itoa(resp.StatusCode)
+		case remoteErr != nil && pcserror.IsRetryable(remoteErr):
+			reason = "errno_" + strconv.Itoa(remoteErr.GetRemoteErrCode())
+		default:
+			return
+		}
+
+		wait := policy.Backoff(retry + 1)
+		if retryAfter, ok := requester.RetryAfter(resp); ok && retryAfter > wait {
+			wait = retryAfter
+		}
+		if policy.MaxElapsed > 0 && time.Since(begin)+wait > policy.MaxElapsed {
+			return
+		}
+		handleRespClose(resp)
+		apiRetries.Inc(op, reason)
+		baiduPCSVerbose.Infof("%s: retry %d/%d after %s, reason: %s, err: %v\n", op, retry+1, policy.MaxRetry, wait, reason, err)
+		if err = sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+		if err = requester.RewindPost(post); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// peekRemoteError 读取响应体中的错误代码, 没有错误时返回 nil.
+// 只检查出错的响应和较短的响应, 读取后会恢复 resp.Body
+func peekRemoteError(op string, resp *http.Response) pcserror.Error {
+	if resp.StatusCode < 400 && (resp.ContentLength < 0 || resp.ContentLength > 4096) {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPeekBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		return nil
+	}
+
+	codes := errCodeJSON{}
+	if jsoniter.Unmarshal(data, &codes) != nil {
+		return nil
+	}
+	switch {
+	case codes.ErrorCode != 0:
+		return &pcserror.PCSErrInfo{Operation: op, ErrType: pcserror.ErrTypeRemoteError, ErrCode: codes.ErrorCode}
+	case codes.Errno != 0:
+		return &pcserror.PanErrorInfo{Operation: op, ErrType: pcserror.ErrTypeRemoteError, ErrNo: codes.Errno}
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,59 @@
+package baidupcs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qjfoidnh/BaiduPCS-Go/requester"
+)
+
+func TestReqWithRetry(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		op       string
+		bodies   []string // 依次返回的响应, 之后返回最后一个
+		requests int32
+		body     string
+	}{
+		{"retryable error_code", OperationFilesDirectoriesMeta, []string{`{"error_code":31023,"error_msg":"x"}`, `{"errno":-1}`, `{"errno":0}`}, 3, `{"errno":0}`},
+		{"rate limited", OperationFilesDirectoriesList, []string{`{"errno":31034}`, `{"errno":0}`}, 2, `{"errno":0}`},
+		{"not found", OperationFilesDirectoriesMeta, []string{`{"errno":-9}`, `{"errno":0}`}, 1, `{"errno":-9}`},
+		{"exhausted", OperationGetUK, []string{`{"error_code":31023}`}, 4, `{"error_code":31023}`},
+		{"not idempotent", OperationMove, []string{`{"errno":-1}`, `{"errno":0}`}, 1, `{"errno":-1}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var n int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				k := int(atomic.AddInt32(&n, 1)) - 1
+				if k >= len(c.bodies) {
+					k = len(c.bodies) - 1
+				}
+				io.WriteString(w, c.bodies[k])
+			}))
+			defer srv.Close()
+
+			pcs := NewPCS(0, "")
+			pcs.SetRetryPolicy(&requester.RetryPolicy{
+				MaxRetry:  3,
+				BaseDelay: time.Millisecond,
+				MaxDelay:  time.Millisecond,
+			})
+			resp, err := pcs.reqWithRetry(c.op, http.MethodGet, srv.URL, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if got := atomic.LoadInt32(&n); got != c.requests {
+				t.Errorf("requests = %d, want %d", got, c.requests)
+			}
+			if string(data) != c.body {
+				t.Errorf("body = %s, want %s", data, c.body)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ func (pcs *BaiduPCS) Remove(paths ...string) (pcsError pcserror.Error) {
 
 	// 更新缓存
 	pcs.deleteCache(allRelatedDir(paths))
+	pcs.invalidateDiskCacheTree(paths...)
 	return nil
 }
 
@@ -40,5 +41,6 @@ func (pcs *BaiduPCS) Mkdir(pcspath string) (pcsError pcserror.Error) {
 
 	// 更新缓存
 	pcs.deleteCache([]string{path.Dir(pcspath)})
+	pcs.invalidateDiskCacheTree(pcspath)
 	return
 }
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,6 +45,12 @@ type (
 	// UploadFunc 上传文件处理函数
 	UploadFunc func(uploadURL string, jar http.CookieJar) (resp *http.Response, err error)
 
+	// LocalFileTime 上传时记录的本地文件时间, unix 时间戳
+	LocalFileTime struct {
+		Mtime int64 // 修改时间
+		Ctime int64 // 创建时间
+	}
+
 	// RapidUploadInfo 文件秒传信息
 	RapidUploadInfo struct {
 		Filename      string
@@ -96,6 +103,27 @@ type (
 	}
 )
 
+// NewLocalFileTime 从本地文件时间生成 LocalFileTime
+func NewLocalFileTime(mtime, ctime time.Time) *LocalFileTime {
+	return &LocalFileTime{
+		Mtime: mtime.Unix(),
+		Ctime: ctime.Unix(),
+	}
+}
+
+// setParams 将本地文件时间写入请求参数, lft 为 nil 时不修改
+func (lft *LocalFileTime) setParams(params map[string]string) {
+	if lft == nil {
+		return
+	}
+	if lft.Mtime > 0 {
+		params["local_mtime"] = strconv.FormatInt(lft.Mtime, 10)
+	}
+	if lft.Ctime > 0 {
+		params["local_ctime"] = strconv.FormatInt(lft.Ctime, 10)
+	}
+}
+
 func randomifyMD5(md5 string) string {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	newmd5bytes := []byte(strings.ToLower(md5))
@@ -108,15 +136,16 @@ func randomifyMD5(md5 string) string {
 	return string(newmd5bytes)
 }
 
-// RapidUpload 秒传文件
-func (pcs *BaiduPCS) RapidUpload(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64) (pcsError pcserror.Error) {
+// RapidUpload 秒传文件, fileTime 为 nil 时本地文件时间使用 dataTime
+func (pcs *BaiduPCS) RapidUpload(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64, fileTime *LocalFileTime) (pcsError pcserror.Error) {
 	defer func() {
 		if pcsError == nil {
 			// 更新缓存
 			pcs.deleteCache([]string{path.Dir(targetPath)})
+			pcs.invalidateDiskCacheTree(targetPath)
 		}
 	}()
-	pcsError = pcs.rapidUploadV2(targetPath, strings.ToLower(contentMD5), strings.ToLower(sliceMD5), dataContent, crc32, offset, length, totalSize, dataTime)
+	pcsError = pcs.rapidUploadV2(targetPath, strings.ToLower(contentMD5), strings.ToLower(sliceMD5), dataContent, crc32, offset, length, totalSize, dataTime, fileTime)
 	return
 }
 
@@ -126,6 +155,7 @@ func (pcs *BaiduPCS) APIRapidUpload(targetPath, contentMD5, sliceMD5, crc32 stri
 		if pcsError == nil {
 			// 更新缓存
 			pcs.deleteCache([]string{path.Dir(targetPath)})
+			pcs.invalidateDiskCacheTree(targetPath)
 		}
 	}()
 	pcsError = pcs.rapidUpload(targetPath, strings.ToLower(contentMD5), strings.ToLower(sliceMD5), "", length)
@@ -141,8 +171,8 @@ func (pcs *BaiduPCS) rapidUpload(targetPath, contentMD5, sliceMD5, crc32 string,
 	return pcserror.DecodePanJSONError(OperationRapidUpload, dataReadCloser)
 }
 
-func (pcs *BaiduPCS) rapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32, offset, length, totalSize, dataTime)
+func (pcs *BaiduPCS) rapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32 string, offset, length, totalSize, dataTime int64, fileTime *LocalFileTime) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareRapidUploadV2(targetPath, contentMD5, sliceMD5, dataContent, crc32, offset, length, totalSize, dataTime, fileTime)
 	if pcsError != nil {
 		return
 	}
@@ -168,8 +198,8 @@ func (pcs *BaiduPCS) RapidUploadNoCheckDir(targetPath, contentMD5, sliceMD5, crc
 }
 
 // Upload 上传单个文件
-func (pcs *BaiduPCS) Upload(policy, targetPath string, uploadFunc UploadFunc) (pcsError pcserror.Error, newpath string) {
-	dataReadCloser, pcsError := pcs.PrepareUpload(policy, targetPath, uploadFunc)
+func (pcs *BaiduPCS) Upload(policy, targetPath string, fileTime *LocalFileTime, uploadFunc UploadFunc) (pcsError pcserror.Error, newpath string) {
+	dataReadCloser, pcsError := pcs.PrepareUpload(policy, targetPath, fileTime, uploadFunc)
 	if pcsError != nil {
 		return pcsError, ""
 	}
@@ -194,6 +224,7 @@ func (pcs *BaiduPCS) Upload(policy, targetPath string, uploadFunc UploadFunc) (p
 
 	// 更新缓存
 	pcs.deleteCache([]string{path.Dir(targetPath)})
+	pcs.invalidateDiskCacheTree(targetPath)
 	return nil, jsonData.Path
 }
 
@@ -227,8 +258,8 @@ func (pcs *BaiduPCS) UploadTmpFile(uploadFunc UploadFunc) (md5 string, pcsError
 }
 
 // UploadCreateSuperFile 分片上传—合并分片文件
-func (pcs *BaiduPCS) UploadCreateSuperFile(policy string, checkDir bool, targetPath string, blockList ...string) (pcsError pcserror.Error) {
-	dataReadCloser, pcsError := pcs.PrepareUploadCreateSuperFile(policy, checkDir, targetPath, blockList...)
+func (pcs *BaiduPCS) UploadCreateSuperFile(policy string, checkDir bool, targetPath string, fileTime *LocalFileTime, blockList ...string) (pcsError pcserror.Error) {
+	dataReadCloser, pcsError := pcs.PrepareUploadCreateSuperFile(policy, checkDir, targetPath, fileTime, blockList...)
 	if pcsError != nil {
 		return pcsError
 	}
@@ -242,6 +273,7 @@ func (pcs *BaiduPCS) UploadCreateSuperFile(policy string, checkDir bool, targetP
 
 	// 更新缓存, targetPath取了dir所以不受重命名策略影响
 	pcs.deleteCache([]string{path.Dir(targetPath)})
+	pcs.invalidateDiskCacheTree(targetPath)
 	return nil
 }
 
@@ -0,0 +1,17 @@
+package injector
+
+import (
+	"errors"
+)
+
+// errAliasExpanded 命令行中的别名已在 Before 中展开并执行, 用于跳过之后的命令分派
+var errAliasExpanded = errors.New("alias expanded")
+
+// Run 运行程序, 别名展开执行后不返回错误
+func (app *App) Run(arguments []string) error {
+	err := app.CliApp.Run(arguments)
+	if err == errAliasExpanded {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package injector
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/urfave/cli"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testCliApp 生成命令行程序, 除 cfg 外的依赖都为零值, ls 命令记录收到的参数
+func testCliApp(cfg *pcsconfig.PCSConfig, calls *[]string) *App {
+	fn := reflect.ValueOf(provideCliApp)
+	in := make([]reflect.Value, fn.Type().NumIn())
+	for i := range in {
+		in[i] = reflect.Zero(fn.Type().In(i))
+	}
+	in[0] = reflect.ValueOf(cfg)
+	cliApp := fn.Call(in)[0].Interface().(*cli.App)
+	for i := range cliApp.Commands {
+		if cliApp.Commands[i].Name == "ls" {
+			cliApp.Commands[i].Action = func(c *cli.Context) error {
+				*calls = append(*calls, strconv.FormatBool(c.Bool("l"))+" "+c.Args().First())
+				return nil
+			}
+		}
+	}
+	return &App{CliApp: cliApp}
+}
+
+func TestCliAlias(t *testing.T) {
+	cfg := pcsconfig.NewConfig("")
+	cfg.SetAlias("ls", "ls -l")
+	cfg.SetAlias("two", "ls /a; ls /b")
+	cfg.SetAlias("self", "self x")
+
+	var calls []string
+	app := testCliApp(cfg, &calls)
+	args := os.Args
+	defer func() { os.Args = args }()
+	for _, c := range []struct {
+		args []string
+		want []string
+	}{
+		// 别名覆盖同名的命令, 别名中的同名命令不再展开
+		{[]string{"ls", "/"}, []string{"true /"}},
+		{[]string{"two"}, []string{"true /a", "true /b"}},
+		// 展开后不是命令, 不会无限展开
+		{[]string{"self"}, nil},
+	} {
+		calls = nil
+		os.Args = append([]string{"BaiduPCS-Go"}, c.args...)
+		if err := app.Run(os.Args); err != nil {
+			t.Errorf("%q: %s", c.args, err)
+		}
+		if !reflect.DeepEqual(calls, c.want) {
+			t.Errorf("%q: calls %q, want %q", c.args, calls, c.want)
+		}
+	}
+}
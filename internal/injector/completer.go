@@ -0,0 +1,409 @@
+package injector
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcscommand"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner/args"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/escaper"
+	"github.com/urfave/cli"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+type (
+	// completeKind 参数的补全类型
+	completeKind int
+
+	// shellCompleter 交互模式下的自动补全, 根据命令的选项区分本地路径和网盘路径
+	shellCompleter struct {
+		app *cli.App
+		cfg *pcsconfig.PCSConfig
+		pcs *baidupcs.BaiduPCS
+	}
+)
+
+const (
+	completeNone completeKind = iota
+	completeLocal
+	completeRemote
+)
+
+var (
+	// remoteCompleteCommands 参数为网盘路径的命令
+	remoteCompleteCommands = []string{
+		"cat-member", "cd", "cp", "download", "export", "fixmd5", "locate", "ls", "meta", "mkdir", "mv", "rapidupload", "rename", "rm", "setastoken", "share", "transfer", "tree",
+	}
+	// localCompleteCommands 参数为本地路径的命令
+	localCompleteCommands = []string{
+		"lcd", "lls", "lmkdir",
+	}
+	// localPathFlags 值为本地路径的选项
+	localPathFlags = map[string][]string{
+		"download":   {"saveto"},
+		"upload":     {"move-to"},
+		"config set": {"savedir"},
+	}
+)
+
+// pcsRuneFunc 补全的路径中需要转义的字符
+func pcsRuneFunc(r rune) bool {
+	switch r {
+	case '\'', '"':
+		return true
+	}
+	return unicode.IsSpace(r)
+}
+
+// flagNames 返回选项的所有名称
+func flagNames(f cli.Flag) []string {
+	names := strings.Split(f.GetName(), ",")
+	for k := range names {
+		names[k] = strings.TrimSpace(names[k])
+	}
+	return names
+}
+
+// flagTakesValue 选项是否需要值
+func flagTakesValue(f cli.Flag) bool {
+	switch f.(type) {
+	case cli.BoolFlag, cli.BoolTFlag, *cli.BoolFlag, *cli.BoolTFlag:
+		return false
+	}
+	return true
+}
+
+// lookupFlag 查找命令的选项, arg 为 -name 或 --name 的形式
+func lookupFlag(cmd *cli.Command, arg string) (cli.Flag, string) {
+	name := strings.TrimLeft(arg, "-")
+	if name == "" || name == arg {
+		return nil, ""
+	}
+	for _, f := range cmd.Flags {
+		for _, n := range flagNames(f) {
+			if n == name {
+				return f, flagNames(f)[0]
+			}
+		}
+	}
+	return nil, ""
+}
+
+// lookupCommand 按名称或别名查找命令
+func lookupCommand(cmds []cli.Command, name string) *cli.Command {
+	for k := range cmds {
+		if cmds[k].HasName(name) {
+			return &cmds[k]
+		}
+	}
+	return nil
+}
+
+// escapedHead 返回正在补全的参数之前的部分
+func escapedHead(lineArgs []string) string {
+	if len(lineArgs) == 0 {
+		return ""
+	}
+	head := make([]string, len(lineArgs))
+	copy(head, lineArgs)
+	escaper.EscapeStringsByRuneFunc(head, unicode.IsSpace)
+	return strings.Join(head, " ") + " "
+}
+
+// Complete 返回 line 的补全结果
+func (sc *shellCompleter) Complete(line string) (s []string) {
+	var (
+		lineArgs = args.Parse(line)
+		numArgs  = len(lineArgs)
+		closed   = strings.LastIndex(line, " ") == len(line)-1
+	)
+
+	for _, cmd := range sc.app.Commands {
+		for _, name := range cmd.Names() {
+			if !strings.HasPrefix(name, line) {
+				continue
+			}
+			s = append(s, name+" ")
+		}
+	}
+
+	if sc.cfg != nil {
+		for _, name := range sc.cfg.AliasNames() {
+			if strings.HasPrefix(name, line) {
+				s = append(s, name+" ")
+			}
+		}
+	}
+
+	switch numArgs {
+	case 0:
+		return
+	case 1:
+		if !closed {
+			return
+		}
+	}
+
+	cmd := lookupCommand(sc.app.Commands, lineArgs[0])
+	if cmd == nil {
+		return append(s, sc.completeAlias(line, lineArgs)...)
+	}
+
+	var (
+		cmdPath  = cmd.Name
+		rest     = lineArgs[1:]
+		word     string
+		headArgs = lineArgs
+	)
+	if !closed {
+		word = lineArgs[numArgs-1]
+		headArgs = lineArgs[:numArgs-1]
+	}
+
+	// 子命令
+	for len(cmd.Subcommands) > 0 {
+		if len(rest) == 0 || len(rest) == 1 && !closed {
+			for _, sub := range cmd.Subcommands {
+				for _, name := range sub.Names() {
+					if strings.HasPrefix(name, word) {
+						s = append(s, escapedHead(headArgs)+name+" ")
+					}
+				}
+			}
+			return
+		}
+		sub := lookupCommand(cmd.Subcommands, rest[0])
+		if sub == nil {
+			break
+		}
+		cmd = sub
+		cmdPath += " " + sub.Name
+		rest = rest[1:]
+	}
+
+	prev := rest
+	if !closed {
+		prev = rest[:len(rest)-1]
+	}
+
+	// 选项名称, config set 的选项即为配置项
+	if strings.HasPrefix(word, "-") {
+		dash := "-"
+		if strings.HasPrefix(word, "--") {
+			dash = "--"
+		}
+		for _, f := range cmd.Flags {
+			for _, name := range flagNames(f) {
+				if strings.HasPrefix(dash+name, word) {
+					s = append(s, escapedHead(headArgs)+dash+name+" ")
+				}
+			}
+		}
+		return
+	}
+
+	switch sc.argKind(cmd, cmdPath, prev, word) {
+	case completeLocal:
+		s = append(s, sc.completeLocal(line, headArgs, word)...)
+	case completeRemote:
+		s = append(s, sc.completeRemote(line, lineArgs, closed)...)
+	}
+	return
+}
+
+// completeAlias 按别名展开后的命令补全, 结果中的命令部分还原为别名
+func (sc *shellCompleter) completeAlias(line string, lineArgs []string) (s []string) {
+	if sc.cfg == nil {
+		return
+	}
+	aliasArgs := sc.cfg.AliasCommandArgs(lineArgs[0])
+	if len(aliasArgs) == 0 || lookupCommand(sc.app.Commands, aliasArgs[0]) == nil {
+		return
+	}
+
+	var (
+		i        = strings.Index(line, lineArgs[0]) + len(lineArgs[0])
+		expanded = strings.TrimSuffix(escapedHead(aliasArgs), " ")
+	)
+	for _, candidate := range sc.Complete(expanded + line[i:]) {
+		if strings.HasPrefix(candidate, expanded) {
+			s = append(s, lineArgs[0]+strings.TrimPrefix(candidate, expanded))
+		}
+	}
+	return
+}
+
+// argKind 判断正在补全的参数是本地路径还是网盘路径
+func (sc *shellCompleter) argKind(cmd *cli.Command, cmdPath string, prev []string, word string) completeKind {
+	// 上一个参数是需要值的选项
+	if n := len(prev); n > 0 && !strings.Contains(prev[n-1], "=") {
+		if f, name := lookupFlag(cmd, prev[n-1]); f != nil && flagTakesValue(f) {
+			if pcsutil.ContainsString(localPathFlags[cmdPath], name) {
+				return completeLocal
+			}
+			return completeNone
+		}
+	}
+
+	// 位置参数的序号
+	pos := 0
+	for i := 0; i < len(prev); i++ {
+		if f, _ := lookupFlag(cmd, prev[i]); f != nil {
+			if flagTakesValue(f) && !strings.Contains(prev[i], "=") {
+				i++
+			}
+			continue
+		}
+		pos++
+	}
+
+	switch {
+	case pcsutil.ContainsString(localCompleteCommands, cmdPath):
+		return completeLocal
+	case cmdPath == "upload":
+		// upload 的最后一个参数为网盘路径, 其余为本地路径
+		if pos == 0 || word != "" && len(sc.completeLocal("", nil, word)) > 0 {
+			return completeLocal
+		}
+		return completeRemote
+	case pcsutil.ContainsString(remoteCompleteCommands, cmdPath):
+		return completeRemote
+	}
+	return completeNone
+}
+
+// completeLocal 补全本地路径
+func (sc *shellCompleter) completeLocal(line string, headArgs []string, word string) (s []string) {
+	switch {
+	case word == "." || strings.HasSuffix(word, "/."), word == ".." || strings.HasSuffix(word, "/.."):
+		return []string{line + "/"}
+	case word == "~":
+		return []string{line + "/"}
+	}
+
+	var dir, base string
+	if strings.HasSuffix(word, "/") || strings.HasSuffix(word, string(os.PathSeparator)) {
+		dir = word
+	} else {
+		dir, base = filepath.Split(word)
+	}
+
+	readDir := pcscommand.ExpandLocalPath(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return
+	}
+
+	head := escapedHead(headArgs)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		// 未输入 . 时不显示隐藏文件
+		if base == "" && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		appendLine := head + escaper.EscapeByRuneFunc(dir+name, pcsRuneFunc)
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(readDir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if isDir {
+			s = append(s, appendLine+"/")
+			continue
+		}
+		s = append(s, appendLine+" ")
+	}
+	return
+}
+
+// completeRemote 补全网盘路径
+func (sc *shellCompleter) completeRemote(line string, lineArgs []string, closed bool) (s []string) {
+	var (
+		numArgs    = len(lineArgs)
+		activeUser = sc.cfg.ActiveUser()
+		runeFunc   = unicode.IsSpace
+		targetPath string
+	)
+	lineArgs = append([]string(nil), lineArgs...)
+
+	if !closed {
+		targetPath = lineArgs[numArgs-1]
+		escaper.EscapeStringsByRuneFunc(lineArgs[:numArgs-1], runeFunc)
+	} else {
+		escaper.EscapeStringsByRuneFunc(lineArgs, runeFunc)
+	}
+
+	switch {
+	case targetPath == "." || strings.HasSuffix(targetPath, "/."):
+		s = append(s, line+"/")
+		return
+	case targetPath == ".." || strings.HasSuffix(targetPath, "/.."):
+		s = append(s, line+"/")
+		return
+	}
+
+	var (
+		targetDir string
+		isAbs     = path.IsAbs(targetPath)
+		isDir     = strings.LastIndex(targetPath, "/") == len(targetPath)-1
+	)
+
+	if isAbs {
+		targetDir = path.Dir(targetPath)
+	} else {
+		targetDir = path.Join(activeUser.Workdir, targetPath)
+		if !isDir {
+			targetDir = path.Dir(targetDir)
+		}
+	}
+
+	files, err := sc.pcs.CacheFilesDirectoriesList(targetDir, baidupcs.DefaultOrderOptions)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+
+		var (
+			appendLine string
+		)
+
+		if !closed {
+			if !strings.HasPrefix(file.Path, path.Clean(path.Join(targetDir, path.Base(targetPath)))) {
+				if path.Base(targetDir) == path.Base(targetPath) {
+					appendLine = strings.Join(append(lineArgs[:numArgs-1], escaper.EscapeByRuneFunc(path.Join(targetPath, file.Filename), pcsRuneFunc)), " ")
+					goto handle
+				}
+				continue
+			}
+			appendLine = strings.Join(append(lineArgs[:numArgs-1], escaper.EscapeByRuneFunc(path.Clean(path.Join(path.Dir(targetPath), file.Filename)), pcsRuneFunc)), " ")
+			goto handle
+		}
+
+		appendLine = strings.Join(append(lineArgs, escaper.EscapeByRuneFunc(file.Filename, pcsRuneFunc)), " ")
+	handle:
+		if file.Isdir {
+			s = append(s, appendLine+"/")
+			continue
+		}
+		s = append(s, appendLine+" ")
+		continue
+	}
+
+	return
+}
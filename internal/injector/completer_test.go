@@ -0,0 +1,116 @@
+package injector
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/urfave/cli"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testCompleter(t *testing.T) *shellCompleter {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "photos"), 0755)
+	os.WriteFile(filepath.Join(dir, "plan.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	app := cli.NewApp()
+	app.Commands = []cli.Command{
+		{
+			Name:    "download",
+			Aliases: []string{"d"},
+			Flags:   []cli.Flag{cli.StringFlag{Name: "saveto"}, cli.IntFlag{Name: "p"}, cli.BoolFlag{Name: "ow"}},
+		},
+		{
+			Name:    "upload",
+			Aliases: []string{"u"},
+			Flags:   []cli.Flag{cli.IntFlag{Name: "p"}, cli.BoolFlag{Name: "norapid"}},
+		},
+		{
+			Name: "config",
+			Subcommands: []cli.Command{
+				{Name: "set", Flags: []cli.Flag{cli.StringFlag{Name: "savedir"}, cli.IntFlag{Name: "max_parallel"}, cli.IntFlag{Name: "max_upload_parallel"}}},
+				{Name: "reset"},
+			},
+		},
+		{Name: "lls"},
+	}
+	cfg := pcsconfig.NewConfig("")
+	cfg.SetAlias("up", "upload -p 8")
+	cfg.SetAlias("dl", `download --saveto "$1"`)
+	return &shellCompleter{app: app, cfg: cfg}
+}
+
+func TestShellCompleterLocal(t *testing.T) {
+	sc := testCompleter(t)
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{"lls p", []string{"lls photos/", "lls plan.txt "}},
+		{"lls .h", []string{"lls .hidden "}},
+		{"upload ph", []string{"upload photos/"}},
+		{"u -p 2 pl", []string{"u -p 2 plan.txt "}},
+		{"download -saveto ph", []string{"download -saveto photos/"}},
+		{"download -p ", nil},
+		{"config set -savedir pho", []string{"config set -savedir photos/"}},
+		{"config set -max_p", []string{"config set -max_parallel "}},
+		{"config set --max_u", []string{"config set --max_upload_parallel "}},
+		{"config ", []string{"config set ", "config reset "}},
+		{"config re", []string{"config reset "}},
+		{"download --sa", []string{"download --saveto "}},
+		{"u", []string{"u ", "up ", "upload "}},
+		{"up ph", []string{"up photos/"}},
+		{"up --nor", []string{"up --norapid "}},
+		{"dl ", []string{"dl photos/", "dl plan.txt "}},
+	}
+	for _, c := range cases {
+		got := sc.Complete(c.line)
+		sort.Strings(got)
+		sort.Strings(c.want)
+		if len(got) == 0 && len(c.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Complete(%q) = %q, want %q", c.line, got, c.want)
+		}
+	}
+}
+
+func TestShellCompleterArgKind(t *testing.T) {
+	sc := testCompleter(t)
+	upload := lookupCommand(sc.app.Commands, "upload")
+	download := lookupCommand(sc.app.Commands, "download")
+
+	cases := []struct {
+		cmd     *cli.Command
+		cmdPath string
+		prev    []string
+		word    string
+		want    completeKind
+	}{
+		{upload, "upload", nil, "", completeLocal},
+		{upload, "upload", []string{"plan.txt"}, "", completeRemote},
+		{upload, "upload", []string{"plan.txt"}, "pho", completeLocal},
+		{upload, "upload", []string{"-p", "2", "plan.txt"}, "/apps", completeRemote},
+		{download, "download", []string{"-p"}, "", completeNone},
+		{download, "download", []string{"-saveto"}, "", completeLocal},
+		{download, "download", []string{"-ow"}, "", completeRemote},
+	}
+	for _, c := range cases {
+		if got := sc.argKind(c.cmd, c.cmdPath, c.prev, c.word); got != c.want {
+			t.Errorf("argKind(%s, %q, %q) = %d, want %d", c.cmdPath, c.prev, c.word, got, c.want)
+		}
+	}
+}
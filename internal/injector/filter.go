@@ -0,0 +1,60 @@
+package injector
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
+	"github.com/urfave/cli"
+	"time"
+)
+
+// fileFilterFlags upload, download, export 和 rename 共用的文件过滤选项
+var fileFilterFlags = []cli.Flag{
+	cli.StringSliceFlag{Name: "include", Usage: "只包含匹配的文件, 可多次指定, 支持通配符和 **, 含 / 时匹配相对路径"},
+	cli.StringSliceFlag{Name: "exclude", Usage: "排除匹配的文件和目录, 可多次指定, 支持通配符和 **, 含 / 时匹配相对路径"},
+	cli.StringFlag{Name: "exclude-from", Usage: "从文件读取排除规则, 使用 gitignore 语法"},
+	cli.StringFlag{Name: "min-size", Usage: "只包含不小于此大小的文件, 如 1KB"},
+	cli.StringFlag{Name: "max-size", Usage: "只包含不大于此大小的文件, 如 100MB"},
+	cli.StringFlag{Name: "newer-than", Usage: "只包含修改时间晚于此时间的文件, 如 2006-01-02, 12h, 7d"},
+	cli.StringFlag{Name: "older-than", Usage: "只包含修改时间早于此时间的文件, 如 2006-01-02, 12h, 7d"},
+	cli.BoolFlag{Name: "no-hidden", Usage: "排除隐藏的文件和目录 (以 . 开头)"},
+}
+
+// newFileFilter 根据命令行选项生成文件过滤规则
+func newFileFilter(c *cli.Context) (filter *filefilter.Filter, err error) {
+	filter = &filefilter.Filter{
+		Includes:      c.StringSlice("include"),
+		Excludes:      c.StringSlice("exclude"),
+		ExcludeHidden: c.Bool("no-hidden"),
+	}
+
+	if name := c.String("exclude-from"); name != "" {
+		if err = filter.LoadIgnoreFile(name); err != nil {
+			return nil, fmt.Errorf("读取排除规则文件错误: %s", err)
+		}
+	}
+
+	if s := c.String("min-size"); s != "" {
+		if filter.MinSize, err = converter.ParseFileSizeStr(s); err != nil {
+			return nil, fmt.Errorf("min-size 设置错误: %s", err)
+		}
+	}
+	if s := c.String("max-size"); s != "" {
+		if filter.MaxSize, err = converter.ParseFileSizeStr(s); err != nil {
+			return nil, fmt.Errorf("max-size 设置错误: %s", err)
+		}
+	}
+
+	now := time.Now()
+	if s := c.String("newer-than"); s != "" {
+		if filter.NewerThan, err = filefilter.ParseTime(s, now); err != nil {
+			return nil, fmt.Errorf("newer-than 设置错误: %s", err)
+		}
+	}
+	if s := c.String("older-than"); s != "" {
+		if filter.OlderThan, err = filefilter.ParseTime(s, now); err != nil {
+			return nil, fmt.Errorf("older-than 设置错误: %s", err)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,22 @@
+package injector
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner/args"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// parseShellLine 解析交互模式的命令行, 末尾单独的, 不在引号内的 & 表示在后台执行
+func parseShellLine(commandLine string) (cmdArgs []string, background bool) {
+	cmdArgs = args.Parse(commandLine)
+	if len(cmdArgs) == 0 || cmdArgs[len(cmdArgs)-1] != "&" {
+		return cmdArgs, false
+	}
+	// 引号内的 & (例如 "&" 或 ""&) 在原始命令行中前面不是空白
+	rest := strings.TrimSuffix(strings.TrimRightFunc(commandLine, unicode.IsSpace), "&")
+	if last, _ := utf8.DecodeLastRuneInString(rest); rest != "" && !unicode.IsSpace(last) {
+		return cmdArgs, false
+	}
+	return cmdArgs[:len(cmdArgs)-1], true
+}
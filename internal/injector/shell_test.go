@@ -0,0 +1,28 @@
+package injector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShellLine(t *testing.T) {
+	for _, c := range []struct {
+		line       string
+		args       []string
+		background bool
+	}{
+		{`download /a &`, []string{"download", "/a"}, true},
+		{"upload a /b\t&  ", []string{"upload", "a", "/b"}, true},
+		{`download /a`, []string{"download", "/a"}, false},
+		{`download "/docs/R&"`, []string{"download", "/docs/R&"}, false},
+		{`download /docs/R&`, []string{"download", "/docs/R&"}, false},
+		{`download "&"`, []string{"download", "&"}, false},
+		{`download ""&`, []string{"download", "&"}, false},
+		{`&`, []string{}, true},
+	} {
+		args, background := parseShellLine(c.line)
+		if !reflect.DeepEqual(args, c.args) || background != c.background {
+			t.Errorf("parseShellLine(%q) = %q, %t, want %q, %t", c.line, args, background, c.args, c.background)
+		}
+	}
+}
@@ -7,6 +7,7 @@
 package injector
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/wire"
 	"github.com/peterh/liner"
@@ -15,14 +16,16 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcscommand"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsdownload"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcshook"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsupdate"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner"
-	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner/args"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
-	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/escaper"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/renamer"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/harlog"
 	"github.com/urfave/cli"
 	"os"
 	"path"
@@ -31,7 +34,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"unicode"
 )
 
 // Injectors from wire.go:
@@ -76,7 +78,14 @@ func InitializeApp() (*App, func(), error) {
 	updateAction := RunUpdateCommand()
 	toolAction := RunToolCommand()
 	runAction := RunRunCommand()
-	app := provideCliApp(pcsConfig, pcsLiner, baiduPCS, quotaAction, configAction, configSetAction, configResetAction, lsAction, cdAction, pwdAction, metaAction, whoAction, mkdirAction, rmAction, cpAction, mvAction, loginAction, downloadAction, uploadAction, locateAction, shareAction, transferAction, treeAction, exportAction, rapidUploadAction, logoutAction, loglistAction, importAction, updateAction, toolAction, runAction)
+	cacheAction := RunCacheCommand()
+	jobsAction := RunJobsCommand()
+	jobControlAction := RunJobControlCommand()
+	localAction := RunLocalCommand()
+	aliasAction := RunAliasCommand()
+	catMemberAction := RunCatMemberCommand()
+	renameAction := RunRenameCommand()
+	app := provideCliApp(pcsConfig, pcsLiner, baiduPCS, quotaAction, configAction, configSetAction, configResetAction, lsAction, cdAction, pwdAction, metaAction, whoAction, mkdirAction, rmAction, cpAction, mvAction, loginAction, downloadAction, uploadAction, locateAction, shareAction, transferAction, treeAction, exportAction, rapidUploadAction, logoutAction, loglistAction, importAction, updateAction, toolAction, runAction, cacheAction, jobsAction, jobControlAction, localAction, aliasAction, catMemberAction, renameAction)
 	injectorApp := &App{
 		CliApp:            app,
 		Config:            pcsConfig,
@@ -111,6 +120,13 @@ func InitializeApp() (*App, func(), error) {
 		UpdateAction:      updateAction,
 		ToolAction:        toolAction,
 		RunAction:         runAction,
+		CacheAction:       cacheAction,
+		JobsAction:        jobsAction,
+		JobControlAction:  jobControlAction,
+		LocalAction:       localAction,
+		AliasAction:       aliasAction,
+		CatMemberAction:   catMemberAction,
+		RenameAction:      renameAction,
 	}
 	return injectorApp, func() {
 	}, nil
@@ -176,6 +192,20 @@ type ToolAction cli.ActionFunc // Placeholder
 
 type RunAction cli.ActionFunc // Placeholder
 
+type RenameAction cli.ActionFunc
+
+type CatMemberAction cli.ActionFunc
+
+type AliasAction cli.ActionFunc
+
+type LocalAction cli.ActionFunc
+
+type JobControlAction cli.ActionFunc
+
+type JobsAction cli.ActionFunc
+
+type CacheAction cli.ActionFunc
+
 // RunQuotaCommand provides the action for the 'quota' command.
 // It depends on the BaiduPCS instance.
 func RunQuotaCommand(pcs *baidupcs.BaiduPCS) QuotaAction {
@@ -271,6 +301,18 @@ func RunConfigSetAction(cfg *pcsconfig.PCSConfig) ConfigSetAction {
 				return nil
 			}
 		}
+		if c.IsSet("download_rate_schedule") {
+			if err := cfg.SetDownloadRateScheduleByStr(c.String("download_rate_schedule")); err != nil {
+				fmt.Printf("设置 download_rate_schedule 错误: %s\n", err)
+				return nil
+			}
+		}
+		if c.IsSet("upload_rate_schedule") {
+			if err := cfg.SetUploadRateScheduleByStr(c.String("upload_rate_schedule")); err != nil {
+				fmt.Printf("设置 upload_rate_schedule 错误: %s\n", err)
+				return nil
+			}
+		}
 		if c.IsSet("savedir") {
 			cfg.SaveDir = c.String("savedir")
 		}
@@ -280,6 +322,49 @@ func RunConfigSetAction(cfg *pcsconfig.PCSConfig) ConfigSetAction {
 		if c.IsSet("local_addrs") {
 			cfg.SetLocalAddrs(c.String("local_addrs"))
 		}
+		if c.IsSet("mirror_blocklist") {
+			cfg.SetMirrorBlocklist(c.String("mirror_blocklist"))
+		}
+		if c.IsSet("meta_cache_ttl") {
+			if err := cfg.SetMetaCacheTTLByStr(c.String("meta_cache_ttl")); err != nil {
+				fmt.Printf("设置 meta_cache_ttl 错误: %s\n", err)
+				return nil
+			}
+		}
+		if c.IsSet("api_max_retry") {
+			cfg.SetAPIMaxRetry(c.Int("api_max_retry"))
+		}
+		if c.IsSet("api_rate_limit") {
+			if err := cfg.SetAPIRateLimitByStr(c.String("api_rate_limit")); err != nil {
+				fmt.Printf("设置 api_rate_limit 错误: %s\n", err)
+				return nil
+			}
+		}
+		if c.IsSet("hook_command") {
+			cfg.SetHookCommand(c.String("hook_command"))
+		}
+		if c.IsSet("hook_url") {
+			if s := strings.TrimSpace(c.String("hook_url")); s != "" {
+				if err := pcshook.CheckURL(s); err != nil {
+					fmt.Printf("设置 hook_url 错误: %s\n", err)
+					return nil
+				}
+			}
+			cfg.SetHookURL(c.String("hook_url"))
+		}
+		if c.IsSet("hook_events") {
+			if _, err := pcshook.ParseEvents(c.String("hook_events")); err != nil {
+				fmt.Printf("设置 hook_events 错误: %s\n", err)
+				return nil
+			}
+			cfg.SetHookEvents(c.String("hook_events"))
+		}
+		if c.IsSet("hook_timeout") {
+			if err := cfg.SetHookTimeoutByStr(c.String("hook_timeout")); err != nil {
+				fmt.Printf("设置 hook_timeout 错误: %s\n", err)
+				return nil
+			}
+		}
 
 		err := cfg.Save()
 		if err != nil {
@@ -424,7 +509,11 @@ func RunCpCommand(pcs *baidupcs.BaiduPCS) CpAction {
 			cli.ShowCommandHelp(c, c.Command.Name)
 			return nil
 		}
-		pcscommand.RunCopy(c.Args()...)
+		pcscommand.RunCopy(&pcscommand.CpMvOptions{
+			Policy: c.String("policy"),
+			Merge:  c.Bool("merge"),
+			DryRun: c.Bool("dry-run"),
+		}, c.Args()...)
 		return nil
 	}
 }
@@ -437,7 +526,11 @@ func RunMvCommand(pcs *baidupcs.BaiduPCS) MvAction {
 			cli.ShowCommandHelp(c, c.Command.Name)
 			return nil
 		}
-		pcscommand.RunMove(c.Args()...)
+		pcscommand.RunMove(&pcscommand.CpMvOptions{
+			Policy: c.String("policy"),
+			Merge:  c.Bool("merge"),
+			DryRun: c.Bool("dry-run"),
+		}, c.Args()...)
 		return nil
 	}
 }
@@ -490,11 +583,6 @@ func RunLoginCommand(qrLogin *login.QrCodeLogin, cfg *pcsconfig.PCSConfig) Login
 // NOTE: Still uses pcscommand.RunDownload which relies on global state.
 func RunDownloadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) DownloadAction {
 	return func(c *cli.Context) error {
-		if c.NArg() == 0 {
-			cli.ShowCommandHelp(c, c.Command.Name)
-			return nil
-		}
-
 		// Process saveTo path
 		var saveTo string
 		if c.Bool("save") {
@@ -503,6 +591,16 @@ func RunDownloadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) Downlo
 			saveTo = filepath.Clean(c.String("saveto"))
 		}
 
+		if c.Bool("clean-partials") {
+			pcscommand.RunCleanPartials(saveTo)
+			return nil
+		}
+
+		if c.NArg() == 0 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return nil
+		}
+
 		// Process download mode
 		var downloadMode pcsdownload.DownloadMode
 		switch c.String("mode") {
@@ -518,6 +616,12 @@ func RunDownloadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) Downlo
 			return fmt.Errorf("无效的下载模式: %s", c.String("mode"))
 		}
 
+		filter, err := newFileFilter(c)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
 		do := &pcscommand.DownloadOptions{
 			IsTest:               c.Bool("test"),
 			IsPrintStatus:        c.Bool("status"),
@@ -532,6 +636,12 @@ func RunDownloadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) Downlo
 			LinkPrefer:           c.Int("dindex"),
 			ModifyMTime:          c.Bool("mtime"),
 			FullPath:             c.Bool("fullpath"),
+			AutoParallel:         c.Bool("auto"),
+			Filter:               filter,
+			Unpack:               c.Bool("unpack"),
+		}
+		if accounts := c.String("accounts"); accounts != "" {
+			do.Accounts = strings.Split(accounts, ",")
 		}
 		pcscommand.RunDownload(c.Args(), do)
 		return nil
@@ -542,20 +652,55 @@ func RunDownloadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) Downlo
 // NOTE: Still uses pcscommand.RunUpload which relies on global state.
 func RunUploadCommand(pcs *baidupcs.BaiduPCS, cfg *pcsconfig.PCSConfig) UploadAction {
 	return func(c *cli.Context) error {
+		if c.Bool("list-unfinished") {
+			pcscommand.RunUploadListUnfinished()
+			return nil
+		}
+		if c.Bool("purge-stale") {
+			pcscommand.RunUploadPurgeStale()
+			return nil
+		}
 		if c.NArg() < 2 {
 			cli.ShowCommandHelp(c, c.Command.Name)
 			return nil
 		}
 
+		filter, err := newFileFilter(c)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
 		subArgs := c.Args()
-		pcscommand.RunUpload(subArgs[:c.NArg()-1], subArgs[c.NArg()-1], &pcscommand.UploadOptions{
+		opt := &pcscommand.UploadOptions{
 			Parallel:      c.Int("p"),
 			MaxRetry:      c.Int("retry"),
 			Load:          c.Int("l"),
 			NoRapidUpload: c.Bool("norapid"),
 			NoSplitFile:   c.Bool("nosplit"),
 			Policy:        c.String("policy"),
-		})
+			Filter:        filter,
+			Pipeline:      c.Bool("pipeline"),
+			Pack:          c.Bool("pack"),
+			PackName:      c.String("pack-name"),
+			Watch:         c.Bool("watch"),
+			DeleteAfter:   c.Bool("delete-after"),
+			MoveTo:        c.String("move-to"),
+		}
+		if s := c.String("pack-threshold"); s != "" {
+			if opt.PackThreshold, err = converter.ParseFileSizeStr(s); err != nil {
+				fmt.Printf("pack-threshold 设置错误: %s\n", err)
+				return nil
+			}
+		}
+		if s := c.String("pack-size"); s != "" {
+			if opt.PackSize, err = converter.ParseFileSizeStr(s); err != nil {
+				fmt.Printf("pack-size 设置错误: %s\n", err)
+				return nil
+			}
+		}
+
+		pcscommand.RunUpload(subArgs[:c.NArg()-1], subArgs[c.NArg()-1], opt)
 		return nil
 	}
 }
@@ -616,6 +761,12 @@ func RunTreeCommand(pcs *baidupcs.BaiduPCS) TreeAction {
 func RunExportCommand(cfg *pcsconfig.PCSConfig) ExportAction {
 	return func(c *cli.Context) error {
 
+		filter, err := newFileFilter(c)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
 		exportOptions := &pcscommand.ExportOptions{
 			SavePath: c.String("file"),
 
@@ -623,6 +774,7 @@ func RunExportCommand(cfg *pcsconfig.PCSConfig) ExportAction {
 			Recursive:  true,
 			LinkFormat: false,
 			StdOut:     false,
+			Filter:     filter,
 		}
 		pcscommand.RunExport(c.Args(), exportOptions)
 		return nil
@@ -832,6 +984,131 @@ func RunRapidUploadCommand(pcs *baidupcs.BaiduPCS) RapidUploadAction {
 	}
 }
 
+// RunCacheCommand provides the action for the 'cache' command.
+func RunCacheCommand() CacheAction {
+	return func(c *cli.Context) error {
+		cli.ShowCommandHelp(c, c.Command.Name)
+		return nil
+	}
+}
+
+// RunJobsCommand provides the action for the 'jobs' command.
+func RunJobsCommand() JobsAction {
+	return func(c *cli.Context) error {
+		pcscommand.RunJobs()
+		return nil
+	}
+}
+
+// RunJobControlCommand provides the action for the 'fg', 'bg', 'kill', 'pause' and 'resume' commands.
+func RunJobControlCommand() JobControlAction {
+	return func(c *cli.Context) error {
+		id := c.Args().Get(0)
+		switch c.Command.Name {
+		case "fg":
+			pcscommand.RunJobFg(id)
+		case "bg":
+			pcscommand.RunJobBg(id)
+		case "kill":
+			if c.NArg() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			}
+			pcscommand.RunJobKill(id)
+		case "pause":
+			pcscommand.RunJobPause(id)
+		case "resume":
+			pcscommand.RunJobResume(id)
+		}
+		return nil
+	}
+}
+
+// RunLocalCommand provides the action for the 'lls', 'lcd', 'lpwd' and 'lmkdir' commands.
+func RunLocalCommand() LocalAction {
+	return func(c *cli.Context) error {
+		switch c.Command.Name {
+		case "lls":
+			pcscommand.RunLocalList(c.Args().Get(0))
+		case "lcd":
+			pcscommand.RunLocalChangeDirectory(c.Args().Get(0))
+		case "lpwd":
+			pcscommand.RunLocalPwd()
+		case "lmkdir":
+			if c.NArg() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			}
+			pcscommand.RunLocalMkdir(c.Args()...)
+		}
+		return nil
+	}
+}
+
+// RunAliasCommand provides the action for the 'alias' command.
+func RunAliasCommand() AliasAction {
+	return func(c *cli.Context) error {
+		pcscommand.RunAliasList()
+		return nil
+	}
+}
+
+// RunCatMemberCommand provides the action for the 'cat-member' command.
+func RunCatMemberCommand() CatMemberAction {
+	return func(c *cli.Context) error {
+		if c.NArg() < 2 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return nil
+		}
+		pcscommand.RunCatMember(c.Args().Get(0), c.Args()[1:])
+		return nil
+	}
+}
+
+// RunRenameCommand provides the action for the 'rename' command.
+func RunRenameCommand() RenameAction {
+	return func(c *cli.Context) error {
+		if c.Bool("undo") {
+			pcscommand.RunRenameUndo(c.Args().Get(0), c.Bool("dry-run"))
+			return nil
+		}
+		if c.NArg() == 0 {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return nil
+		}
+
+		rules := &renamer.Rules{
+			Template: c.String("template"),
+			SetExt:   c.IsSet("ext"),
+			Ext:      c.String("ext"),
+			Case:     c.String("case"),
+		}
+		for _, expr := range c.StringSlice("sub") {
+			sub, err := renamer.ParseSubstitution(expr)
+			if err != nil {
+				fmt.Println(err)
+				return nil
+			}
+			rules.Subs = append(rules.Subs, sub)
+		}
+		filter, err := newFileFilter(c)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		pcscommand.RunRename(&pcscommand.RenameOptions{
+			Rules:     rules,
+			Start:     c.Int("start"),
+			Recursive: c.Bool("r"),
+			Type:      c.String("type"),
+			Filter:    filter,
+			DryRun:    c.Bool("dry-run"),
+		}, c.Args()...)
+		return nil
+	}
+}
+
 // App holds the application's dependencies.
 type App struct {
 	CliApp *cli.App
@@ -870,7 +1147,13 @@ type App struct {
 	UpdateAction      UpdateAction
 	ToolAction        ToolAction // Placeholder
 	RunAction         RunAction  // Placeholder
-
+	RenameAction      RenameAction
+	CatMemberAction   CatMemberAction
+	AliasAction       AliasAction
+	LocalAction       LocalAction
+	JobControlAction  JobControlAction
+	JobsAction        JobsAction
+	CacheAction       CacheAction
 }
 
 // Cleanup performs necessary cleanup actions like closing resources.
@@ -917,6 +1200,13 @@ func provideCliApp(
 	updateAction UpdateAction,
 	toolAction ToolAction,
 	runAction RunAction,
+	cacheAction CacheAction,
+	jobsAction JobsAction,
+	jobControlAction JobControlAction,
+	localAction LocalAction,
+	aliasAction AliasAction,
+	catMemberAction CatMemberAction,
+	renameAction RenameAction,
 
 ) *cli.App {
 	cliApp := cli.NewApp()
@@ -948,11 +1238,91 @@ func provideCliApp(
 		EnvVar: pcsverbose.EnvVerbose,
 
 		Destination: &pcsverbose.IsVerbose,
+	}, cli.StringFlag{
+		Name:   "metrics-addr",
+		Usage:  "在指定地址启动指标服务 (Prometheus 格式, 路径 /metrics), 例如 127.0.0.1:9090",
+		EnvVar: metrics.EnvMetricsAddr,
 	},
+		cli.BoolFlag{
+			Name:  "offline",
+			Usage: "离线模式, 只读取目录列表和元信息的磁盘缓存, 不访问网络",
+		},
+		cli.StringFlag{
+			Name:  "trace-http",
+			Usage: "将所有 http 请求和响应记录到指定的 HAR 文件, 用于调试, 会自动隐藏 Cookie 和 token 等敏感信息",
+		},
+	}
+
+	var (
+		stopCommandCtx context.CancelFunc
+		traceRecorder  *harlog.Recorder
+		// aliasExpanded 正在执行展开后的命令, 不再展开别名
+		aliasExpanded bool
+	)
+	cliApp.Before = func(c *cli.Context) error {
+		if c.NArg() > 0 && !aliasExpanded {
+			// 在分派命令之前展开别名, 别名可以覆盖同名的命令
+			cmdList, err := cfg.ExpandAlias(c.Args())
+			if err != nil {
+				fmt.Println(err)
+				return errAliasExpanded
+			}
+			if cmdList != nil {
+				// 保留全局选项, 展开后的命令各自执行 Before 和 After
+				globalArgs := os.Args[:len(os.Args)-c.NArg()]
+				aliasExpanded = true
+				for _, cmdArgs := range cmdList {
+					runArgs := append(append([]string{}, globalArgs...), cmdArgs...)
+					c.App.Run(runArgs)
+				}
+				aliasExpanded = false
+				return errAliasExpanded
+			}
+		}
+		if addr := c.String("metrics-addr"); addr != "" {
+			// 交互模式下每条命令都会执行 Before, 服务只启动一次
+			if err := metrics.ListenAndServe(addr); err != nil {
+				fmt.Printf("启动指标服务错误: %s\n", err)
+			}
+		}
+		if tracePath := c.String("trace-http"); tracePath != "" && traceRecorder == nil {
+			rec, err := harlog.NewRecorder(tracePath, c.App.Version)
+			if err != nil {
+				fmt.Printf("创建 http 记录文件错误: %s\n", err)
+			} else {
+				traceRecorder = rec
+				requester.SetTraceRecorder(rec)
+			}
+		}
+		if c.Bool("offline") {
+			// 交互模式下的命令不带全局参数, 只开启不关闭
+			cfg.SetOffline(true)
+		}
+		if c.NArg() > 0 {
+			// 执行命令期间, Ctrl-C 取消进行中的 API 请求和任务
+			var ctx context.Context
+			ctx, stopCommandCtx = pcscommand.NotifyInterrupt(context.Background())
+			pcscommand.SetContext(ctx)
+		}
+		return nil
+	}
+	cliApp.After = func(c *cli.Context) error {
+		if traceRecorder != nil {
+			if err := traceRecorder.Save(); err != nil {
+				fmt.Printf("保存 http 记录文件错误: %s\n", err)
+			}
+		}
+		if stopCommandCtx != nil {
+			stopCommandCtx()
+			stopCommandCtx = nil
+			pcscommand.SetContext(context.Background())
+		}
+		return nil
 	}
 
 	cliApp.Action = func(c *cli.Context) {
 		if c.NArg() != 0 {
+			// 别名已在 Before 中展开
 			fmt.Printf("未找到命令: %s\n运行命令 %s help 获取帮助\n", c.Args().Get(0), c.App.Name)
 			return
 		}
@@ -977,128 +1347,12 @@ func provideCliApp(
 
 		}()
 
-		line.State.SetCompleter(func(line string) (s []string) {
-			var (
-				lineArgs                   = args.Parse(line)
-				numArgs                    = len(lineArgs)
-				acceptCompleteFileCommands = []string{
-					"cd", "cp", "download", "export", "fixmd5", "locate", "ls", "meta", "mkdir", "mv", "rapidupload", "rm", "setastoken", "share", "transfer", "tree", "upload",
-				}
-				closed = strings.LastIndex(line, " ") == len(line)-1
-			)
-
-			currentCliApp := c.App
-			for _, cmd := range currentCliApp.Commands {
-				for _, name := range cmd.Names() {
-					if !strings.HasPrefix(name, line) {
-						continue
-					}
-					s = append(s, name+" ")
-				}
-			}
-
-			switch numArgs {
-			case 0:
-				return
-			case 1:
-				if !closed {
-					return
-				}
-			}
-
-			thisCmd := currentCliApp.Command(lineArgs[0])
-			if thisCmd == nil {
-				return
-			}
-
-			if !pcsutil.ContainsString(acceptCompleteFileCommands, thisCmd.FullName()) {
-				return
-			}
-
-			var (
-				activeUser = cfg.ActiveUser() // Use injected config
-				// pcs is already injected into provideCliApp
-				runeFunc    = unicode.IsSpace
-				pcsRuneFunc = func(r rune) bool {
-					switch r {
-					case '\'', '"':
-						return true
-					}
-					return unicode.IsSpace(r)
-				}
-				targetPath string
-			)
-
-			if !closed {
-				targetPath = lineArgs[numArgs-1]
-				escaper.EscapeStringsByRuneFunc(lineArgs[:numArgs-1], runeFunc)
-			} else {
-				escaper.EscapeStringsByRuneFunc(lineArgs, runeFunc)
-			}
-
-			switch {
-			case targetPath == "." || strings.HasSuffix(targetPath, "/."):
-				s = append(s, line+"/")
-				return
-			case targetPath == ".." || strings.HasSuffix(targetPath, "/.."):
-				s = append(s, line+"/")
-				return
-			}
-
-			var (
-				targetDir string
-				isAbs     = path.IsAbs(targetPath)
-				isDir     = strings.LastIndex(targetPath, "/") == len(targetPath)-1
-			)
-
-			if isAbs {
-				targetDir = path.Dir(targetPath)
-			} else {
-				targetDir = path.Join(activeUser.Workdir, targetPath)
-				if !isDir {
-					targetDir = path.Dir(targetDir)
-				}
-			}
-
-			files, err := pcs.CacheFilesDirectoriesList(targetDir, baidupcs.DefaultOrderOptions)
-			if err != nil {
-
-				return
-			}
-
-			for _, file := range files {
-				if file == nil {
-					continue
-				}
-
-				var (
-					appendLine string
-				)
-
-				if !closed {
-					if !strings.HasPrefix(file.Path, path.Clean(path.Join(targetDir, path.Base(targetPath)))) {
-						if path.Base(targetDir) == path.Base(targetPath) {
-							appendLine = strings.Join(append(lineArgs[:numArgs-1], escaper.EscapeByRuneFunc(path.Join(targetPath, file.Filename), pcsRuneFunc)), " ")
-							goto handle
-						}
-						continue
-					}
-					appendLine = strings.Join(append(lineArgs[:numArgs-1], escaper.EscapeByRuneFunc(path.Clean(path.Join(path.Dir(targetPath), file.Filename)), pcsRuneFunc)), " ")
-					goto handle
-				}
-
-				appendLine = strings.Join(append(lineArgs, escaper.EscapeByRuneFunc(file.Filename, pcsRuneFunc)), " ")
-			handle:
-				if file.Isdir {
-					s = append(s, appendLine+"/")
-					continue
-				}
-				s = append(s, appendLine+" ")
-				continue
-			}
-
-			return
-		})
+		// 自动补全, 区分本地路径和网盘路径
+		line.State.SetCompleter((&shellCompleter{
+			app: c.App,
+			cfg: cfg,
+			pcs: pcs,
+		}).Complete)
 		fmt.Printf("提示: 方向键上下可切换历史命令.\n")
 		fmt.Printf("提示: Ctrl + A / E 跳转命令 首 / 尾.\n")
 		fmt.Printf("提示: 输入 help 获取帮助.\n")
@@ -1115,11 +1369,20 @@ func provideCliApp(
 				prompt = c.App.Name + " > "
 			}
 
+			// 输出已结束的后台任务, 并在提示符中显示未结束的后台任务
+			for _, msg := range pcscommand.JobNotifications() {
+				fmt.Println(msg)
+			}
+			if summary := pcscommand.JobsSummary(); summary != "" {
+				prompt = summary + " " + prompt
+			}
+
 			commandLine, err := line.State.Prompt(prompt)
 			switch err {
 			case liner.ErrPromptAborted:
 
 				line.DoWriteHistory()
+				pcscommand.StopJobs()
 				return
 			case nil:
 
@@ -1132,17 +1395,45 @@ func provideCliApp(
 
 			line.State.AppendHistory(commandLine)
 
-			cmdArgs := args.Parse(commandLine)
+			// 末尾单独的 & 表示在后台执行
+			cmdArgs, background := parseShellLine(commandLine)
 			if len(cmdArgs) == 0 {
 				continue
 			}
 
-			runArgs := []string{os.Args[0]}
-			runArgs = append(runArgs, cmdArgs...)
+			// 展开别名
+			cmdList := [][]string{cmdArgs}
+			expanded, err := cfg.ExpandAlias(cmdArgs)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if expanded != nil {
+				cmdList = expanded
+			}
 
-			line.Pause()
+			if background {
+				if len(cmdList) != 1 || !pcsutil.ContainsString([]string{"download", "d", "upload", "u"}, cmdList[0][0]) {
+					fmt.Printf("仅 download 和 upload 命令支持在后台执行\n")
+					continue
+				}
+				pcscommand.SetNextRunner(&pcscommand.Runner{
+					IsBackground: true,
+					Cmdline:      strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(commandLine), "&")),
+				})
+			}
 
-			c.App.Run(runArgs)
+			line.Pause()
+			aliasExpanded = true
+			for _, cmdArgs := range cmdList {
+				runArgs := []string{os.Args[0]}
+				runArgs = append(runArgs, cmdArgs...)
+				c.App.Run(runArgs)
+			}
+			aliasExpanded = false
+			if background {
+				pcscommand.SetNextRunner(nil)
+			}
 			line.Resume()
 		}
 	}
@@ -1167,7 +1458,7 @@ func provideCliApp(
 					Usage:  "修改程序配置项",
 					Action: cli.ActionFunc(configSetAction),
 
-					Flags: []cli.Flag{cli.IntFlag{Name: "appid", Usage: "百度 PCS 应用ID"}, cli.StringFlag{Name: "cache_size", Usage: "下载缓存"}, cli.IntFlag{Name: "max_parallel", Usage: "下载网络全部连接的最大并发量"}, cli.IntFlag{Name: "max_upload_parallel", Usage: "上传网络单个连接的最大并发量"}, cli.IntFlag{Name: "max_download_load", Usage: "同时进行下载文件的最大数量"}, cli.IntFlag{Name: "max_upload_load", Usage: "同时进行上传文件的最大数量"}, cli.StringFlag{Name: "max_download_rate", Usage: "限制最大下载速度, 0代表不限制"}, cli.StringFlag{Name: "max_upload_rate", Usage: "限制最大上传速度, 0代表不限制"}, cli.StringFlag{Name: "savedir", Usage: "下载文件的储存目录"}, cli.BoolFlag{Name: "enable_https", Usage: "启用 https"}, cli.BoolFlag{Name: "ignore_illegal", Usage: "忽略上传时文件名中的非法字符"}, cli.StringFlag{Name: "force_login_username", Usage: "强制登录指定用户名"}, cli.BoolFlag{Name: "no_check", Usage: "关闭下载文件md5校验"}, cli.StringFlag{Name: "upload_policy", Usage: "设置上传遇到同名文件时的策略"}, cli.StringFlag{Name: "user_agent", Usage: "浏览器标识"}, cli.StringFlag{Name: "pcs_ua", Usage: "PCS 浏览器标识"}, cli.StringFlag{Name: "pcs_addr", Usage: "PCS 服务器地址"}, cli.StringFlag{Name: "pan_ua", Usage: "Pan 浏览器标识"}, cli.StringFlag{Name: "proxy", Usage: "设置代理, 支持 http/socks5 代理"}, cli.StringFlag{Name: "local_addrs", Usage: "设置本地网卡地址"}},
+					Flags: []cli.Flag{cli.IntFlag{Name: "appid", Usage: "百度 PCS 应用ID"}, cli.StringFlag{Name: "cache_size", Usage: "下载缓存"}, cli.IntFlag{Name: "max_parallel", Usage: "下载网络全部连接的最大并发量"}, cli.IntFlag{Name: "max_upload_parallel", Usage: "上传网络单个连接的最大并发量"}, cli.IntFlag{Name: "max_download_load", Usage: "同时进行下载文件的最大数量"}, cli.IntFlag{Name: "max_upload_load", Usage: "同时进行上传文件的最大数量"}, cli.StringFlag{Name: "max_download_rate", Usage: "限制最大下载速度, 0代表不限制"}, cli.StringFlag{Name: "max_upload_rate", Usage: "限制最大上传速度, 0代表不限制"}, cli.StringFlag{Name: "download_rate_schedule", Usage: "分时段下载限速规则, 例如 \"2MB/s 09:00-18:00 weekdays; 0 otherwise\""}, cli.StringFlag{Name: "upload_rate_schedule", Usage: "分时段上传限速规则, 格式同 download_rate_schedule"}, cli.StringFlag{Name: "savedir", Usage: "下载文件的储存目录"}, cli.BoolFlag{Name: "enable_https", Usage: "启用 https"}, cli.BoolFlag{Name: "ignore_illegal", Usage: "忽略上传时文件名中的非法字符"}, cli.StringFlag{Name: "force_login_username", Usage: "强制登录指定用户名"}, cli.BoolFlag{Name: "no_check", Usage: "关闭下载文件md5校验"}, cli.StringFlag{Name: "upload_policy", Usage: "设置上传遇到同名文件时的策略"}, cli.StringFlag{Name: "user_agent", Usage: "浏览器标识"}, cli.StringFlag{Name: "pcs_ua", Usage: "PCS 浏览器标识"}, cli.StringFlag{Name: "pcs_addr", Usage: "PCS 服务器地址"}, cli.StringFlag{Name: "pan_ua", Usage: "Pan 浏览器标识"}, cli.StringFlag{Name: "proxy", Usage: "设置代理, 支持 http/socks5 代理"}, cli.StringFlag{Name: "local_addrs", Usage: "设置本地网卡地址"}, cli.StringFlag{Name: "mirror_blocklist", Usage: "禁止使用的下载服务器, 多个主机用逗号隔开"}, cli.StringFlag{Name: "meta_cache_ttl", Usage: "目录列表和文件元信息的磁盘缓存有效期, 例如 10m 或 list=10m,meta=1h"}, cli.IntFlag{Name: "api_max_retry", Usage: "API 请求遇到临时性错误时的最大重试次数, 0代表不重试"}, cli.StringFlag{Name: "api_rate_limit", Usage: "各类接口每秒最多的请求数, 例如 5 或 meta=8,file=4,locate=2,share=1"}, cli.StringFlag{Name: "hook_command", Usage: "上传, 下载或转存结束后执行的外部命令, 标准输入为 JSON 格式的传输信息"}, cli.StringFlag{Name: "hook_url", Usage: "上传, 下载或转存结束后接收 POST 请求的本机 URL"}, cli.StringFlag{Name: "hook_events", Usage: "触发钩子的事件, 例如 upload,download.failed, 留空表示全部触发"}, cli.StringFlag{Name: "hook_timeout", Usage: "钩子命令和请求的超时时间, 例如 30s"}},
 				},
 				{
 					Name:   "reset",
@@ -1241,6 +1532,7 @@ func provideCliApp(
 			Usage:    "拷贝文件/目录",
 			Category: "百度网盘",
 			Action:   cli.ActionFunc(cpAction),
+			Flags:    []cli.Flag{cli.StringFlag{Name: "policy", Usage: "目标已存在时的处理策略: fail(失败), skip(跳过), overwrite(删除已存在的目标后覆盖), newcopy(重命名为 名称(1).扩展名)", Value: "fail"}, cli.BoolFlag{Name: "merge", Usage: "目标为已存在的目录时, 递归合并到该目录中, 目录中的同名文件按 --policy 处理"}, cli.BoolFlag{Name: "dry-run", Usage: "只列出计划执行的操作, 不执行"}},
 		},
 
 		{
@@ -1248,6 +1540,7 @@ func provideCliApp(
 			Usage:    "移动/重命名文件/目录",
 			Category: "百度网盘",
 			Action:   cli.ActionFunc(mvAction),
+			Flags:    []cli.Flag{cli.StringFlag{Name: "policy", Usage: "目标已存在时的处理策略: fail(失败), skip(跳过), overwrite(删除已存在的目标后覆盖), newcopy(重命名为 名称(1).扩展名)", Value: "fail"}, cli.BoolFlag{Name: "merge", Usage: "目标为已存在的目录时, 递归合并到该目录中, 目录中的同名文件按 --policy 处理"}, cli.BoolFlag{Name: "dry-run", Usage: "只列出计划执行的操作, 不执行"}},
 		},
 
 		{
@@ -1256,7 +1549,7 @@ func provideCliApp(
 			Usage:    "下载文件/目录",
 			Category: "百度网盘",
 			Action:   cli.ActionFunc(downloadAction),
-			Flags:    []cli.Flag{cli.BoolFlag{Name: "test", Usage: "测试下载"}, cli.BoolFlag{Name: "ow", Usage: "覆盖已存在的文件"}, cli.BoolFlag{Name: "status", Usage: "输出所有线程的工作状态"}, cli.BoolFlag{Name: "save", Usage: "将下载的文件直接保存到当前工作目录"}, cli.StringFlag{Name: "saveto", Usage: "将下载的文件直接保存到指定的目录"}, cli.BoolFlag{Name: "x", Usage: "为文件加上执行权限"}, cli.StringFlag{Name: "mode", Usage: "下载模式 (pcs, stream, locate)", Value: "locate"}, cli.IntFlag{Name: "p", Usage: "指定下载线程数"}, cli.IntFlag{Name: "l", Usage: "指定同时进行下载文件的数量"}, cli.IntFlag{Name: "retry", Usage: "下载失败最大重试次数", Value: 3}, cli.BoolFlag{Name: "nocheck", Usage: "下载文件完成后不校验文件"}, cli.BoolFlag{Name: "mtime", Usage: "将本地文件的修改时间设置为服务器上的修改时间"}, cli.IntFlag{Name: "dindex", Usage: "使用备选下载链接中的第几个"}, cli.BoolFlag{Name: "fullpath", Usage: "以网盘完整路径保存到本地"}},
+			Flags:    append([]cli.Flag{cli.BoolFlag{Name: "test", Usage: "测试下载"}, cli.BoolFlag{Name: "ow", Usage: "覆盖已存在的文件"}, cli.BoolFlag{Name: "status", Usage: "输出所有线程的工作状态"}, cli.BoolFlag{Name: "save", Usage: "将下载的文件直接保存到当前工作目录"}, cli.StringFlag{Name: "saveto", Usage: "将下载的文件直接保存到指定的目录"}, cli.BoolFlag{Name: "x", Usage: "为文件加上执行权限"}, cli.StringFlag{Name: "mode", Usage: "下载模式 (pcs, stream, locate)", Value: "locate"}, cli.IntFlag{Name: "p", Usage: "指定下载线程数"}, cli.IntFlag{Name: "l", Usage: "指定同时进行下载文件的数量"}, cli.IntFlag{Name: "retry", Usage: "下载失败最大重试次数", Value: 3}, cli.BoolFlag{Name: "nocheck", Usage: "下载文件完成后不校验文件"}, cli.BoolFlag{Name: "mtime", Usage: "将本地文件的修改时间设置为服务器上的修改时间"}, cli.IntFlag{Name: "dindex", Usage: "使用备选下载链接中的第几个"}, cli.BoolFlag{Name: "fullpath", Usage: "以网盘完整路径保存到本地"}, cli.BoolFlag{Name: "auto", Usage: "根据下载速度自动调整线程数, -p 指定的线程数作为上限"}, cli.BoolFlag{Name: "clean-partials", Usage: "清理下载目录中超过24小时未修改的临时文件 (*.bpcs-part)"}, cli.StringFlag{Name: "accounts", Usage: "多帐号下载, 同时使用其他已登录帐号 (百度ID或uid, 逗号分隔) 的下载链接, 仅支持 locate 模式"}, cli.BoolFlag{Name: "unpack", Usage: "下载的路径为 upload --pack 生成的打包索引, 用 Range 请求只下载其中的成员, 不下载整个分段"}}, fileFilterFlags...),
 		},
 
 		{
@@ -1265,7 +1558,7 @@ func provideCliApp(
 			Usage:    "上传文件/目录",
 			Category: "百度网盘",
 			Action:   cli.ActionFunc(uploadAction),
-			Flags:    []cli.Flag{cli.IntFlag{Name: "p", Usage: "指定单个文件上传的最大线程数"}, cli.IntFlag{Name: "retry", Usage: "上传失败最大重试次数", Value: 3}, cli.IntFlag{Name: "l", Usage: "指定同时上传的最大文件数"}, cli.BoolFlag{Name: "norapid", Usage: "不检测秒传"}, cli.BoolFlag{Name: "nosplit", Usage: "禁用分片上传"}, cli.StringFlag{Name: "policy", Usage: "对同名文件的处理策略"}},
+			Flags:    append([]cli.Flag{cli.IntFlag{Name: "p", Usage: "指定单个文件上传的最大线程数"}, cli.IntFlag{Name: "retry", Usage: "上传失败最大重试次数", Value: 3}, cli.IntFlag{Name: "l", Usage: "指定同时上传的最大文件数"}, cli.BoolFlag{Name: "norapid", Usage: "不检测秒传"}, cli.BoolFlag{Name: "nosplit", Usage: "禁用分片上传"}, cli.StringFlag{Name: "policy", Usage: "对同名文件的处理策略"}, cli.BoolFlag{Name: "pipeline", Usage: "流水线模式, 大文件在上传的同时计算秒传信息, 只读取一次文件, 小文件在后台提前计算秒传信息"}, cli.BoolFlag{Name: "pack", Usage: "将小文件打包成 tar 分段上传, 并生成索引, 可使用 download --unpack 或 cat-member 获取其中的文件"}, cli.StringFlag{Name: "pack-name", Usage: "打包名称, 分段为 <名称>.000.tar, 索引为 <名称>.index.json, 默认为 pack-<时间>"}, cli.StringFlag{Name: "pack-threshold", Usage: "小于此大小的文件才打包", Value: "1MB"}, cli.StringFlag{Name: "pack-size", Usage: "打包分段的大小", Value: "256MB"}, cli.BoolFlag{Name: "watch", Usage: "监视本地目录, 文件写入完成后自动上传目录中的文件, 直到按 Ctrl-C 停止"}, cli.BoolFlag{Name: "delete-after", Usage: "监视目录时, 上传成功后删除本地文件"}, cli.StringFlag{Name: "move-to", Usage: "监视目录时, 上传成功后将本地文件移动到此目录"}, cli.BoolFlag{Name: "list-unfinished", Usage: "列出未完成的上传"}, cli.BoolFlag{Name: "purge-stale", Usage: "清除已过期的断点续传信息, 以及本地文件已删除或修改的未完成上传"}}, fileFilterFlags...),
 		},
 
 		{
@@ -1345,7 +1638,7 @@ func provideCliApp(
 			Usage:    "导出当前帐号的所有百度ID, BDUSS, PTOKEN, STOKEN",
 			Category: "配置",
 			Action:   cli.ActionFunc(exportAction),
-			Flags:    []cli.Flag{cli.StringFlag{Name: "file", Usage: "导出路径"}},
+			Flags:    append([]cli.Flag{cli.StringFlag{Name: "file", Usage: "导出路径"}}, fileFilterFlags...),
 		},
 
 		{
@@ -1399,6 +1692,244 @@ func provideCliApp(
 			Category: "其他",
 			Action:   cli.ActionFunc(runAction),
 		},
+		{
+			Name:      "cache",
+			Usage:     "管理目录列表和文件元信息的磁盘缓存",
+			UsageText: "BaiduPCS-Go cache <stats|clear>",
+			Description: `
+	启用缓存: BaiduPCS-Go config set -meta_cache_ttl 10m
+	分别设置有效期: BaiduPCS-Go config set -meta_cache_ttl list=10m,meta=1h
+	离线浏览已缓存的目录: BaiduPCS-Go --offline ls /`,
+			Category: "配置",
+			Action:   cli.ActionFunc(cacheAction),
+			Subcommands: []cli.Command{
+				{
+					Name:  "stats",
+					Usage: "显示缓存统计",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunCacheStats()
+						return nil
+					},
+				},
+				{
+					Name:  "clear",
+					Usage: "清除当前帐号的缓存",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunCacheClear()
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "jobs",
+			Usage:     "列出后台任务",
+			UsageText: "BaiduPCS-Go jobs",
+			Description: `
+	在交互模式下, 在 download 或 upload 命令的末尾加上 & 即可转入后台执行, 例如:
+	BaiduPCS-Go download /我的资源 &
+	后台任务的输出保存在各自的缓冲区中, 可用 fg 查看, 提示符中会显示未结束的后台任务的状态.`,
+			Category: "后台任务",
+			Action:   cli.ActionFunc(jobsAction),
+		},
+		{
+			Name:      "fg",
+			Usage:     "将后台任务转到前台",
+			UsageText: "BaiduPCS-Go fg [id]",
+			Description: `
+	输出后台任务已保留的内容, 并继续输出进度直到任务结束, 已暂停的任务会被恢复.
+	按 Ctrl-C 将任务转回后台. 不指定 id 时使用最近的后台任务.`,
+			Category: "后台任务",
+			Action:   cli.ActionFunc(jobControlAction),
+		},
+		{
+			Name:        "bg",
+			Usage:       "在后台继续执行已暂停的任务",
+			UsageText:   "BaiduPCS-Go bg [id]",
+			Description: "不指定 id 时使用最近的后台任务.",
+			Category:    "后台任务",
+			Action:      cli.ActionFunc(jobControlAction),
+		},
+		{
+			Name:        "kill",
+			Usage:       "取消后台任务",
+			UsageText:   "BaiduPCS-Go kill <id>",
+			Description: "取消后台任务, 未完成的上传和下载可在之后断点续传.",
+			Category:    "后台任务",
+			Action:      cli.ActionFunc(jobControlAction),
+		},
+		{
+			Name:        "pause",
+			Usage:       "暂停后台任务",
+			UsageText:   "BaiduPCS-Go pause [id]",
+			Description: "暂停后台任务中正在进行的传输, 之后开始的传输也会保持暂停. 不指定 id 时使用最近的后台任务.",
+			Category:    "后台任务",
+			Action:      cli.ActionFunc(jobControlAction),
+		},
+		{
+			Name:        "resume",
+			Usage:       "恢复已暂停的后台任务",
+			UsageText:   "BaiduPCS-Go resume [id]",
+			Description: "不指定 id 时使用最近的后台任务.",
+			Category:    "后台任务",
+			Action:      cli.ActionFunc(jobControlAction),
+		},
+		{
+			Name:        "lls",
+			Usage:       "列出本地目录",
+			UsageText:   "BaiduPCS-Go lls [本地目录]",
+			Description: "默认列出本地工作目录.",
+			Category:    "本地",
+			Action:      cli.ActionFunc(localAction),
+		},
+		{
+			Name:      "lcd",
+			Usage:     "切换本地工作目录",
+			UsageText: "BaiduPCS-Go lcd [本地目录]",
+			Description: `
+	本地工作目录影响 upload 等命令中的本地相对路径, 不指定目录时切换到用户主目录.`,
+			Category: "本地",
+			Action:   cli.ActionFunc(localAction),
+		},
+		{
+			Name:      "lpwd",
+			Usage:     "输出本地工作目录",
+			UsageText: "BaiduPCS-Go lpwd",
+			Category:  "本地",
+			Action:    cli.ActionFunc(localAction),
+		},
+		{
+			Name:        "lmkdir",
+			Usage:       "创建本地目录",
+			UsageText:   "BaiduPCS-Go lmkdir <本地目录1> <本地目录2> ...",
+			Description: "会同时创建不存在的上级目录.",
+			Category:    "本地",
+			Action:      cli.ActionFunc(localAction),
+		},
+		{
+			Name:      "alias",
+			Usage:     "管理命令别名",
+			UsageText: "BaiduPCS-Go alias <add|list|rm>",
+			Description: `
+	别名可以展开为完整的命令行, 多条命令用分号隔开.
+	命令中可以使用 $1 ~ $9 引用位置参数, $@ 引用所有参数, $$ 表示 $,
+	没有引用任何位置参数时, 参数追加到 (最后一条) 命令的末尾.
+	别名在命令行和交互模式下均可使用, 别名中也可以引用其他别名.
+	与别名同名的命令会被别名覆盖, 别名中的同名命令不再展开, 可以用于给命令添加默认参数.
+
+	示例:
+
+	添加别名 dl, 执行 dl /我的资源 即为 download -p 16 -l 4 --mtime /我的资源
+	BaiduPCS-Go alias add dl download -p 16 -l 4 --mtime
+
+	使用位置参数, 执行 get 电影 /我的资源 即为 download --saveto 电影 /我的资源
+	BaiduPCS-Go alias add get 'download --saveto "$1" $2'
+
+	多条命令
+	BaiduPCS-Go alias add sync 'cd $1; download .'
+
+	ls 默认详细显示
+	BaiduPCS-Go alias add ls ls -l
+
+	列出所有别名
+	BaiduPCS-Go alias
+
+	删除别名
+	BaiduPCS-Go alias rm dl get`,
+			Category: "配置",
+			Action:   cli.ActionFunc(aliasAction),
+			Subcommands: []cli.Command{
+				{
+					Name:      "add",
+					Usage:     "添加或修改别名",
+					UsageText: "BaiduPCS-Go alias add <别名> <命令>",
+					Action: func(c *cli.Context) error {
+						if c.NArg() < 2 {
+							cli.ShowCommandHelp(c, c.Command.Name)
+							return nil
+						}
+						name := c.Args().Get(0)
+						if c.App.Command(name) != nil {
+							fmt.Printf("别名不能与已有的命令重名: %s\n", name)
+							return nil
+						}
+						pcscommand.RunAliasAdd(name, c.Args().Tail()...)
+						return nil
+					},
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "列出所有别名",
+					Action: func(c *cli.Context) error {
+						pcscommand.RunAliasList()
+						return nil
+					},
+				},
+				{
+					Name:      "rm",
+					Usage:     "删除别名",
+					UsageText: "BaiduPCS-Go alias rm <别名1> <别名2> ...",
+					Action: func(c *cli.Context) error {
+						if c.NArg() == 0 {
+							cli.ShowCommandHelp(c, c.Command.Name)
+							return nil
+						}
+						pcscommand.RunAliasRemove(c.Args()...)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "cat-member",
+			Usage:     "输出打包中的文件内容",
+			UsageText: "BaiduPCS-Go cat-member <打包索引> <成员路径1> <成员路径2> ...",
+			Description: `
+	读取 upload --pack 生成的打包索引, 只用 Range 请求下载成员在分段中的数据, 输出到标准输出.
+	下载打包中的全部或部分文件, 请使用 download --unpack.
+
+	示例:
+
+	输出打包中的 docs/a.txt
+	BaiduPCS-Go cat-member /备份/pack-20260101-120000.index.json docs/a.txt
+`,
+			Category: "百度网盘",
+			Action:   cli.ActionFunc(catMemberAction),
+		},
+		{
+			Name:      "rename",
+			Usage:     "批量重命名文件/目录",
+			UsageText: "BaiduPCS-Go rename [选项] <文件/目录1> <文件/目录2> ...",
+			Description: `
+	按规则批量重命名匹配的文件和目录, 只改变名称, 不改变所在的目录.
+	规则依次应用: --sub, --template, --ext, --case.
+	执行前先输出预览, 存在重名或无效的名称时不执行任何重命名.
+	重命名分批提交, 并在配置目录的 rename_undo 中写入撤销日志, 可使用 --undo 撤销.
+
+	--sub 为 sed 风格的正则替换 s/正则/替换/标志, 替换中可使用 \1 引用分组, & 引用整个匹配,
+	标志支持 g (全部替换) 和 i (忽略大小写), 可多次指定.
+	--template 为名称模板, 支持 {name} (不含扩展名的名称), {ext} (扩展名, 含 .), {n} (序号), {n:3} (补零到 3 位的序号),
+	序号按路径排序后从 --start 开始递增.
+
+	示例:
+
+	预览将 /相册 中的 IMG_0001.JPG 重命名为 photo-0001.jpg
+	BaiduPCS-Go rename --dry-run --sub 's/^IMG_/photo-/' --case lower /相册/IMG_*
+
+	按顺序重命名为 trip_001.jpg, trip_002.jpg, ...
+	BaiduPCS-Go rename --template 'trip_{n:3}{ext}' /相册/*.jpg
+
+	递归将目录中所有 .jpeg 文件的扩展名改为 .jpg
+	BaiduPCS-Go rename -r --include '*.jpeg' --ext jpg /相册
+
+	撤销最近一次重命名
+	BaiduPCS-Go rename --undo
+`,
+			Category: "百度网盘",
+			Action:   cli.ActionFunc(renameAction),
+			Flags:    append([]cli.Flag{cli.StringSliceFlag{Name: "sub", Usage: "sed 风格的正则替换, 如 s/IMG_(\\d+)/photo-\\1/g, 可多次指定"}, cli.StringFlag{Name: "case", Usage: "大小写转换: lower, upper, title (扩展名不变)"}, cli.StringFlag{Name: "template", Usage: "名称模板, 支持 {name}, {ext}, {n}, {n:3}"}, cli.IntFlag{Name: "start", Usage: "模板中 {n} 的起始序号", Value: 1}, cli.StringFlag{Name: "ext", Usage: "修改文件的扩展名, 为空则去掉扩展名"}, cli.BoolFlag{Name: "r", Usage: "递归选择目录中的文件和目录, 而不是目录本身"}, cli.StringFlag{Name: "type", Usage: "选择的类型: f (文件), d (目录), a (全部), 默认递归时为 f, 否则为 a"}, cli.BoolFlag{Name: "dry-run", Usage: "只输出预览, 不执行"}, cli.BoolFlag{Name: "undo", Usage: "按撤销日志恢复原名称, 参数为撤销日志的路径, 默认使用最近一次的撤销日志"}}, fileFilterFlags...),
+		},
 	}
 	sort.Sort(cli.FlagsByName(cliApp.Flags))
 	sort.Sort(cli.CommandsByName(cliApp.Commands))
@@ -1490,4 +2021,11 @@ var CommonSet = wire.NewSet(
 	RunUpdateCommand,
 	RunToolCommand,
 	RunRunCommand,
+	RunCacheCommand,
+	RunJobsCommand,
+	RunJobControlCommand,
+	RunLocalCommand,
+	RunAliasCommand,
+	RunCatMemberCommand,
+	RunRenameCommand,
 )
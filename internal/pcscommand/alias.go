@@ -0,0 +1,84 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner/args"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/escaper"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// RunAliasList 列出所有别名
+func RunAliasList() {
+	names := pcsconfig.Config.AliasNames()
+	if len(names) == 0 {
+		fmt.Printf("未设置别名\n")
+		return
+	}
+
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"别名", "命令"})
+	for _, name := range names {
+		cmdline, _ := pcsconfig.Config.Alias(name)
+		tb.Append([]string{name, cmdline})
+	}
+	tb.Render()
+}
+
+// RunAliasAdd 添加或修改别名, cmdArgs 只有一个时作为完整的命令行, 否则转义后拼接
+func RunAliasAdd(name string, cmdArgs ...string) {
+	var cmdline string
+	if len(cmdArgs) == 1 {
+		cmdline = cmdArgs[0]
+	} else {
+		escaped := append([]string(nil), cmdArgs...)
+		escaper.EscapeStringsByRuneFunc(escaped, func(r rune) bool {
+			return unicode.IsSpace(r) || args.IsQuote(r)
+		})
+		cmdline = strings.Join(escaped, " ")
+	}
+
+	old, exist := pcsconfig.Config.Alias(name)
+	err := pcsconfig.Config.SetAlias(name, cmdline)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = pcsconfig.Config.Save()
+	if err != nil {
+		fmt.Printf("保存配置错误: %s\n", err)
+		return
+	}
+
+	if exist {
+		fmt.Printf("修改别名成功: %s, 原命令: %s\n", name, old)
+		return
+	}
+	fmt.Printf("添加别名成功: %s\n", name)
+}
+
+// RunAliasRemove 删除别名
+func RunAliasRemove(names ...string) {
+	removed := 0
+	for _, name := range names {
+		err := pcsconfig.Config.RemoveAlias(name)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		removed++
+		fmt.Printf("删除别名成功: %s\n", name)
+	}
+	if removed == 0 {
+		return
+	}
+
+	err := pcsconfig.Config.Save()
+	if err != nil {
+		fmt.Printf("保存配置错误: %s\n", err)
+	}
+}
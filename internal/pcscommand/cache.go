@@ -0,0 +1,42 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"os"
+	"strconv"
+)
+
+// RunCacheStats 执行 输出当前帐号的元信息磁盘缓存统计
+func RunCacheStats() {
+	stats, err := GetBaiduPCS().DiskCache().Stats()
+	if err != nil {
+		fmt.Printf("读取缓存统计错误: %s\n", err)
+		return
+	}
+
+	fmt.Printf("缓存目录: %s\n", stats.Dir)
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"操作", "有效期", "缓存项", "已过期", "大小"})
+	for _, opStat := range stats.Ops {
+		ttl := "未启用"
+		if opStat.TTL > 0 {
+			ttl = opStat.TTL.String()
+		}
+		tb.Append([]string{opStat.Op, ttl, strconv.Itoa(opStat.Entries), strconv.Itoa(opStat.Expired), converter.ConvertFileSize(opStat.Size, 2)})
+	}
+	tb.Render()
+	fmt.Printf("本次运行: 命中 %d 次, 未命中 %d 次, 离线读取过期缓存 %d 次\n", stats.Hits, stats.Misses, stats.Stales)
+}
+
+// RunCacheClear 执行 清除当前帐号的元信息磁盘缓存
+func RunCacheClear() {
+	dc := GetBaiduPCS().DiskCache()
+	err := dc.Clear()
+	if err != nil {
+		fmt.Printf("清除缓存错误: %s\n", err)
+		return
+	}
+	fmt.Printf("已清除缓存: %s\n", dc.Dir())
+}
@@ -1,29 +1,47 @@
 package pcscommand
 
 import (
+	"errors"
 	"fmt"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/retry"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester"
 	"path"
 	"strings"
+	"time"
 )
 
 // RunCopy 执行 批量拷贝文件/目录
-func RunCopy(paths ...string) {
-	runCpMvOp("copy", paths...)
+func RunCopy(opt *CpMvOptions, paths ...string) {
+	runCpMvOp("copy", opt, paths...)
 }
 
 // RunMove 执行 批量 重命名/移动 文件/目录
-func RunMove(paths ...string) {
-	runCpMvOp("move", paths...)
+func RunMove(opt *CpMvOptions, paths ...string) {
+	runCpMvOp("move", opt, paths...)
 }
 
-func runCpMvOp(op string, paths ...string) {
+func runCpMvOp(op string, opt *CpMvOptions, paths ...string) {
 	err := cpmvPathValid(paths...) // 检查路径的有效性, 目前只是判断数量
 	if err != nil {
 		fmt.Printf("%s path error, %s\n", op, err)
 		return
 	}
+	if opt == nil {
+		opt = &CpMvOptions{}
+	}
+	if opt.Policy == "" {
+		opt.Policy = "fail"
+	}
+	if !cpmvPolicyValid(opt.Policy) {
+		fmt.Printf("未知的同名文件处理策略: %s, 可选: fail, skip, overwrite, newcopy\n", opt.Policy)
+		return
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultCpMvBatchSize
+	}
 
 	froms, to := cpmvParsePath(paths...) // 分割
 
@@ -49,7 +67,7 @@ func runCpMvOp(op string, paths ...string) {
 		}
 	}
 
-	pcs := GetBaiduPCS()
+	pcs := GetBaiduPCS().WithoutCache() // 按服务器上的文件规划复制/移动
 	toInfo, pcsError := pcs.FilesDirectoriesMeta(to)
 	switch {
 	case toInfo != nil && toInfo.Path != path.Clean(to):
@@ -64,6 +82,11 @@ func runCpMvOp(op string, paths ...string) {
 			return
 		}
 
+		if opt.DryRun {
+			fmt.Printf("[dry-run] %s: %s -> %s\n", cpmvOpName(op), froms[0], path.Clean(to))
+			return
+		}
+
 		if op == "copy" { // 拷贝
 			err = pcs.Copy(&baidupcs.CpMvJSON{
 				From: froms[0],
@@ -99,40 +122,272 @@ func runCpMvOp(op string, paths ...string) {
 		return
 	}
 
-	cj := new(baidupcs.CpMvListJSON)
-	cj.List = make([]*baidupcs.CpMvJSON, len(froms))
-	for k := range froms {
-		cj.List[k] = &baidupcs.CpMvJSON{
-			From: froms[k],
-			To:   path.Clean(to + baidupcs.PathSeparator + path.Base(froms[k])),
-		}
-	}
-
-	switch op {
-	case "copy":
-		err = pcs.Copy(cj.List...)
+	planner := newCpMvPlanner(pcs, op, opt)
+	for _, from := range froms {
+		err = planner.add(from, nil, path.Clean(to+baidupcs.PathSeparator+path.Base(from)))
 		if err != nil {
 			fmt.Println(err)
-			fmt.Println("操作失败, 以下文件/目录拷贝失败: ")
-			fmt.Println(cj)
 			return
 		}
-		fmt.Println("操作成功, 以下文件/目录拷贝成功: ")
-		fmt.Println(cj)
-	case "move":
-		err = pcs.Move(cj.List...)
-		if err != nil {
-			fmt.Println(err)
-			fmt.Println("操作失败, 以下文件/目录移动失败: ")
-			fmt.Println(cj)
-			return
+	}
+
+	if opt.DryRun {
+		printCpMvPlan(op, planner)
+		return
+	}
+	runCpMvPlan(pcs, op, opt, planner)
+}
+
+// cpmvOpName 返回操作的名称
+func cpmvOpName(op string) string {
+	if op == "copy" {
+		return "拷贝"
+	}
+	return "移动"
+}
+
+// printCpMvPlan 列出计划执行的所有操作
+func printCpMvPlan(op string, planner *cpmvPlanner) {
+	counts := map[cpmvAction]int{}
+	for _, item := range planner.items {
+		counts[item.action]++
+		switch item.action {
+		case cpmvActionDo:
+			fmt.Printf("[dry-run] %s: %s -> %s\n", cpmvOpName(op), item.from, item.to)
+		case cpmvActionOverwrite:
+			fmt.Printf("[dry-run] 删除已存在的目标并%s: %s -> %s\n", cpmvOpName(op), item.from, item.to)
+		case cpmvActionSkip:
+			fmt.Printf("[dry-run] 目标已存在, 跳过: %s -> %s\n", item.from, item.to)
+		case cpmvActionConflict:
+			fmt.Printf("[dry-run] 目标已存在, 失败: %s -> %s\n", item.from, item.to)
 		}
-		fmt.Println("操作成功, 以下文件/目录移动成功: ")
-		fmt.Println(cj)
-	default:
-		panic("Unknown operation:" + op)
 	}
-	return
+	for _, dir := range planner.removableDirs(nil) {
+		fmt.Printf("[dry-run] 合并后删除源目录: %s\n", dir)
+	}
+	fmt.Printf("共 %d 项, %s %d, 覆盖 %d, 跳过 %d, 冲突 %d\n", len(planner.items), cpmvOpName(op), counts[cpmvActionDo], counts[cpmvActionOverwrite], counts[cpmvActionSkip], counts[cpmvActionConflict])
+}
+
+// runCpMvPlan 分批执行计划, 每批失败时按错误类型重试, 并输出进度
+func runCpMvPlan(pcs *baidupcs.BaiduPCS, op string, opt *CpMvOptions, planner *cpmvPlanner) {
+	var (
+		todo      []*cpmvPlanItem
+		overwrite []*cpmvPlanItem
+		failed    = map[*cpmvPlanItem]bool{}
+		skipped   int
+		conflicts int
+	)
+	for _, item := range planner.items {
+		switch item.action {
+		case cpmvActionDo:
+			todo = append(todo, item)
+		case cpmvActionOverwrite:
+			overwrite = append(overwrite, item)
+		case cpmvActionSkip:
+			skipped++
+			pcsCommandVerbose.Infof("目标已存在, 跳过: %s -> %s\n", item.from, item.to)
+		case cpmvActionConflict:
+			conflicts++
+			fmt.Printf("目标已存在, %s失败: %s -> %s\n", cpmvOpName(op), item.from, item.to)
+		}
+	}
+
+	// 覆盖: 每批先删除已存在的目标, 再立即拷贝/移动这一批
+	var (
+		total = len(todo) + len(overwrite)
+		done  int
+	)
+	for _, group := range [][]*cpmvPlanItem{todo, overwrite} {
+		for i := 0; i < len(group); i += opt.BatchSize {
+			batch := group[i:min(i+opt.BatchSize, len(group))]
+			done += len(batch)
+			runCpMvBatch(pcs, op, batch, failed)
+			if total > opt.BatchSize {
+				fmt.Printf("\r[%s] %d/%d, 失败 %d ......", cpmvOpName(op), done, total, len(failed))
+			}
+		}
+	}
+	if total > opt.BatchSize {
+		fmt.Printf("\n")
+	}
+	todo = append(todo, overwrite...)
+
+	// 移动并合并: 删除已移空的源目录
+	dirs := planner.removableDirs(failed)
+	for i := 0; i < len(dirs); i += opt.BatchSize {
+		batch := dirs[i:min(i+opt.BatchSize, len(dirs))]
+		_, pcsError := cpmvSubmit(len(batch), func(idx []int) pcserror.Error {
+			return pcs.Remove(cpmvSubPaths(batch, idx)...)
+		}, func(k int) bool {
+			return cpmvRemoved(pcs, batch[k])
+		})
+		if pcsError != nil {
+			fmt.Printf("删除已合并的源目录错误: %s\n", pcsError)
+		}
+	}
+
+	if total <= opt.BatchSize && len(failed) == 0 && skipped == 0 && conflicts == 0 {
+		list := make([]*baidupcs.CpMvJSON, len(todo))
+		for k, item := range todo {
+			list[k] = &baidupcs.CpMvJSON{
+				From: item.from,
+				To:   item.to,
+			}
+		}
+		fmt.Printf("操作成功, 以下文件/目录%s成功: \n", cpmvOpName(op))
+		fmt.Println(&baidupcs.CpMvListJSON{List: list})
+		return
+	}
+	fmt.Printf("操作结束, %s成功 %d, 跳过 %d, 冲突 %d, 失败 %d\n", cpmvOpName(op), total-countCpMvFailed(todo, failed), skipped, conflicts, len(failed))
+}
+
+// runCpMvBatch 拷贝/移动一批条目, 覆盖的条目先删除已存在的目标, 失败的条目记录到 failed
+func runCpMvBatch(pcs *baidupcs.BaiduPCS, op string, batch []*cpmvPlanItem, failed map[*cpmvPlanItem]bool) {
+	var overwrite []*cpmvPlanItem
+	for _, item := range batch {
+		if item.action == cpmvActionOverwrite {
+			overwrite = append(overwrite, item)
+		}
+	}
+	if len(overwrite) > 0 {
+		pending, pcsError := cpmvSubmit(len(overwrite), func(idx []int) pcserror.Error {
+			targets := make([]string, len(idx))
+			for k, i := range idx {
+				targets[k] = overwrite[i].to
+			}
+			return pcs.Remove(targets...)
+		}, func(k int) bool {
+			return cpmvRemoved(pcs, overwrite[k].to)
+		})
+		if pcsError != nil {
+			fmt.Printf("\n删除已存在的目标错误: %s\n", pcsError)
+			for _, k := range pending {
+				failed[overwrite[k]] = true
+			}
+		}
+	}
+
+	var items []*cpmvPlanItem
+	for _, item := range batch {
+		if !failed[item] {
+			items = append(items, item)
+		}
+	}
+	pending, pcsError := cpmvSubmit(len(items), func(idx []int) pcserror.Error {
+		list := make([]*baidupcs.CpMvJSON, len(idx))
+		for k, i := range idx {
+			list[k] = &baidupcs.CpMvJSON{
+				From: items[i].from,
+				To:   items[i].to,
+			}
+		}
+		if op == "copy" {
+			return pcs.Copy(list...)
+		}
+		return pcs.Move(list...)
+	}, func(k int) bool {
+		return cpmvApplied(pcs, op, items[k].from, items[k].to)
+	})
+	if pcsError == nil {
+		return
+	}
+
+	var (
+		list    = make([]*baidupcs.CpMvJSON, len(pending))
+		removed []string
+	)
+	for k, i := range pending {
+		item := items[i]
+		list[k] = &baidupcs.CpMvJSON{
+			From: item.from,
+			To:   item.to,
+		}
+		if item.action == cpmvActionOverwrite {
+			removed = append(removed, item.to)
+		}
+		failed[item] = true
+	}
+	fmt.Printf("\n%s失败: %s, 以下文件/目录未完成: \n", cpmvOpName(op), pcsError)
+	fmt.Println(&baidupcs.CpMvListJSON{List: list})
+	if len(removed) > 0 {
+		fmt.Printf("以下已存在的目标已被删除, 可在回收站中找回: \n")
+		for _, target := range removed {
+			fmt.Println(target)
+		}
+	}
+}
+
+// countCpMvFailed 统计 items 中失败的数量
+func countCpMvFailed(items []*cpmvPlanItem, failed map[*cpmvPlanItem]bool) (n int) {
+	for _, item := range items {
+		if failed[item] {
+			n++
+		}
+	}
+	return n
+}
+
+// cpmvSubmit 提交 n 个条目的非幂等批量操作 (拷贝, 移动, 删除), submit 提交序号为 idx 的条目,
+// 返回未完成的条目序号和最后的错误.
+// 只有请求过于频繁或请求未发出时直接重试; 其他错误 (例如发出请求后网络中断, 部分条目失败) 时,
+// 请求可能已经执行, 先用 applied 逐个检查, 只重新提交未完成的条目
+func cpmvSubmit(n int, submit func(idx []int) pcserror.Error, applied func(k int) bool) (pending []int, pcsError pcserror.Error) {
+	pending = make([]int, n)
+	for k := range pending {
+		pending[k] = k
+	}
+	for i := 0; ; i++ {
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		pcsError = submit(pending)
+		if pcsError == nil {
+			return nil, nil
+		}
+		if !errors.Is(pcsError, pcserror.ErrRateLimited) && !requester.IsRequestNotSent(pcsError) {
+			left := pending[:0]
+			for _, k := range pending {
+				if !applied(k) {
+					left = append(left, k)
+				}
+			}
+			pending = left
+			if len(pending) == 0 {
+				return nil, nil
+			}
+		}
+		if i >= pcsconfig.Config.APIMaxRetry || !pcserror.IsRetryable(pcsError) {
+			return pending, pcsError
+		}
+		pcsCommandVerbose.Warnf("%s, 重试 %d/%d\n", pcsError, i+1, pcsconfig.Config.APIMaxRetry)
+		time.Sleep(retry.Backoff(i + 1))
+	}
+}
+
+// cpmvApplied 检查拷贝/移动是否已执行: 目标已存在, 移动时源已不存在
+func cpmvApplied(pcs *baidupcs.BaiduPCS, op, from, to string) bool {
+	if _, pcsError := pcs.FilesDirectoriesMeta(to); pcsError != nil {
+		return false
+	}
+	if op == "copy" {
+		return true
+	}
+	return cpmvRemoved(pcs, from)
+}
+
+// cpmvRemoved 检查 pcspath 是否已不存在
+func cpmvRemoved(pcs *baidupcs.BaiduPCS, pcspath string) bool {
+	_, pcsError := pcs.FilesDirectoriesMeta(pcspath)
+	return pcsError != nil && errors.Is(pcsError, pcserror.ErrNotFound)
+}
+
+// cpmvSubPaths 返回 paths 中序号为 idx 的路径
+func cpmvSubPaths(paths []string, idx []int) []string {
+	sub := make([]string, len(idx))
+	for k, i := range idx {
+		sub[k] = paths[i]
+	}
+	return sub
 }
 
 // cpmvPathValid 检查路径的有效性
@@ -0,0 +1,238 @@
+package pcscommand
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"path"
+	"strings"
+)
+
+const (
+	// DefaultCpMvBatchSize 拷贝/移动每次请求的默认最大条目数
+	DefaultCpMvBatchSize = 100
+)
+
+const (
+	// cpmvActionDo 直接拷贝/移动
+	cpmvActionDo cpmvAction = iota
+	// cpmvActionOverwrite 删除已存在的目标后拷贝/移动
+	cpmvActionOverwrite
+	// cpmvActionSkip 目标已存在, 跳过
+	cpmvActionSkip
+	// cpmvActionConflict 目标已存在, 按 fail 策略不执行
+	cpmvActionConflict
+)
+
+type (
+	// CpMvOptions 拷贝/移动可选项
+	CpMvOptions struct {
+		Policy    string // 目标已存在时的处理策略: fail, skip, overwrite, newcopy
+		Merge     bool   // 目标为已存在的目录时, 递归合并到该目录中
+		DryRun    bool   // 只列出计划执行的操作, 不执行
+		BatchSize int    // 每次请求的最大条目数
+	}
+
+	cpmvAction int
+
+	// cpmvPlanItem 计划执行的一项操作
+	cpmvPlanItem struct {
+		action cpmvAction
+		from   string
+		to     string
+	}
+
+	// cpmvPlanner 根据目标目录中已存在的文件, 生成拷贝/移动的计划
+	cpmvPlanner struct {
+		op     string
+		policy string
+		merge  bool
+		list   func(dir string) (baidupcs.FileDirectoryList, error)
+		meta   func(pcspath string) (*baidupcs.FileDirectory, error)
+
+		dirs       map[string]map[string]bool // 目标目录中已存在或计划写入的名称, 值为是否为目录
+		items      []*cpmvPlanItem
+		mergedDirs []string // 移动并合并后, 需要删除的源目录
+	}
+)
+
+func (action cpmvAction) String() string {
+	switch action {
+	case cpmvActionDo:
+		return "执行"
+	case cpmvActionOverwrite:
+		return "覆盖"
+	case cpmvActionSkip:
+		return "跳过"
+	case cpmvActionConflict:
+		return "冲突"
+	}
+	return "未知"
+}
+
+// cpmvPolicyValid 检查同名处理策略
+func cpmvPolicyValid(policy string) bool {
+	switch policy {
+	case "fail", "skip", "overwrite", "newcopy":
+		return true
+	}
+	return false
+}
+
+func newCpMvPlanner(pcs *baidupcs.BaiduPCS, op string, opt *CpMvOptions) *cpmvPlanner {
+	return &cpmvPlanner{
+		op:     op,
+		policy: opt.Policy,
+		merge:  opt.Merge,
+		list: func(dir string) (baidupcs.FileDirectoryList, error) {
+			fdl, pcsError := pcs.FilesDirectoriesList(dir, baidupcs.DefaultOrderOptions)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			return fdl, nil
+		},
+		meta: func(pcspath string) (*baidupcs.FileDirectory, error) {
+			fd, pcsError := pcs.FilesDirectoriesMeta(pcspath)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			return fd, nil
+		},
+	}
+}
+
+// names 返回目标目录中的名称, 目录不存在时为空
+func (p *cpmvPlanner) names(dir string) (map[string]bool, error) {
+	if p.dirs == nil {
+		p.dirs = map[string]map[string]bool{}
+	}
+	if names, ok := p.dirs[dir]; ok {
+		return names, nil
+	}
+	fdl, err := p.list(dir)
+	if err != nil && !errors.Is(err, pcserror.ErrNotFound) {
+		return nil, fmt.Errorf("获取目录 %s 的文件列表错误: %w", dir, err)
+	}
+	names := make(map[string]bool, len(fdl))
+	for _, fd := range fdl {
+		names[fd.Filename] = fd.Isdir
+	}
+	p.dirs[dir] = names
+	return names, nil
+}
+
+// add 将 from 拷贝/移动到 to, fromInfo 为源文件的信息, 为 nil 时获取,
+// 以记录计划写入的是文件还是目录, 之后同名的源目录才能合并
+func (p *cpmvPlanner) add(from string, fromInfo *baidupcs.FileDirectory, to string) error {
+	if fromInfo == nil {
+		var err error
+		fromInfo, err = p.meta(from)
+		if err != nil {
+			return fmt.Errorf("获取 %s 的元信息错误: %w", from, err)
+		}
+	}
+
+	dir, name := path.Split(to)
+	dir = path.Clean(dir)
+	names, err := p.names(dir)
+	if err != nil {
+		return err
+	}
+	existIsDir, exists := names[name]
+	if !exists {
+		names[name] = fromInfo.Isdir
+		p.items = append(p.items, &cpmvPlanItem{action: cpmvActionDo, from: from, to: to})
+		return nil
+	}
+
+	if p.merge && existIsDir && fromInfo.Isdir {
+		return p.addMerge(from, to)
+	}
+
+	switch p.policy {
+	case "skip":
+		p.items = append(p.items, &cpmvPlanItem{action: cpmvActionSkip, from: from, to: to})
+	case "overwrite":
+		p.items = append(p.items, &cpmvPlanItem{action: cpmvActionOverwrite, from: from, to: to})
+	case "newcopy":
+		isDir := fromInfo.Isdir
+		newName := cpmvNewcopyName(name, isDir, func(n string) bool {
+			_, ok := names[n]
+			return ok
+		})
+		names[newName] = isDir
+		p.items = append(p.items, &cpmvPlanItem{action: cpmvActionDo, from: from, to: path.Join(dir, newName)})
+	default:
+		p.items = append(p.items, &cpmvPlanItem{action: cpmvActionConflict, from: from, to: to})
+	}
+	return nil
+}
+
+// addMerge 将目录 from 中的文件和目录逐个加入计划, 合并到已存在的目录 to
+func (p *cpmvPlanner) addMerge(from, to string) error {
+	fdl, err := p.list(from)
+	if err != nil {
+		return fmt.Errorf("获取目录 %s 的文件列表错误: %w", from, err)
+	}
+	if p.op == "move" {
+		p.mergedDirs = append(p.mergedDirs, from)
+	}
+	for _, fd := range fdl {
+		err = p.add(fd.Path, fd, path.Join(to, fd.Filename))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removableDirs 返回移动并合并后可以删除的源目录, 目录下有跳过, 冲突或失败的条目时保留
+func (p *cpmvPlanner) removableDirs(failed map[*cpmvPlanItem]bool) (dirs []string) {
+	kept := func(dir string) bool {
+		for _, item := range p.items {
+			if item.action != cpmvActionSkip && item.action != cpmvActionConflict && !failed[item] {
+				continue
+			}
+			if strings.HasPrefix(item.from, dir+baidupcs.PathSeparator) {
+				return true
+			}
+		}
+		return false
+	}
+	// 先加入的为上层目录, 上层目录删除时, 子目录一并删除
+	for _, dir := range p.mergedDirs {
+		if kept(dir) {
+			continue
+		}
+		covered := false
+		for _, d := range dirs {
+			if strings.HasPrefix(dir, d+baidupcs.PathSeparator) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// cpmvNewcopyName 生成不重名的名称, 例如 a.txt -> a(1).txt
+func cpmvNewcopyName(name string, isDir bool, exists func(string) bool) string {
+	base, ext := name, ""
+	if !isDir {
+		ext = path.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+	}
+	if base == "" { // 例如 .bashrc
+		base, ext = name, ""
+	}
+	for i := 1; ; i++ {
+		newName := fmt.Sprintf("%s(%d)%s", base, i, ext)
+		if !exists(newName) {
+			return newName
+		}
+	}
+}
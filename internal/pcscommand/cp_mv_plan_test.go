@@ -0,0 +1,169 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// newTestCpMvPlanner 使用内存中的目录树生成计划, 以 / 结尾的路径为目录
+func newTestCpMvPlanner(op string, opt *CpMvOptions, paths ...string) *cpmvPlanner {
+	tree := map[string]baidupcs.FileDirectoryList{}
+	metas := map[string]*baidupcs.FileDirectory{}
+	for _, p := range paths {
+		isDir := p[len(p)-1] == '/'
+		p = path.Clean(p)
+		fd := &baidupcs.FileDirectory{Path: p, Filename: path.Base(p), Isdir: isDir}
+		metas[p] = fd
+		tree[path.Dir(p)] = append(tree[path.Dir(p)], fd)
+	}
+	return &cpmvPlanner{
+		op:     op,
+		policy: opt.Policy,
+		merge:  opt.Merge,
+		list: func(dir string) (baidupcs.FileDirectoryList, error) {
+			if _, ok := metas[dir]; !ok && dir != "/" {
+				return nil, pcserror.ErrNotFound
+			}
+			return tree[dir], nil
+		},
+		meta: func(pcspath string) (*baidupcs.FileDirectory, error) {
+			if fd, ok := metas[pcspath]; ok {
+				return fd, nil
+			}
+			return nil, pcserror.ErrNotFound
+		},
+	}
+}
+
+func cpmvPlanStrings(p *cpmvPlanner) (plan []string) {
+	for _, item := range p.items {
+		plan = append(plan, fmt.Sprintf("%s %s -> %s", item.action, item.from, item.to))
+	}
+	return plan
+}
+
+func TestCpMvPlanPolicy(t *testing.T) {
+	paths := []string{"/src/", "/src/a.txt", "/src/b.txt", "/dst/", "/dst/a.txt", "/dst/a(1).txt"}
+	for policy, want := range map[string][]string{
+		"fail":      {"冲突 /src/a.txt -> /dst/a.txt", "执行 /src/b.txt -> /dst/b.txt"},
+		"skip":      {"跳过 /src/a.txt -> /dst/a.txt", "执行 /src/b.txt -> /dst/b.txt"},
+		"overwrite": {"覆盖 /src/a.txt -> /dst/a.txt", "执行 /src/b.txt -> /dst/b.txt"},
+		"newcopy":   {"执行 /src/a.txt -> /dst/a(2).txt", "执行 /src/b.txt -> /dst/b.txt"},
+	} {
+		p := newTestCpMvPlanner("copy", &CpMvOptions{Policy: policy}, paths...)
+		for _, from := range []string{"/src/a.txt", "/src/b.txt"} {
+			if err := p.add(from, nil, path.Join("/dst", path.Base(from))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := cpmvPlanStrings(p); !reflect.DeepEqual(got, want) {
+			t.Errorf("policy %s: got %q, want %q", policy, got, want)
+		}
+	}
+}
+
+func TestCpMvPlanMerge(t *testing.T) {
+	p := newTestCpMvPlanner("move", &CpMvOptions{Policy: "skip", Merge: true},
+		"/src/", "/src/d/", "/src/d/x.txt", "/src/d/sub/", "/src/d/sub/y.txt", "/src/d/new/",
+		"/dst/", "/dst/d/", "/dst/d/x.txt", "/dst/d/sub/")
+	if err := p.add("/src/d", nil, "/dst/d"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"跳过 /src/d/x.txt -> /dst/d/x.txt",
+		"执行 /src/d/sub/y.txt -> /dst/d/sub/y.txt",
+		"执行 /src/d/new -> /dst/d/new",
+	}
+	if got := cpmvPlanStrings(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// x.txt 被跳过, 保留 /src/d, 已移空的 /src/d/sub 可以删除
+	if dirs := p.removableDirs(nil); !reflect.DeepEqual(dirs, []string{"/src/d/sub"}) {
+		t.Fatalf("removable dirs: %q", dirs)
+	}
+	failed := map[*cpmvPlanItem]bool{p.items[1]: true}
+	if dirs := p.removableDirs(failed); len(dirs) != 0 {
+		t.Fatalf("removable dirs with failure: %q", dirs)
+	}
+}
+
+func TestCpMvPlanMergeTopLevel(t *testing.T) {
+	// 第一个源目录计划写入 /dst/d 后, 同名的第二个源目录合并到其中
+	p := newTestCpMvPlanner("copy", &CpMvOptions{Policy: "fail", Merge: true},
+		"/a/", "/a/d/", "/b/", "/b/d/", "/b/d/y.txt", "/dst/")
+	for _, from := range []string{"/a/d", "/b/d"} {
+		if err := p.add(from, nil, path.Join("/dst", path.Base(from))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"执行 /a/d -> /dst/d", "执行 /b/d/y.txt -> /dst/d/y.txt"}
+	if got := cpmvPlanStrings(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if err := p.add("/missing", nil, "/dst/missing"); err == nil {
+		t.Fatal("missing source should fail")
+	}
+}
+
+func TestCpMvNewcopyName(t *testing.T) {
+	exists := func(name string) bool { return name == "a(1).txt" }
+	for _, c := range []struct {
+		name  string
+		isDir bool
+		want  string
+	}{
+		{"a.txt", false, "a(2).txt"},
+		{"v1.2", true, "v1.2(1)"},
+		{".bashrc", false, ".bashrc(1)"},
+	} {
+		if got := cpmvNewcopyName(c.name, c.isDir, exists); got != c.want {
+			t.Errorf("cpmvNewcopyName(%s) = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCpMvSubmit(t *testing.T) {
+	maxRetry := pcsconfig.Config.APIMaxRetry
+	pcsconfig.Config.APIMaxRetry = 0
+	defer func() { pcsconfig.Config.APIMaxRetry = maxRetry }()
+
+	var (
+		unknown = &pcserror.PanErrorInfo{Operation: "test", ErrType: pcserror.ErrTypeRemoteError, ErrNo: -1}
+		limited = &pcserror.PanErrorInfo{Operation: "test", ErrType: pcserror.ErrTypeRemoteError, ErrNo: 31034}
+	)
+	for _, c := range []struct {
+		name    string
+		err     pcserror.Error
+		applied map[int]bool
+		checked bool
+		pending []int
+	}{
+		{"ok", nil, nil, false, nil},
+		// 请求已发出, 只有未执行的条目算作失败
+		{"partly applied", unknown, map[int]bool{0: true, 2: true}, true, []int{1}},
+		{"all applied", unknown, map[int]bool{0: true, 1: true, 2: true}, true, nil},
+		// 请求过于频繁时没有执行, 不需要检查
+		{"rate limited", limited, map[int]bool{0: true}, false, []int{0, 1, 2}},
+	} {
+		var checked bool
+		pending, pcsError := cpmvSubmit(3, func(idx []int) pcserror.Error {
+			return c.err
+		}, func(k int) bool {
+			checked = true
+			return c.applied[k]
+		})
+		if !reflect.DeepEqual(pending, c.pending) || checked != c.checked {
+			t.Errorf("%s: pending %v, checked %t, want %v, %t", c.name, pending, checked, c.pending, c.checked)
+		}
+		if (pcsError == nil) != (c.pending == nil) {
+			t.Errorf("%s: error %v", c.name, pcsError)
+		}
+	}
+}
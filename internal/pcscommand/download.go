@@ -8,13 +8,15 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsdownload"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/downloader"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/transfer"
-	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 type (
@@ -33,6 +35,10 @@ type (
 		ModifyMTime          bool
 		FullPath             bool
 		LinkPrefer           int
+		AutoParallel         bool
+		Accounts             []string           // 多帐号下载使用的其他帐号, 百度ID或uid
+		Filter               *filefilter.Filter // 文件过滤规则
+		Unpack               bool               // 下载的路径为打包索引, 只下载其中的成员
 	}
 
 	// LocateDownloadOption 获取下载链接可选参数
@@ -77,9 +83,15 @@ func RunDownload(paths []string, options *DownloadOptions) {
 		CacheSize:                  pcsconfig.Config.CacheSize,
 		BlockSize:                  baidupcs.InitRangeSize,
 		MaxRate:                    pcsconfig.Config.MaxDownloadRate,
+		RateLimit:                  pcsconfig.Config.DownloadRateLimit(),
 		InstanceStateStorageFormat: downloader.InstanceStateStorageFormatProto3,
 		IsTest:                     options.IsTest,
 		TryHTTP:                    !pcsconfig.Config.EnableHTTPS,
+		AutoParallel:               options.AutoParallel,
+		MirrorHealth:               downloader.NewMirrorHealth(pcsconfig.Config.MirrorBlocklistHosts()),
+	}
+	if options.AutoParallel {
+		cfg.ParallelHistory = downloader.NewParallelHistory(filepath.Join(pcsconfig.GetConfigDir(), pcsdownload.ParallelHistoryFileName))
 	}
 
 	// 设置下载最大并发量
@@ -93,14 +105,51 @@ func RunDownload(paths []string, options *DownloadOptions) {
 		return
 	}
 
-	fmt.Print("\n")
-	fmt.Printf("[0] 提示: 当前下载最大并发量为: %d, 下载缓存为: %d\n", options.Parallel, cfg.CacheSize)
+	runner := takeRunner()
+	if runner.IsBackground {
+		job := startJob(runner.Cmdline, func(r *Runner) {
+			runDownload(paths, options, cfg, r)
+		})
+		fmt.Printf("[%d] %s\n", job.ID, job.Cmdline)
+		return
+	}
+	runDownload(paths, options, cfg, runner)
+}
+
+// runDownload 下载已匹配的网盘路径, 输出到 r.Output
+func runDownload(paths []string, options *DownloadOptions, cfg *downloader.Config, r *Runner) {
+	if options.Unpack {
+		runUnpack(paths, options, r)
+		return
+	}
+
+	fmt.Fprint(r.Output, "\n")
+	fmt.Fprintf(r.Output, "[0] 提示: 当前下载最大并发量为: %d, 下载缓存为: %d\n", options.Parallel, cfg.CacheSize)
+	if options.AutoParallel {
+		fmt.Fprintf(r.Output, "[0] 提示: 已开启自动调整并发量, 最大并发量作为上限\n")
+	}
 
 	var (
-		pcs       = GetBaiduPCS()
+		pcs       = r.baiduPCS().WithoutCache() // 文件的大小和 md5 以服务器为准
 		loadCount = 0
+		pool      *pcsdownload.AccountPool
 	)
 
+	if len(options.Accounts) > 0 {
+		if options.DownloadMode != pcsdownload.DownloadModeLocate {
+			fmt.Fprintf(r.Output, "多帐号下载只支持定位下载模式 (locate)\n")
+			return
+		}
+		accounts, err := poolAccounts(pcs, options.Accounts)
+		if err != nil {
+			fmt.Fprintf(r.Output, "%s\n", err)
+			return
+		}
+		pool = pcsdownload.NewAccountPool(pcsCommandVerbose, accounts...)
+		defer pool.Cleanup()
+		fmt.Fprintf(r.Output, "[0] 提示: 已开启多帐号下载, 额外使用 %d 个帐号\n", pool.Len())
+	}
+
 	// 预测要下载的文件数量
 	file_dir_list := make([]*baidupcs.FileDirectory,0,10)
 	for k := range paths {
@@ -109,6 +158,9 @@ func RunDownload(paths []string, options *DownloadOptions) {
 				pcsCommandVerbose.Warnf("%s\n", pcsError)
 				return true
 			}
+			if !remoteFilterMatch(paths[k], fd, options.Filter) {
+				return true
+			}
 			file_dir_list = append(file_dir_list, fd)
 			// 忽略统计文件夹数量
 			if !fd.Isdir {
@@ -158,6 +210,9 @@ func RunDownload(paths []string, options *DownloadOptions) {
 			ModifyMTime:          options.ModifyMTime,
 			PcsPath:              v.Path,
 			FileInfo:             v,
+			Out:                  r.Output,
+			Control:              r.Control,
+			AccountPool:          pool,
 		}
 		// 设置下载并发数
 		executor.SetParallel(loadCount)
@@ -173,7 +228,7 @@ func RunDownload(paths []string, options *DownloadOptions) {
 			unit.SavePath = GetActiveUser().GetSavePath(vPath)
 		}
 		info := executor.Append(&unit, options.MaxRetry)
-		fmt.Printf("[%s] 加入下载队列: %s\n", info.Id(), v.Path)
+		fmt.Fprintf(r.Output, "[%s] 加入下载队列: %s\n", info.Id(), v.Path)
 	}
 
 	// 开始计时
@@ -182,13 +237,13 @@ func RunDownload(paths []string, options *DownloadOptions) {
 	// 开始执行
 	executor.Execute()
 
-	fmt.Printf("\n下载结束, 时间: %s, 数据总量: %s\n", statistic.Elapsed()/1e6*1e6, converter.ConvertFileSize(statistic.TotalSize()))
+	fmt.Fprintf(r.Output, "\n下载结束, 时间: %s, 数据总量: %s\n", statistic.Elapsed()/1e6*1e6, converter.ConvertFileSize(statistic.TotalSize()))
 
 	// 输出失败的文件列表
 	failedList := executor.FailedDeque()
 	if failedList.Size() != 0 {
-		fmt.Printf("以下文件下载失败: \n")
-		tb := pcstable.NewTable(os.Stdout)
+		fmt.Fprintf(r.Output, "以下文件下载失败: \n")
+		tb := pcstable.NewTable(r.Output)
 		for e := failedList.Shift(); e != nil; e = failedList.Shift() {
 			item := e.(*taskframework.TaskInfoItem)
 			tb.Append([]string{item.Info.Id(), item.Unit.(*pcsdownload.DownloadTaskUnit).PcsPath})
@@ -196,3 +251,63 @@ func RunDownload(paths []string, options *DownloadOptions) {
 		tb.Render()
 	}
 }
+
+// poolAccounts 查找多帐号下载使用的帐号, names 以逗号分隔, 跳过当前帐号
+func poolAccounts(pcs *baidupcs.BaiduPCS, names []string) (accounts []*pcsdownload.PoolAccount, err error) {
+	var (
+		activeUID = GetActiveUser().UID
+		seen      = map[uint64]bool{activeUID: true}
+	)
+	for _, name := range names {
+		for _, n := range strings.Split(name, ",") {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			user, err := lookupBaiduUser(n)
+			if err != nil {
+				return nil, fmt.Errorf("多帐号下载: 帐号 %s 不存在或未登录", n)
+			}
+			if seen[user.UID] {
+				continue
+			}
+			seen[user.UID] = true
+			accounts = append(accounts, &pcsdownload.PoolAccount{
+				Name: user.Name,
+				PCS:  user.BaiduPCS().WithContext(pcs.Context()).WithoutCache(),
+			})
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("多帐号下载: 没有可用的其他帐号")
+	}
+	return accounts, nil
+}
+
+// lookupBaiduUser 按uid或百度ID查找已登录的帐号
+func lookupBaiduUser(name string) (*pcsconfig.Baidu, error) {
+	if uid, err := strconv.ParseUint(name, 10, 64); err == nil {
+		if user, err := pcsconfig.Config.GetBaiduUser(&pcsconfig.BaiduBase{UID: uid}); err == nil {
+			return user, nil
+		}
+	}
+	return pcsconfig.Config.GetBaiduUser(&pcsconfig.BaiduBase{Name: name})
+}
+
+// RunCleanPartials 清理下载目录中失效的临时文件
+func RunCleanPartials(saveTo string) {
+	dir := saveTo
+	if dir == "" {
+		dir = pcsconfig.Config.SaveDir
+	}
+
+	removed, err := pcsdownload.CleanPartials(dir, pcsdownload.PartialStaleDuration)
+	for _, path := range removed {
+		fmt.Printf("已删除: %s\n", path)
+	}
+	if err != nil {
+		fmt.Printf("清理临时文件错误: %s\n", err)
+		return
+	}
+	fmt.Printf("清理完成, 共删除 %d 个超过 %s 未修改的临时文件, 目录: %s\n", len(removed), pcsdownload.PartialStaleDuration, dir)
+}
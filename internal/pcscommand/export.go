@@ -2,6 +2,7 @@ package pcscommand
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -11,6 +12,7 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/pcstime"
 )
 
@@ -19,6 +21,7 @@ type (
 		*ListTask
 		path     string
 		rootPath string
+		walkRoot string // 过滤规则的根目录
 		fd       *baidupcs.FileDirectory
 		err      pcserror.Error
 	}
@@ -31,6 +34,7 @@ type (
 		Recursive  bool
 		LinkFormat bool
 		StdOut     bool
+		Filter     *filefilter.Filter // 文件过滤规则
 	}
 )
 
@@ -40,15 +44,19 @@ func (task *etask) handleExportTaskError(l *list.List, failedList *list.List) {
 	}
 
 	// 不重试
-	switch task.err.GetError() {
-	case baidupcs.ErrGetRapidUploadInfoMD5NotFound, baidupcs.ErrGetRapidUploadInfoCrc32NotFound:
+	switch {
+	case errors.Is(task.err, baidupcs.ErrGetRapidUploadInfoMD5NotFound), errors.Is(task.err, baidupcs.ErrGetRapidUploadInfoCrc32NotFound):
 		fmt.Printf("[%d] - [%s] 导出失败, 可能是服务器未刷新文件的md5, 请过一段时间再试一试\n", task.ID, task.path)
 		failedList.PushBack(task)
 		return
-	case baidupcs.ErrFileTooLarge:
+	case errors.Is(task.err, pcserror.ErrFileTooLarge):
 		fmt.Printf("[%d] - [%s] 导出失败, 文件大于20GB, 无法导出\n", task.ID, task.path)
 		failedList.PushBack(task)
 		return
+	case !pcserror.IsRetryable(task.err):
+		fmt.Printf("[%d] - [%s] 导出错误, %s\n", task.ID, task.path, task.err)
+		failedList.PushBack(task)
+		return
 	}
 
 	// 未达到失败重试最大次数, 将任务推送到队列末尾
@@ -126,6 +134,7 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 			},
 			path:     pcspaths[id],
 			rootPath: rootPath,
+			walkRoot: pcspaths[id],
 		})
 	}
 
@@ -149,6 +158,9 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 				continue
 			}
 			task.fd = fd
+			if !remoteFilterMatch(task.walkRoot, fd, opt.Filter) {
+				continue
+			}
 		}
 
 		if task.fd.Isdir { // 导出目录
@@ -175,6 +187,9 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 
 			// 加入队列
 			for _, fd := range fds {
+				if !remoteFilterMatch(task.walkRoot, fd, opt.Filter) {
+					continue
+				}
 				// 加入队列
 				id++
 				l.PushBack(&etask{
@@ -185,6 +200,7 @@ func RunExport(pcspaths []string, opt *ExportOptions) {
 					path:     fd.Path,
 					fd:       fd,
 					rootPath: task.rootPath,
+					walkRoot: task.walkRoot,
 				})
 			}
 			continue
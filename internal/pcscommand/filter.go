@@ -0,0 +1,54 @@
+package pcscommand
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localFilterFunc 返回遍历本地目录 root 时的过滤函数, 没有过滤规则时返回 nil
+func localFilterFunc(root string, filter *filefilter.Filter) func(filename string, fi os.FileInfo) bool {
+	if filter.IsEmpty() {
+		return nil
+	}
+	return func(filename string, fi os.FileInfo) bool {
+		rel, err := filepath.Rel(root, filename)
+		if err != nil || rel == "." {
+			rel = filepath.Base(filename)
+		}
+		rel = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			return !filter.SkipDir(rel)
+		}
+		return filter.MatchFile(rel, fi.Size(), fi.ModTime())
+	}
+}
+
+// remoteFilterRel 返回网盘文件相对于遍历根目录 root 的路径
+func remoteFilterRel(root string, fd *baidupcs.FileDirectory) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(fd.Path, path.Clean(root)), "/")
+	if rel == "" {
+		rel = path.Base(fd.Path)
+	}
+	return rel
+}
+
+// remoteFilterMatch 网盘文件或目录及其上级目录是否符合过滤规则
+func remoteFilterMatch(root string, fd *baidupcs.FileDirectory, filter *filefilter.Filter) bool {
+	if filter.IsEmpty() || fd == nil {
+		return true
+	}
+	if fd.Isdir {
+		// 根目录不过滤
+		if path.Clean(fd.Path) == path.Clean(root) {
+			return true
+		}
+		return filter.MatchDir(remoteFilterRel(root, fd))
+	}
+	rel := remoteFilterRel(root, fd)
+	return filter.Match(rel, fd.Size, time.Unix(fd.Mtime, 0))
+}
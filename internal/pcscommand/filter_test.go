@@ -0,0 +1,56 @@
+package pcscommand
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLocalFilterFunc(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "src")
+	for _, name := range []string{"a.go", "node_modules/x/b.go", ".git/config", "logs/d.log", "logs/keep.txt"} {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(filename), 0755)
+		os.WriteFile(filename, []byte("data"), 0644)
+	}
+
+	filter := &filefilter.Filter{
+		Excludes:      []string{"node_modules", "*.log"},
+		ExcludeHidden: true,
+	}
+	files, _, err := pcsutil.WalkDirFunc(root, localFilterFunc(root, filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range files {
+		files[k], _ = filepath.Rel(root, files[k])
+		files[k] = filepath.ToSlash(files[k])
+	}
+	sort.Strings(files)
+	if want := []string{"a.go", "logs/keep.txt"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("WalkDirFunc = %q, want %q", files, want)
+	}
+}
+
+func TestRemoteFilterMatch(t *testing.T) {
+	filter := &filefilter.Filter{Excludes: []string{"tmp"}}
+	cases := []struct {
+		fd   *baidupcs.FileDirectory
+		want bool
+	}{
+		{&baidupcs.FileDirectory{Path: "/share", Isdir: true}, true},
+		{&baidupcs.FileDirectory{Path: "/share/a.txt"}, true},
+		{&baidupcs.FileDirectory{Path: "/share/tmp", Isdir: true}, false},
+		{&baidupcs.FileDirectory{Path: "/share/tmp/b.txt"}, false},
+	}
+	for _, c := range cases {
+		if got := remoteFilterMatch("/share", c.fd, filter); got != c.want {
+			t.Errorf("remoteFilterMatch(%s) = %v, want %v", c.fd.Path, got, c.want)
+		}
+	}
+}
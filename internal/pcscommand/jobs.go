@@ -0,0 +1,409 @@
+package pcscommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// JobState 后台任务状态
+	JobState int
+
+	// Job 后台任务
+	Job struct {
+		ID        int
+		Cmdline   string
+		StartTime time.Time
+
+		output   jobOutput
+		control  pcsfunctions.TransferControl
+		cancel   context.CancelFunc
+		done     chan struct{}
+		killed   bool
+		notified bool
+	}
+
+	// jobOutput 后台任务的输出, 保留最近的输出, 转到前台时同时输出到 follow
+	jobOutput struct {
+		mu     sync.Mutex
+		buf    []byte
+		status string
+		follow io.Writer
+	}
+)
+
+const (
+	// JobRunning 运行中
+	JobRunning JobState = iota
+	// JobPaused 已暂停
+	JobPaused
+	// JobDone 已完成
+	JobDone
+	// JobKilled 已取消
+	JobKilled
+)
+
+const (
+	// maxJobOutputSize 每个后台任务最多保留的输出
+	maxJobOutputSize = int(256 * converter.KB)
+	// maxJobStatusLen 提示符中每个任务状态的最大长度
+	maxJobStatusLen = 16
+)
+
+var (
+	jobList   []*Job
+	jobListMu sync.Mutex
+	lastJobID int
+
+	// ErrJobNotFound 后台任务不存在
+	ErrJobNotFound = errors.New("后台任务不存在")
+	// ErrJobFinished 后台任务已结束
+	ErrJobFinished = errors.New("后台任务已结束")
+
+	jobSpeedRegexp = regexp.MustCompile(`([↓↑]) \S+/\S+ (\S+/s)`)
+)
+
+func (js JobState) String() string {
+	switch js {
+	case JobRunning:
+		return "运行中"
+	case JobPaused:
+		return "已暂停"
+	case JobDone:
+		return "已完成"
+	case JobKilled:
+		return "已取消"
+	}
+	return "未知"
+}
+
+func (jo *jobOutput) Write(p []byte) (int, error) {
+	jo.mu.Lock()
+	defer jo.mu.Unlock()
+
+	jo.buf = append(jo.buf, p...)
+	if len(jo.buf) > maxJobOutputSize {
+		// 丢弃最早的输出, 从完整的一行开始保留
+		drop := len(jo.buf) - maxJobOutputSize
+		if i := strings.IndexByte(string(jo.buf[drop:]), '\n'); i >= 0 {
+			drop += i + 1
+		}
+		jo.buf = append(jo.buf[:0], jo.buf[drop:]...)
+	}
+
+	// 记录最近的一行, 用于状态显示
+	lines := strings.FieldsFunc(string(p), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(strings.TrimRight(lines[i], "."))
+		if line != "" {
+			jo.status = line
+			break
+		}
+	}
+
+	if jo.follow != nil {
+		jo.follow.Write(p)
+	}
+	return len(p), nil
+}
+
+// setFollow 设置前台输出, 并输出已保留的内容
+func (jo *jobOutput) setFollow(w io.Writer) {
+	jo.mu.Lock()
+	defer jo.mu.Unlock()
+	if w != nil {
+		w.Write(jo.buf)
+	}
+	jo.follow = w
+}
+
+func (jo *jobOutput) lastStatus() string {
+	jo.mu.Lock()
+	defer jo.mu.Unlock()
+	return jo.status
+}
+
+// State 返回任务状态
+func (job *Job) State() JobState {
+	select {
+	case <-job.done:
+		jobListMu.Lock()
+		defer jobListMu.Unlock()
+		if job.killed {
+			return JobKilled
+		}
+		return JobDone
+	default:
+	}
+	if job.control.Paused() {
+		return JobPaused
+	}
+	return JobRunning
+}
+
+// Status 返回任务最近的进度输出
+func (job *Job) Status() string {
+	return job.output.lastStatus()
+}
+
+// shortStatus 返回提示符中显示的简短状态
+func (job *Job) shortStatus() string {
+	state := job.State()
+	if state != JobRunning {
+		return state.String()
+	}
+	m := jobSpeedRegexp.FindStringSubmatch(job.Status())
+	if m == nil {
+		return state.String()
+	}
+	s := m[1] + m[2]
+	if len(s) > maxJobStatusLen {
+		s = s[:maxJobStatusLen]
+	}
+	return s
+}
+
+func (job *Job) kill() {
+	jobListMu.Lock()
+	job.killed = true
+	jobListMu.Unlock()
+	job.cancel()
+	// 暂停中的传输需要恢复才能响应取消
+	job.control.Resume()
+}
+
+// startJob 在后台执行任务
+func startJob(cmdline string, run func(r *Runner)) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	jobListMu.Lock()
+	lastJobID++
+	job := &Job{
+		ID:        lastJobID,
+		Cmdline:   cmdline,
+		StartTime: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	jobList = append(jobList, job)
+	jobListMu.Unlock()
+
+	r := &Runner{
+		Output:       &job.output,
+		IsBackground: true,
+		Cmdline:      cmdline,
+		Control:      &job.control,
+		ctx:          ctx,
+	}
+	go func() {
+		defer close(job.done)
+		defer cancel()
+		run(r)
+	}()
+	return job
+}
+
+// findJob 查找后台任务, id 为空时返回最近的未结束任务
+func findJob(id string) (*Job, error) {
+	jobListMu.Lock()
+	defer jobListMu.Unlock()
+
+	id = strings.TrimPrefix(id, "%")
+	if id == "" {
+		for i := len(jobList) - 1; i >= 0; i-- {
+			select {
+			case <-jobList[i].done:
+			default:
+				return jobList[i], nil
+			}
+		}
+		return nil, ErrJobNotFound
+	}
+
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrJobNotFound, id)
+	}
+	for _, job := range jobList {
+		if job.ID == n {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %d", ErrJobNotFound, n)
+}
+
+// findActiveJob 查找未结束的后台任务
+func findActiveJob(id string) (*Job, error) {
+	job, err := findJob(id)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-job.done:
+		return nil, fmt.Errorf("%s: %d", ErrJobFinished, job.ID)
+	default:
+	}
+	return job, nil
+}
+
+// Jobs 返回所有后台任务
+func Jobs() []*Job {
+	jobListMu.Lock()
+	defer jobListMu.Unlock()
+	return append([]*Job(nil), jobList...)
+}
+
+// JobsSummary 返回未结束的后台任务的简短状态, 用于显示在提示符中
+func JobsSummary() string {
+	items := make([]string, 0, 4)
+	for _, job := range Jobs() {
+		switch job.State() {
+		case JobRunning, JobPaused:
+			items = append(items, strconv.Itoa(job.ID)+":"+job.shortStatus())
+		}
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(items, " ") + "]"
+}
+
+// JobNotifications 返回已结束但尚未通知的后台任务, 通知后从任务列表中移除
+func JobNotifications() []string {
+	jobListMu.Lock()
+	defer jobListMu.Unlock()
+
+	var (
+		msgs = make([]string, 0)
+		kept = jobList[:0]
+	)
+	for _, job := range jobList {
+		select {
+		case <-job.done:
+			if !job.notified {
+				state := JobDone
+				if job.killed {
+					state = JobKilled
+				}
+				msgs = append(msgs, fmt.Sprintf("[%d] %s\t%s", job.ID, state, job.Cmdline))
+				job.notified = true
+			}
+			continue
+		default:
+		}
+		kept = append(kept, job)
+	}
+	jobList = kept
+	return msgs
+}
+
+// RunJobs 列出后台任务
+func RunJobs() {
+	list := Jobs()
+	if len(list) == 0 {
+		fmt.Printf("没有后台任务\n")
+		return
+	}
+
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"#", "状态", "运行时间", "命令", "进度"})
+	for _, job := range list {
+		tb.Append([]string{strconv.Itoa(job.ID), job.State().String(), time.Since(job.StartTime).Round(time.Second).String(), job.Cmdline, job.Status()})
+	}
+	tb.Render()
+}
+
+// RunJobFg 将后台任务转到前台, 输出任务的进度直到结束, Ctrl-C 将任务转回后台
+func RunJobFg(id string) {
+	job, err := findJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	job.control.Resume()
+	fmt.Printf("[%d] %s\n", job.ID, job.Cmdline)
+	job.output.setFollow(os.Stdout)
+	defer job.output.setFollow(nil)
+
+	select {
+	case <-job.done:
+		fmt.Printf("\n[%d] %s\n", job.ID, job.State())
+	case <-Context().Done():
+		fmt.Printf("\n[%d] 已转入后台\n", job.ID)
+	}
+}
+
+// RunJobBg 在后台继续执行已暂停的任务
+func RunJobBg(id string) {
+	job, err := findActiveJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	job.control.Resume()
+	fmt.Printf("[%d] %s &\n", job.ID, job.Cmdline)
+}
+
+// RunJobKill 取消后台任务
+func RunJobKill(id string) {
+	job, err := findActiveJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	job.kill()
+	fmt.Printf("[%d] 已取消: %s\n", job.ID, job.Cmdline)
+}
+
+// RunJobPause 暂停后台任务
+func RunJobPause(id string) {
+	job, err := findActiveJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	job.control.Pause()
+	fmt.Printf("[%d] 已暂停: %s\n", job.ID, job.Cmdline)
+}
+
+// RunJobResume 恢复已暂停的后台任务
+func RunJobResume(id string) {
+	job, err := findActiveJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	job.control.Resume()
+	fmt.Printf("[%d] 已恢复: %s\n", job.ID, job.Cmdline)
+}
+
+// StopJobs 取消所有未结束的后台任务, 并等待任务保存断点续传信息后退出
+func StopJobs() {
+	for _, job := range Jobs() {
+		select {
+		case <-job.done:
+			continue
+		default:
+		}
+		job.kill()
+		<-job.done
+	}
+}
@@ -0,0 +1,83 @@
+package pcscommand
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakePauser struct {
+	paused chan bool
+}
+
+func (fp *fakePauser) Pause()  { fp.paused <- true }
+func (fp *fakePauser) Resume() { fp.paused <- false }
+
+func TestJobOutput(t *testing.T) {
+	var jo jobOutput
+	fmt.Fprintf(&jo, "[1] 准备下载: /a\n")
+	fmt.Fprintf(&jo, "\r[1] ↓ 1.00MB/4.00MB 512.00KB/s in 2s, left 6s ............")
+	if status := jo.lastStatus(); status != "[1] ↓ 1.00MB/4.00MB 512.00KB/s in 2s, left 6s" {
+		t.Fatalf("status: %q", status)
+	}
+
+	line := strings.Repeat("x", 1023) + "\n"
+	for i := 0; i < maxJobOutputSize/len(line)+10; i++ {
+		jo.Write([]byte(line))
+	}
+	if len(jo.buf) > maxJobOutputSize {
+		t.Fatalf("buffer not trimmed: %d", len(jo.buf))
+	}
+	if jo.buf[0] != 'x' || len(jo.buf)%len(line) != 0 {
+		t.Fatalf("buffer should start at a complete line")
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	var (
+		pauser  = &fakePauser{paused: make(chan bool, 4)}
+		started = make(chan struct{})
+	)
+	job := startJob("download /a", func(r *Runner) {
+		remove := r.Control.Add(pauser)
+		defer remove()
+		fmt.Fprintf(r.Output, "\r[1] ↓ 1.00MB/4.00MB 512.00KB/s in 2s, left 6s ...")
+		close(started)
+		<-r.ctx.Done()
+	})
+	<-started
+
+	if got := job.shortStatus(); got != "↓512.00KB/s" {
+		t.Fatalf("short status: %q", got)
+	}
+
+	found, err := findActiveJob("")
+	if err != nil || found != job {
+		t.Fatalf("findActiveJob: %v, %v", found, err)
+	}
+
+	job.control.Pause()
+	if !<-pauser.paused || job.State() != JobPaused {
+		t.Fatalf("job should be paused")
+	}
+	if summary := JobsSummary(); !strings.Contains(summary, fmt.Sprintf("%d:%s", job.ID, JobPaused)) {
+		t.Fatalf("summary: %q", summary)
+	}
+
+	job.kill()
+	<-job.done
+	if job.State() != JobKilled {
+		t.Fatalf("job should be killed, got %s", job.State())
+	}
+	if _, err = findActiveJob(fmt.Sprint(job.ID)); err == nil {
+		t.Fatalf("finished job should not be active")
+	}
+
+	msgs := JobNotifications()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "download /a") {
+		t.Fatalf("notifications: %v", msgs)
+	}
+	if len(Jobs()) != 0 {
+		t.Fatalf("notified jobs should be removed")
+	}
+}
@@ -0,0 +1,135 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/pcstime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExpandLocalPath 展开本地路径开头的 ~ 为用户主目录
+func ExpandLocalPath(localPath string) string {
+	if localPath != "~" && !strings.HasPrefix(localPath, "~/") && !strings.HasPrefix(localPath, "~"+string(os.PathSeparator)) {
+		return localPath
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return localPath
+	}
+	return filepath.Join(home, localPath[1:])
+}
+
+// RunLocalList 执行列出本地目录
+func RunLocalList(localPath string) {
+	if localPath == "" {
+		localPath = "."
+	}
+	localPath = ExpandLocalPath(localPath)
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var entries []os.FileInfo
+	if info.IsDir() {
+		dirEntries, err := os.ReadDir(localPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, entry := range dirEntries {
+			// 链接需要获取链接指向的文件信息
+			entryInfo, err := os.Stat(filepath.Join(localPath, entry.Name()))
+			if err != nil {
+				entryInfo, err = entry.Info()
+				if err != nil {
+					continue
+				}
+			}
+			entries = append(entries, entryInfo)
+		}
+	} else {
+		entries = []os.FileInfo{info}
+	}
+
+	// 目录在前
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		absPath = localPath
+	}
+	fmt.Printf("\n当前本地目录: %s\n----\n", absPath)
+
+	var (
+		tb        = pcstable.NewTable(os.Stdout)
+		fN, dN    int
+		totalSize int64
+	)
+	tb.SetHeader([]string{"#", "文件大小", "修改日期", "文件(目录)"})
+	tb.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	for k, entry := range entries {
+		if entry.IsDir() {
+			dN++
+			tb.Append([]string{strconv.Itoa(k), "-", pcstime.FormatTime(entry.ModTime().Unix()), entry.Name() + string(os.PathSeparator)})
+			continue
+		}
+		fN++
+		totalSize += entry.Size()
+		tb.Append([]string{strconv.Itoa(k), converter.ConvertFileSize(entry.Size(), 2), pcstime.FormatTime(entry.ModTime().Unix()), entry.Name()})
+	}
+	tb.Append([]string{"", "总: " + converter.ConvertFileSize(totalSize, 2), "", fmt.Sprintf("文件总数: %d, 目录总数: %d", fN, dN)})
+	tb.Render()
+	fmt.Printf("----\n")
+}
+
+// RunLocalChangeDirectory 执行更改本地工作目录, 影响 upload 等命令中的本地相对路径
+func RunLocalChangeDirectory(localPath string) {
+	if localPath == "" {
+		localPath = "~"
+	}
+	localPath = ExpandLocalPath(localPath)
+
+	err := os.Chdir(localPath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	RunLocalPwd()
+}
+
+// RunLocalPwd 输出本地工作目录
+func RunLocalPwd() {
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("本地工作目录: %s\n", wd)
+}
+
+// RunLocalMkdir 执行创建本地目录, 包括不存在的上级目录
+func RunLocalMkdir(localPaths ...string) {
+	for _, localPath := range localPaths {
+		err := os.MkdirAll(ExpandLocalPath(localPath), 0777)
+		if err != nil {
+			fmt.Printf("创建本地目录 %s 失败, %s\n", localPath, err)
+			continue
+		}
+		fmt.Println("创建本地目录成功:", localPath)
+	}
+}
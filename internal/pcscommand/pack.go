@@ -0,0 +1,344 @@
+package pcscommand
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsdownload"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcspack"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+type (
+	// packFile 打包上传的本地文件, name 为成员在索引中的名称
+	packFile struct {
+		localPath string
+		name      string
+	}
+
+	// segmentUpload 打包分段的输出, 写入的数据通过 StreamUploader 上传, Close 时等待上传完成,
+	// Abort 时中断上传, 不创建分段文件
+	segmentUpload struct {
+		pw   *io.PipeWriter
+		done chan error
+	}
+
+	// sequentialWriter 将单线程下载按顺序写入的数据输出到 w, 不需要在内存中保存整个成员
+	sequentialWriter struct {
+		w io.Writer
+		n int64
+	}
+)
+
+// MaxPackSize 打包分段的最大大小, 受数据流上传的分片数限制
+const MaxPackSize = uploader.DefaultStreamBlockSize * uploader.MaxStreamBlockNum
+
+// packPolicy 打包上传的同名文件处理策略, 分段和索引必须一一对应, 只支持覆盖, 其余策略均按 fail 处理
+func packPolicy(policy string) string {
+	if policy == "overwrite" {
+		return policy
+	}
+	return "fail"
+}
+
+func newSegmentUpload(pcs *baidupcs.BaiduPCS, savePath string, opt *UploadOptions, r *Runner) *segmentUpload {
+	pr, pw := io.Pipe()
+	su := uploader.NewStreamUploader(pcsupload.NewPCSUpload(pcs, savePath, nil), pr, &uploader.MultiUploaderConfig{
+		Parallel:  opt.Parallel,
+		BlockSize: uploader.DefaultStreamBlockSize,
+		MaxRate:   pcsconfig.Config.MaxUploadRate,
+		RateLimit: pcsconfig.Config.UploadRateLimit(),
+		Policy:    packPolicy(opt.Policy),
+	})
+	printFormat := uploadPrintFormat(1)
+	su.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
+		fmt.Fprintf(r.Output, printFormat, "pack",
+			converter.ConvertFileSize(status.Uploaded(), 2),
+			converter.ConvertFileSize(status.TotalSize(), 2),
+			converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
+			status.TimeElapsed(),
+		)
+	})
+
+	seg := &segmentUpload{
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+	go func() {
+		// 取消 (例如 Ctrl-C) 后中断上传
+		stopCancel := context.AfterFunc(pcs.Context(), su.Cancel)
+		defer stopCancel()
+		removeControl := r.Control.Add(su)
+		defer removeControl()
+
+		err := su.Execute()
+		// 上传失败时, 打包的写入也随之失败
+		pr.CloseWithError(err)
+		seg.done <- err
+	}()
+	return seg
+}
+
+func (seg *segmentUpload) Write(p []byte) (int, error) {
+	return seg.pw.Write(p)
+}
+
+func (seg *segmentUpload) Close() error {
+	seg.pw.Close()
+	return <-seg.done
+}
+
+func (seg *segmentUpload) Abort(err error) error {
+	// 读取数据出错时 StreamUploader 不会合并分片
+	seg.pw.CloseWithError(err)
+	return <-seg.done
+}
+
+// runUploadPack 将小文件按顺序打包成 tar 分段, 分段在打包的同时上传到 savePath, 最后上传索引
+func runUploadPack(files []*packFile, savePath string, opt *UploadOptions, r *Runner) {
+	var (
+		pcs       = r.baiduPCS()
+		name      = opt.PackName
+		startTime = time.Now()
+		total     int64
+	)
+	if name == "" {
+		name = "pack-" + startTime.Format("20060102-150405")
+	}
+	savePath = path.Clean(savePath)
+	fmt.Fprintf(r.Output, "[pack] 打包上传 %d 个小于 %s 的文件, 分段大小: %s\n", len(files), converter.ConvertFileSize(opt.PackThreshold), converter.ConvertFileSize(opt.PackSize))
+
+	packer := pcspack.NewPacker(name, opt.PackSize, func(seg int, segName string) (pcspack.SegmentWriter, error) {
+		segPath := path.Join(savePath, segName)
+		fmt.Fprintf(r.Output, "[pack] 上传分段: %s\n", segPath)
+		return newSegmentUpload(pcs, segPath, opt, r), nil
+	})
+
+	for _, f := range files {
+		file, err := os.Open(f.localPath)
+		if err != nil {
+			fmt.Fprintf(r.Output, "[pack] 打开文件错误, 已跳过: %s\n", err)
+			continue
+		}
+		fi, err := file.Stat()
+		if err != nil {
+			file.Close()
+			fmt.Fprintf(r.Output, "[pack] 获取文件信息错误, 已跳过: %s\n", err)
+			continue
+		}
+		// 先读取整个文件, 打包期间被修改的文件只跳过该文件
+		data, err := io.ReadAll(io.LimitReader(file, fi.Size()+1))
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(r.Output, "[pack] 读取文件错误, 已跳过: %s\n", err)
+			continue
+		}
+		if int64(len(data)) != fi.Size() {
+			fmt.Fprintf(r.Output, "[pack] 文件在打包期间被修改, 已跳过: %s\n", f.localPath)
+			continue
+		}
+		err = packer.Add(f.name, fi, bytes.NewReader(data))
+		if err != nil {
+			// 分段中已写入部分数据, 放弃当前分段, 不上传不完整的分段
+			packer.Abort(err)
+			fmt.Fprintf(r.Output, "\n[pack] %s, %s\n", pcsupload.StrUploadFailed, err)
+			return
+		}
+		total += fi.Size()
+		pcsCommandVerbose.Infof("[pack] 已打包: %s\n", f.localPath)
+	}
+	err := packer.Close()
+	fmt.Fprintf(r.Output, "\n")
+	if err != nil {
+		fmt.Fprintf(r.Output, "[pack] %s, %s\n", pcsupload.StrUploadFailed, err)
+		return
+	}
+
+	index := packer.Index()
+	if len(index.Members) == 0 {
+		fmt.Fprintf(r.Output, "[pack] 没有打包任何文件\n")
+		return
+	}
+	data, err := index.Marshal()
+	if err != nil {
+		fmt.Fprintf(r.Output, "[pack] 生成索引错误: %s\n", err)
+		return
+	}
+	indexPath := path.Join(savePath, pcspack.IndexName(name))
+	su := uploader.NewStreamUploader(pcsupload.NewPCSUpload(pcs, indexPath, nil), bytes.NewReader(data), &uploader.MultiUploaderConfig{
+		Policy: packPolicy(opt.Policy),
+	})
+	su.SetFileSize(int64(len(data)))
+	err = su.Execute()
+	if err != nil {
+		fmt.Fprintf(r.Output, "[pack] 上传索引 %s 失败, %s\n", indexPath, err)
+		return
+	}
+	fmt.Fprintf(r.Output, "[pack] 打包上传结束, 时间: %s, 成员数: %d, 总大小: %s, 分段数: %d, 索引: %s\n", time.Since(startTime)/1e6*1e6, len(index.Members), converter.ConvertFileSize(total), len(index.Segments), indexPath)
+}
+
+// readPackIndex 读取网盘中的打包索引
+func readPackIndex(pcs *baidupcs.BaiduPCS, indexPath string) (*pcspack.Index, error) {
+	fd, pcsError := pcs.FilesDirectoriesMeta(indexPath)
+	if pcsError != nil {
+		return nil, pcsError
+	}
+	if fd.Isdir {
+		return nil, fmt.Errorf("%s 是目录, 不是打包索引", indexPath)
+	}
+	buf := rio.NewBuffer(make([]byte, fd.Size))
+	err := pcsdownload.DownloadRange(pcs, fd.Path, fd.Size, 0, fd.Size, buf)
+	if err != nil {
+		return nil, err
+	}
+	return pcspack.ParseIndex(buf.Bytes())
+}
+
+func (sw *sequentialWriter) WriteAt(p []byte, off int64) (int, error) {
+	if off != sw.n {
+		return 0, fmt.Errorf("写入位置 %d 不连续, 应为 %d", off, sw.n)
+	}
+	n, err := sw.w.Write(p)
+	sw.n += int64(n)
+	return n, err
+}
+
+// downloadPackMember 用 Range 请求从分段中只下载成员 m 的数据, links 缓存每个分段的下载链接
+func downloadPackMember(pcs *baidupcs.BaiduPCS, indexPath string, index *pcspack.Index, m *pcspack.Member, links map[int]*url.URL, writer io.WriterAt) error {
+	if m.Size == 0 {
+		return nil
+	}
+	segment := index.SegmentOf(m)
+	dlink, ok := links[m.Segment]
+	if !ok {
+		var err error
+		dlink, err = pcsdownload.GetRangeLink(pcs, path.Join(path.Dir(indexPath), segment.Name))
+		if err != nil {
+			return err
+		}
+		links[m.Segment] = dlink
+	}
+	return pcsdownload.DownloadLinkRange(pcs, dlink, segment.Size, m.Offset, m.Offset+m.Size, writer)
+}
+
+// runUnpack 根据打包索引下载每个成员, 只下载成员在分段中的数据, 不下载整个分段
+func runUnpack(indexPaths []string, options *DownloadOptions, r *Runner) {
+	var (
+		pcs       = r.baiduPCS()
+		startTime = time.Now()
+		total     int64
+		failed    int
+	)
+	for _, indexPath := range indexPaths {
+		index, err := readPackIndex(pcs, indexPath)
+		if err != nil {
+			fmt.Fprintf(r.Output, "[unpack] 读取打包索引 %s 错误: %s\n", indexPath, err)
+			continue
+		}
+
+		var saveDir string
+		if options.FullPath {
+			saveDir = path.Dir(indexPath)
+		}
+		if options.SaveTo != "" {
+			saveDir = filepath.Join(options.SaveTo, saveDir)
+		} else {
+			saveDir = GetActiveUser().GetSavePath(saveDir)
+		}
+
+		links := map[int]*url.URL{}
+		for _, m := range index.Members {
+			if !options.Filter.Match(m.Name, m.Size, time.Unix(m.Mtime, 0)) {
+				continue
+			}
+			if !pcspack.IsLocalName(m.Name) {
+				failed++
+				fmt.Fprintf(r.Output, "[unpack] 成员名称不合法, 跳过: %s\n", m.Name)
+				continue
+			}
+			savePath := filepath.Join(saveDir, filepath.FromSlash(m.Name))
+			if !options.IsOverwrite && pcsdownload.FileExist(savePath) {
+				fmt.Fprintf(r.Output, "[unpack] 文件已经存在, 跳过: %s\n", savePath)
+				continue
+			}
+			err = unpackMember(pcs, indexPath, index, m, links, savePath, options)
+			if err != nil {
+				failed++
+				fmt.Fprintf(r.Output, "[unpack] 下载 %s 失败: %s\n", m.Name, err)
+				continue
+			}
+			total += m.Size
+			fmt.Fprintf(r.Output, "[unpack] 下载完成: %s\n", savePath)
+		}
+	}
+	fmt.Fprintf(r.Output, "\n下载结束, 时间: %s, 数据总量: %s, 失败: %d\n", time.Since(startTime)/1e6*1e6, converter.ConvertFileSize(total), failed)
+}
+
+func unpackMember(pcs *baidupcs.BaiduPCS, indexPath string, index *pcspack.Index, m *pcspack.Member, links map[int]*url.URL, savePath string, options *DownloadOptions) error {
+	err := os.MkdirAll(filepath.Dir(savePath), 0777)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(m.Mode).Perm()
+	if perm == 0 || !options.IsExecutedPermission {
+		perm = 0666
+	}
+	file, err := os.OpenFile(savePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	err = downloadPackMember(pcs, indexPath, index, m, links, file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(savePath)
+		return err
+	}
+	if options.ModifyMTime {
+		mtime := time.Unix(m.Mtime, 0)
+		os.Chtimes(savePath, mtime, mtime)
+	}
+	return nil
+}
+
+// RunCatMember 将打包中的成员输出到标准输出, 只下载成员在分段中的数据
+func RunCatMember(indexPath string, names []string) {
+	err := matchPathByShellPatternOnce(&indexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return
+	}
+
+	pcs := GetBaiduPCS()
+	index, err := readPackIndex(pcs, indexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取打包索引 %s 错误: %s\n", indexPath, err)
+		return
+	}
+	links := map[int]*url.URL{}
+	for _, name := range names {
+		m, err := index.Lookup(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return
+		}
+		// 索引来自网盘, 成员大小不可信, 边下载边输出
+		err = downloadPackMember(pcs, indexPath, index, m, links, &sequentialWriter{w: os.Stdout})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "下载 %s 失败: %s\n", name, err)
+			return
+		}
+	}
+}
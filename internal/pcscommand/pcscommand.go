@@ -2,13 +2,20 @@
 package pcscommand
 
 import (
+	"context"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
+	"os"
+	"os/signal"
+	"sync"
 )
 
 var (
 	pcsCommandVerbose = pcsverbose.New("PCSCOMMAND")
+
+	commandCtx   = context.Background()
+	commandCtxMu sync.RWMutex
 )
 
 // GetActiveUser 获取当前登录的百度帐号
@@ -16,7 +23,40 @@ func GetActiveUser() *pcsconfig.Baidu {
 	return pcsconfig.Config.ActiveUser()
 }
 
-// GetBaiduPCS 从配置读取BaiduPCS
+// GetBaiduPCS 从配置读取BaiduPCS, 使用当前命令的 context 发起请求
 func GetBaiduPCS() *baidupcs.BaiduPCS {
-	return pcsconfig.Config.ActiveUserBaiduPCS()
+	pcs := pcsconfig.Config.ActiveUserBaiduPCS()
+	ctx := Context()
+	if ctx == context.Background() {
+		return pcs
+	}
+	return pcs.WithContext(ctx)
+}
+
+// SetContext 设置当前命令的 context, ctx 取消后 (例如 Ctrl-C), 进行中的 API 请求和任务都会中断
+func SetContext(ctx context.Context) {
+	commandCtxMu.Lock()
+	defer commandCtxMu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	commandCtx = ctx
+}
+
+// Context 返回当前命令的 context
+func Context() context.Context {
+	commandCtxMu.RLock()
+	defer commandCtxMu.RUnlock()
+	return commandCtx
+}
+
+// NotifyInterrupt 返回收到 Ctrl-C 时取消的 context. 取消后即恢复默认的信号处理,
+// 不检查 context 的操作 (例如读取标准输入, 执行钩子命令) 卡住时, 再次 Ctrl-C 可结束程序
+func NotifyInterrupt(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(parent, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
 }
@@ -0,0 +1,48 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package pcscommand
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNotifyInterrupt(t *testing.T) {
+	ctx, stop := NotifyInterrupt(context.Background())
+	defer stop()
+	SetContext(ctx)
+	defer SetContext(nil)
+
+	if Context() != ctx {
+		t.Fatalf("command context not set")
+	}
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Signal(os.Interrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command context not cancelled by interrupt")
+	}
+	if Context().Err() != context.Canceled {
+		t.Errorf("got err %v, want context.Canceled", Context().Err())
+	}
+	if Context() == context.Background() {
+		t.Errorf("context reset before the command ends")
+	}
+
+	SetContext(nil)
+	if Context() != context.Background() {
+		t.Errorf("SetContext(nil): got %v, want context.Background()", Context())
+	}
+}
@@ -0,0 +1,371 @@
+package pcscommand
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RenameUndoDirName 重命名撤销日志的目录名, 位于配置目录中
+	RenameUndoDirName = "rename_undo"
+)
+
+type (
+	// renameUndoLog 重命名的撤销日志
+	renameUndoLog struct {
+		UID  uint64               `json:"uid"`
+		Time int64                `json:"time"`
+		List []*baidupcs.CpMvJSON `json:"list"` // 已完成的重命名, 按执行的顺序
+	}
+)
+
+// RunRename 按规则批量重命名匹配的文件和目录
+func RunRename(opt *RenameOptions, patterns ...string) {
+	err := opt.Rules.Check()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	switch opt.Type {
+	case "":
+		opt.Type = "a"
+		if opt.Recursive {
+			opt.Type = "f"
+		}
+	case "f", "d", "a":
+	default:
+		fmt.Printf("未知的类型: %s, 可选: f (文件), d (目录), a (全部)\n", opt.Type)
+		return
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultCpMvBatchSize
+	}
+
+	paths, err := matchPathByShellPattern(patterns...)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if len(paths) == 0 {
+		fmt.Printf("未匹配到文件或目录\n")
+		return
+	}
+
+	pcs := GetBaiduPCS()
+	fds, err := selectRenameFiles(pcs, paths, opt)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if len(fds) == 0 {
+		fmt.Printf("没有符合条件的文件或目录\n")
+		return
+	}
+
+	planner := newRenamePlanner(pcs, opt)
+	err = planner.plan(fds)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	printRenamePlan(planner)
+
+	counts := planner.count()
+	fmt.Printf("共 %d 项, 重命名 %d, 未改变 %d, 名称无效 %d, 冲突 %d\n", len(planner.items), counts[renameStatusDo], counts[renameStatusUnchanged], counts[renameStatusInvalid], counts[renameStatusConflict])
+	if counts[renameStatusInvalid] > 0 || counts[renameStatusConflict] > 0 {
+		fmt.Printf("存在无效的名称或冲突, 未执行任何重命名\n")
+		return
+	}
+	if counts[renameStatusDo] == 0 || opt.DryRun {
+		return
+	}
+
+	var (
+		undoLog = &renameUndoLog{
+			UID:  GetActiveUser().UID,
+			Time: time.Now().Unix(),
+		}
+		undoFile  = filepath.Join(pcsconfig.GetConfigDir(), RenameUndoDirName, time.Now().Format("rename-20060102-150405.000.json"))
+		undoSaved = true
+	)
+	done, failed := runRenameBatches(pcs, planner.moveList(), opt.BatchSize, false, func(batch []*baidupcs.CpMvJSON) {
+		undoLog.List = append(undoLog.List, batch...)
+		err := undoLog.save(undoFile)
+		if err != nil && undoSaved {
+			fmt.Printf("保存撤销日志错误: %s\n", err)
+			undoSaved = false
+		}
+	})
+
+	fmt.Printf("操作结束, 重命名成功 %d, 失败 %d\n", done, failed)
+	if done > 0 && undoSaved {
+		fmt.Printf("撤销日志: %s, 可使用 rename --undo 撤销\n", undoFile)
+	}
+}
+
+// RunRenameUndo 按撤销日志恢复原名称, undoFile 为空时使用最近的撤销日志.
+// 全部恢复后删除撤销日志, 部分失败时日志中保留未恢复的条目
+func RunRenameUndo(undoFile string, dryRun bool) {
+	var err error
+	if undoFile == "" {
+		undoFile, err = latestRenameUndoFile()
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		if undoFile == "" {
+			fmt.Printf("没有可以撤销的重命名\n")
+			return
+		}
+	}
+
+	undoLog, err := loadRenameUndoLog(undoFile)
+	if err != nil {
+		fmt.Printf("读取撤销日志错误: %s\n", err)
+		return
+	}
+	if uid := GetActiveUser().UID; undoLog.UID != uid {
+		fmt.Printf("撤销日志属于其他帐号 (uid: %d), 请切换到该帐号后重试\n", undoLog.UID)
+		return
+	}
+	if len(undoLog.List) == 0 {
+		fmt.Printf("撤销日志 %s 中没有需要恢复的条目\n", undoFile)
+		return
+	}
+
+	// 逆序恢复, 先恢复上层目录的名称
+	list := make([]*baidupcs.CpMvJSON, len(undoLog.List))
+	for k, item := range undoLog.List {
+		list[len(list)-1-k] = &baidupcs.CpMvJSON{
+			From: item.To,
+			To:   item.From,
+		}
+	}
+
+	fmt.Printf("撤销日志: %s, 重命名时间: %s\n", undoFile, time.Unix(undoLog.Time, 0).Format("2006-01-02 15:04:05"))
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"#", "当前路径", "恢复为"})
+	for k, item := range list {
+		tb.Append([]string{strconv.Itoa(k + 1), item.From, path.Base(item.To)})
+	}
+	tb.Render()
+	if dryRun {
+		return
+	}
+
+	done, failed := runRenameBatches(GetBaiduPCS(), list, DefaultCpMvBatchSize, true, func(batch []*baidupcs.CpMvJSON) {
+		undoLog.remove(batch)
+		if len(undoLog.List) == 0 {
+			err = os.Remove(undoFile)
+		} else {
+			err = undoLog.save(undoFile)
+		}
+		if err != nil {
+			fmt.Printf("更新撤销日志错误: %s\n", err)
+		}
+	})
+	fmt.Printf("操作结束, 恢复成功 %d, 失败 %d\n", done, failed)
+}
+
+// selectRenameFiles 选择需要重命名的文件和目录.
+// 递归时选择目录中的条目 (不包括目录本身), 否则选择匹配的路径本身
+func selectRenameFiles(pcs *baidupcs.BaiduPCS, paths []string, opt *RenameOptions) (fds []*baidupcs.FileDirectory, err error) {
+	var (
+		selected = map[string]bool{}
+		add      = func(root string, fd *baidupcs.FileDirectory) {
+			if selected[fd.Path] || !remoteFilterMatch(root, fd, opt.Filter) {
+				return
+			}
+			if (opt.Type == "f" && fd.Isdir) || (opt.Type == "d" && !fd.Isdir) {
+				return
+			}
+			selected[fd.Path] = true
+			fds = append(fds, fd)
+		}
+	)
+	for _, p := range paths {
+		if !opt.Recursive {
+			fd, pcsError := pcs.FilesDirectoriesMeta(p)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			add(path.Dir(p), fd)
+			continue
+		}
+
+		var walkErr pcserror.Error
+		pcs.FilesDirectoriesRecurseList(p, baidupcs.DefaultOrderOptions, func(depth int, _ string, fd *baidupcs.FileDirectory, pcsError pcserror.Error) bool {
+			if pcsError != nil {
+				walkErr = pcsError
+				return false
+			}
+			if fd.Isdir && path.Clean(fd.Path) == path.Clean(p) {
+				return true
+			}
+			add(p, fd)
+			return true
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+	return fds, nil
+}
+
+// printRenamePlan 输出重命名计划的预览, 不包括名称未改变的条目
+func printRenamePlan(planner *renamePlanner) {
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"#", "原路径", "新名称", "状态"})
+	for k, item := range planner.items {
+		if item.status == renameStatusUnchanged {
+			continue
+		}
+		status := item.status.String()
+		if item.reason != "" {
+			status += ": " + item.reason
+		}
+		tb.Append([]string{strconv.Itoa(k + 1), item.from, item.newName, status})
+	}
+	tb.Render()
+}
+
+// runRenameBatches 分批提交移动操作, 每批完成后以已完成的条目调用 onSuccess, stopOnError 为 true 时遇到失败即停止
+func runRenameBatches(pcs *baidupcs.BaiduPCS, list []*baidupcs.CpMvJSON, batchSize int, stopOnError bool, onSuccess func(batch []*baidupcs.CpMvJSON)) (done, failed int) {
+	total := len(list)
+	for _, batch := range renameBatches(list, batchSize) {
+		pending, pcsError := cpmvSubmit(len(batch), func(idx []int) pcserror.Error {
+			return pcs.Move(renameSubList(batch, idx)...)
+		}, func(k int) bool {
+			return cpmvApplied(pcs, "move", batch[k].From, batch[k].To)
+		})
+
+		// 失败时已经确认过各条目, 已完成的条目仍然记录
+		applied := renameApplied(batch, pending)
+		if len(applied) > 0 {
+			done += len(applied)
+			onSuccess(applied)
+		}
+		if pcsError != nil {
+			fmt.Printf("\n重命名失败: %s, 以下文件/目录未完成: \n", pcsError)
+			fmt.Println(&baidupcs.CpMvListJSON{List: renameSubList(batch, pending)})
+			failed += len(pending)
+			if stopOnError {
+				failed = total - done
+				break
+			}
+		}
+		if total > batchSize {
+			fmt.Printf("\r[重命名] %d/%d, 失败 %d ......", done+failed, total, failed)
+		}
+	}
+	if total > batchSize {
+		fmt.Printf("\n")
+	}
+	return done, failed
+}
+
+// renameSubList 返回 list 中序号为 idx 的条目
+func renameSubList(list []*baidupcs.CpMvJSON, idx []int) []*baidupcs.CpMvJSON {
+	sub := make([]*baidupcs.CpMvJSON, len(idx))
+	for k, i := range idx {
+		sub[k] = list[i]
+	}
+	return sub
+}
+
+// renameApplied 返回 batch 中不在 pending 里的条目
+func renameApplied(batch []*baidupcs.CpMvJSON, pending []int) []*baidupcs.CpMvJSON {
+	left := make(map[int]bool, len(pending))
+	for _, k := range pending {
+		left[k] = true
+	}
+	applied := make([]*baidupcs.CpMvJSON, 0, len(batch)-len(pending))
+	for k, item := range batch {
+		if !left[k] {
+			applied = append(applied, item)
+		}
+	}
+	return applied
+}
+
+// remove 从撤销日志中移除已恢复的条目, restored 为恢复时的移动操作
+func (l *renameUndoLog) remove(restored []*baidupcs.CpMvJSON) {
+	done := make(map[baidupcs.CpMvJSON]bool, len(restored))
+	for _, item := range restored {
+		done[baidupcs.CpMvJSON{From: item.To, To: item.From}] = true
+	}
+	list := l.List[:0]
+	for _, item := range l.List {
+		if !done[*item] {
+			list = append(list, item)
+		}
+	}
+	l.List = list
+}
+
+// save 保存撤销日志
+func (l *renameUndoLog) save(filename string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// loadRenameUndoLog 读取撤销日志
+func loadRenameUndoLog(filename string) (*renameUndoLog, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	l := &renameUndoLog{}
+	err = json.Unmarshal(data, l)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// latestRenameUndoFile 返回最近的撤销日志, 没有时返回空
+func latestRenameUndoFile() (string, error) {
+	dir := filepath.Join(pcsconfig.GetConfigDir(), RenameUndoDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	// 文件名包含时间, 按名称排序即按时间排序
+	sort.Strings(names)
+	return filepath.Join(dir, names[len(names)-1]), nil
+}
@@ -0,0 +1,223 @@
+package pcscommand
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/renamer"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// renameStatusDo 重命名
+	renameStatusDo renameStatus = iota
+	// renameStatusUnchanged 名称未改变, 跳过
+	renameStatusUnchanged
+	// renameStatusInvalid 新名称无效
+	renameStatusInvalid
+	// renameStatusConflict 新名称与其他文件或目录冲突
+	renameStatusConflict
+)
+
+type (
+	// RenameOptions 批量重命名可选项
+	RenameOptions struct {
+		Rules     *renamer.Rules
+		Start     int    // 模板中 {n} 的起始序号
+		Recursive bool   // 选择目录中的所有文件和目录, 而不是目录本身
+		Type      string // 选择的类型: f (文件), d (目录), a (全部)
+		Filter    *filefilter.Filter
+		DryRun    bool // 只列出重命名计划, 不执行
+		BatchSize int  // 每次请求的最大条目数
+	}
+
+	renameStatus int
+
+	// renamePlanItem 计划重命名的一项
+	renamePlanItem struct {
+		status  renameStatus
+		from    string
+		isDir   bool
+		newName string
+		reason  string // 冲突的原因
+	}
+
+	// renamePlanner 生成重命名的计划, 并检测冲突
+	renamePlanner struct {
+		rules *renamer.Rules
+		start int
+		list  func(dir string) (baidupcs.FileDirectoryList, error)
+
+		dirs  map[string]map[string]bool // 目录中已存在的名称, 不区分大小写
+		items []*renamePlanItem
+	}
+)
+
+func (status renameStatus) String() string {
+	switch status {
+	case renameStatusDo:
+		return "重命名"
+	case renameStatusUnchanged:
+		return "未改变"
+	case renameStatusInvalid:
+		return "名称无效"
+	case renameStatusConflict:
+		return "冲突"
+	}
+	return "未知"
+}
+
+func newRenamePlanner(pcs *baidupcs.BaiduPCS, opt *RenameOptions) *renamePlanner {
+	return &renamePlanner{
+		rules: opt.Rules,
+		start: opt.Start,
+		list: func(dir string) (baidupcs.FileDirectoryList, error) {
+			fdl, pcsError := pcs.FilesDirectoriesList(dir, baidupcs.DefaultOrderOptions)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			return fdl, nil
+		},
+	}
+}
+
+// names 返回目录中已存在的名称
+func (p *renamePlanner) names(dir string) (map[string]bool, error) {
+	if p.dirs == nil {
+		p.dirs = map[string]map[string]bool{}
+	}
+	if names, ok := p.dirs[dir]; ok {
+		return names, nil
+	}
+	fdl, err := p.list(dir)
+	if err != nil && !errors.Is(err, pcserror.ErrNotFound) {
+		return nil, fmt.Errorf("获取目录 %s 的文件列表错误: %w", dir, err)
+	}
+	names := make(map[string]bool, len(fdl))
+	for _, fd := range fdl {
+		names[strings.ToLower(fd.Filename)] = true
+	}
+	p.dirs[dir] = names
+	return names, nil
+}
+
+// plan 按路径排序后依次生成新名称, 序号按排序后的顺序递增.
+// 名称按不区分大小写比较, 保守地检测冲突
+func (p *renamePlanner) plan(fds []*baidupcs.FileDirectory) error {
+	sort.SliceStable(fds, func(i, j int) bool {
+		return fds[i].Path < fds[j].Path
+	})
+
+	var (
+		sources = map[string]map[string]*renamePlanItem{} // 目录 -> 原名称
+		targets = map[string]map[string]int{}             // 目录 -> 新名称的数量
+	)
+	p.items = make([]*renamePlanItem, 0, len(fds))
+	for k, fd := range fds {
+		name := path.Base(fd.Path)
+		item := &renamePlanItem{
+			status:  renameStatusDo,
+			from:    fd.Path,
+			isDir:   fd.Isdir,
+			newName: p.rules.Apply(name, fd.Isdir, p.start+k),
+		}
+		switch {
+		case item.newName == name:
+			item.status = renameStatusUnchanged
+		case item.newName == "" || item.newName == "." || item.newName == ".." || strings.ContainsAny(item.newName, "/\\"):
+			item.status = renameStatusInvalid
+		}
+		p.items = append(p.items, item)
+
+		dir := path.Dir(fd.Path)
+		if sources[dir] == nil {
+			sources[dir] = map[string]*renamePlanItem{}
+			targets[dir] = map[string]int{}
+		}
+		sources[dir][strings.ToLower(name)] = item
+		if item.status == renameStatusDo {
+			targets[dir][strings.ToLower(item.newName)]++
+		}
+	}
+
+	for _, item := range p.items {
+		if item.status != renameStatusDo {
+			continue
+		}
+		dir, oldName, newName := path.Dir(item.from), strings.ToLower(path.Base(item.from)), strings.ToLower(item.newName)
+		if targets[dir][newName] > 1 {
+			item.status, item.reason = renameStatusConflict, "与其他条目的新名称相同"
+			continue
+		}
+		if src, ok := sources[dir][newName]; ok && src != item {
+			item.status = renameStatusConflict
+			if src.status == renameStatusDo {
+				item.reason = "与其他条目的原名称相同, 不支持链式或交换重命名"
+			} else {
+				item.reason = "目标已存在"
+			}
+			continue
+		}
+		if newName == oldName { // 只改变大小写
+			continue
+		}
+		names, err := p.names(dir)
+		if err != nil {
+			return err
+		}
+		if names[newName] {
+			item.status, item.reason = renameStatusConflict, "目标已存在"
+		}
+	}
+	return nil
+}
+
+// count 统计各状态的数量
+func (p *renamePlanner) count() map[renameStatus]int {
+	counts := map[renameStatus]int{}
+	for _, item := range p.items {
+		counts[item.status]++
+	}
+	return counts
+}
+
+// moveList 返回需要执行的移动操作, 深层的文件和目录在前, 先重命名目录中的条目, 再重命名目录本身
+func (p *renamePlanner) moveList() (list []*baidupcs.CpMvJSON) {
+	for _, item := range p.items {
+		if item.status != renameStatusDo {
+			continue
+		}
+		list = append(list, &baidupcs.CpMvJSON{
+			From: item.from,
+			To:   path.Join(path.Dir(item.from), item.newName),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return renamePathDepth(list[i].From) > renamePathDepth(list[j].From)
+	})
+	return list
+}
+
+// renamePathDepth 路径的深度
+func renamePathDepth(pcspath string) int {
+	return strings.Count(path.Clean(pcspath), baidupcs.PathSeparator)
+}
+
+// renameBatches 将移动操作分批, 每批最多 size 条, 且只包含同一深度的条目,
+// 保证上层目录改名时, 下层的条目已经处理完毕
+func renameBatches(list []*baidupcs.CpMvJSON, size int) (batches [][]*baidupcs.CpMvJSON) {
+	for i := 0; i < len(list); {
+		depth := renamePathDepth(list[i].From)
+		j := i + 1
+		for j < len(list) && j-i < size && renamePathDepth(list[j].From) == depth {
+			j++
+		}
+		batches = append(batches, list[i:j])
+		i = j
+	}
+	return batches
+}
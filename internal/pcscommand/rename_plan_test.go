@@ -0,0 +1,129 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/renamer"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// newTestRenamePlanner 使用内存中的目录树生成计划, 以 / 结尾的路径为目录, 返回 planner 和 selected 中的文件和目录
+func newTestRenamePlanner(rules *renamer.Rules, tree []string, selected ...string) (*renamePlanner, []*baidupcs.FileDirectory) {
+	dirs := map[string]baidupcs.FileDirectoryList{}
+	metas := map[string]*baidupcs.FileDirectory{}
+	for _, p := range tree {
+		isDir := p[len(p)-1] == '/'
+		p = path.Clean(p)
+		fd := &baidupcs.FileDirectory{Path: p, Filename: path.Base(p), Isdir: isDir}
+		metas[p] = fd
+		dirs[path.Dir(p)] = append(dirs[path.Dir(p)], fd)
+	}
+	fds := make([]*baidupcs.FileDirectory, 0, len(selected))
+	for _, p := range selected {
+		fds = append(fds, metas[p])
+	}
+	return &renamePlanner{
+		rules: rules,
+		start: 1,
+		list: func(dir string) (baidupcs.FileDirectoryList, error) {
+			return dirs[dir], nil
+		},
+	}, fds
+}
+
+func renamePlanStrings(p *renamePlanner) (plan []string) {
+	for _, item := range p.items {
+		plan = append(plan, fmt.Sprintf("%s %s -> %s", item.status, item.from, item.newName))
+	}
+	return plan
+}
+
+func TestRenamePlan(t *testing.T) {
+	tree := []string{"/p/", "/p/IMG_2.JPG", "/p/IMG_1.JPG", "/p/x.txt", "/p/trip_002.jpg", "/q/", "/q/A.txt", "/q/b.txt"}
+
+	p, fds := newTestRenamePlanner(&renamer.Rules{Template: "trip_{n:3}{ext}", Case: "lower"}, tree, "/p/IMG_2.JPG", "/p/IMG_1.JPG")
+	if err := p.plan(fds); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"重命名 /p/IMG_1.JPG -> trip_001.jpg", "冲突 /p/IMG_2.JPG -> trip_002.jpg"}
+	if got := renamePlanStrings(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// 新名称重复, 名称无效, 只改变大小写
+	sub, _ := renamer.ParseSubstitution(`s/.*/same/`)
+	p, fds = newTestRenamePlanner(&renamer.Rules{Subs: []*renamer.Substitution{sub}}, tree, "/p/IMG_1.JPG", "/p/IMG_2.JPG")
+	p.plan(fds)
+	want = []string{"冲突 /p/IMG_1.JPG -> same", "冲突 /p/IMG_2.JPG -> same"}
+	if got := renamePlanStrings(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	sub, _ = renamer.ParseSubstitution(`s/.*//`)
+	p, fds = newTestRenamePlanner(&renamer.Rules{Subs: []*renamer.Substitution{sub}}, tree, "/p/x.txt")
+	p.plan(fds)
+	if p.items[0].status != renameStatusInvalid {
+		t.Errorf("empty name: got %s", p.items[0].status)
+	}
+
+	p, fds = newTestRenamePlanner(&renamer.Rules{Case: "upper"}, tree, "/q/A.txt", "/q/b.txt")
+	p.plan(fds)
+	want = []string{"重命名 /q/A.txt -> A.TXT", "重命名 /q/b.txt -> B.TXT"}
+	if got := renamePlanStrings(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// 交换名称
+	swap, _ := renamer.ParseSubstitution(`s/^(A|b)/\1x/`)
+	back, _ := renamer.ParseSubstitution(`s/^Ax/b/`)
+	p, fds = newTestRenamePlanner(&renamer.Rules{Subs: []*renamer.Substitution{swap, back}}, tree, "/q/A.txt", "/q/b.txt")
+	p.plan(fds)
+	if p.items[0].status != renameStatusConflict || p.items[1].status != renameStatusDo {
+		t.Errorf("chain: got %v", renamePlanStrings(p))
+	}
+}
+
+func TestRenameBatches(t *testing.T) {
+	tree := []string{"/d/", "/d/a.txt", "/d/b.txt", "/d/sub/", "/d/sub/c.txt", "/e.txt"}
+	p, fds := newTestRenamePlanner(&renamer.Rules{Template: "{name}_old{ext}"}, tree, "/d", "/d/a.txt", "/d/b.txt", "/d/sub/c.txt", "/e.txt", "/d/sub")
+	if err := p.plan(fds); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]string
+	for _, batch := range renameBatches(p.moveList(), 2) {
+		var froms []string
+		for _, item := range batch {
+			froms = append(froms, item.From)
+		}
+		got = append(got, froms)
+	}
+	want := [][]string{{"/d/sub/c.txt"}, {"/d/a.txt", "/d/b.txt"}, {"/d/sub"}, {"/d", "/e.txt"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRenameUndoLogRemove(t *testing.T) {
+	l := &renameUndoLog{List: []*baidupcs.CpMvJSON{
+		{From: "/a", To: "/A"},
+		{From: "/b", To: "/B"},
+		{From: "/c", To: "/C"},
+	}}
+	// 逆序恢复的一批中只有部分条目完成
+	batch := []*baidupcs.CpMvJSON{
+		{From: "/C", To: "/c"},
+		{From: "/B", To: "/b"},
+	}
+	applied := renameApplied(batch, []int{1})
+	if !reflect.DeepEqual(applied, batch[:1]) {
+		t.Fatalf("renameApplied: %v", applied)
+	}
+	l.remove(applied)
+	want := []*baidupcs.CpMvJSON{{From: "/a", To: "/A"}, {From: "/b", To: "/B"}}
+	if !reflect.DeepEqual(l.List, want) {
+		t.Fatalf("remove: %v", l.List)
+	}
+}
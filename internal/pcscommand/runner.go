@@ -1,8 +1,13 @@
 package pcscommand
 
 import (
+	"context"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions"
 	"io"
 	"os"
+	"sync"
 )
 
 var (
@@ -10,6 +15,9 @@ var (
 	DefaultRunner = Runner{
 		Output: os.Stdout,
 	}
+
+	nextRunner   *Runner
+	nextRunnerMu sync.Mutex
 )
 
 type (
@@ -17,5 +25,39 @@ type (
 	Runner struct {
 		Output       io.Writer
 		IsBackground bool
+		Cmdline      string                        // 后台任务的命令行
+		Control      *pcsfunctions.TransferControl // 暂停和恢复控制
+
+		ctx context.Context
 	}
 )
+
+// SetNextRunner 设置下一个执行的 download 或 upload 命令使用的 Runner, nil 则恢复为 DefaultRunner
+func SetNextRunner(r *Runner) {
+	nextRunnerMu.Lock()
+	defer nextRunnerMu.Unlock()
+	nextRunner = r
+}
+
+// takeRunner 取出当前命令使用的 Runner
+func takeRunner() *Runner {
+	nextRunnerMu.Lock()
+	defer nextRunnerMu.Unlock()
+	r := nextRunner
+	nextRunner = nil
+	if r == nil {
+		return &DefaultRunner
+	}
+	if r.Output == nil {
+		r.Output = os.Stdout
+	}
+	return r
+}
+
+// baiduPCS 返回 Runner 使用的 BaiduPCS, 后台任务使用自己的 context
+func (r *Runner) baiduPCS() *baidupcs.BaiduPCS {
+	if r.ctx == nil {
+		return GetBaiduPCS()
+	}
+	return pcsconfig.Config.ActiveUserBaiduPCS().WithContext(r.ctx)
+}
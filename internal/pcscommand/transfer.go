@@ -2,8 +2,11 @@ package pcscommand
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcshook"
 	"path"
 	"regexp"
 	"strconv"
@@ -31,6 +34,11 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 		link = params[0]
 		extraCode = params[1]
 	}
+	startTime := time.Now()
+	transferFailed := func(errMsg string) {
+		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, errMsg)
+		fireTransferHook(link, nil, startTime, errors.New(errMsg))
+	}
 	if link[len(link)-1:] == "/" {
 		link = link[0 : len(link)-1]
 	}
@@ -40,13 +48,13 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 		featureStr = "1" + strings.Split(featureStr, "=")[1]
 	}
 	if len(featureStr) > 23 || featureStr[0:1] != "1" || len(extraCode) != 4 {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, "链接地址或提取码非法")
+		transferFailed("链接地址或提取码非法")
 		return
 	}
 	pcs := GetBaiduPCS()
 	tokens := pcs.AccessSharePage(featureStr, true)
 	if tokens["ErrMsg"] != "0" {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, tokens["ErrMsg"])
+		transferFailed(tokens["ErrMsg"])
 		return
 	}
 
@@ -64,7 +72,7 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 			"bdstoken":  tokens["bdstoken"],
 		})
 		if res["ErrMsg"] != "0" {
-			fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, res["ErrMsg"])
+			transferFailed(res["ErrMsg"])
 			return
 		}
 	}
@@ -72,7 +80,7 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 
 	tokens = pcs.AccessSharePage(featureStr, false)
 	if tokens["ErrMsg"] != "0" {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, tokens["ErrMsg"])
+		transferFailed(tokens["ErrMsg"])
 		return
 	}
 	featureMap := map[string]string{
@@ -87,7 +95,7 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 	transMetas := pcs.ExtractShareInfo(queryShareInfoUrl, tokens["shareid"], tokens["share_uk"], tokens["bdstoken"])
 
 	if transMetas["ErrMsg"] != "success" {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, transMetas["ErrMsg"])
+		transferFailed(transMetas["ErrMsg"])
 		return
 	}
 	transMetas["path"] = GetActiveUser().Workdir
@@ -100,7 +108,7 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 	pcs.UpdatePCSCookies(true)
 	resp := pcs.GenerateRequestQuery("POST", transMetas)
 	if resp["ErrNo"] != "0" {
-		fmt.Printf("%s失败: %s\n", baidupcs.OperationShareFileSavetoLocal, resp["ErrMsg"])
+		transferFailed(resp["ErrMsg"])
 		//if resp["ErrNo"] == "4" {
 		//	transMetas["shorturl"] = featureStr
 		//	pcs.SuperTransfer(transMetas, resp["limit"]) // 试验性功能, 当前未启用
@@ -111,6 +119,13 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 		resp["filename"] = transMetas["filename"]
 	}
 	fmt.Printf("%s成功, 保存了%s到当前目录\n", baidupcs.OperationShareFileSavetoLocal, resp["filename"])
+	var remotePaths []string
+	for _, name := range strings.Split(resp["filenames"], ",") {
+		if name != "" {
+			remotePaths = append(remotePaths, path.Join(transMetas["path"], name))
+		}
+	}
+	fireTransferHook(link, remotePaths, startTime, nil)
 	if opt.Download {
 		fmt.Println("即将开始下载")
 		paths := strings.Split(resp["filenames"], ",")
@@ -119,6 +134,40 @@ func RunShareTransfer(params []string, opt *baidupcs.TransferOption) {
 	}
 }
 
+// fireTransferHook 执行配置的钩子, 转存成功时每个转存的文件或目录执行一次, 钩子失败不影响转存结果
+func fireTransferHook(link string, remotePaths []string, startTime time.Time, err error) {
+	hook := pcshook.FromConfig(pcsconfig.Config)
+	if err != nil {
+		if hook.Enabled(pcshook.KindTransfer + "." + pcshook.StatusFailed) {
+			payload := pcshook.NewPayload(pcshook.KindTransfer, pcshook.StatusFailed, startTime, err)
+			payload.Link = link
+			if err = hook.Fire(Context(), payload); err != nil {
+				fmt.Printf("执行钩子错误: %s\n", err)
+			}
+		}
+		return
+	}
+	if !hook.Enabled(pcshook.KindTransfer + "." + pcshook.StatusSuccess) {
+		return
+	}
+
+	pcs := GetBaiduPCS()
+	for _, remotePath := range remotePaths {
+		payload := pcshook.NewPayload(pcshook.KindTransfer, pcshook.StatusSuccess, startTime, nil)
+		payload.Link = link
+		payload.RemotePath = remotePath
+		fd, pcsError := pcs.FilesDirectoriesMeta(remotePath)
+		if pcsError == nil {
+			payload.Size = fd.Size
+			payload.MD5 = fd.MD5
+			payload.FsID = fd.FsID
+		}
+		if err = hook.Fire(Context(), payload); err != nil {
+			fmt.Printf("执行钩子错误: %s\n", err)
+		}
+	}
+}
+
 // RunRapidTransfer 执行秒传链接解析及保存
 func RunRapidTransfer(link string, rnameOpt ...bool) {
 	if strings.Contains(link, "bdlink=") || strings.Contains(link, "bdpan://") {
@@ -1,14 +1,18 @@
 package pcscommand
 
 import (
+	"errors"
 	"fmt"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcspack"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
 	"os"
 	"path"
@@ -31,6 +35,15 @@ type (
 		NoSplitFile   bool // 禁用分片上传
 		Policy        string // 同名文件处理策略
 		NoFilenameCheck bool // 禁用文件名合法性检查
+		Filter          *filefilter.Filter // 文件过滤规则
+		Pipeline        bool               // 流水线模式, 边上传边计算秒传信息
+		Pack            bool               // 将小文件打包成 tar 分段上传
+		PackName        string             // 打包名称
+		PackThreshold   int64              // 小于此大小的文件才打包
+		PackSize        int64              // 打包分段的大小
+		Watch           bool               // 监视本地目录, 文件写入完成后自动上传
+		DeleteAfter     bool               // 监视目录时, 上传成功后删除本地文件
+		MoveTo          string             // 监视目录时, 上传成功后将本地文件移动到此目录
 	}
 )
 
@@ -65,7 +78,7 @@ func RunCreateSuperFile(policy string, targetPath string, blockList ...string) {
 		fmt.Printf("警告: %s, 获取网盘路径 %s 错误, %s\n", baidupcs.OperationUploadCreateSuperFile, targetPath, err)
 	}
 
-	err = GetBaiduPCS().UploadCreateSuperFile(policy, true, targetPath, blockList...)
+	err = GetBaiduPCS().UploadCreateSuperFile(policy, true, targetPath, nil, blockList...)
 	if err != nil {
 		fmt.Printf("%s失败, 消息: %s\n", baidupcs.OperationUploadCreateSuperFile, err)
 		return
@@ -100,6 +113,19 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 		opt.Policy = pcsconfig.Config.UPolicy
 	}
 
+	if opt.Pack {
+		if opt.PackThreshold <= 0 {
+			opt.PackThreshold = pcspack.DefaultThreshold
+		}
+		if opt.PackSize <= 0 {
+			opt.PackSize = pcspack.DefaultSegmentSize
+		}
+		if opt.PackSize > MaxPackSize {
+			fmt.Printf("打包分段的大小不能超过 %s\n", converter.ConvertFileSize(MaxPackSize))
+			return
+		}
+	}
+
 	err := matchPathByShellPatternOnce(&savePath)
 	if err != nil {
 		fmt.Printf("警告: 上传文件, 获取网盘路径 %s 错误, %s\n", savePath, err)
@@ -111,16 +137,64 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 		return
 	}
 
+	runner := takeRunner()
+	for _, localPath := range localPaths {
+		if localPath != StdinPath {
+			continue
+		}
+		// 从标准输入上传
+		if len(localPaths) > 1 {
+			fmt.Printf("从标准输入上传时只能指定一个本地路径 %s\n", StdinPath)
+			return
+		}
+		if runner.IsBackground {
+			fmt.Printf("从标准输入上传不支持后台运行\n")
+			return
+		}
+		runUploadStream(os.Stdin, savePath, opt, runner)
+		return
+	}
+	if opt.Watch {
+		if len(localPaths) > 1 {
+			fmt.Printf("监视目录时只能指定一个本地目录\n")
+			return
+		}
+		if fi, err := os.Stat(localPaths[0]); err != nil || !fi.IsDir() {
+			fmt.Printf("监视的本地路径 %s 不是目录\n", localPaths[0])
+			return
+		}
+		if runner.IsBackground {
+			job := startJob(runner.Cmdline, func(r *Runner) {
+				runUploadWatch(localPaths[0], savePath, opt, r)
+			})
+			fmt.Printf("[%d] %s\n", job.ID, job.Cmdline)
+			return
+		}
+		runUploadWatch(localPaths[0], savePath, opt, runner)
+		return
+	}
+	if runner.IsBackground {
+		job := startJob(runner.Cmdline, func(r *Runner) {
+			runUpload(localPaths, savePath, opt, r)
+		})
+		fmt.Printf("[%d] %s\n", job.ID, job.Cmdline)
+		return
+	}
+	runUpload(localPaths, savePath, opt, runner)
+}
+
+// runUpload 上传本地文件到网盘路径 savePath, 输出到 r.Output
+func runUpload(localPaths []string, savePath string, opt *UploadOptions, r *Runner) {
 	// 打开上传状态
 	uploadDatabase, err := pcsupload.NewUploadingDatabase()
 	if err != nil {
-		fmt.Printf("打开上传未完成数据库错误: %s\n", err)
+		fmt.Fprintf(r.Output, "打开上传未完成数据库错误: %s\n", err)
 		return
 	}
 	defer uploadDatabase.Close()
 
 	var (
-		pcs = GetBaiduPCS()
+		pcs = r.baiduPCS().WithoutCache() // 目标文件的信息以服务器为准
 		// 使用 task framework
 		executor = &taskframework.TaskExecutor{
 			IsFailedDeque: true, // 失败统计
@@ -129,20 +203,49 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 		// 统计
 		statistic = &pcsupload.UploadStatistic{}
 	)
-	fmt.Print("\n")
-	fmt.Printf("[0] 提示: 当前上传单个文件最大并发量为: %d, 最大同时上传文件数为: %d\n", opt.Parallel, opt.Load)
+	fmt.Fprint(r.Output, "\n")
+	fmt.Fprintf(r.Output, "[0] 提示: 当前上传单个文件最大并发量为: %d, 最大同时上传文件数为: %d\n", opt.Parallel, opt.Load)
 
 	statistic.StartTimer() // 开始计时
 
 	LoadCount := 0
+	emptyDirCount := 0
+	var packFiles []*packFile
+
+	// 流水线模式下, 小文件的秒传信息在后台提前计算
+	var checksumPool *pcsupload.ChecksumPool
+	if opt.Pipeline && !opt.NoRapidUpload {
+		checksumPool = pcsupload.NewChecksumPool()
+		defer checksumPool.Close()
+	}
 
 	for k := range localPaths {
-		walkedFiles, err := pcsutil.WalkDir(localPaths[k], "")
+		walkedFiles, emptyDirs, err := pcsutil.WalkDirFunc(localPaths[k], localFilterFunc(localPaths[k], opt.Filter))
 		if err != nil {
-			fmt.Printf("警告: 遍历错误: %s\n", err)
+			fmt.Fprintf(r.Output, "警告: 遍历错误: %s\n", err)
 			continue
 		}
 
+		// 创建空目录
+		for _, emptyDir := range emptyDirs {
+			localPathDir := filepath.Dir(localPaths[k])
+			if os.PathSeparator == '\\' {
+				emptyDir = pcsutil.ConvertToUnixPathSeparator(emptyDir)
+				localPathDir = pcsutil.ConvertToUnixPathSeparator(localPathDir)
+			}
+			if localPathDir == "." {
+				localPathDir = ""
+			}
+			panDir := path.Clean(savePath + baidupcs.PathSeparator + strings.TrimPrefix(emptyDir, localPathDir))
+			pcsError := pcs.Mkdir(panDir)
+			if pcsError != nil && !errors.Is(pcsError, pcserror.ErrAlreadyExists) {
+				fmt.Fprintf(r.Output, "[0] 创建空目录 %s 错误: %s\n", panDir, pcsError)
+				continue
+			}
+			emptyDirCount++
+			fmt.Fprintf(r.Output, "[0] 创建空目录: %s\n", panDir)
+		}
+
 		for k3 := range walkedFiles {
 			var localPathDir string
 			// 针对 windows 的目录处理
@@ -162,10 +265,25 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 			}
 			subSavePath = strings.TrimPrefix(walkedFiles[k3], localPathDir)
 			if !opt.NoFilenameCheck && !pcsutil.ChPathLegal(walkedFiles[k3]) {
-				fmt.Printf("[0] %s 文件路径含有非法字符，已跳过!\n", walkedFiles[k3])
+				fmt.Fprintf(r.Output, "[0] %s 文件路径含有非法字符，已跳过!\n", walkedFiles[k3])
 				continue
 			}
+			// 小文件打包上传
+			if opt.Pack {
+				fi, err := os.Stat(walkedFiles[k3])
+				if err == nil && fi.Size() < opt.PackThreshold {
+					packFiles = append(packFiles, &packFile{
+						localPath: walkedFiles[k3],
+						name:      subSavePath,
+					})
+					continue
+				}
+			}
 			LoadCount++
+			var checksumJob *pcsupload.ChecksumJob
+			if checksumPool != nil {
+				checksumJob = checksumPool.Submit(walkedFiles[k3])
+			}
 			info := executor.Append(&pcsupload.UploadTaskUnit{
 				LocalFileChecksum: checksum.NewLocalFileChecksum(walkedFiles[k3], int(baidupcs.SliceMD5Size)),
 				SavePath:          path.Clean(savePath + baidupcs.PathSeparator + subSavePath),
@@ -177,17 +295,27 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 				NoSplitFile:       opt.NoSplitFile,
 				UploadStatistic:   statistic,
 				Policy:            opt.Policy,
+				Pipeline:          opt.Pipeline,
+				Checksum:          checksumJob,
+				Out:               r.Output,
+				Control:           r.Control,
 			}, opt.MaxRetry)
 			if LoadCount >= opt.Load {
 				LoadCount = opt.Load
 			}
-			fmt.Printf("[%s] 加入上传队列: %s\n", info.Id(), walkedFiles[k3])
+			fmt.Fprintf(r.Output, "[%s] 加入上传队列: %s\n", info.Id(), walkedFiles[k3])
 		}
 	}
 
+	if len(packFiles) > 0 {
+		runUploadPack(packFiles, savePath, opt, r)
+	}
+
 	// 没有添加任何任务
 	if executor.Count() == 0 {
-		fmt.Printf("未检测到上传的文件.\n")
+		if emptyDirCount == 0 && len(packFiles) == 0 {
+			fmt.Fprintf(r.Output, "未检测到上传的文件.\n")
+		}
 		return
 	}
 
@@ -196,14 +324,14 @@ func RunUpload(localPaths []string, savePath string, opt *UploadOptions) {
 	// 执行上传任务
 	executor.Execute()
 
-	fmt.Printf("\n")
-	fmt.Printf("上传结束, 时间: %s, 总大小: %s\n", statistic.Elapsed()/1e6*1e6, converter.ConvertFileSize(statistic.TotalSize()))
+	fmt.Fprintf(r.Output, "\n")
+	fmt.Fprintf(r.Output, "上传结束, 时间: %s, 总大小: %s\n", statistic.Elapsed()/1e6*1e6, converter.ConvertFileSize(statistic.TotalSize()))
 
 	// 输出上传失败的文件列表
 	failedList := executor.FailedDeque()
 	if failedList.Size() != 0 {
-		fmt.Printf("以下文件上传失败: \n")
-		tb := pcstable.NewTable(os.Stdout)
+		fmt.Fprintf(r.Output, "以下文件上传失败: \n")
+		tb := pcstable.NewTable(r.Output)
 		for e := failedList.Shift(); e != nil; e = failedList.Shift() {
 			item := e.(*taskframework.TaskInfoItem)
 			tb.Append([]string{item.Info.Id(), item.Unit.(*pcsupload.UploadTaskUnit).LocalFileChecksum.Path})
@@ -0,0 +1,64 @@
+package pcscommand
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"io"
+	"path"
+	"time"
+)
+
+const (
+	// StdinPath 上传时表示标准输入的本地路径
+	StdinPath = "-"
+)
+
+// runUploadStream 将长度未知的数据流上传到网盘文件 savePath, 不支持秒传和断点续传
+func runUploadStream(reader io.Reader, savePath string, opt *UploadOptions, r *Runner) {
+	pcs := r.baiduPCS()
+	savePath = path.Clean(savePath)
+	fmt.Fprintf(r.Output, "[0] 从标准输入上传到: %s, 分片大小: %s, 数据流无法秒传\n", savePath, converter.ConvertFileSize(uploader.DefaultStreamBlockSize))
+
+	su := uploader.NewStreamUploader(pcsupload.NewPCSUpload(pcs, savePath, nil), reader, &uploader.MultiUploaderConfig{
+		Parallel:  opt.Parallel,
+		BlockSize: uploader.DefaultStreamBlockSize,
+		MaxRate:   pcsconfig.Config.MaxUploadRate,
+		RateLimit: pcsconfig.Config.UploadRateLimit(),
+		Policy:    opt.Policy,
+	})
+	// 取消 (例如 Ctrl-C) 后中断上传
+	stopCancel := context.AfterFunc(pcs.Context(), su.Cancel)
+	defer stopCancel()
+	removeControl := r.Control.Add(su)
+	defer removeControl()
+
+	printFormat := uploadPrintFormat(1)
+	su.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
+		fmt.Fprintf(r.Output, printFormat, "0",
+			converter.ConvertFileSize(status.Uploaded(), 2),
+			converter.ConvertFileSize(status.TotalSize(), 2),
+			converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
+			status.TimeElapsed(),
+		)
+	})
+
+	startTime := time.Now()
+	err := su.Execute()
+	fmt.Fprintf(r.Output, "\n")
+	if err != nil {
+		if err == context.Canceled {
+			fmt.Fprintf(r.Output, "[0] %s\n", pcsupload.StrUploadCanceled)
+			return
+		}
+		fmt.Fprintf(r.Output, "[0] %s, %s\n", pcsupload.StrUploadFailed, err)
+		return
+	}
+
+	fmt.Fprintf(r.Output, "[0] 上传文件成功, 保存到网盘路径: %s\n", savePath)
+	fmt.Fprintf(r.Output, "上传结束, 时间: %s, 总大小: %s, md5: %s\n", time.Since(startTime)/1e6*1e6, converter.ConvertFileSize(su.Size()), hex.EncodeToString(su.MD5()))
+}
@@ -0,0 +1,67 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RunUploadListUnfinished 列出未完成的上传
+func RunUploadListUnfinished() {
+	uploadDatabase, err := pcsupload.NewUploadingDatabase()
+	if err != nil {
+		fmt.Printf("打开上传未完成数据库错误: %s\n", err)
+		return
+	}
+	defer uploadDatabase.Close()
+
+	list := uploadDatabase.List()
+	if len(list) == 0 {
+		fmt.Println("没有未完成的上传")
+		return
+	}
+
+	tb := pcstable.NewTable(os.Stdout)
+	tb.SetHeader([]string{"#", "本地路径", "文件大小", "已上传", "开始时间", "状态"})
+	for k, uploading := range list {
+		var (
+			created = "-"
+			status  = "等待上传"
+		)
+		if uploading.Created > 0 {
+			created = time.Unix(uploading.Created, 0).Format("2006-01-02 15:04:05")
+		}
+		if stale, reason := uploading.IsStale(pcsupload.UploadingMaxAge); stale {
+			status = "失效, " + reason.Error()
+		} else if uploading.State != nil {
+			status = "可续传"
+		}
+		tb.Append([]string{strconv.Itoa(k), uploading.Path, converter.ConvertFileSize(uploading.Length, 2), converter.ConvertFileSize(uploading.Uploaded(), 2), created, status})
+	}
+	tb.Render()
+	fmt.Printf("断点续传信息的有效期: %s, 失效的条目可使用 upload --purge-stale 清除\n", pcsupload.UploadingMaxAge)
+}
+
+// RunUploadPurgeStale 清除已过期的断点续传信息, 以及本地文件已删除或修改的未完成上传
+func RunUploadPurgeStale() {
+	uploadDatabase, err := pcsupload.NewUploadingDatabase()
+	if err != nil {
+		fmt.Printf("打开上传未完成数据库错误: %s\n", err)
+		return
+	}
+	defer uploadDatabase.Close()
+
+	removed, err := uploadDatabase.PurgeStale(pcsupload.UploadingMaxAge)
+	for _, uploading := range removed {
+		fmt.Printf("已清除: %s\n", uploading.Path)
+	}
+	if err != nil {
+		fmt.Printf("保存上传未完成数据库错误: %s\n", err)
+		return
+	}
+	fmt.Printf("共清除 %d 个失效的未完成上传\n", len(removed))
+}
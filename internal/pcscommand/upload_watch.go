@@ -0,0 +1,153 @@
+package pcscommand
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcsupload"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/dirwatch"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// runUploadWatch 监视本地目录 localDir, 文件写入完成后上传到网盘目录 savePath, 直到取消 (例如 Ctrl-C).
+// 目录中的文件按相对路径保存到 savePath 下
+func runUploadWatch(localDir, savePath string, opt *UploadOptions, r *Runner) {
+	localDir, err := filepath.Abs(localDir)
+	if err != nil {
+		fmt.Fprintf(r.Output, "%s\n", err)
+		return
+	}
+	var moveTo string
+	if opt.MoveTo != "" {
+		moveTo, err = filepath.Abs(opt.MoveTo)
+		if err != nil {
+			fmt.Fprintf(r.Output, "%s\n", err)
+			return
+		}
+	}
+
+	uploadDatabase, err := pcsupload.NewUploadingDatabase()
+	if err != nil {
+		fmt.Fprintf(r.Output, "打开上传未完成数据库错误: %s\n", err)
+		return
+	}
+	defer uploadDatabase.Close()
+
+	var (
+		pcs      = r.baiduPCS().WithoutCache() // 目标文件的信息以服务器为准
+		executor = &taskframework.TaskExecutor{}
+		done     = make(chan struct{})
+		served   = make(chan struct{})
+		// 统计
+		statistic = &pcsupload.UploadStatistic{}
+		// 已加入队列还未结束的文件
+		queuedMu sync.Mutex
+		queued   = map[string]bool{}
+		filter   = localFilterFunc(localDir, opt.Filter)
+	)
+	statistic.StartTimer()
+	executor.SetParallel(opt.Load)
+	go func() {
+		executor.Serve(done)
+		close(served)
+	}()
+
+	watcher := dirwatch.NewWatcher(localDir)
+	watcher.Filter = func(localPath string, fi os.FileInfo) bool {
+		// 不监视移入的已上传目录
+		if moveTo != "" && fi.IsDir() && localPath == moveTo {
+			return false
+		}
+		if !opt.NoFilenameCheck && !pcsutil.ChPathLegal(localPath) {
+			return false
+		}
+		return filter == nil || filter(localPath, fi)
+	}
+	watcher.OnNotifyError = func(err error) {
+		fmt.Fprintf(r.Output, "[0] 文件系统事件通知出错, 改为轮询: %s\n", err)
+	}
+
+	fmt.Fprintf(r.Output, "[0] 监视目录: %s, 文件写入完成后上传到: %s, 按 Ctrl-C 停止\n", localDir, savePath)
+	err = watcher.Run(pcs.Context(), func(localPath string, fi os.FileInfo) bool {
+		queuedMu.Lock()
+		defer queuedMu.Unlock()
+		// 上传中的文件又发生了变化, 结束后再上传
+		if queued[localPath] {
+			return false
+		}
+		queued[localPath] = true
+
+		rel, _ := filepath.Rel(localDir, localPath)
+		rel = filepath.ToSlash(rel)
+		info := executor.Append(&pcsupload.UploadTaskUnit{
+			LocalFileChecksum: checksum.NewLocalFileChecksum(localPath, int(baidupcs.SliceMD5Size)),
+			SavePath:          path.Join(savePath, rel),
+			PCS:               pcs,
+			UploadingDatabase: uploadDatabase,
+			Parallel:          opt.Parallel,
+			PrintFormat:       uploadPrintFormat(opt.Load),
+			NoRapidUpload:     opt.NoRapidUpload,
+			NoSplitFile:       opt.NoSplitFile,
+			UploadStatistic:   statistic,
+			Policy:            opt.Policy,
+			Out:               r.Output,
+			Control:           r.Control,
+			OnFinish: func(succeed bool) {
+				if succeed {
+					afterWatchUpload(localPath, rel, fi, opt.DeleteAfter, moveTo, r)
+				}
+				queuedMu.Lock()
+				delete(queued, localPath)
+				queuedMu.Unlock()
+			},
+		}, opt.MaxRetry)
+		fmt.Fprintf(r.Output, "[%s] 加入上传队列: %s\n", info.Id(), localPath)
+		return true
+	})
+	if err != nil {
+		fmt.Fprintf(r.Output, "监视目录 %s 错误: %s\n", localDir, err)
+	}
+
+	// 等待进行中的任务结束
+	close(done)
+	<-served
+	fmt.Fprintf(r.Output, "\n停止监视, 时间: %s, 上传总大小: %s\n", statistic.Elapsed()/1e6*1e6, converter.ConvertFileSize(statistic.TotalSize()))
+}
+
+// afterWatchUpload 上传成功后删除本地文件或移动到 moveTo 目录, 上传后又发生变化的文件不处理
+func afterWatchUpload(localPath, rel string, fi os.FileInfo, deleteAfter bool, moveTo string, r *Runner) {
+	if !deleteAfter && moveTo == "" {
+		return
+	}
+	now, err := os.Stat(localPath)
+	if err != nil || now.Size() != fi.Size() || !now.ModTime().Equal(fi.ModTime()) {
+		return
+	}
+
+	if deleteAfter {
+		err = os.Remove(localPath)
+		if err != nil {
+			fmt.Fprintf(r.Output, "删除已上传的文件错误: %s\n", err)
+			return
+		}
+		fmt.Fprintf(r.Output, "已删除已上传的文件: %s\n", localPath)
+		return
+	}
+
+	target := filepath.Join(moveTo, filepath.FromSlash(rel))
+	err = os.MkdirAll(filepath.Dir(target), 0777)
+	if err == nil {
+		err = os.Rename(localPath, target)
+	}
+	if err != nil {
+		fmt.Fprintf(r.Output, "移动已上传的文件错误: %s\n", err)
+		return
+	}
+	fmt.Fprintf(r.Output, "已移动已上传的文件: %s -> %s\n", localPath, target)
+}
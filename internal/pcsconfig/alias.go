@@ -0,0 +1,253 @@
+package pcsconfig
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsliner/args"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// aliasMaxDepth 别名嵌套展开的最大层数
+	aliasMaxDepth = 10
+)
+
+var (
+	// ErrAliasNameInvalid 别名名称不合法
+	ErrAliasNameInvalid = errors.New("别名只能包含字母, 数字, 下划线, 点和短横线, 且不能以短横线开头")
+	// ErrAliasEmpty 别名对应的命令为空
+	ErrAliasEmpty = errors.New("别名对应的命令为空")
+	// ErrAliasNotFound 别名不存在
+	ErrAliasNotFound = errors.New("别名不存在")
+	// ErrAliasTooDeep 别名嵌套的层数过多
+	ErrAliasTooDeep = errors.New("别名嵌套的层数过多")
+	// ErrAliasMissingArgs 别名缺少参数
+	ErrAliasMissingArgs = errors.New("别名缺少参数")
+
+	aliasNameRegexp  = regexp.MustCompile(`^[\p{L}\p{N}_.][\p{L}\p{N}_.\-]*$`)
+	aliasParamRegexp = regexp.MustCompile(`\$(\$|@|[1-9])`)
+)
+
+// ValidAliasName 别名名称是否合法
+func ValidAliasName(name string) bool {
+	return aliasNameRegexp.MatchString(name)
+}
+
+// SetAlias 添加或修改别名, cmdline 为展开后的命令行, 多条命令用分号隔开,
+// 可以使用 $1 ~ $9 引用位置参数, $@ 引用所有参数, $$ 表示 $
+func (c *PCSConfig) SetAlias(name, cmdline string) error {
+	if !ValidAliasName(name) {
+		return fmt.Errorf("%s: %s", ErrAliasNameInvalid, name)
+	}
+	cmdline = strings.TrimSpace(cmdline)
+	if len(splitCommands(cmdline)) == 0 {
+		return ErrAliasEmpty
+	}
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	c.Aliases[name] = cmdline
+	return nil
+}
+
+// RemoveAlias 删除别名
+func (c *PCSConfig) RemoveAlias(name string) error {
+	if _, ok := c.Aliases[name]; !ok {
+		return fmt.Errorf("%s: %s", ErrAliasNotFound, name)
+	}
+	delete(c.Aliases, name)
+	return nil
+}
+
+// Alias 返回别名对应的命令行
+func (c *PCSConfig) Alias(name string) (cmdline string, ok bool) {
+	cmdline, ok = c.Aliases[name]
+	return
+}
+
+// AliasNames 返回排序后的所有别名
+func (c *PCSConfig) AliasNames() []string {
+	names := make([]string, 0, len(c.Aliases))
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandAlias 展开命令中的别名, cmdArgs[0] 不是别名时返回 nil,
+// 别名可以引用其他别名, 展开后可能包含多条命令.
+// 和 bash 一样, 正在展开的别名不会再次展开, 所以别名可以包装同名的命令, 例如 ls 展开为 ls -l
+func (c *PCSConfig) ExpandAlias(cmdArgs []string) (cmds [][]string, err error) {
+	if len(cmdArgs) == 0 {
+		return nil, nil
+	}
+	if _, ok := c.Aliases[cmdArgs[0]]; !ok {
+		return nil, nil
+	}
+	return c.expandAlias(cmdArgs, nil)
+}
+
+func (c *PCSConfig) expandAlias(cmdArgs []string, expanding []string) (cmds [][]string, err error) {
+	name := cmdArgs[0]
+	cmdline, ok := c.Aliases[name]
+	if !ok || aliasExpanding(expanding, name) {
+		return [][]string{cmdArgs}, nil
+	}
+	if len(expanding) >= aliasMaxDepth {
+		return nil, fmt.Errorf("%s: %s", ErrAliasTooDeep, strings.Join(expanding, " -> "))
+	}
+	expanding = append(expanding, name)
+
+	var (
+		subs = splitCommands(cmdline)
+		// 没有引用任何位置参数时, 参数追加到最后一条命令末尾
+		appendParams = !referencesParams(cmdline)
+	)
+	for k, sub := range subs {
+		subArgs, err := substituteAliasArgs(name, args.Parse(sub), cmdArgs[1:])
+		if err != nil {
+			return nil, err
+		}
+		if appendParams && k == len(subs)-1 {
+			subArgs = append(subArgs, cmdArgs[1:]...)
+		}
+		if len(subArgs) == 0 {
+			continue
+		}
+		expanded, err := c.expandAlias(subArgs, expanding)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, expanded...)
+	}
+	return cmds, nil
+}
+
+// AliasCommandArgs 返回别名展开后最后一条命令中不含位置参数的部分, 用于自动补全
+func (c *PCSConfig) AliasCommandArgs(name string) []string {
+	return c.aliasCommandArgs(name, nil)
+}
+
+func (c *PCSConfig) aliasCommandArgs(name string, expanding []string) []string {
+	cmdline, ok := c.Aliases[name]
+	if !ok || len(expanding) >= aliasMaxDepth {
+		return nil
+	}
+	subs := splitCommands(cmdline)
+	if len(subs) == 0 {
+		return nil
+	}
+
+	cmdArgs := make([]string, 0, 8)
+	for _, arg := range args.Parse(subs[len(subs)-1]) {
+		if !referencesParams(arg) {
+			cmdArgs = append(cmdArgs, arg)
+		}
+	}
+	if len(cmdArgs) == 0 {
+		return nil
+	}
+	expanding = append(expanding, name)
+	if _, ok := c.Aliases[cmdArgs[0]]; ok && !aliasExpanding(expanding, cmdArgs[0]) {
+		nested := c.aliasCommandArgs(cmdArgs[0], expanding)
+		if nested == nil {
+			return nil
+		}
+		return append(nested, cmdArgs[1:]...)
+	}
+	return cmdArgs
+}
+
+// aliasExpanding 别名 name 是否正在展开
+func aliasExpanding(expanding []string, name string) bool {
+	for _, e := range expanding {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesParams 命令行是否引用了位置参数
+func referencesParams(cmdline string) bool {
+	for _, m := range aliasParamRegexp.FindAllString(cmdline, -1) {
+		if m != "$$" {
+			return true
+		}
+	}
+	return false
+}
+
+// substituteAliasArgs 替换位置参数
+func substituteAliasArgs(name string, tmpl, params []string) ([]string, error) {
+	var (
+		result = make([]string, 0, len(tmpl)+len(params))
+		err    error
+	)
+	for _, t := range tmpl {
+		if t == "$@" {
+			result = append(result, params...)
+			continue
+		}
+		t = aliasParamRegexp.ReplaceAllStringFunc(t, func(m string) string {
+			switch m {
+			case "$$":
+				return "$"
+			case "$@":
+				return strings.Join(params, " ")
+			}
+			n, _ := strconv.Atoi(m[1:])
+			if n > len(params) {
+				if err == nil {
+					err = fmt.Errorf("%s: %s 需要至少 %d 个参数", ErrAliasMissingArgs, name, n)
+				}
+				return ""
+			}
+			return params[n-1]
+		})
+		result = append(result, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// splitCommands 以引号外的分号分隔多条命令
+func splitCommands(cmdline string) (cmds []string) {
+	var (
+		buf       strings.Builder
+		quoteChar rune
+		escaped   bool
+	)
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			cmds = append(cmds, s)
+		}
+		buf.Reset()
+	}
+	for _, r := range cmdline {
+		switch {
+		case escaped:
+			escaped = false
+		case r == args.CharEscape:
+			escaped = true
+		case quoteChar != 0:
+			if r == quoteChar {
+				quoteChar = 0
+			}
+		case args.IsQuote(r):
+			quoteChar = r
+		case r == ';':
+			flush()
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	flush()
+	return
+}
@@ -0,0 +1,92 @@
+package pcsconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAlias(t *testing.T) {
+	c := NewConfig("")
+	for name, cmdline := range map[string]string{
+		"dl":   "download -p 16 -l 4 --mtime",
+		"get":  `dl --saveto "$1" $2`,
+		"sync": "cd $1; get ~/Downloads/$$HOME/x .; ls",
+		"all":  "rm $@",
+		"loop": "loop2",
+		"ls":   "ls -l",
+		"wrap": "wrap2 -v",
+	} {
+		if err := c.SetAlias(name, cmdline); err != nil {
+			t.Fatalf("SetAlias(%s): %s", name, err)
+		}
+	}
+	c.SetAlias("loop2", "loop x")
+	c.SetAlias("wrap2", "ls $@; wrap")
+
+	cases := []struct {
+		args []string
+		want [][]string
+	}{
+		{[]string{"pwd"}, nil},
+		{[]string{"dl", "/a", "/b"}, [][]string{{"download", "-p", "16", "-l", "4", "--mtime", "/a", "/b"}}},
+		{[]string{"get", "my dir", "/a"}, [][]string{{"download", "-p", "16", "-l", "4", "--mtime", "--saveto", "my dir", "/a"}}},
+		{[]string{"sync", "/apps"}, [][]string{{"cd", "/apps"}, {"download", "-p", "16", "-l", "4", "--mtime", "--saveto", "~/Downloads/$HOME/x", "."}, {"ls", "-l"}}},
+		{[]string{"all", "a", "b"}, [][]string{{"rm", "a", "b"}}},
+		// 正在展开的别名不再展开
+		{[]string{"ls", "/"}, [][]string{{"ls", "-l", "/"}}},
+		{[]string{"loop"}, [][]string{{"loop", "x"}}},
+		{[]string{"wrap", "/"}, [][]string{{"ls", "-l", "-v", "/"}, {"wrap"}}},
+	}
+	for _, tc := range cases {
+		got, err := c.ExpandAlias(tc.args)
+		if err != nil {
+			t.Errorf("ExpandAlias(%q): %s", tc.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ExpandAlias(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	for i := 0; i <= aliasMaxDepth; i++ {
+		c.SetAlias(fmt.Sprintf("deep%d", i), fmt.Sprintf("deep%d", i+1))
+	}
+	if _, err := c.ExpandAlias([]string{"deep0"}); err == nil || !strings.Contains(err.Error(), ErrAliasTooDeep.Error()) {
+		t.Errorf("deeply nested alias should fail, got %v", err)
+	}
+	if got := c.AliasCommandArgs("get"); !reflect.DeepEqual(got, []string{"download", "-p", "16", "-l", "4", "--mtime", "--saveto"}) {
+		t.Errorf("AliasCommandArgs(get) = %q", got)
+	}
+	if got := c.AliasCommandArgs("ls"); !reflect.DeepEqual(got, []string{"ls", "-l"}) {
+		t.Errorf("AliasCommandArgs(ls) = %q", got)
+	}
+	if got := c.AliasCommandArgs("loop"); !reflect.DeepEqual(got, []string{"loop", "x"}) {
+		t.Errorf("AliasCommandArgs(loop) = %q", got)
+	}
+	if _, err := c.ExpandAlias([]string{"get", "only-one"}); err == nil || !strings.Contains(err.Error(), ErrAliasMissingArgs.Error()) {
+		t.Errorf("missing args should fail, got %v", err)
+	}
+}
+
+func TestSetAlias(t *testing.T) {
+	c := NewConfig("")
+	if err := c.SetAlias("-x", "ls"); err == nil {
+		t.Errorf("invalid alias name should fail")
+	}
+	if err := c.SetAlias("x", " ; "); err != ErrAliasEmpty {
+		t.Errorf("empty alias should fail, got %v", err)
+	}
+	c.SetAlias("b", "ls")
+	c.SetAlias("a", "pwd")
+	if names := c.AliasNames(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("AliasNames() = %q", names)
+	}
+	if err := c.RemoveAlias("a"); err != nil {
+		t.Errorf("RemoveAlias: %s", err)
+	}
+	if err := c.RemoveAlias("a"); err == nil {
+		t.Errorf("removing a missing alias should fail")
+	}
+}
@@ -0,0 +1,89 @@
+package pcsconfig
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultAPIRateLimit 默认的 API 限速, 每秒请求数
+	DefaultAPIRateLimit = "meta=8,file=4,locate=2,share=1"
+)
+
+var (
+	// ErrAPIRateLimitInvalid API 限速格式错误
+	ErrAPIRateLimitInvalid = errors.New("API 限速格式错误")
+)
+
+// ParseAPIRateLimit 解析各类接口每秒最多的请求数, 例如 "5" 表示所有类别均为每秒5次,
+// "meta=8,file=4,locate=2,share=1" 分别设置, 空字符串或 0 表示不限速
+func ParseAPIRateLimit(s string) (limits map[string]float64, err error) {
+	limits = map[string]float64{}
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return limits, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		class, value := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			class, value = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("%s: %s", ErrAPIRateLimitInvalid, part)
+		}
+
+		if class == "" {
+			for _, class := range baidupcs.APIClasses {
+				limits[class] = rate
+			}
+			continue
+		}
+
+		valid := false
+		for _, knownClass := range baidupcs.APIClasses {
+			if class == knownClass {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%s: 未知的接口类别 %s, 可选: %s", ErrAPIRateLimitInvalid, class, strings.Join(baidupcs.APIClasses, ", "))
+		}
+		limits[class] = rate
+	}
+	return limits, nil
+}
+
+// SetAPIRateLimitByStr 设置各类接口的限速
+func (c *PCSConfig) SetAPIRateLimitByStr(s string) error {
+	limits, err := ParseAPIRateLimit(s)
+	if err != nil {
+		return err
+	}
+	c.APIRateLimit = strings.TrimSpace(s)
+	if c.pcs != nil {
+		c.pcs.SetAPIRateLimits(limits)
+	}
+	return nil
+}
+
+// APIRateLimits 返回各类接口的限速, 配置错误时使用默认值
+func (c *PCSConfig) APIRateLimits() map[string]float64 {
+	limits, err := ParseAPIRateLimit(c.APIRateLimit)
+	if err != nil {
+		pcsConfigVerbose.Warnf("parse api_rate_limit error: %s\n", err)
+		limits, _ = ParseAPIRateLimit(DefaultAPIRateLimit)
+	}
+	return limits
+}
@@ -0,0 +1,27 @@
+package pcsconfig_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"testing"
+)
+
+func TestParseAPIRateLimit(t *testing.T) {
+	limits, err := pcsconfig.ParseAPIRateLimit("2, file=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["meta"] != 2 || limits["share"] != 2 || limits["file"] != 0.5 {
+		t.Fatalf("unexpected limits: %v", limits)
+	}
+
+	limits, err = pcsconfig.ParseAPIRateLimit("0")
+	if err != nil || len(limits) != 0 {
+		t.Fatalf("0 should disable limits: %v, %v", limits, err)
+	}
+
+	for _, s := range []string{"fast", "unknown=1", "meta=-1"} {
+		if _, err = pcsconfig.ParseAPIRateLimit(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
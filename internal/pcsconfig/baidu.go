@@ -62,6 +62,10 @@ func (baidu *Baidu) BaiduPCS() *baidupcs.BaiduPCS {
 	pcs.SetPanUserAgent(Config.PanUA)
 	pcs.SetUID(baidu.UID)
 	pcs.SetaccessToken(baidu.AccessToken)
+	pcs.SetDiskCache(baidu.MetaCache())
+	pcs.SetOffline(Config.Offline)
+	pcs.SetRetryPolicy(Config.APIRetryPolicy())
+	pcs.SetAPIRateLimits(Config.APIRateLimits())
 	return pcs
 }
 
@@ -73,6 +73,8 @@ func (c *PCSConfig) PrintTable() {
 		[]string{"max_download_load", strconv.Itoa(c.MaxDownloadLoad), "1 ~ 5", "同时进行下载文件的最大数量"},
 		[]string{"max_download_rate", showMaxRate(c.MaxDownloadRate), "", "限制最大下载速度, 0代表不限制"},
 		[]string{"max_upload_rate", showMaxRate(c.MaxUploadRate), "", "限制最大上传速度, 0代表不限制"},
+		[]string{"download_rate_schedule", c.DownloadRateSchedule, "2MB/s 09:00-18:00 weekdays; 0 otherwise", "分时段下载限速规则, 多条规则用分号隔开, 第一条匹配的规则生效, 均不匹配时使用 max_download_rate"},
+		[]string{"upload_rate_schedule", c.UploadRateSchedule, "", "分时段上传限速规则, 格式同 download_rate_schedule, 均不匹配时使用 max_upload_rate"},
 		[]string{"max_upload_load", strconv.Itoa(c.MaxUploadLoad), "1-4", "同时进行上传文件的最大数量"},
 		[]string{"savedir", c.SaveDir, "", "下载文件的储存目录"},
 		[]string{"enable_https", fmt.Sprint(c.EnableHTTPS), "true", "启用 https"},
@@ -86,6 +88,14 @@ func (c *PCSConfig) PrintTable() {
 		[]string{"pan_ua", c.PanUA, baidupcs.NetdiskUA, "Pan 浏览器标识"},
 		[]string{"proxy", c.Proxy, "", "设置代理, 支持 http/socks5 代理"},
 		[]string{"local_addrs", c.LocalAddrs, "", "设置本地网卡地址, 多个地址用逗号隔开"},
+		[]string{"mirror_blocklist", c.MirrorBlocklist, "", "禁止使用的下载服务器, 多个主机用逗号隔开, 以 *. 开头表示所有子域名"},
+		[]string{"meta_cache_ttl", c.MetaCacheTTL, "10m 或 list=10m,meta=1h", "目录列表和文件元信息的磁盘缓存有效期, 留空或 0 表示不启用"},
+		[]string{"api_max_retry", strconv.Itoa(c.APIMaxRetry), "3", "API 请求遇到网络错误, 服务器临时错误或请求过于频繁时的最大重试次数, 只重试不会产生副作用的请求, 0代表不重试"},
+		[]string{"api_rate_limit", c.APIRateLimit, DefaultAPIRateLimit, "各类接口每秒最多的请求数, 类别: meta(列表/元信息), file(文件操作), locate(下载链接), share(分享), 服务器返回请求过于频繁时自动降速, 0代表不限速"},
+		[]string{"hook_command", c.HookCommand, "", "上传, 下载或转存结束后执行的外部命令, 标准输入为 JSON 格式的传输信息, 留空表示不执行"},
+		[]string{"hook_url", c.HookURL, "http://127.0.0.1:8080/notify", "上传, 下载或转存结束后接收 POST 请求的本机 URL, 请求体为 JSON 格式的传输信息, 留空表示不发送"},
+		[]string{"hook_events", c.HookEvents, "upload,download.failed", "触发钩子的事件, 类别: upload, download, transfer, 可加上状态: success, failed, skipped, 留空表示全部触发"},
+		[]string{"hook_timeout", c.HookTimeout, DefaultHookTimeout.String(), "钩子命令和请求的超时时间, 超时或失败不影响传输结果"},
 	})
 	tb.Render()
 }
@@ -0,0 +1,35 @@
+package pcsconfig
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout 默认的钩子超时时间
+const DefaultHookTimeout = 30 * time.Second
+
+// SetHookCommand 设置传输结束后执行的外部命令, 空字符串表示不执行
+func (c *PCSConfig) SetHookCommand(command string) {
+	c.HookCommand = strings.TrimSpace(command)
+}
+
+// SetHookURL 设置传输结束后接收 POST 请求的 URL, 空字符串表示不发送, 由调用者检查格式
+func (c *PCSConfig) SetHookURL(s string) {
+	c.HookURL = strings.TrimSpace(s)
+}
+
+// SetHookEvents 设置触发钩子的事件, 由调用者检查格式
+func (c *PCSConfig) SetHookEvents(s string) {
+	c.HookEvents = strings.TrimSpace(s)
+}
+
+// SetHookTimeoutByStr 设置钩子的超时时间
+func (c *PCSConfig) SetHookTimeoutByStr(s string) error {
+	timeout, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("钩子超时时间格式错误: %s", s)
+	}
+	c.HookTimeout = timeout.String()
+	return nil
+}
@@ -168,7 +168,9 @@ func (c *PCSConfig) SetMaxDownloadRateByStr(sizeStr string) error {
 	if err != nil {
 		return err
 	}
-	c.MaxDownloadRate = size
+	c.setRateConfig(func() {
+		c.MaxDownloadRate = size
+	})
 	return nil
 }
 
@@ -178,7 +180,9 @@ func (c *PCSConfig) SetMaxUploadRateByStr(sizeStr string) error {
 	if err != nil {
 		return err
 	}
-	c.MaxUploadRate = size
+	c.setRateConfig(func() {
+		c.MaxUploadRate = size
+	})
 	return nil
 }
 
@@ -245,6 +249,37 @@ func (c *PCSConfig) SetLocalAddrs(localAddrs string) {
 	requester.SetLocalTCPAddrList(strings.Split(localAddrs, ",")...)
 }
 
+// SetMirrorBlocklist 设置禁止使用的下载服务器, 多个主机用逗号隔开
+func (c *PCSConfig) SetMirrorBlocklist(blocklist string) {
+	c.MirrorBlocklist = blocklist
+}
+
+// SetAPIMaxRetry 设置 API 请求的最大重试次数
+func (c *PCSConfig) SetAPIMaxRetry(maxRetry int) {
+	if maxRetry < 0 {
+		maxRetry = 0
+	}
+	c.APIMaxRetry = maxRetry
+	if c.pcs != nil {
+		c.pcs.SetRetryPolicy(c.APIRetryPolicy())
+	}
+}
+
+// APIRetryPolicy 返回 API 请求的重试策略
+func (c *PCSConfig) APIRetryPolicy() *requester.RetryPolicy {
+	policy := requester.DefaultRetryPolicy
+	policy.MaxRetry = c.APIMaxRetry
+	return &policy
+}
+
+// MirrorBlocklistHosts 返回禁止使用的下载服务器列表
+func (c *PCSConfig) MirrorBlocklistHosts() []string {
+	if c.MirrorBlocklist == "" {
+		return nil
+	}
+	return strings.Split(c.MirrorBlocklist, ",")
+}
+
 // SetIgnoreIllegal 设置忽略上传文件名非法字符
 func (c *PCSConfig) SetIgnoreIllegal(ignore bool) {
 	c.IgnoreIllegal = ignore
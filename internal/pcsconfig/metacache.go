@@ -0,0 +1,122 @@
+package pcsconfig
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/diskcache"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MetaCacheDirName 元信息磁盘缓存的目录名
+	MetaCacheDirName = "pcs_cache"
+)
+
+var (
+	// ErrMetaCacheTTLInvalid 缓存有效期格式错误
+	ErrMetaCacheTTLInvalid = errors.New("缓存有效期格式错误")
+
+	metaCacheOps = []string{baidupcs.DiskCacheOpList, baidupcs.DiskCacheOpMeta}
+)
+
+// ParseMetaCacheTTL 解析元信息缓存的有效期, 例如 "10m" 表示所有操作的有效期均为10分钟,
+// "list=10m,meta=1h" 分别设置目录列表和元信息的有效期, 空字符串或 0 表示不启用缓存
+func ParseMetaCacheTTL(s string) (ttls map[string]time.Duration, err error) {
+	ttls = map[string]time.Duration{}
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return ttls, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		op, value := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			op, value = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+
+		ttl, err := parseTTL(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", ErrMetaCacheTTLInvalid, part)
+		}
+
+		if op == "" {
+			for _, op := range metaCacheOps {
+				ttls[op] = ttl
+			}
+			continue
+		}
+
+		valid := false
+		for _, knownOp := range metaCacheOps {
+			if op == knownOp {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%s: 未知的操作 %s, 可选: %s", ErrMetaCacheTTLInvalid, op, strings.Join(metaCacheOps, ", "))
+		}
+		ttls[op] = ttl
+	}
+	return ttls, nil
+}
+
+// parseTTL 解析有效期, 纯数字表示秒数
+func parseTTL(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, errors.New("negative duration")
+	}
+	return ttl, nil
+}
+
+// SetMetaCacheTTLByStr 设置元信息磁盘缓存的有效期
+func (c *PCSConfig) SetMetaCacheTTLByStr(s string) error {
+	_, err := ParseMetaCacheTTL(s)
+	if err != nil {
+		return err
+	}
+	c.MetaCacheTTL = strings.TrimSpace(s)
+	if c.pcs != nil && c.activeUser != nil {
+		c.pcs.SetDiskCache(c.activeUser.MetaCache())
+	}
+	return nil
+}
+
+// SetOffline 设置离线模式, 只读取磁盘缓存, 不发起网络请求, 不保存到配置文件
+func (c *PCSConfig) SetOffline(offline bool) {
+	c.Offline = offline
+	if c.pcs != nil {
+		c.pcs.SetOffline(offline)
+	}
+}
+
+// MetaCacheDir 返回帐号的元信息磁盘缓存目录
+func (baidu *Baidu) MetaCacheDir() string {
+	return filepath.Join(GetConfigDir(), MetaCacheDirName, strconv.FormatUint(baidu.UID, 10))
+}
+
+// MetaCache 返回帐号的元信息磁盘缓存, 未启用缓存时, 仍然返回缓存对象, 以便离线模式读取和清除缓存
+func (baidu *Baidu) MetaCache() *diskcache.Cache {
+	ttls, err := ParseMetaCacheTTL(Config.MetaCacheTTL)
+	if err != nil {
+		pcsConfigVerbose.Warnf("parse meta_cache_ttl error: %s\n", err)
+		ttls = map[string]time.Duration{}
+	}
+	return diskcache.New(baidu.MetaCacheDir(), ttls)
+}
@@ -46,24 +46,41 @@ type PCSConfig struct {
 	MaxDownloadRate int64 `json:"max_download_rate"` // 限制最大下载速度
 	MaxUploadRate   int64 `json:"max_upload_rate"`   // 限制最大上传速度
 
-	UserAgent   string `json:"user_agent"`   // 浏览器标识
-	PCSUA       string `json:"pcs_ua"`       // PCS浏览器标识
-	PCSAddr     string `json:"pcs_addr"`     // PCS服务器域名
-	PanUA       string `json:"pan_ua"`       // PAN浏览器标识
-	SaveDir     string `json:"savedir"`      // 下载储存路径
-	EnableHTTPS bool   `json:"enable_https"` // 启用https
-	ForceLogin  string `json:"force_login_username"` // 强制登录
-	Proxy       string `json:"proxy"`        // 代理
-	LocalAddrs  string `json:"local_addrs"`  // 本地网卡地址
-	NoCheck     bool   `json:"no_check"`     // 禁用下载md5校验
-	IgnoreIllegal bool `json:"ignore_illegal"` // 禁用上传文件名非法字符检查
-	UPolicy     string `json:"u_policy"`     // 上传重名文件处理策略
-
-	configFilePath string
-	configFile     *os.File
-	fileMu         sync.Mutex
-	activeUser     *Baidu
-	pcs            *baidupcs.BaiduPCS
+	DownloadRateSchedule string `json:"download_rate_schedule"` // 分时段下载限速规则
+	UploadRateSchedule   string `json:"upload_rate_schedule"`   // 分时段上传限速规则
+
+	UserAgent       string `json:"user_agent"`           // 浏览器标识
+	PCSUA           string `json:"pcs_ua"`               // PCS浏览器标识
+	PCSAddr         string `json:"pcs_addr"`             // PCS服务器域名
+	PanUA           string `json:"pan_ua"`               // PAN浏览器标识
+	SaveDir         string `json:"savedir"`              // 下载储存路径
+	EnableHTTPS     bool   `json:"enable_https"`         // 启用https
+	ForceLogin      string `json:"force_login_username"` // 强制登录
+	Proxy           string `json:"proxy"`                // 代理
+	LocalAddrs      string `json:"local_addrs"`          // 本地网卡地址
+	MirrorBlocklist string `json:"mirror_blocklist"`     // 禁止使用的下载服务器
+	MetaCacheTTL    string `json:"meta_cache_ttl"`       // 元信息磁盘缓存的有效期
+	APIMaxRetry     int    `json:"api_max_retry"`        // API 请求的最大重试次数
+	APIRateLimit    string `json:"api_rate_limit"`       // 各类接口每秒最多的请求数
+	HookCommand     string `json:"hook_command"`         // 传输结束后执行的外部命令
+	HookURL         string `json:"hook_url"`             // 传输结束后接收 POST 请求的 URL
+	HookEvents      string `json:"hook_events"`          // 触发钩子的事件
+	HookTimeout     string `json:"hook_timeout"`         // 钩子的超时时间
+	NoCheck         bool   `json:"no_check"`             // 禁用下载md5校验
+	IgnoreIllegal   bool   `json:"ignore_illegal"`       // 禁用上传文件名非法字符检查
+	UPolicy         string `json:"u_policy"`             // 上传重名文件处理策略
+
+	Aliases map[string]string `json:"aliases"` // 命令别名
+
+	Offline bool `json:"-"` // 离线模式, 只读取缓存
+
+	configFilePath    string
+	configFile        *os.File
+	fileMu            sync.Mutex
+	rateSchedulerOnce sync.Once
+	rateMu            sync.Mutex // 保护限速相关的配置, 限速规则定时检查时读取
+	activeUser        *Baidu
+	pcs               *baidupcs.BaiduPCS
 }
 
 // NewConfig 返回 PCSConfig 指针对象
@@ -217,7 +234,9 @@ func (c *PCSConfig) loadConfigFromFile() (err error) {
 		return err
 	}
 
+	c.rateMu.Lock()
 	err = jsonhelper.UnmarshalData(c.configFile, c)
+	c.rateMu.Unlock()
 	if err != nil {
 		return ErrConfigContentsParseError
 	}
@@ -238,6 +257,9 @@ func (c *PCSConfig) InitDefaultConfig() {
 	c.EnableHTTPS = true
 	c.NoCheck = true
 	c.UPolicy = "fail"
+	c.APIMaxRetry = requester.DefaultRetryPolicy.MaxRetry
+	c.APIRateLimit = DefaultAPIRateLimit
+	c.HookTimeout = DefaultHookTimeout.String()
 
 	// 设置默认的下载路径
 	switch runtime.GOOS {
@@ -319,6 +341,9 @@ func (c *PCSConfig) fix() {
 	if c.MaxUploadLoad < 1 {
 		c.MaxUploadLoad = 1
 	}
+	if c.APIMaxRetry < 0 {
+		c.APIMaxRetry = 0
+	}
 	if c.UPolicy != "fail" && c.UPolicy != "newcopy" && c.UPolicy != "overwrite" && c.UPolicy != "skip" && c.UPolicy != "rsync" {
 		c.UPolicy = "fail"
 	}
@@ -0,0 +1,294 @@
+package pcsconfig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio/speeds"
+)
+
+const (
+	// rateScheduleInterval 检查限速规则的周期
+	rateScheduleInterval = 10 * time.Second
+)
+
+var (
+	// ErrRateRuleInvalid 限速规则格式错误
+	ErrRateRuleInvalid = errors.New("限速规则格式错误")
+
+	// 所有下载/上传任务共享的限速器
+	downloadRateLimit = speeds.NewRateLimit(0)
+	uploadRateLimit   = speeds.NewRateLimit(0)
+
+	weekdayNames = map[string]time.Weekday{
+		"sun": time.Sunday,
+		"mon": time.Monday,
+		"tue": time.Tuesday,
+		"wed": time.Wednesday,
+		"thu": time.Thursday,
+		"fri": time.Friday,
+		"sat": time.Saturday,
+	}
+)
+
+type (
+	// RateRule 单条限速规则, 例如 "2MB/s 09:00-18:00 weekdays"
+	RateRule struct {
+		Rate     int64 // 最大速度, <= 0 代表不限制
+		hasTime  bool
+		begin    int     // 开始时间, 一天中的第几分钟
+		end      int     // 结束时间, 一天中的第几分钟
+		weekdays [7]bool // 生效的星期
+	}
+
+	// RateSchedule 限速规则列表, 按顺序匹配, 第一条匹配的规则生效
+	RateSchedule []*RateRule
+)
+
+// ParseRateSchedule 解析限速规则, 多条规则用分号隔开,
+// 每条规则格式为: <速度> [HH:MM-HH:MM] [weekdays|weekends|everyday|otherwise|mon,tue,...]
+func ParseRateSchedule(str string) (RateSchedule, error) {
+	var rs RateSchedule
+	for _, rawRule := range strings.Split(str, ";") {
+		rawRule = strings.TrimSpace(rawRule)
+		if rawRule == "" {
+			continue
+		}
+		rule, err := parseRateRule(rawRule)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, rule)
+	}
+	return rs, nil
+}
+
+func parseRateRule(rawRule string) (*RateRule, error) {
+	fields := strings.Fields(rawRule)
+	rate, err := parseRate(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s, %s", ErrRateRuleInvalid, rawRule, err)
+	}
+
+	rule := &RateRule{
+		Rate: rate,
+	}
+	hasDays := false
+	for _, field := range fields[1:] {
+		field = strings.ToLower(field)
+		switch {
+		case strings.ContainsAny(field, ":"):
+			if rule.hasTime {
+				return nil, fmt.Errorf("%s: %s, 重复的时间段", ErrRateRuleInvalid, rawRule)
+			}
+			rule.begin, rule.end, err = parseTimeRange(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s, %s", ErrRateRuleInvalid, rawRule, err)
+			}
+			rule.hasTime = true
+		case field == "otherwise" || field == "everyday":
+			// 不限制星期
+		default:
+			err = rule.parseWeekdays(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s, %s", ErrRateRuleInvalid, rawRule, err)
+			}
+			hasDays = true
+		}
+	}
+	if !hasDays {
+		for k := range rule.weekdays {
+			rule.weekdays[k] = true
+		}
+	}
+	return rule, nil
+}
+
+func parseRate(rateStr string) (int64, error) {
+	switch strings.ToLower(rateStr) {
+	case "unlimited", "不限制":
+		return 0, nil
+	}
+	return converter.ParseFileSizeStr(stripPerSecond(rateStr))
+}
+
+// parseTimeRange 解析 HH:MM-HH:MM, 结束时间小于开始时间时表示跨越午夜
+func parseTimeRange(field string) (begin, end int, err error) {
+	// 兼容 en dash
+	field = strings.Replace(field, "–", "-", 1)
+	parts := strings.Split(field, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("时间段格式应为 HH:MM-HH:MM")
+	}
+	begin, err = parseClock(parts[0])
+	if err != nil {
+		return
+	}
+	end, err = parseClock(parts[1])
+	return
+}
+
+func parseClock(clock string) (int, error) {
+	hm := strings.Split(clock, ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", clock)
+	}
+	h, err := strconv.Atoi(hm[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("小时不合法: %s", clock)
+	}
+	m, err := strconv.Atoi(hm[1])
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("分钟不合法: %s", clock)
+	}
+	return h*60 + m, nil
+}
+
+func (rr *RateRule) parseWeekdays(field string) error {
+	switch field {
+	case "weekdays", "workdays":
+		for d := time.Monday; d <= time.Friday; d++ {
+			rr.weekdays[d] = true
+		}
+		return nil
+	case "weekends":
+		rr.weekdays[time.Saturday] = true
+		rr.weekdays[time.Sunday] = true
+		return nil
+	}
+
+	for _, name := range strings.Split(field, ",") {
+		if len(name) < 3 {
+			return fmt.Errorf("未知的星期: %s", name)
+		}
+		d, ok := weekdayNames[name[:3]]
+		if !ok {
+			return fmt.Errorf("未知的星期: %s", name)
+		}
+		rr.weekdays[d] = true
+	}
+	return nil
+}
+
+// Match 规则在时间 t 是否生效
+func (rr *RateRule) Match(t time.Time) bool {
+	if !rr.weekdays[t.Weekday()] {
+		return false
+	}
+	if !rr.hasTime {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if rr.begin <= rr.end {
+		return minute >= rr.begin && minute < rr.end
+	}
+	// 跨越午夜
+	return minute >= rr.begin || minute < rr.end
+}
+
+// Rate 返回时间 t 生效的最大速度, ok 为 false 表示没有规则匹配
+func (rs RateSchedule) Rate(t time.Time) (rate int64, ok bool) {
+	for _, rule := range rs {
+		if rule.Match(t) {
+			return rule.Rate, true
+		}
+	}
+	return 0, false
+}
+
+// SetDownloadRateScheduleByStr 设置 download_rate_schedule
+func (c *PCSConfig) SetDownloadRateScheduleByStr(str string) error {
+	_, err := ParseRateSchedule(str)
+	if err != nil {
+		return err
+	}
+	c.setRateConfig(func() {
+		c.DownloadRateSchedule = str
+	})
+	return nil
+}
+
+// SetUploadRateScheduleByStr 设置 upload_rate_schedule
+func (c *PCSConfig) SetUploadRateScheduleByStr(str string) error {
+	_, err := ParseRateSchedule(str)
+	if err != nil {
+		return err
+	}
+	c.setRateConfig(func() {
+		c.UploadRateSchedule = str
+	})
+	return nil
+}
+
+// CurrentDownloadRate 返回当前生效的最大下载速度
+func (c *PCSConfig) CurrentDownloadRate() int64 {
+	c.rateMu.Lock()
+	schedule, rate := c.DownloadRateSchedule, c.MaxDownloadRate
+	c.rateMu.Unlock()
+	return currentRate(schedule, rate, time.Now())
+}
+
+// CurrentUploadRate 返回当前生效的最大上传速度
+func (c *PCSConfig) CurrentUploadRate() int64 {
+	c.rateMu.Lock()
+	schedule, rate := c.UploadRateSchedule, c.MaxUploadRate
+	c.rateMu.Unlock()
+	return currentRate(schedule, rate, time.Now())
+}
+
+func currentRate(schedule string, defaultRate int64, t time.Time) int64 {
+	rs, err := ParseRateSchedule(schedule)
+	if err != nil {
+		pcsConfigVerbose.Warnf("parse rate schedule error: %s\n", err)
+		return defaultRate
+	}
+	rate, ok := rs.Rate(t)
+	if !ok {
+		return defaultRate
+	}
+	return rate
+}
+
+// DownloadRateLimit 返回所有下载任务共享的限速器,
+// 限速值根据 max_download_rate 和 download_rate_schedule 自动调整
+func (c *PCSConfig) DownloadRateLimit() *speeds.RateLimit {
+	c.rateSchedulerOnce.Do(c.startRateScheduler)
+	return downloadRateLimit
+}
+
+// UploadRateLimit 返回所有上传任务共享的限速器,
+// 限速值根据 max_upload_rate 和 upload_rate_schedule 自动调整
+func (c *PCSConfig) UploadRateLimit() *speeds.RateLimit {
+	c.rateSchedulerOnce.Do(c.startRateScheduler)
+	return uploadRateLimit
+}
+
+// setRateConfig 在 rateMu 的保护下修改限速相关的配置, 并立即更新限速器
+func (c *PCSConfig) setRateConfig(set func()) {
+	c.rateMu.Lock()
+	set()
+	c.rateMu.Unlock()
+	c.updateRateLimit()
+}
+
+func (c *PCSConfig) updateRateLimit() {
+	downloadRateLimit.SetMaxRate(c.CurrentDownloadRate())
+	uploadRateLimit.SetMaxRate(c.CurrentUploadRate())
+}
+
+// startRateScheduler 定时检查限速规则, 传输进行中也会生效
+func (c *PCSConfig) startRateScheduler() {
+	c.updateRateLimit()
+	go func() {
+		ticker := time.NewTicker(rateScheduleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c.updateRateLimit()
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+package pcsconfig_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"testing"
+	"time"
+)
+
+func TestRateSchedule(t *testing.T) {
+	rs, err := pcsconfig.ParseRateSchedule("2MB/s 09:00-18:00 weekdays; 512KB 22:00-06:00; unlimited otherwise")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		t    time.Time
+		rate int64
+	}{
+		{time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local), 2 << 20},   // 周一
+		{time.Date(2024, 1, 6, 10, 0, 0, 0, time.Local), 0},         // 周六
+		{time.Date(2024, 1, 6, 23, 0, 0, 0, time.Local), 512 << 10}, // 跨越午夜
+		{time.Date(2024, 1, 7, 5, 59, 0, 0, time.Local), 512 << 10},
+		{time.Date(2024, 1, 1, 18, 0, 0, 0, time.Local), 0},
+	}
+	for _, c := range cases {
+		rate, ok := rs.Rate(c.t)
+		if !ok || rate != c.rate {
+			t.Errorf("%s: got %d, %v, want %d", c.t, rate, ok, c.rate)
+		}
+	}
+
+	for _, invalid := range []string{"1MB 25:00-26:00", "1MB someday", "abc"} {
+		if _, err := pcsconfig.ParseRateSchedule(invalid); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package pcsfunctions
+
+import (
+	"sync"
+)
+
+type (
+	// Pauser 可暂停的传输, 例如 downloader.Downloader, uploader.MultiUploader
+	Pauser interface {
+		Pause()
+		Resume()
+	}
+
+	// TransferControl 统一暂停和恢复一组正在进行的传输, 零值可用, nil 时所有操作无效
+	TransferControl struct {
+		mu      sync.Mutex
+		paused  bool
+		pausers map[Pauser]struct{}
+	}
+)
+
+// Add 加入正在进行的传输, 已暂停时立即暂停, 返回移除的函数
+func (tc *TransferControl) Add(p Pauser) (remove func()) {
+	if tc == nil {
+		return func() {}
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.pausers == nil {
+		tc.pausers = make(map[Pauser]struct{})
+	}
+	tc.pausers[p] = struct{}{}
+	if tc.paused {
+		p.Pause()
+	}
+	return func() {
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+		delete(tc.pausers, p)
+	}
+}
+
+// Pause 暂停所有传输
+func (tc *TransferControl) Pause() {
+	if tc == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.paused = true
+	for p := range tc.pausers {
+		p.Pause()
+	}
+}
+
+// Resume 恢复所有传输
+func (tc *TransferControl) Resume() {
+	if tc == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.paused = false
+	for p := range tc.pausers {
+		p.Resume()
+	}
+}
+
+// Paused 是否已暂停
+func (tc *TransferControl) Paused() bool {
+	if tc == nil {
+		return false
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.paused
+}
@@ -0,0 +1,209 @@
+package pcsdownload
+
+import (
+	"context"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/downloader"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// PoolScratchDir 多帐号下载时, 秒传文件到其他帐号的暂存目录
+	PoolScratchDir = "/.BaiduPCS-Go-pool"
+)
+
+type (
+	// PoolAccount 多帐号下载池中的帐号
+	PoolAccount struct {
+		Name string
+		PCS  *baidupcs.BaiduPCS
+	}
+
+	// AccountPool 多帐号下载池, 在其他帐号中定位同一个文件,
+	// 将其下载链接作为备用服务器, 由 downloader 分配 worker
+	AccountPool struct {
+		accounts []*PoolAccount
+		verbose  *pcsverbose.PCSVerbose
+
+		mu      sync.Mutex
+		scratch map[*PoolAccount][]string // 使用过的暂存目录, 下载结束后删除
+
+		// 以下为网盘接口, 便于测试时替换
+		meta            func(account *PoolAccount, pcspath string) (*baidupcs.FileDirectory, error)
+		rapidUpload     func(account *PoolAccount, pcspath string, rinfo *baidupcs.RapidUploadInfo) error
+		rapidUploadInfo func(pcs *baidupcs.BaiduPCS, fd *baidupcs.FileDirectory) (*baidupcs.RapidUploadInfo, error)
+		locate          func(account *PoolAccount, pcspath string) ([]*url.URL, error)
+	}
+)
+
+// NewAccountPool 初始化多帐号下载池
+func NewAccountPool(verbose *pcsverbose.PCSVerbose, accounts ...*PoolAccount) *AccountPool {
+	return &AccountPool{
+		accounts: accounts,
+		verbose:  verbose,
+		scratch:  map[*PoolAccount][]string{},
+		meta: func(account *PoolAccount, pcspath string) (*baidupcs.FileDirectory, error) {
+			fd, pcsError := account.PCS.FilesDirectoriesMeta(pcspath)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			return fd, nil
+		},
+		rapidUpload: func(account *PoolAccount, pcspath string, rinfo *baidupcs.RapidUploadInfo) error {
+			pcsError := account.PCS.APIRapidUpload(pcspath, rinfo.ContentMD5, rinfo.SliceMD5, "", rinfo.ContentLength)
+			if pcsError != nil {
+				return pcsError
+			}
+			return nil
+		},
+		rapidUploadInfo: func(pcs *baidupcs.BaiduPCS, fd *baidupcs.FileDirectory) (*baidupcs.RapidUploadInfo, error) {
+			rinfo, pcsError := pcs.GetRapidUploadInfoByFileInfo(fd)
+			if pcsError != nil {
+				return nil, pcsError
+			}
+			return rinfo, nil
+		},
+		locate: func(account *PoolAccount, pcspath string) ([]*url.URL, error) {
+			return GetLocateDownloadLinks(account.PCS, pcspath)
+		},
+	}
+}
+
+// Len 返回下载池中的帐号数量
+func (ap *AccountPool) Len() int {
+	if ap == nil {
+		return 0
+	}
+	return len(ap.accounts)
+}
+
+func (ap *AccountPool) verboseInfof(format string, a ...interface{}) {
+	if ap.verbose != nil {
+		ap.verbose.Infof(format, a...)
+	}
+}
+
+// Links 返回其他帐号中与 fd 相同的文件的下载链接,
+// 优先使用相同路径的文件, 否则按 md5 秒传到帐号的暂存目录. mh 不为 nil 时, 跳过被禁止的主机
+func (ap *AccountPool) Links(pcs *baidupcs.BaiduPCS, fd *baidupcs.FileDirectory, mh *downloader.MirrorHealth) (links []string) {
+	if ap.Len() == 0 || fd == nil || fd.Isdir {
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		rinfo    *baidupcs.RapidUploadInfo
+		rinfoErr error
+		once     sync.Once
+	)
+	// 秒传信息只获取一次
+	rapidUploadInfo := func() (*baidupcs.RapidUploadInfo, error) {
+		once.Do(func() {
+			rinfo, rinfoErr = ap.rapidUploadInfo(pcs, fd)
+		})
+		return rinfo, rinfoErr
+	}
+
+	for _, account := range ap.accounts {
+		wg.Add(1)
+		go func(account *PoolAccount) {
+			defer wg.Done()
+			accountPath, err := ap.resolve(account, fd, rapidUploadInfo)
+			if err != nil {
+				ap.verboseInfof("多帐号下载: 帐号 %s 无法获取文件 %s, %s\n", account.Name, fd.Path, err)
+				return
+			}
+
+			dlinks, err := ap.locate(account, accountPath)
+			if err != nil {
+				ap.verboseInfof("多帐号下载: 帐号 %s 获取下载链接失败, %s\n", account.Name, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, dlink := range dlinks {
+				if strings.HasPrefix(dlink.Host, "nb.cache") || (mh != nil && mh.IsBlocked(dlink.Host)) {
+					continue
+				}
+				FixHTTPLinkURL(dlink)
+				links = append(links, dlink.String())
+			}
+		}(account)
+	}
+	wg.Wait()
+	return
+}
+
+// resolve 返回文件在 account 中的路径
+func (ap *AccountPool) resolve(account *PoolAccount, fd *baidupcs.FileDirectory, rapidUploadInfo func() (*baidupcs.RapidUploadInfo, error)) (string, error) {
+	// 相同路径
+	meta, err := ap.meta(account, fd.Path)
+	if err == nil && sameFile(meta, fd) {
+		return fd.Path, nil
+	}
+
+	rinfo, err := rapidUploadInfo()
+	if err != nil {
+		return "", fmt.Errorf("获取秒传信息失败, %s", err)
+	}
+
+	scratchPath := path.Join(PoolScratchDir, strings.ToLower(rinfo.ContentMD5), fd.Filename)
+	meta, err = ap.meta(account, scratchPath)
+	if err == nil && sameFile(meta, fd) {
+		// 之前的下载留下的, 一并清理
+		ap.addScratch(account, path.Dir(scratchPath))
+		return scratchPath, nil
+	}
+
+	err = ap.rapidUpload(account, scratchPath, rinfo)
+	if err != nil {
+		return "", fmt.Errorf("秒传到暂存目录失败, %s", err)
+	}
+	ap.verboseInfof("多帐号下载: 已秒传到帐号 %s 的暂存目录: %s\n", account.Name, scratchPath)
+	ap.addScratch(account, path.Dir(scratchPath))
+	return scratchPath, nil
+}
+
+// addScratch 记录使用过的暂存目录
+func (ap *AccountPool) addScratch(account *PoolAccount, dir string) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	for _, d := range ap.scratch[account] {
+		if d == dir {
+			return
+		}
+	}
+	ap.scratch[account] = append(ap.scratch[account], dir)
+}
+
+// sameFile 判断是否为同一个文件, 大小和 md5 都相同, 没有 md5 记录则无法确认
+func sameFile(meta, fd *baidupcs.FileDirectory) bool {
+	if meta == nil || meta.Isdir || meta.Size != fd.Size || meta.MD5 == "" || fd.MD5 == "" {
+		return false
+	}
+	return strings.EqualFold(meta.MD5, fd.MD5)
+}
+
+// Cleanup 删除秒传到各个帐号暂存目录的文件, 下载被取消 (例如 Ctrl-C) 后仍然执行
+func (ap *AccountPool) Cleanup() {
+	if ap == nil {
+		return
+	}
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	for account, dirs := range ap.scratch {
+		pcsError := account.PCS.WithContext(context.Background()).Remove(dirs...)
+		if pcsError != nil {
+			ap.verboseInfof("多帐号下载: 清理帐号 %s 的暂存目录失败, %s\n", account.Name, pcsError)
+		}
+	}
+	ap.scratch = map[*PoolAccount][]string{}
+}
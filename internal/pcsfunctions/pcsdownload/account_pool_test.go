@@ -0,0 +1,175 @@
+package pcsdownload
+
+import (
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/downloader"
+	"net/url"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+)
+
+const testPoolMD5 = "0123456789abcdef0123456789abcdef"
+
+// testPoolAPI 模拟各个帐号的网盘文件
+type testPoolAPI struct {
+	mu       sync.Mutex
+	files    map[string]map[string]*baidupcs.FileDirectory // 帐号名 -> 路径 -> 文件
+	uploaded []string
+}
+
+func newTestAccountPool(api *testPoolAPI, accounts ...*PoolAccount) *AccountPool {
+	ap := NewAccountPool(nil, accounts...)
+	ap.meta = func(account *PoolAccount, pcspath string) (*baidupcs.FileDirectory, error) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		fd, ok := api.files[account.Name][pcspath]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return fd, nil
+	}
+	ap.rapidUpload = func(account *PoolAccount, pcspath string, rinfo *baidupcs.RapidUploadInfo) error {
+		if account.Name == "noquota" {
+			return errors.New("no quota")
+		}
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.uploaded = append(api.uploaded, account.Name+":"+pcspath)
+		api.files[account.Name][pcspath] = &baidupcs.FileDirectory{Path: pcspath, Size: rinfo.ContentLength, MD5: rinfo.ContentMD5}
+		return nil
+	}
+	ap.rapidUploadInfo = func(pcs *baidupcs.BaiduPCS, fd *baidupcs.FileDirectory) (*baidupcs.RapidUploadInfo, error) {
+		return &baidupcs.RapidUploadInfo{ContentMD5: fd.MD5, ContentLength: fd.Size}, nil
+	}
+	ap.locate = func(account *PoolAccount, pcspath string) ([]*url.URL, error) {
+		return []*url.URL{
+			{Scheme: "http", Host: account.Name + ".example.com", Path: pcspath},
+			{Scheme: "http", Host: "nb.cache.example.com", Path: pcspath},
+		}, nil
+	}
+	return ap
+}
+
+func TestSameFile(t *testing.T) {
+	fd := &baidupcs.FileDirectory{Size: 10, MD5: testPoolMD5}
+	cases := []struct {
+		name string
+		meta *baidupcs.FileDirectory
+		fd   *baidupcs.FileDirectory
+		want bool
+	}{
+		{"same", &baidupcs.FileDirectory{Size: 10, MD5: testPoolMD5}, fd, true},
+		{"md5 case", &baidupcs.FileDirectory{Size: 10, MD5: "0123456789ABCDEF0123456789ABCDEF"}, fd, true},
+		{"nil", nil, fd, false},
+		{"dir", &baidupcs.FileDirectory{Size: 10, MD5: testPoolMD5, Isdir: true}, fd, false},
+		{"size", &baidupcs.FileDirectory{Size: 11, MD5: testPoolMD5}, fd, false},
+		{"md5", &baidupcs.FileDirectory{Size: 10, MD5: "ffffffffffffffffffffffffffffffff"}, fd, false},
+		{"no md5", &baidupcs.FileDirectory{Size: 10}, fd, false},
+		{"local no md5", &baidupcs.FileDirectory{Size: 10, MD5: testPoolMD5}, &baidupcs.FileDirectory{Size: 10}, false},
+	}
+	for _, c := range cases {
+		if got := sameFile(c.meta, c.fd); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestAccountPoolResolve(t *testing.T) {
+	var (
+		fd          = &baidupcs.FileDirectory{Path: "/a/b.mp4", Filename: "b.mp4", Size: 10, MD5: testPoolMD5}
+		scratchPath = path.Join(PoolScratchDir, testPoolMD5, "b.mp4")
+		samePath    = &PoolAccount{Name: "same"}
+		leftover    = &PoolAccount{Name: "leftover"}
+		other       = &PoolAccount{Name: "other"}
+		noquota     = &PoolAccount{Name: "noquota"}
+		api         = &testPoolAPI{
+			files: map[string]map[string]*baidupcs.FileDirectory{
+				"same":     {fd.Path: {Path: fd.Path, Size: 10, MD5: testPoolMD5}},
+				"leftover": {scratchPath: {Path: scratchPath, Size: 10, MD5: testPoolMD5}},
+				"other":    {fd.Path: {Path: fd.Path, Size: 10, MD5: "ffffffffffffffffffffffffffffffff"}},
+				"noquota":  {},
+			},
+		}
+		ap   = newTestAccountPool(api, samePath, leftover, other, noquota)
+		info = func() (*baidupcs.RapidUploadInfo, error) {
+			return ap.rapidUploadInfo(nil, fd)
+		}
+	)
+
+	cases := []struct {
+		account *PoolAccount
+		want    string
+		wantErr bool
+	}{
+		{samePath, fd.Path, false},
+		{leftover, scratchPath, false},
+		{other, scratchPath, false},
+		{noquota, "", true},
+	}
+	for _, c := range cases {
+		got, err := ap.resolve(c.account, fd, info)
+		if got != c.want || (err != nil) != c.wantErr {
+			t.Errorf("%s: got %q, %v, want %q, error %v", c.account.Name, got, err, c.want, c.wantErr)
+		}
+	}
+
+	if len(api.uploaded) != 1 || api.uploaded[0] != "other:"+scratchPath {
+		t.Errorf("uploaded: got %v", api.uploaded)
+	}
+	// 之前留下的和新秒传的暂存目录都需要清理
+	scratchDir := path.Dir(scratchPath)
+	for _, account := range []*PoolAccount{leftover, other} {
+		if dirs := ap.scratch[account]; len(dirs) != 1 || dirs[0] != scratchDir {
+			t.Errorf("%s: scratch dirs: got %v", account.Name, dirs)
+		}
+	}
+	if dirs := ap.scratch[samePath]; len(dirs) != 0 {
+		t.Errorf("same: scratch dirs: got %v", dirs)
+	}
+
+	// 再次获取, 不重复记录
+	ap.resolve(leftover, fd, info)
+	if dirs := ap.scratch[leftover]; len(dirs) != 1 {
+		t.Errorf("leftover again: scratch dirs: got %v", dirs)
+	}
+}
+
+func TestAccountPoolLinks(t *testing.T) {
+	fd := &baidupcs.FileDirectory{Path: "/a/b.mp4", Filename: "b.mp4", Size: 10, MD5: testPoolMD5}
+	api := &testPoolAPI{
+		files: map[string]map[string]*baidupcs.FileDirectory{
+			"a":       {fd.Path: {Path: fd.Path, Size: 10, MD5: testPoolMD5}},
+			"b":       {},
+			"noquota": {},
+		},
+	}
+	ap := newTestAccountPool(api, &PoolAccount{Name: "a"}, &PoolAccount{Name: "b"}, &PoolAccount{Name: "noquota"})
+
+	links := ap.Links(nil, fd, nil)
+	sort.Strings(links)
+	want := []string{
+		"http://a.example.com/a/b.mp4",
+		"http://b.example.com" + path.Join(PoolScratchDir, testPoolMD5, "b.mp4"),
+	}
+	if len(links) != len(want) || links[0] != want[0] || links[1] != want[1] {
+		t.Errorf("links: got %v, want %v", links, want)
+	}
+
+	// 跳过被禁止的主机
+	links = ap.Links(nil, fd, downloader.NewMirrorHealth([]string{"b.example.com"}))
+	if len(links) != 1 || links[0] != want[0] {
+		t.Errorf("blocked: got %v, want [%s]", links, want[0])
+	}
+
+	// 目录没有下载链接
+	if links = ap.Links(nil, &baidupcs.FileDirectory{Path: "/a", Isdir: true}, nil); len(links) != 0 {
+		t.Errorf("dir: got %v", links)
+	}
+	var empty *AccountPool
+	if links = empty.Links(nil, fd, nil); len(links) != 0 {
+		t.Errorf("nil pool: got %v", links)
+	}
+}
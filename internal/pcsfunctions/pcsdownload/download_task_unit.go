@@ -6,6 +6,8 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcshook"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/retry"
@@ -49,10 +51,19 @@ type (
 
 		DownloadMode DownloadMode // 下载模式
 
+		Out     io.Writer                     // 输出, 默认为标准输出
+		Control *pcsfunctions.TransferControl // 暂停和恢复控制
+
+		AccountPool *AccountPool // 多帐号下载池, 仅定位下载模式有效
+
 		PcsPath  string // 要下载的网盘文件路径
 		SavePath string // 保存的路径
 
 		FileInfo *baidupcs.FileDirectory // 文件或目录详情
+
+		mirrors   []string  // 备用的下载链接
+		startTime time.Time // 第一次执行的时间, 用于钩子
+		skipped   bool      // 文件已存在, 跳过
 	}
 )
 
@@ -61,10 +72,16 @@ const (
 	DefaultPrintFormat = "\r[%s] ↓ %s/%s %s/s in %s, left %s ............"
 	//DownloadSuffix 文件下载后缀
 	DownloadSuffix = ".BaiduPCS-Go-downloading"
+	// PartialSuffix 下载中的临时文件后缀, 校验通过后才重命名为目标文件
+	PartialSuffix = ".bpcs-part"
+	// PartialStaleDuration 超过该时间未修改的临时文件视为失效
+	PartialStaleDuration = 24 * time.Hour
 	//StrDownloadInitError 初始化下载发生错误
 	StrDownloadInitError = "初始化下载发生错误"
 	// StrDownloadFailed 下载文件失败
 	StrDownloadFailed = "下载文件失败"
+	// StrDownloadCanceled 下载已取消
+	StrDownloadCanceled = "下载已取消"
 	// StrDownloadGetDlinkFailed 获取下载链接失败
 	StrDownloadGetDlinkFailed = "获取下载链接失败"
 	// StrDownloadChecksumFailed 检测文件有效性失败
@@ -73,6 +90,8 @@ const (
 	StrDownloadCheckLengthFailed = "检测文件大小一致性失败"
 	// DefaultDownloadMaxRetry 默认下载失败最大重试次数
 	DefaultDownloadMaxRetry = 3
+	// ParallelHistoryFileName 各个主机最佳下载并发量的记录文件
+	ParallelHistoryFileName = "pcs_download_parallel.json"
 )
 
 const (
@@ -87,6 +106,14 @@ func (dtu *DownloadTaskUnit) SetTaskInfo(info *taskframework.TaskInfo) {
 	dtu.taskInfo = info
 }
 
+// out 返回输出
+func (dtu *DownloadTaskUnit) out() io.Writer {
+	if dtu.Out == nil {
+		return os.Stdout
+	}
+	return dtu.Out
+}
+
 func (dtu *DownloadTaskUnit) verboseInfof(format string, a ...interface{}) {
 	if dtu.VerbosePrinter != nil {
 		dtu.VerbosePrinter.Infof(format, a...)
@@ -101,8 +128,9 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 	)
 
 	if !dtu.Cfg.IsTest {
-		// 非测试下载
-		dtu.Cfg.InstanceStatePath = dtu.SavePath + DownloadSuffix
+		// 非测试下载, 先下载到临时文件, 断点续传信息保存在临时文件旁边
+		partPath := dtu.partialPath()
+		dtu.Cfg.InstanceStatePath = partPath + DownloadSuffix
 
 		// 创建下载的目录
 		// 获取SavePath所在的目录
@@ -119,8 +147,10 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 			return fmt.Errorf("%s, path %s: not a directory", StrDownloadInitError, dir)
 		}
 
+		dtu.migrateLegacyPartial()
+
 		// 打开文件
-		writer, file, err = downloader.NewDownloaderWriterByFilename(dtu.SavePath, os.O_CREATE|os.O_WRONLY, 0666)
+		writer, file, err = downloader.NewDownloaderWriterByFilename(partPath, os.O_CREATE|os.O_WRONLY, 0666)
 		if err != nil {
 			return fmt.Errorf("%s, %s", StrDownloadInitError, err)
 		}
@@ -129,6 +159,8 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 
 	der := downloader.NewDownloader(downloadURL, writer, dtu.Cfg)
 	der.SetClient(client)
+	der.SetContext(dtu.PCS.Context())
+	der.AddLoadBalanceServer(dtu.mirrors...)
 	der.SetDURLCheckFunc(BaiduPCSURLCheckFunc)
 	//der.SetFileContentLength(dtu.FileInfo.Size)
 	der.SetStatusCodeBodyCheckFunc(func(respBody io.Reader) error {
@@ -162,6 +194,17 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 			// 先空两行
 			builder.WriteString("\n\n")
 			tb.Render()
+
+			// 输出下载服务器的统计信息
+			if dtu.Cfg.MirrorHealth != nil {
+				mtb := pcstable.NewTable(builder)
+				mtb.SetHeader([]string{"host", "score", "latency", "speeds", "downloaded", "requests", "errors", "evicted"})
+				for _, stat := range dtu.Cfg.MirrorHealth.Stats() {
+					mtb.Append([]string{stat.Host, strconv.FormatFloat(stat.Score, 'f', 2, 64), stat.Latency.Round(time.Millisecond).String(), converter.ConvertFileSize(stat.Speeds, 2) + "/s", converter.ConvertFileSize(stat.Downloaded, 2), strconv.Itoa(stat.Requests), strconv.Itoa(stat.Errors), strconv.FormatBool(stat.Evicted)})
+				}
+				builder.WriteString("\n")
+				mtb.Render()
+			}
 		}
 
 		// 如果下载速度为0, 剩余下载时间未知, 则用 - 代替
@@ -182,19 +225,21 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 
 		if !isComplete {
 			// 如果未完成下载, 就输出
-			fmt.Print(builder.String())
+			fmt.Fprint(dtu.out(), builder.String())
 		}
 	})
 
 	der.OnExecute(func() {
 		if dtu.Cfg.IsTest {
-			fmt.Printf("[%s] 测试下载开始\n\n", dtu.taskInfo.Id())
+			fmt.Fprintf(dtu.out(), "[%s] 测试下载开始\n\n", dtu.taskInfo.Id())
 		}
 	})
 
+	removeControl := dtu.Control.Add(der)
 	err = der.Execute()
+	removeControl()
 	isComplete = true
-	fmt.Print("\n")
+	fmt.Fprint(dtu.out(), "\n")
 
 	if err != nil {
 		// 下载发生错误
@@ -203,8 +248,8 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 			if info, infoErr := file.Stat(); infoErr == nil {
 				if info.Size() == 0 {
 					// 空文件, 应该删除
-					dtu.verboseInfof("[%s] remove empty file: %s\n", dtu.taskInfo.Id(), dtu.SavePath)
-					removeErr := os.Remove(dtu.SavePath)
+					dtu.verboseInfof("[%s] remove empty file: %s\n", dtu.taskInfo.Id(), dtu.partialPath())
+					removeErr := os.Remove(dtu.partialPath())
 					if removeErr != nil {
 						dtu.verboseInfof("[%s] remove file error: %s\n", dtu.taskInfo.Id(), removeErr)
 					}
@@ -214,25 +259,67 @@ func (dtu *DownloadTaskUnit) download(downloadURL string, client *requester.HTTP
 		return err
 	}
 
-	// 下载成功
-	if !dtu.Cfg.IsTest {
-		if dtu.IsExecutedPermission {
-			err = file.Chmod(0766)
-			if err != nil {
-				fmt.Printf("[%s] 警告, 加执行权限错误: %s\n", dtu.taskInfo.Id(), err)
-			}
-		}
-
-		fmt.Printf("[%s] 下载完成, 保存位置: %s\n", dtu.taskInfo.Id(), dtu.SavePath)
-	} else {
-		fmt.Printf("[%s] 测试下载结束\n", dtu.taskInfo.Id())
+	// 下载成功, 校验通过后才保存到目标文件
+	if dtu.Cfg.IsTest {
+		fmt.Fprintf(dtu.out(), "[%s] 测试下载结束\n", dtu.taskInfo.Id())
 	}
 
 	return nil
 }
 
+// partialPath 下载中的临时文件路径
+func (dtu *DownloadTaskUnit) partialPath() string {
+	return dtu.SavePath + PartialSuffix
+}
+
+// migrateLegacyPartial 旧版本直接下载到目标文件, 将未完成的下载迁移到临时文件, 以便继续断点续传
+func (dtu *DownloadTaskUnit) migrateLegacyPartial() {
+	legacyState := dtu.SavePath + DownloadSuffix
+	if _, err := os.Stat(legacyState); err != nil {
+		return
+	}
+	if _, err := os.Stat(dtu.partialPath()); err == nil {
+		return
+	}
+
+	err := os.Rename(dtu.SavePath, dtu.partialPath())
+	if err == nil {
+		err = os.Rename(legacyState, dtu.partialPath()+DownloadSuffix)
+	}
+	if err != nil {
+		dtu.verboseInfof("[%s] migrate legacy download error: %s\n", dtu.taskInfo.Id(), err)
+	}
+}
+
+// commitPartial 文件校验通过后, 设置修改时间和权限, 并将临时文件重命名为目标文件
+func (dtu *DownloadTaskUnit) commitPartial() error {
+	partPath := dtu.partialPath()
+	if dtu.ModifyMTime {
+		mtime := time.Unix(dtu.FileInfo.Mtime, 0)
+		if dtu.FileInfo.LocalMtime > 0 {
+			// 优先使用上传时记录的本地修改时间
+			mtime = time.Unix(dtu.FileInfo.LocalMtime, 0)
+		}
+		err := os.Chtimes(partPath, mtime, mtime)
+		if err != nil {
+			fmt.Fprintf(dtu.out(), "[%s] 警告, 修改文件时间错误: %s\n", dtu.taskInfo.Id(), err)
+		}
+	}
+	if dtu.IsExecutedPermission {
+		err := os.Chmod(partPath, 0766)
+		if err != nil {
+			fmt.Fprintf(dtu.out(), "[%s] 警告, 加执行权限错误: %s\n", dtu.taskInfo.Id(), err)
+		}
+	}
+	return os.Rename(partPath, dtu.SavePath)
+}
+
 // panHTTPClient 获取包含特定User-Agent的HTTPClient
 func (dtu *DownloadTaskUnit) panHTTPClient() *requester.HTTPClient {
+	return panHTTPClient()
+}
+
+func panHTTPClient() *requester.HTTPClient {
 	if client == nil {
 		client = pcsconfig.Config.PanHTTPClient()
 	}
@@ -253,30 +340,10 @@ func (dtu *DownloadTaskUnit) panHTTPClient() *requester.HTTPClient {
 }
 
 func (dtu *DownloadTaskUnit) handleError(result *taskframework.TaskUnitRunResult) {
-	switch value := result.Err.(type) {
-	case pcserror.Error: // pcserror 接口
-		switch value.GetErrType() {
-		case pcserror.ErrTypeRemoteError:
-		// 远程服务器错误
-		case 31045: // user not exists
-			fallthrough
-		case 31066: // file does not exist
-			result.NeedRetry = false
-		case 31297: // file does not exist
-			result.NeedRetry = false
-		case 31626: // user is not authorized
-			//可能是User-Agent不对
-			//重试
-			fallthrough
-		default:
-			result.NeedRetry = true
-		}
-	case *os.PathError:
-		// 系统级别的错误, 可能是权限问题
-		result.NeedRetry = false
-	default:
-		// 其他错误, 需要重试
-		result.NeedRetry = true
+	// 文件不存在, 登录过期, 本地文件系统错误等不重试
+	result.NeedRetry = pcserror.IsRetryable(result.Err)
+	if pcserror.IsAuthError(result.Err) {
+		result.ResultMessage = StrDownloadFailed + ", 登录状态过期, 请尝试重新登录"
 	}
 }
 
@@ -303,6 +370,24 @@ func (dtu *DownloadTaskUnit) locateDownload(result *taskframework.TaskUnitRunRes
 		return
 	}
 
+	// 过滤禁止使用的下载服务器
+	if dtu.Cfg.MirrorHealth != nil {
+		dlinks := rawDlinks[:0]
+		for _, dlink := range rawDlinks {
+			if dtu.Cfg.MirrorHealth.IsBlocked(dlink.Host) {
+				dtu.verboseInfof("[%s] 跳过禁止使用的下载服务器: %s\n", dtu.taskInfo.Id(), dlink.Host)
+				continue
+			}
+			dlinks = append(dlinks, dlink)
+		}
+		if len(dlinks) == 0 {
+			result.ResultMessage = StrDownloadGetDlinkFailed
+			result.Err = ErrDlinkAllBlocked
+			return
+		}
+		rawDlinks = dlinks
+	}
+
 	// 更新链接的协议
 	// 跳过nb.cache这种还没有证书的
 	if len(rawDlinks) < dtu.DlinkPrefer+1 {
@@ -315,6 +400,25 @@ func (dtu *DownloadTaskUnit) locateDownload(result *taskframework.TaskUnitRunRes
 	FixHTTPLinkURL(raw_dlink)
 	dlink := raw_dlink.String()
 
+	// 其余的下载链接作为备用服务器, 由 downloader 根据健康状况分配
+	dtu.mirrors = nil
+	if dtu.Cfg.MirrorHealth != nil {
+		for _, mirror := range rawDlinks {
+			if mirror == raw_dlink || strings.HasPrefix(mirror.Host, "nb.cache") {
+				continue
+			}
+			FixHTTPLinkURL(mirror)
+			dtu.mirrors = append(dtu.mirrors, mirror.String())
+		}
+	}
+
+	// 其他帐号中同一文件的下载链接
+	if dtu.AccountPool.Len() > 0 {
+		poolLinks := dtu.AccountPool.Links(dtu.PCS, dtu.FileInfo, dtu.Cfg.MirrorHealth)
+		fmt.Fprintf(dtu.out(), "[%s] 多帐号下载: 从其他 %d 个帐号获取到 %d 个下载链接\n", dtu.taskInfo.Id(), dtu.AccountPool.Len(), len(poolLinks))
+		dtu.mirrors = append(dtu.mirrors, poolLinks...)
+	}
+
 	dtu.execPanDownload(dlink, result, &ok)
 	return
 }
@@ -348,9 +452,9 @@ func (dtu *DownloadTaskUnit) pcsOrStreamingDownload(mode DownloadMode, result *t
 	return true // 下载成功
 }
 
-// checkFileValid 检测文件有效性
+// checkFileValid 检测文件有效性, 检测的是下载中的临时文件
 func (dtu *DownloadTaskUnit) checkFileValid(result *taskframework.TaskUnitRunResult) (ok bool) {
-	fi, err := os.Stat(dtu.SavePath)
+	fi, err := os.Stat(dtu.partialPath())
 	if err == nil {
 		if fi.Size() != dtu.FileInfo.Size {
 			result.ResultMessage = StrDownloadCheckLengthFailed
@@ -361,17 +465,17 @@ func (dtu *DownloadTaskUnit) checkFileValid(result *taskframework.TaskUnitRunRes
 	}
 	if dtu.Cfg.IsTest || dtu.NoCheck {
 		// 不检测文件有效性
-		fmt.Printf("[%s] 跳过文件有效性检验\n", dtu.taskInfo.Id())
+		fmt.Fprintf(dtu.out(), "[%s] 跳过文件有效性检验\n", dtu.taskInfo.Id())
 		return true
 	}
 
 	if dtu.FileInfo.Size >= 128*converter.MB {
 		// 大文件, 输出一句提示消息
-		fmt.Printf("[%s] 开始检验文件有效性, 请稍候...\n", dtu.taskInfo.Id())
+		fmt.Fprintf(dtu.out(), "[%s] 开始检验文件有效性, 请稍候...\n", dtu.taskInfo.Id())
 	}
 
 	// 就在这里处理校验出错
-	err = CheckFileValid(dtu.SavePath, dtu.FileInfo)
+	err = CheckFileValid(dtu.partialPath(), dtu.FileInfo)
 	if err != nil {
 		result.ResultMessage = StrDownloadChecksumFailed
 		result.Err = err
@@ -380,7 +484,7 @@ func (dtu *DownloadTaskUnit) checkFileValid(result *taskframework.TaskUnitRunRes
 			// 文件不支持校验
 			result.ResultMessage = "检验文件有效性"
 			result.Err = err
-			fmt.Printf("[%s] 检验文件有效性: %s\n", dtu.taskInfo.Id(), err)
+			fmt.Fprintf(dtu.out(), "[%s] 检验文件有效性: %s\n", dtu.taskInfo.Id(), err)
 			return true
 		case ErrDownloadFileBanned:
 			// 违规文件
@@ -398,7 +502,7 @@ func (dtu *DownloadTaskUnit) checkFileValid(result *taskframework.TaskUnitRunRes
 		}
 	}
 
-	fmt.Printf("[%s] 检验文件有效性成功: %s\n", dtu.taskInfo.Id(), dtu.SavePath)
+	fmt.Fprintf(dtu.out(), "[%s] 检验文件有效性成功: %s\n", dtu.taskInfo.Id(), dtu.partialPath())
 	return true
 }
 
@@ -406,23 +510,54 @@ func (dtu *DownloadTaskUnit) OnRetry(lastRunResult *taskframework.TaskUnitRunRes
 	// 输出错误信息
 	if lastRunResult.Err == nil {
 		// result中不包含Err, 忽略输出
-		fmt.Printf("[%s] %s, 重试 %d/%d\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, dtu.taskInfo.Retry(), dtu.taskInfo.MaxRetry())
+		fmt.Fprintf(dtu.out(), "[%s] %s, 重试 %d/%d\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, dtu.taskInfo.Retry(), dtu.taskInfo.MaxRetry())
 		return
 	}
-	fmt.Printf("[%s] %s, %s, 重试 %d/%d\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err, dtu.taskInfo.Retry(), dtu.taskInfo.MaxRetry())
+	fmt.Fprintf(dtu.out(), "[%s] %s, %s, 重试 %d/%d\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err, dtu.taskInfo.Retry(), dtu.taskInfo.MaxRetry())
 }
 
 func (dtu *DownloadTaskUnit) OnSuccess(lastRunResult *taskframework.TaskUnitRunResult) {
+	if dtu.skipped {
+		dtu.fireHook(pcshook.StatusSkipped, nil)
+		return
+	}
+	dtu.fireHook(pcshook.StatusSuccess, nil)
 }
 
 func (dtu *DownloadTaskUnit) OnFailed(lastRunResult *taskframework.TaskUnitRunResult) {
 	// 失败
 	if lastRunResult.Err == nil {
 		// result中不包含Err, 忽略输出
-		fmt.Printf("[%s] %s\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage)
+		fmt.Fprintf(dtu.out(), "[%s] %s\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage)
+		dtu.fireHook(pcshook.StatusFailed, errors.New(lastRunResult.ResultMessage))
 		return
 	}
-	fmt.Printf("[%s] %s, %s\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err)
+	fmt.Fprintf(dtu.out(), "[%s] %s, %s\n", dtu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err)
+	dtu.fireHook(pcshook.StatusFailed, fmt.Errorf("%s, %w", lastRunResult.ResultMessage, lastRunResult.Err))
+}
+
+// fireHook 执行配置的钩子, 目录和测试下载不执行, 钩子失败不影响下载结果
+func (dtu *DownloadTaskUnit) fireHook(status string, err error) {
+	if dtu.Cfg.IsTest || (dtu.FileInfo != nil && dtu.FileInfo.Isdir) {
+		return
+	}
+	hook := pcshook.FromConfig(pcsconfig.Config)
+	if !hook.Enabled(pcshook.KindDownload + "." + status) {
+		return
+	}
+
+	payload := pcshook.NewPayload(pcshook.KindDownload, status, dtu.startTime, err)
+	payload.LocalPath = dtu.SavePath
+	payload.RemotePath = dtu.PcsPath
+	if dtu.FileInfo != nil {
+		payload.Size = dtu.FileInfo.Size
+		payload.MD5 = dtu.FileInfo.MD5
+		payload.FsID = dtu.FileInfo.FsID
+	}
+	err = hook.Fire(dtu.PCS.Context(), payload)
+	if err != nil {
+		fmt.Fprintf(dtu.out(), "[%s] 执行钩子错误: %s\n", dtu.taskInfo.Id(), err)
+	}
 }
 
 func (dtu *DownloadTaskUnit) OnComplete(lastRunResult *taskframework.TaskUnitRunResult) {
@@ -434,6 +569,23 @@ func (dtu *DownloadTaskUnit) RetryWait() time.Duration {
 
 func (dtu *DownloadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 	result = &taskframework.TaskUnitRunResult{}
+	if dtu.startTime.IsZero() {
+		dtu.startTime = time.Now()
+	}
+	// 已取消 (例如 Ctrl-C), 中断的任务不再重试, 保留断点续传信息
+	ctx := dtu.PCS.Context()
+	if ctx.Err() != nil {
+		result.ResultMessage = StrDownloadCanceled
+		result.Err = ctx.Err()
+		return
+	}
+	defer func() {
+		if ctx.Err() != nil && !result.Succeed {
+			result.ResultMessage = StrDownloadCanceled
+			result.Err = ctx.Err()
+			result.NeedRetry = false
+		}
+	}()
 	// 获取文件信息
 	var err error
 	if dtu.FileInfo == nil || dtu.taskInfo.Retry() > 0 {
@@ -442,7 +594,7 @@ func (dtu *DownloadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 		// 如果该任务重试过, 则应该再获取一次文件信息
 		dtu.FileInfo, err = dtu.PCS.FilesDirectoriesMeta(dtu.PcsPath)
 		if err != nil {
-			// 如果不是未登录或文件不存在, 则不重试
+			// 未登录或文件不存在, 则不重试
 			result.ResultMessage = "获取下载路径信息错误"
 			result.Err = err
 			dtu.handleError(result)
@@ -451,8 +603,8 @@ func (dtu *DownloadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 	}
 
 	// 输出文件信息
-	fmt.Print("\n")
-	fmt.Printf("[%s] ----\n%s\n", dtu.taskInfo.Id(), dtu.FileInfo.String())
+	fmt.Fprint(dtu.out(), "\n")
+	fmt.Fprintf(dtu.out(), "[%s] ----\n%s\n", dtu.taskInfo.Id(), dtu.FileInfo.String())
 
 	// 如果是一个目录, 将子文件和子目录加入队列
 	if dtu.FileInfo.Isdir {
@@ -495,17 +647,18 @@ func (dtu *DownloadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 		return
 	}
 
-	fmt.Printf("[%s] 准备下载: %s\n", dtu.taskInfo.Id(), dtu.PcsPath)
+	fmt.Fprintf(dtu.out(), "[%s] 准备下载: %s\n", dtu.taskInfo.Id(), dtu.PcsPath)
 
 	if !dtu.Cfg.IsTest && !dtu.IsOverwrite && FileExist(dtu.SavePath) {
-		fmt.Printf("[%s] 文件已经存在: %s, 跳过...\n", dtu.taskInfo.Id(), dtu.SavePath)
+		fmt.Fprintf(dtu.out(), "[%s] 文件已经存在: %s, 跳过...\n", dtu.taskInfo.Id(), dtu.SavePath)
+		dtu.skipped = true
 		result.Succeed = true // 执行成功
 		return
 	}
 
 	if !dtu.Cfg.IsTest {
 		// 不是测试下载, 输出下载路径
-		fmt.Printf("[%s] 将会下载到路径: %s\n\n", dtu.taskInfo.Id(), dtu.SavePath)
+		fmt.Fprintf(dtu.out(), "[%s] 将会下载到路径: %s\n\n", dtu.taskInfo.Id(), dtu.SavePath)
 	}
 
 	var ok bool
@@ -530,10 +683,18 @@ func (dtu *DownloadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 		}
 		// 校验不成功, 返回结果
 		return result
-	} else {
-		if dtu.ModifyMTime {
-			os.Chtimes(dtu.SavePath, time.Unix(dtu.FileInfo.Mtime, 0), time.Unix(dtu.FileInfo.Mtime, 0))
+	}
+
+	// 校验通过, 保存到目标文件
+	if !dtu.Cfg.IsTest {
+		err = dtu.commitPartial()
+		if err != nil {
+			result.ResultMessage = "保存文件失败"
+			result.Err = err
+			result.NeedRetry = false
+			return
 		}
+		fmt.Fprintf(dtu.out(), "[%s] 下载完成, 保存位置: %s\n", dtu.taskInfo.Id(), dtu.SavePath)
 	}
 	// 统计下载
 	dtu.DownloadStatistic.AddTotalSize(dtu.FileInfo.Size)
@@ -0,0 +1,76 @@
+package pcsdownload
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readTestFile(name string) (string, bool) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func TestMigrateLegacyPartial(t *testing.T) {
+	var (
+		dir = t.TempDir()
+		now = time.Now()
+	)
+	newTaskUnit := func(name string) *DownloadTaskUnit {
+		dtu := &DownloadTaskUnit{SavePath: filepath.Join(dir, name)}
+		dtu.SetTaskInfo(&taskframework.TaskInfo{})
+		return dtu
+	}
+
+	// 旧版本未完成的下载, 迁移到临时文件
+	dtu := newTaskUnit("legacy.mp4")
+	writeTestFile(t, dtu.SavePath, "partial data", now)
+	writeTestFile(t, dtu.SavePath+DownloadSuffix, "state", now)
+	dtu.migrateLegacyPartial()
+	if _, ok := readTestFile(dtu.SavePath); ok {
+		t.Errorf("legacy: %s should be moved", dtu.SavePath)
+	}
+	if data, ok := readTestFile(dtu.partialPath()); !ok || data != "partial data" {
+		t.Errorf("legacy: partial file: got %q, %v", data, ok)
+	}
+	if data, ok := readTestFile(dtu.partialPath() + DownloadSuffix); !ok || data != "state" {
+		t.Errorf("legacy: state file: got %q, %v", data, ok)
+	}
+
+	// 已完成的文件, 没有断点续传信息, 不迁移
+	dtu = newTaskUnit("done.mp4")
+	writeTestFile(t, dtu.SavePath, "complete", now)
+	dtu.migrateLegacyPartial()
+	if data, ok := readTestFile(dtu.SavePath); !ok || data != "complete" {
+		t.Errorf("done: got %q, %v", data, ok)
+	}
+	if _, ok := readTestFile(dtu.partialPath()); ok {
+		t.Errorf("done: partial file should not exist")
+	}
+
+	// 已有临时文件, 不覆盖
+	dtu = newTaskUnit("both.mp4")
+	writeTestFile(t, dtu.SavePath, "old", now)
+	writeTestFile(t, dtu.SavePath+DownloadSuffix, "old state", now)
+	writeTestFile(t, dtu.partialPath(), "new", now)
+	dtu.migrateLegacyPartial()
+	if data, _ := readTestFile(dtu.SavePath); data != "old" {
+		t.Errorf("both: target: got %q", data)
+	}
+	if data, _ := readTestFile(dtu.partialPath()); data != "new" {
+		t.Errorf("both: partial file: got %q", data)
+	}
+
+	// 只有断点续传信息, 目标文件不存在
+	dtu = newTaskUnit("state-only.mp4")
+	writeTestFile(t, dtu.SavePath+DownloadSuffix, "state", now)
+	dtu.migrateLegacyPartial()
+	if _, ok := readTestFile(dtu.partialPath()); ok {
+		t.Errorf("state only: partial file should not exist")
+	}
+}
@@ -11,6 +11,8 @@ var (
 	ErrDownloadFileBanned = errors.New("该文件可能是违规文件, 不支持校验")
 	// ErrDlinkNotFound 未取得下载链接
 	ErrDlinkNotFound = errors.New("未取得下载链接")
+	// ErrDlinkAllBlocked 下载链接的服务器都在禁止列表中
+	ErrDlinkAllBlocked = errors.New("所有下载链接的服务器都在禁止列表中")
 	// ErrShareInfoNotFound 未在已分享列表中找到分享信息
 	ErrShareInfoNotFound = errors.New("未在已分享列表中找到分享信息")
 )
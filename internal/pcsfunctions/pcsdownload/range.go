@@ -0,0 +1,65 @@
+package pcsdownload
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/downloader"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// DownloadRange 只下载网盘文件 pcspath 中 [begin, end) 范围的数据, 不下载整个文件,
+// fileSize 为网盘文件的大小, 写入 writer 的偏移量相对于 begin
+func DownloadRange(pcs *baidupcs.BaiduPCS, pcspath string, fileSize, begin, end int64, writer io.WriterAt) error {
+	if begin >= end {
+		return nil
+	}
+
+	dlink, err := GetRangeLink(pcs, pcspath)
+	if err != nil {
+		return err
+	}
+	return DownloadLinkRange(pcs, dlink, fileSize, begin, end, writer)
+}
+
+// GetRangeLink 获取用于 Range 请求的下载链接, 同一文件的多次 DownloadLinkRange 可复用
+func GetRangeLink(pcs *baidupcs.BaiduPCS, pcspath string) (*url.URL, error) {
+	dlinks, err := GetLocateDownloadLinks(pcs, pcspath)
+	if err != nil {
+		return nil, err
+	}
+	// 跳过nb.cache这种还没有证书的
+	dlink := dlinks[0]
+	for _, u := range dlinks {
+		if !strings.HasPrefix(u.Host, "nb.cache") {
+			dlink = u
+			break
+		}
+	}
+	FixHTTPLinkURL(dlink)
+	return dlink, nil
+}
+
+// DownloadLinkRange 从下载链接 dlink 下载 [begin, end) 范围的数据, 参数同 DownloadRange
+func DownloadLinkRange(pcs *baidupcs.BaiduPCS, dlink *url.URL, fileSize, begin, end int64, writer io.WriterAt) error {
+	if begin >= end {
+		return nil
+	}
+
+	cfg := downloader.NewConfig()
+	cfg.MaxParallel = 1
+	cfg.MaxRate = pcsconfig.Config.MaxDownloadRate
+	cfg.RateLimit = pcsconfig.Config.DownloadRateLimit()
+
+	der := downloader.NewDownloader(dlink.String(), writer, cfg)
+	der.SetClient(panHTTPClient())
+	der.SetContext(pcs.Context())
+	der.SetFileContentLength(fileSize)
+	der.SetRange(begin, end)
+	der.SetStatusCodeBodyCheckFunc(func(respBody io.Reader) error {
+		return pcserror.DecodePCSJSONError(baidupcs.OperationDownloadFile, respBody)
+	})
+	return der.Execute()
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // CheckFileValid 检测文件有效性
@@ -61,3 +64,31 @@ func FixHTTPLinkURL(linkURL *url.URL) {
 		}
 	}
 }
+
+// CleanPartials 删除 dir 目录下超过 staleDuration 未修改的下载临时文件及其断点续传信息
+func CleanPartials(dir string, staleDuration time.Duration) (removed []string, err error) {
+	now := time.Now()
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			if os.IsNotExist(walkErr) {
+				return nil
+			}
+			return walkErr
+		}
+		if info.IsDir() || !strings.HasSuffix(path, PartialSuffix) {
+			return nil
+		}
+		if now.Sub(info.ModTime()) < staleDuration { // 可能仍在下载
+			return nil
+		}
+
+		err := os.Remove(path)
+		if err != nil {
+			return err
+		}
+		os.Remove(path + DownloadSuffix)
+		removed = append(removed, path)
+		return nil
+	})
+	return
+}
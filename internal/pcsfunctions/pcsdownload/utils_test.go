@@ -0,0 +1,80 @@
+package pcsdownload
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, name, content string, mtime time.Time) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(name), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(name, []byte(content), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(name, mtime, mtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanPartials(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		now   = time.Now()
+		stale = now.Add(-48 * time.Hour)
+		fresh = now.Add(-time.Hour)
+
+		stalePart  = filepath.Join(dir, "a", "old.mp4"+PartialSuffix)
+		staleState = stalePart + DownloadSuffix
+		freshPart  = filepath.Join(dir, "new.mp4"+PartialSuffix)
+		freshState = freshPart + DownloadSuffix
+		oldFile    = filepath.Join(dir, "a", "old.mp4")
+		partDir    = filepath.Join(dir, "dir"+PartialSuffix)
+	)
+	writeTestFile(t, stalePart, "part", stale)
+	writeTestFile(t, staleState, "state", stale)
+	writeTestFile(t, freshPart, "part", fresh)
+	writeTestFile(t, freshState, "state", stale)
+	writeTestFile(t, oldFile, "done", stale)
+	err := os.Mkdir(partDir, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(partDir, stale, stale)
+
+	removed, err := CleanPartials(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(removed)
+	if len(removed) != 1 || removed[0] != stalePart {
+		t.Errorf("removed: got %v, want [%s]", removed, stalePart)
+	}
+
+	for name, want := range map[string]bool{
+		stalePart:  false,
+		staleState: false,
+		freshPart:  true,
+		freshState: true, // 按临时文件的修改时间判断
+		oldFile:    true,
+		partDir:    true,
+	} {
+		_, err := os.Stat(name)
+		if exists := err == nil; exists != want {
+			t.Errorf("%s: exists %v, want %v", name, exists, want)
+		}
+	}
+
+	// 目录不存在
+	removed, err = CleanPartials(filepath.Join(dir, "not-exist"), 24*time.Hour)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("not exist: got %v, %v", removed, err)
+	}
+}
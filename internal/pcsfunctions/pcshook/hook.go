@@ -0,0 +1,276 @@
+// Package pcshook 传输任务的生命周期钩子, 上传, 下载或转存结束后执行外部命令或向 URL 发送 POST 请求
+package pcshook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout 默认的钩子超时时间
+	DefaultTimeout = pcsconfig.DefaultHookTimeout
+
+	// maxOutput 钩子执行失败时, 错误信息中保留的最大输出长度
+	maxOutput = 512
+)
+
+// 事件的类别
+const (
+	KindUpload   = "upload"
+	KindDownload = "download"
+	KindTransfer = "transfer"
+)
+
+// 事件的状态
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+var (
+	// ErrInvalidEvents 事件列表格式错误
+	ErrInvalidEvents = errors.New("钩子事件格式错误")
+	// ErrInvalidURL URL 格式错误
+	ErrInvalidURL = errors.New("钩子 URL 格式错误, 只支持本机的 http 和 https 地址")
+
+	kinds    = []string{KindUpload, KindDownload, KindTransfer}
+	statuses = []string{StatusSuccess, StatusFailed, StatusSkipped}
+
+	client = &http.Client{}
+
+	hookVerbose = pcsverbose.New("PCSHOOK")
+)
+
+type (
+	// Payload 钩子收到的 JSON 数据
+	Payload struct {
+		Event      string  `json:"event"` // 事件, 格式为 <类别>.<状态>, 例如 upload.success
+		LocalPath  string  `json:"local_path"`
+		RemotePath string  `json:"remote_path"`
+		Size       int64   `json:"size"`
+		MD5        string  `json:"md5"`
+		FsID       int64   `json:"fs_id"`
+		Duration   float64 `json:"duration"` // 耗时, 单位为秒
+		Error      string  `json:"error"`
+		Link       string  `json:"link,omitempty"` // 转存的分享链接
+		Time       int64   `json:"time"`
+	}
+
+	// Hook 钩子配置, Command 和 URL 均为空时不执行
+	Hook struct {
+		Command string        // 外部命令, 通过 shell 执行, 标准输入为 JSON 数据
+		URL     string        // 接收 POST 请求的 URL, 请求体为 JSON 数据
+		Events  []string      // 触发的事件, 为空时全部触发
+		Timeout time.Duration // 命令和请求各自的超时时间
+	}
+)
+
+// FromConfig 根据配置返回传输任务的钩子, 未设置命令和 URL 时返回 nil, 格式错误的 URL 被忽略
+func FromConfig(c *pcsconfig.PCSConfig) *Hook {
+	if c.HookCommand == "" && c.HookURL == "" {
+		return nil
+	}
+	events, err := ParseEvents(c.HookEvents)
+	if err != nil {
+		hookVerbose.Warnf("parse hook_events error: %s\n", err)
+	}
+	timeout, err := time.ParseDuration(c.HookTimeout)
+	if err != nil {
+		timeout = DefaultTimeout
+	}
+	hook := &Hook{
+		Command: c.HookCommand,
+		URL:     c.HookURL,
+		Events:  events,
+		Timeout: timeout,
+	}
+	if hook.URL != "" {
+		if err = CheckURL(hook.URL); err != nil {
+			hookVerbose.Warnf("hook_url %s: %s\n", hook.URL, err)
+			hook.URL = ""
+		}
+	}
+	return hook
+}
+
+// NewPayload 初始化事件数据
+func NewPayload(kind, status string, start time.Time, err error) *Payload {
+	p := &Payload{
+		Event: kind + "." + status,
+		Time:  time.Now().Unix(),
+	}
+	if !start.IsZero() {
+		p.Duration = time.Since(start).Seconds()
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	return p
+}
+
+// ParseEvents 解析事件列表, 用逗号隔开, 每项为类别 (例如 upload) 或 <类别>.<状态> (例如 download.failed)
+func ParseEvents(s string) (events []string, err error) {
+	for _, event := range strings.Split(s, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		kind, status, hasStatus := strings.Cut(event, ".")
+		if !contains(kinds, kind) {
+			return nil, fmt.Errorf("%w: 未知的类别 %s, 可选: %s", ErrInvalidEvents, kind, strings.Join(kinds, ", "))
+		}
+		if hasStatus && !contains(statuses, status) {
+			return nil, fmt.Errorf("%w: 未知的状态 %s, 可选: %s", ErrInvalidEvents, status, strings.Join(statuses, ", "))
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CheckURL 检查 URL 是否可用, 只允许本机地址, 避免传输信息被发送到其他主机
+func CheckURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled 是否需要触发事件 event
+func (h *Hook) Enabled(event string) bool {
+	if h == nil || (h.Command == "" && h.URL == "") {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event || strings.HasPrefix(event, e+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire 执行命令并发送请求, 超时或 ctx 取消 (例如 Ctrl-C) 后中止, 返回遇到的错误
+func (h *Hook) Fire(ctx context.Context, p *Payload) error {
+	if !h.Enabled(p.Event) {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if h.Command != "" {
+		if err = h.runCommand(ctx, p, data); err != nil {
+			errs = append(errs, fmt.Errorf("执行命令错误: %w", err))
+		}
+	}
+	if h.URL != "" {
+		if err = h.post(ctx, data); err != nil {
+			errs = append(errs, fmt.Errorf("发送请求错误: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *Hook) context(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+func (h *Hook) runCommand(parent context.Context, p *Payload, data []byte) error {
+	ctx, cancel := h.context(parent)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Command)
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"PCS_HOOK_EVENT="+p.Event,
+		"PCS_HOOK_LOCAL_PATH="+p.LocalPath,
+		"PCS_HOOK_REMOTE_PATH="+p.RemotePath,
+		"PCS_HOOK_ERROR="+p.Error,
+	)
+	// 命令的子进程未退出时, 不一直等待输出
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		if len(output) > maxOutput {
+			output = output[:maxOutput]
+		}
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%w, 输出: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+func (h *Hook) post(parent context.Context, data []byte) error {
+	if err := CheckURL(h.URL); err != nil {
+		return err
+	}
+	ctx, cancel := h.context(parent)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxOutput))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+	return nil
+}
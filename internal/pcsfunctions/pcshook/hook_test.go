@@ -0,0 +1,166 @@
+package pcshook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("upload, download.failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Hook{URL: "http://127.0.0.1", Events: events}
+	for event, want := range map[string]bool{
+		"upload.success":   true,
+		"upload.failed":    true,
+		"download.failed":  true,
+		"download.success": false,
+		"transfer.success": false,
+	} {
+		if got := h.Enabled(event); got != want {
+			t.Errorf("Enabled(%s) = %v, want %v", event, got, want)
+		}
+	}
+
+	for _, s := range []string{"copy", "upload.done"} {
+		if _, err = ParseEvents(s); !errors.Is(err, ErrInvalidEvents) {
+			t.Errorf("ParseEvents(%s) error = %v", s, err)
+		}
+	}
+}
+
+func TestFirePost(t *testing.T) {
+	received := make(chan *Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := &Payload{}
+		json.NewDecoder(r.Body).Decode(p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	h := &Hook{URL: srv.URL}
+	p := NewPayload(KindUpload, StatusSuccess, time.Now(), nil)
+	p.RemotePath = "/a.txt"
+	if err := h.Fire(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	if got.Event != "upload.success" || got.RemotePath != "/a.txt" {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+}
+
+func TestFireTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	h := &Hook{URL: srv.URL, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	if err := h.Fire(context.Background(), NewPayload(KindDownload, StatusFailed, time.Time{}, errors.New("x"))); err == nil {
+		t.Fatal("expect timeout error")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("hook not canceled after timeout")
+	}
+}
+
+func TestFireCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	out := filepath.Join(t.TempDir(), "payload.json")
+	h := &Hook{Command: "cat > " + out}
+	p := NewPayload(KindTransfer, StatusSuccess, time.Now(), nil)
+	p.Link = "https://pan.baidu.com/s/1abc"
+	if err := h.Fire(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Payload{}
+	if err = json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != "transfer.success" || got.Link != p.Link {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+
+	h.Command = "echo oops; exit 3"
+	if err = h.Fire(context.Background(), p); err == nil {
+		t.Fatal("expect command error")
+	}
+}
+
+func TestFireCanceled(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	h := &Hook{URL: srv.URL}
+	start := time.Now()
+	if err := h.Fire(ctx, NewPayload(KindUpload, StatusFailed, time.Time{}, nil)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("hook not canceled with context")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"http://127.0.0.1:8080/notify": true,
+		"https://localhost/notify":     true,
+		"http://[::1]:8080":            true,
+		"http://192.168.1.2/notify":    false,
+		"https://example.com/notify":   false,
+		"ftp://127.0.0.1/":             false,
+		"127.0.0.1:8080":               false,
+	} {
+		if err := CheckURL(s); (err == nil) != ok {
+			t.Errorf("CheckURL(%s) = %v", s, err)
+		}
+	}
+
+	h := &Hook{URL: "https://example.com/notify"}
+	if err := h.Fire(context.Background(), NewPayload(KindUpload, StatusSuccess, time.Time{}, nil)); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("post to remote host, err = %v", err)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	c := &pcsconfig.PCSConfig{}
+	if FromConfig(c) != nil {
+		t.Fatal("hook without command and url")
+	}
+	c.SetHookURL("https://example.com/notify")
+	c.SetHookCommand("true")
+	c.SetHookEvents("upload.failed")
+	h := FromConfig(c)
+	if h == nil || h.URL != "" || h.Command != "true" || h.Timeout != DefaultTimeout {
+		t.Fatalf("unexpected hook: %+v", h)
+	}
+	if h.Enabled("upload.success") || !h.Enabled("upload.failed") {
+		t.Errorf("unexpected events: %v", h.Events)
+	}
+}
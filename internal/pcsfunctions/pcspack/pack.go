@@ -0,0 +1,293 @@
+// Package pcspack 将大量小文件打包成 tar 分段上传, 并生成记录每个成员位置的索引,
+// 下载时根据索引用 Range 请求只获取单个成员
+package pcspack
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"github.com/json-iterator/go"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// IndexVersion 索引格式版本
+	IndexVersion = 1
+	// IndexSuffix 索引文件名后缀
+	IndexSuffix = ".index.json"
+	// SegmentSuffix 分段文件名后缀
+	SegmentSuffix = ".tar"
+	// DefaultThreshold 默认打包的文件大小上限, 小于此大小的文件才打包
+	DefaultThreshold = 1 << 20
+	// DefaultSegmentSize 默认的分段大小
+	DefaultSegmentSize = 256 << 20
+
+	// tar 文件头和结尾的块大小
+	tarBlockSize = 512
+)
+
+type (
+	// Index 打包索引, 记录每个分段和每个成员在分段中的位置
+	Index struct {
+		Version  int        `json:"version"`
+		Created  int64      `json:"created"`
+		Segments []*Segment `json:"segments"`
+		Members  []*Member  `json:"members"`
+	}
+
+	// Segment 分段信息, Name 为与索引文件在同一目录下的文件名
+	Segment struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+
+	// Member 成员信息, Offset 为成员数据 (不含 tar 文件头) 在分段中的偏移量
+	Member struct {
+		Name    string `json:"name"`
+		Segment int    `json:"segment"`
+		Offset  int64  `json:"offset"`
+		Size    int64  `json:"size"`
+		Mtime   int64  `json:"mtime"`
+		Mode    uint32 `json:"mode"`
+	}
+
+	// SegmentWriter 分段的输出, Close 时应等待分段写入完成,
+	// Abort 放弃分段, 不能留下不完整的分段
+	SegmentWriter interface {
+		io.WriteCloser
+		Abort(err error) error
+	}
+
+	// OpenSegmentFunc 打开第 seg 个分段的输出
+	OpenSegmentFunc func(seg int, name string) (SegmentWriter, error)
+
+	// Packer 将文件按顺序写入 tar 分段, 分段大小超过上限时切换到下一个分段
+	Packer struct {
+		name        string
+		segmentSize int64
+		open        OpenSegmentFunc
+		index       *Index
+
+		wc SegmentWriter
+		cw *countWriter
+		tw *tar.Writer
+	}
+
+	countWriter struct {
+		io.Writer
+		n int64
+	}
+)
+
+var (
+	// ErrMemberNotFound 索引中不存在该成员
+	ErrMemberNotFound = errors.New("索引中不存在该成员")
+	// ErrIndexVersion 不支持的索引版本
+	ErrIndexVersion = errors.New("不支持的索引版本")
+	// ErrSegmentAborted 分段已放弃
+	ErrSegmentAborted = errors.New("分段已放弃")
+)
+
+// IndexName 返回打包名称对应的索引文件名
+func IndexName(name string) string {
+	return name + IndexSuffix
+}
+
+// SegmentName 返回打包名称对应的第 seg 个分段的文件名
+func SegmentName(name string, seg int) string {
+	return fmt.Sprintf("%s.%03d%s", name, seg, SegmentSuffix)
+}
+
+// IsIndexName 判断文件名是否为索引文件
+func IsIndexName(name string) bool {
+	return strings.HasSuffix(name, IndexSuffix)
+}
+
+// IsLocalName 判断成员名称是否为不超出解包目录的相对路径
+func IsLocalName(name string) bool {
+	return filepath.IsLocal(filepath.FromSlash(name))
+}
+
+// ParseIndex 解析索引, 成员名称不是相对路径或位置超出分段时返回错误
+func ParseIndex(data []byte) (*Index, error) {
+	idx := &Index{}
+	err := jsoniter.Unmarshal(data, idx)
+	if err != nil {
+		return nil, err
+	}
+	if idx.Version != IndexVersion {
+		return nil, ErrIndexVersion
+	}
+	for _, m := range idx.Members {
+		if !IsLocalName(m.Name) {
+			return nil, fmt.Errorf("成员名称 %s 不合法", m.Name)
+		}
+		if m.Segment < 0 || m.Segment >= len(idx.Segments) {
+			return nil, fmt.Errorf("成员 %s 的分段序号 %d 不合法", m.Name, m.Segment)
+		}
+		if m.Offset < 0 || m.Size < 0 || m.Offset+m.Size > idx.Segments[m.Segment].Size {
+			return nil, fmt.Errorf("成员 %s 的位置超出分段范围", m.Name)
+		}
+	}
+	return idx, nil
+}
+
+// Marshal 序列化索引
+func (idx *Index) Marshal() ([]byte, error) {
+	return jsoniter.MarshalIndent(idx, "", " ")
+}
+
+// Lookup 查找成员, 名称不合法的成员不会被找到
+func (idx *Index) Lookup(name string) (*Member, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	for _, m := range idx.Members {
+		if m.Name == name && IsLocalName(m.Name) {
+			return m, nil
+		}
+	}
+	return nil, ErrMemberNotFound
+}
+
+// SegmentOf 返回成员所在的分段
+func (idx *Index) SegmentOf(m *Member) *Segment {
+	return idx.Segments[m.Segment]
+}
+
+// NewPacker 初始化 Packer, name 为打包名称, 用于生成分段文件名
+func NewPacker(name string, segmentSize int64, open OpenSegmentFunc) *Packer {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	return &Packer{
+		name:        name,
+		segmentSize: segmentSize,
+		open:        open,
+		index: &Index{
+			Version: IndexVersion,
+			Created: time.Now().Unix(),
+		},
+	}
+}
+
+// Add 将文件写入当前分段, name 为成员在索引中的名称, 从 r 中读取 fi.Size() 字节
+func (p *Packer) Add(name string, fi os.FileInfo, r io.Reader) error {
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	// 当前分段放不下 (文件头, 数据和结尾) 时切换分段, 单个成员大于分段大小时独占一个分段
+	if p.tw != nil && p.cw.n > 0 && roundBlock(p.cw.n)+roundBlock(fi.Size())+3*tarBlockSize > p.segmentSize {
+		err = p.closeSegment()
+		if err != nil {
+			return err
+		}
+	}
+	if p.tw == nil {
+		err = p.openSegment()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+	member := &Member{
+		Name:    hdr.Name,
+		Segment: len(p.index.Segments) - 1,
+		Offset:  p.cw.n,
+		Size:    fi.Size(),
+		Mtime:   fi.ModTime().Unix(),
+		Mode:    uint32(fi.Mode().Perm()),
+	}
+	_, err = io.CopyN(p.tw, r, fi.Size())
+	if err != nil {
+		return fmt.Errorf("写入成员 %s 失败, %s", hdr.Name, err)
+	}
+	p.index.Members = append(p.index.Members, member)
+	return nil
+}
+
+// Close 结束当前分段
+func (p *Packer) Close() error {
+	if p.tw == nil {
+		return nil
+	}
+	return p.closeSegment()
+}
+
+// Abort 放弃当前分段, 用于写入成员失败之后, 当前分段中的成员从索引中移除.
+// 之前已结束的分段不受影响
+func (p *Packer) Abort(err error) error {
+	if p.tw == nil {
+		return nil
+	}
+	if err == nil {
+		err = ErrSegmentAborted
+	}
+	aerr := p.wc.Abort(err)
+	seg := len(p.index.Segments) - 1
+	p.index.Segments = p.index.Segments[:seg]
+	members := p.index.Members[:0]
+	for _, m := range p.index.Members {
+		if m.Segment != seg {
+			members = append(members, m)
+		}
+	}
+	p.index.Members = members
+	p.tw, p.cw, p.wc = nil, nil, nil
+	return aerr
+}
+
+// Index 返回索引, 应在 Close 之后调用
+func (p *Packer) Index() *Index {
+	return p.index
+}
+
+func (p *Packer) openSegment() error {
+	seg := len(p.index.Segments)
+	segment := &Segment{
+		Name: SegmentName(p.name, seg),
+	}
+	wc, err := p.open(seg, segment.Name)
+	if err != nil {
+		return err
+	}
+	p.index.Segments = append(p.index.Segments, segment)
+	p.wc = wc
+	p.cw = &countWriter{Writer: wc}
+	p.tw = tar.NewWriter(p.cw)
+	return nil
+}
+
+func (p *Packer) closeSegment() error {
+	err := p.tw.Close()
+	if err == nil {
+		p.index.Segments[len(p.index.Segments)-1].Size = p.cw.n
+	}
+	cerr := p.wc.Close()
+	p.tw, p.cw, p.wc = nil, nil, nil
+	if err != nil {
+		return err
+	}
+	return cerr
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.Writer.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+// roundBlock 按 tar 块大小补齐
+func roundBlock(size int64) int64 {
+	return (size + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+}
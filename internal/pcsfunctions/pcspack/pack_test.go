@@ -0,0 +1,182 @@
+package pcspack_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcspack"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memSegment struct {
+	bytes.Buffer
+	closed  bool
+	aborted error
+}
+
+func (ms *memSegment) Close() error {
+	ms.closed = true
+	return nil
+}
+
+func (ms *memSegment) Abort(err error) error {
+	ms.aborted = err
+	return nil
+}
+
+func TestPacker(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string][]byte{
+		"a.txt":     []byte("hello"),
+		"sub/b.txt": bytes.Repeat([]byte("b"), 1500),
+		"sub/c.txt": {},
+		"d.txt":     bytes.Repeat([]byte("d"), 700),
+	}
+	names := []string{"a.txt", "sub/b.txt", "sub/c.txt", "d.txt"}
+
+	var segments []*memSegment
+	packer := pcspack.NewPacker("pack", 5120, func(seg int, name string) (pcspack.SegmentWriter, error) {
+		if name != pcspack.SegmentName("pack", seg) {
+			t.Errorf("segment name = %s", name)
+		}
+		ms := &memSegment{}
+		segments = append(segments, ms)
+		return ms, nil
+	})
+	for _, name := range names {
+		localPath := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(localPath), 0777)
+		if err := os.WriteFile(localPath, contents[name], 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(localPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, _ := f.Stat()
+		err = packer.Add(name, fi, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := packer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := packer.Index().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := pcspack.ParseIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Segments) != 2 || len(segments) != 2 {
+		t.Fatalf("segments = %d, want 2", len(idx.Segments))
+	}
+
+	for i, ms := range segments {
+		if !ms.closed || int64(ms.Len()) != idx.Segments[i].Size {
+			t.Errorf("segment %d size = %d, index size = %d", i, ms.Len(), idx.Segments[i].Size)
+		}
+		// 分段是合法的 tar 文件
+		tr := tar.NewReader(bytes.NewReader(ms.Bytes()))
+		for {
+			_, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("segment %d: %s", i, err)
+			}
+		}
+	}
+
+	for _, name := range names {
+		m, err := idx.Lookup("/" + name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		seg := segments[m.Segment].Bytes()
+		if got := seg[m.Offset : m.Offset+m.Size]; !bytes.Equal(got, contents[name]) {
+			t.Errorf("member %s content mismatch", name)
+		}
+	}
+	if _, err := idx.Lookup("missing"); err != pcspack.ErrMemberNotFound {
+		t.Errorf("lookup missing member, err = %v", err)
+	}
+}
+
+func TestParseIndexInvalid(t *testing.T) {
+	_, err := pcspack.ParseIndex([]byte(`{"version":1,"segments":[],"members":[{"name":"a","segment":0}]}`))
+	if err == nil || !strings.Contains(err.Error(), "a") {
+		t.Errorf("invalid segment should fail, err = %v", err)
+	}
+	if _, err := pcspack.ParseIndex([]byte(`{"version":2}`)); err != pcspack.ErrIndexVersion {
+		t.Errorf("err = %v, want ErrIndexVersion", err)
+	}
+	for _, name := range []string{"../evil", "a/../../evil", "/etc/passwd", ""} {
+		data := `{"version":1,"segments":[{"name":"s","size":10}],"members":[{"name":"` + name + `","segment":0}]}`
+		if _, err := pcspack.ParseIndex([]byte(data)); err == nil {
+			t.Errorf("member name %q should fail", name)
+		}
+	}
+	_, err = pcspack.ParseIndex([]byte(`{"version":1,"segments":[{"name":"s","size":10}],"members":[{"name":"a","segment":0,"offset":8,"size":1048576}]}`))
+	if err == nil {
+		t.Error("member out of segment should fail")
+	}
+}
+
+func TestPackerAbort(t *testing.T) {
+	var segments []*memSegment
+	packer := pcspack.NewPacker("pack", 4096, func(seg int, name string) (pcspack.SegmentWriter, error) {
+		ms := &memSegment{}
+		segments = append(segments, ms)
+		return ms, nil
+	})
+	fi := func(name string, size int64) os.FileInfo {
+		return memFileInfo{name, size}
+	}
+	if err := packer.Add("a", fi("a", 1000), bytes.NewReader(make([]byte, 1000))); err != nil {
+		t.Fatal(err)
+	}
+	if err := packer.Add("b", fi("b", 200), bytes.NewReader(make([]byte, 200))); err != nil {
+		t.Fatal(err)
+	}
+	// 数据比文件信息中的短, 成员写入一半
+	if err := packer.Add("c", fi("c", 1000), bytes.NewReader(make([]byte, 10))); err == nil {
+		t.Fatal("short member should fail")
+	}
+	packer.Abort(nil)
+
+	if len(segments) != 2 || !segments[0].closed || segments[0].aborted != nil {
+		t.Fatalf("first segment should be closed normally")
+	}
+	if segments[1].closed || segments[1].aborted != pcspack.ErrSegmentAborted {
+		t.Fatalf("second segment should be aborted, got %v", segments[1].aborted)
+	}
+	idx := packer.Index()
+	if len(idx.Segments) != 1 || len(idx.Members) != 2 || idx.Members[1].Name != "b" {
+		t.Fatalf("index after abort: %d segments, %d members", len(idx.Segments), len(idx.Members))
+	}
+	if err := packer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi memFileInfo) ModTime() time.Time { return time.Unix(0, 0) }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() interface{}   { return nil }
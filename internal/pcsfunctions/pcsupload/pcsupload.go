@@ -6,7 +6,10 @@ import (
 )
 
 const (
+	// UploadingFileName 旧版的未完成上传数据库, 打开数据库时迁移到日志中
 	UploadingFileName = "pcs_uploading.json"
+	// UploadingJournalFileName 未完成上传数据库的日志
+	UploadingJournalFileName = "pcs_uploading.journal"
 )
 
 var (
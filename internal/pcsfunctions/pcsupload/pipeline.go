@@ -0,0 +1,333 @@
+package pcsupload
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"hash"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+const (
+	// PipelineMinSize 流水线模式下, 不小于此大小的文件在上传分片的同时计算 md5,
+	// 小于此大小的文件由 ChecksumPool 提前计算
+	PipelineMinSize = 128 * converter.MB
+	// MaxChecksumWorkers ChecksumPool 的最大并发数
+	MaxChecksumWorkers = 4
+)
+
+type (
+	// ChecksumPool 在后台计算文件秒传信息的工作池, 使小文件的校验与其他文件的上传同时进行.
+	// 同时记录已计算的 md5, 大小和前 256KB 相同的大文件可以先尝试秒传
+	ChecksumPool struct {
+		mu     sync.Mutex
+		cond   *sync.Cond
+		queue  []*ChecksumJob
+		closed bool
+		known  map[string][]byte // 已计算的 md5, 键为大小和 slice md5
+	}
+
+	// ChecksumJob 计算单个文件秒传信息的任务
+	ChecksumJob struct {
+		pool *ChecksumPool
+		path string
+		once sync.Once
+		meta *checksum.LocalFileMeta
+		err  error
+	}
+
+	// headHash 只计算写入数据中前 left 字节的 hash, 用于计算 slice md5
+	headHash struct {
+		hash.Hash
+		left int64
+	}
+)
+
+// NewChecksumPool 初始化并启动工作池, 并发数为 CPU 数, 最大为 MaxChecksumWorkers
+func NewChecksumPool() *ChecksumPool {
+	cp := &ChecksumPool{
+		known: map[string][]byte{},
+	}
+	cp.cond = sync.NewCond(&cp.mu)
+	workers := runtime.NumCPU()
+	if workers > MaxChecksumWorkers {
+		workers = MaxChecksumWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go cp.work()
+	}
+	return cp
+}
+
+func (cp *ChecksumPool) work() {
+	for {
+		cp.mu.Lock()
+		for len(cp.queue) == 0 && !cp.closed {
+			cp.cond.Wait()
+		}
+		if cp.closed {
+			cp.mu.Unlock()
+			return
+		}
+		job := cp.queue[0]
+		cp.queue = cp.queue[1:]
+		cp.mu.Unlock()
+
+		job.run()
+	}
+}
+
+// Submit 按提交顺序在后台计算文件的秒传信息
+func (cp *ChecksumPool) Submit(localPath string) *ChecksumJob {
+	job := &ChecksumJob{
+		pool: cp,
+		path: localPath,
+	}
+	cp.mu.Lock()
+	cp.queue = append(cp.queue, job)
+	cp.mu.Unlock()
+	cp.cond.Signal()
+	return job
+}
+
+// Close 停止工作池, 未开始的任务在 Wait 时计算
+func (cp *ChecksumPool) Close() {
+	cp.mu.Lock()
+	cp.closed = true
+	cp.queue = nil
+	cp.mu.Unlock()
+	cp.cond.Broadcast()
+}
+
+func knownKey(length int64, sliceMD5 []byte) string {
+	return strconv.FormatInt(length, 10) + "-" + hex.EncodeToString(sliceMD5)
+}
+
+// Remember 记录文件的 md5, cp 为 nil 时忽略
+func (cp *ChecksumPool) Remember(meta *checksum.LocalFileMeta) {
+	if cp == nil || meta == nil || len(meta.MD5) == 0 || len(meta.SliceMD5) == 0 {
+		return
+	}
+	cp.mu.Lock()
+	cp.known[knownKey(meta.Length, meta.SliceMD5)] = meta.MD5
+	cp.mu.Unlock()
+}
+
+// LookupMD5 返回已记录的大小和 slice md5 相同的文件的 md5, 没有记录时返回 nil
+func (cp *ChecksumPool) LookupMD5(length int64, sliceMD5 []byte) []byte {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.known[knownKey(length, sliceMD5)]
+}
+
+func (job *ChecksumJob) run() {
+	job.once.Do(func() {
+		lfc := checksum.NewLocalFileChecksum(job.path, int(baidupcs.SliceMD5Size))
+		job.err = lfc.OpenPath()
+		if job.err != nil {
+			return
+		}
+		defer lfc.Close()
+		// 大文件和空文件不需要提前计算
+		if lfc.Length == 0 || lfc.Length >= PipelineMinSize {
+			return
+		}
+		job.err = lfc.Sum(checksum.CHECKSUM_MD5 | checksum.CHECKSUM_SLICE_MD5)
+		if job.err == nil {
+			job.meta = &lfc.LocalFileMeta
+			job.pool.Remember(job.meta)
+		}
+	})
+}
+
+// Wait 等待计算完成, 任务还未开始时直接在当前协程计算, 不需要计算的文件返回 nil
+func (job *ChecksumJob) Wait() (*checksum.LocalFileMeta, error) {
+	if job == nil {
+		return nil, nil
+	}
+	job.run()
+	return job.meta, job.err
+}
+
+// Pool 返回任务所属的工作池, job 为 nil 时返回 nil
+func (job *ChecksumJob) Pool() *ChecksumPool {
+	if job == nil {
+		return nil
+	}
+	return job.pool
+}
+
+func (h *headHash) Write(p []byte) (int, error) {
+	n := len(p)
+	if int64(len(p)) > h.left {
+		p = p[:h.left]
+	}
+	h.Hash.Write(p)
+	h.left -= int64(len(p))
+	return n, nil
+}
+
+// sumRapidUploadInfo 计算文件的 md5 和 slice md5, 已由 ChecksumPool 计算且文件未改变时直接使用其结果
+func (utu *UploadTaskUnit) sumRapidUploadInfo() error {
+	meta, err := utu.Checksum.Wait()
+	lfc := utu.LocalFileChecksum
+	if err == nil && meta != nil && meta.Length == lfc.Length && meta.ModTime == lfc.ModTime {
+		lfc.MD5, lfc.SliceMD5 = meta.MD5, meta.SliceMD5
+		return nil
+	}
+	err = lfc.Sum(checksum.CHECKSUM_MD5 | checksum.CHECKSUM_SLICE_MD5)
+	if err == nil {
+		utu.Checksum.Pool().Remember(&lfc.LocalFileMeta)
+	}
+	return err
+}
+
+// sumSliceMD5 计算文件前 256KB 的 md5
+func sumSliceMD5(file io.ReaderAt, length int64) ([]byte, error) {
+	h := &headHash{
+		Hash: md5.New(),
+		left: baidupcs.SliceMD5Size,
+	}
+	_, err := io.Copy(h, io.NewSectionReader(file, 0, min(length, baidupcs.SliceMD5Size)))
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// tryRapidUpload 使用已计算的秒传信息秒传, 返回是否成功
+func (utu *UploadTaskUnit) tryRapidUpload() bool {
+	uk, pcsError := utu.PCS.UK()
+	if pcsError != nil {
+		return false
+	}
+	pcsError, _, err := utu.requestRapidUpload(uk)
+	if err != nil || pcsError != nil {
+		rapidUploadResults.Inc("miss")
+		return false
+	}
+	rapidUploadResults.Inc("hit")
+	return true
+}
+
+// saveStreamState 保存流水线上传的断点续传信息, 之后由 upload 继续上传, 没有已上传的分片时不保存
+func (utu *UploadTaskUnit) saveStreamState(su *uploader.StreamUploader) {
+	state := su.InstanceState()
+	if state == nil {
+		return
+	}
+	for _, blockState := range state.BlockList {
+		if blockState.CheckSum != "" {
+			utu.UploadingDatabase.UpdateUploading(&utu.LocalFileChecksum.LocalFileMeta, state)
+			utu.UploadingDatabase.Save()
+			return
+		}
+	}
+}
+
+// pipelineUpload 流水线上传, 只读取一次文件: 按顺序读取分片, 在上传分片的同时计算整个文件的 md5,
+// 所有分片上传完成后尝试秒传, 秒传失败时合并已上传的分片.
+// 本次上传中已计算过大小和前 256KB 相同的文件时, 先使用其 md5 尝试秒传, 未命中再上传
+func (utu *UploadTaskUnit) pipelineUpload() (result *taskframework.TaskUnitRunResult) {
+	utu.Step = StepUploadPipeline
+	result = &taskframework.TaskUnitRunResult{}
+
+	lfc := utu.LocalFileChecksum
+	sliceMD5, err := sumSliceMD5(lfc.GetFile(), lfc.Length)
+	if err != nil {
+		result.ResultMessage = "计算文件秒传信息错误"
+		result.Err = err
+		return
+	}
+	if knownMD5 := utu.Checksum.Pool().LookupMD5(lfc.Length, sliceMD5); knownMD5 != nil {
+		lfc.MD5, lfc.SliceMD5 = knownMD5, sliceMD5
+		fmt.Fprintf(utu.out(), "[%s] 检测秒传中, 请稍候...\n", utu.taskInfo.Id())
+		if utu.tryRapidUpload() {
+			fmt.Fprintf(utu.out(), "[%s] 秒传成功, 保存到网盘路径: %s\n\n", utu.taskInfo.Id(), utu.SavePath)
+			utu.UploadStatistic.AddTotalSize(lfc.Length)
+			result.Succeed = true
+			return
+		}
+		// 已知 md5, 无需流水线
+		fmt.Fprintf(utu.out(), "[%s] 秒传失败, 开始上传文件...\n\n", utu.taskInfo.Id())
+		return utu.upload()
+	}
+
+	sliceHash := &headHash{
+		Hash: md5.New(),
+		left: baidupcs.SliceMD5Size,
+	}
+	reader := io.TeeReader(io.NewSectionReader(lfc.GetFile(), 0, lfc.Length), sliceHash)
+	su := uploader.NewStreamUploader(NewPCSUpload(utu.PCS, utu.SavePath, utu.fileTime()), reader, &uploader.MultiUploaderConfig{
+		Parallel:  utu.Parallel,
+		BlockSize: getBlockSize(lfc.Length),
+		MaxRate:   pcsconfig.Config.MaxUploadRate,
+		RateLimit: pcsconfig.Config.UploadRateLimit(),
+		Policy:    utu.Policy,
+	})
+	su.SetFileSize(lfc.Length)
+	su.OnBeforeCreateSuperFile(func() (done bool, err error) {
+		lfc.MD5 = su.MD5()
+		lfc.SliceMD5 = sliceHash.Sum(nil)
+		utu.Checksum.Pool().Remember(&lfc.LocalFileMeta)
+		if !utu.tryRapidUpload() {
+			return false, nil
+		}
+		fmt.Fprintf(utu.out(), "\n[%s] 秒传成功, 无需合并分片\n", utu.taskInfo.Id())
+		return true, nil
+	})
+
+	// 取消 (例如 Ctrl-C) 后中断上传
+	stopCancel := context.AfterFunc(utu.PCS.Context(), su.Cancel)
+	defer stopCancel()
+	removeControl := utu.Control.Add(su)
+	defer removeControl()
+	su.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
+		select {
+		case <-updateChan:
+			utu.saveStreamState(su)
+		default:
+		}
+
+		fmt.Fprintf(utu.out(), utu.PrintFormat, utu.taskInfo.Id(),
+			converter.ConvertFileSize(status.Uploaded(), 2),
+			converter.ConvertFileSize(status.TotalSize(), 2),
+			converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
+			status.TimeElapsed(),
+		)
+	})
+
+	fmt.Fprintf(utu.out(), "[%s] 流水线上传, 上传的同时计算秒传信息...\n", utu.taskInfo.Id())
+	err = su.Execute()
+	fmt.Fprintf(utu.out(), "\n")
+	switch {
+	case err == nil:
+		fmt.Fprintf(utu.out(), "[%s] 上传文件成功, 保存到网盘路径: %s\n", utu.taskInfo.Id(), utu.SavePath)
+		utu.UploadStatistic.AddTotalSize(lfc.Length)
+		utu.UploadingDatabase.Delete(&lfc.LocalFileMeta)
+		utu.UploadingDatabase.Save()
+		result.Succeed = true
+	case err == context.Canceled:
+		result.ResultMessage = StrUploadCanceled
+		result.Err = err
+		// 保存断点续传信息
+		utu.saveStreamState(su)
+	default:
+		utu.saveStreamState(su)
+		utu.setFailedResult(result, err)
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package pcsupload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksumPool(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(small, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cp := NewChecksumPool()
+	smallJob, emptyJob, missingJob := cp.Submit(small), cp.Submit(empty), cp.Submit(filepath.Join(dir, "missing"))
+
+	sum := md5.Sum([]byte("hello"))
+	meta, err := smallJob.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.Length != 5 || !bytes.Equal(meta.MD5, sum[:]) || !bytes.Equal(meta.SliceMD5, sum[:]) {
+		t.Fatalf("small: %+v", meta)
+	}
+	if got := cp.LookupMD5(5, sum[:]); !bytes.Equal(got, sum[:]) {
+		t.Fatalf("LookupMD5: %x", got)
+	}
+	if got := cp.LookupMD5(6, sum[:]); got != nil {
+		t.Fatalf("LookupMD5 with other length: %x", got)
+	}
+
+	meta, err = emptyJob.Wait()
+	if meta != nil || err != nil {
+		t.Fatalf("empty: %+v, %s", meta, err)
+	}
+	if _, err = missingJob.Wait(); err == nil {
+		t.Fatal("missing: expected error")
+	}
+
+	// 关闭后提交的任务在 Wait 时计算
+	cp.Close()
+	meta, err = cp.Submit(small).Wait()
+	if err != nil || meta == nil || !bytes.Equal(meta.MD5, sum[:]) {
+		t.Fatalf("after close: %+v, %s", meta, err)
+	}
+
+	var job *ChecksumJob
+	if meta, err = job.Wait(); meta != nil || err != nil {
+		t.Fatal("nil job")
+	}
+	job.Pool().Remember(meta)
+	if job.Pool().LookupMD5(5, sum[:]) != nil {
+		t.Fatal("nil pool")
+	}
+}
+
+func TestHeadHash(t *testing.T) {
+	data := make([]byte, baidupcs.SliceMD5Size+1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	want := md5.Sum(data[:baidupcs.SliceMD5Size])
+
+	h := &headHash{
+		Hash: md5.New(),
+		left: baidupcs.SliceMD5Size,
+	}
+	for i := 0; i < len(data); i += 100000 {
+		p := data[i:min(i+100000, len(data))]
+		n, err := h.Write(p)
+		if err != nil || n != len(p) {
+			t.Fatalf("Write: %d, %s", n, err)
+		}
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Fatalf("headHash: %x, want %x", got, want)
+	}
+
+	got, err := sumSliceMD5(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !bytes.Equal(got, want[:]) {
+		t.Fatalf("sumSliceMD5: %x, %s", got, err)
+	}
+	small := md5.Sum(data[:10])
+	got, err = sumSliceMD5(bytes.NewReader(data), 10)
+	if err != nil || !bytes.Equal(got, small[:]) {
+		t.Fatalf("sumSliceMD5 small: %x, %s", got, err)
+	}
+}
@@ -18,6 +18,7 @@ type (
 	PCSUpload struct {
 		pcs        *baidupcs.BaiduPCS
 		targetPath string
+		fileTime   *baidupcs.LocalFileTime // 本地文件时间, 为 nil 时不设置
 	}
 
 	EmptyReaderLen64 struct {
@@ -34,10 +35,11 @@ func (e EmptyReaderLen64) Len() int64 {
 	return 0
 }
 
-func NewPCSUpload(pcs *baidupcs.BaiduPCS, targetPath string) uploader.MultiUpload {
+func NewPCSUpload(pcs *baidupcs.BaiduPCS, targetPath string, fileTime *baidupcs.LocalFileTime) uploader.MultiUpload {
 	return &PCSUpload{
 		pcs:        pcs,
 		targetPath: targetPath,
+		fileTime:   fileTime,
 	}
 }
 
@@ -99,12 +101,16 @@ func (pu *PCSUpload) TmpFile(ctx context.Context, partseq int, partOffset int64,
 	return checksum, pcsError
 }
 
-func (pu *PCSUpload) CreateSuperFile(policy string, checksumList ...string) (err error) {
+// UploadEmpty 上传空文件
+func (pu *PCSUpload) UploadEmpty(policy string) pcserror.Error {
 	pu.lazyInit()
-	//newpath := ""
-	// 先在网盘目标位置, 上传一个空文件
-	// 防止出现file does not exist
-	pcsError, newpath := pu.pcs.Upload(policy, pu.targetPath, func(uploadURL string, jar http.CookieJar) (resp *http.Response, err error) {
+	pcsError, _ := pu.uploadEmpty(policy)
+	return pcsError
+}
+
+// uploadEmpty 在网盘目标位置上传一个空文件, 返回实际的保存路径
+func (pu *PCSUpload) uploadEmpty(policy string) (pcserror.Error, string) {
+	return pu.pcs.Upload(policy, pu.targetPath, pu.fileTime, func(uploadURL string, jar http.CookieJar) (resp *http.Response, err error) {
 		mr := multipartreader.NewMultipartReader()
 		mr.AddFormFile("file", "file", &EmptyReaderLen64{})
 		mr.CloseMultipart()
@@ -113,6 +119,14 @@ func (pu *PCSUpload) CreateSuperFile(policy string, checksumList ...string) (err
 		c.SetCookiejar(jar)
 		return c.Req(http.MethodPost, uploadURL, mr, nil)
 	})
+}
+
+func (pu *PCSUpload) CreateSuperFile(policy string, checksumList ...string) (err error) {
+	pu.lazyInit()
+	//newpath := ""
+	// 先在网盘目标位置, 上传一个空文件
+	// 防止出现file does not exist
+	pcsError, newpath := pu.uploadEmpty(policy)
 	if pcsError != nil {
 		// 修改操作
 		pcsError.(*pcserror.PCSErrInfo).Operation = baidupcs.OperationUploadCreateSuperFile
@@ -121,5 +135,5 @@ func (pu *PCSUpload) CreateSuperFile(policy string, checksumList ...string) (err
 
 	// 此时已到了最后的合并环节，policy只能使用overwrite, newpath而不用pu.targetPath是因为newcopy策略可能导致文件名变化
 	//return pu.pcs.UploadCreateSuperFile("overwrite",false, pu.targetPath, checksumList...)
-	return pu.pcs.UploadCreateSuperFile("overwrite",false, newpath, checksumList...)
+	return pu.pcs.UploadCreateSuperFile("overwrite",false, newpath, pu.fileTime, checksumList...)
 }
@@ -1,124 +1,345 @@
 package pcsupload
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
-	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/jsonhelper"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	// UploadingMaxAge 断点续传信息的有效期, 服务器上已上传的分片会过期, 超过有效期的上传重新开始
+	UploadingMaxAge = 24 * time.Hour
+
+	// 日志中的记录数超过 当前条目数*compactFactor+compactMinRecords 时压缩日志
+	compactFactor     = 4
+	compactMinRecords = 64
+
+	journalOpPut    = "put"
+	journalOpDelete = "del"
+)
+
 type (
 	// Uploading 未完成上传的信息
 	Uploading struct {
 		*checksum.LocalFileMeta
-		State *uploader.InstanceState `json:"state"`
+		State   *uploader.InstanceState `json:"state"`
+		Created int64                   `json:"created"` // 第一次保存断点续传信息的时间, 用于判断是否过期
 	}
 
-	// UploadingDatabase 未完成上传的数据库
+	// UploadingDatabase 未完成上传的数据库, 以追加写入的日志保存, 定期压缩
 	UploadingDatabase struct {
-		lock sync.RWMutex
+		lock          sync.RWMutex
 		UploadingList []*Uploading `json:"upload_state"`
 		Timestamp     int64        `json:"timestamp"`
 
 		dataFile *os.File
+		path     string
+		pending  [][]byte // 还未写入日志的记录
+		records  int      // 日志中的记录数
+		refs     int      // 共享的引用数, 由 sharedDatabasesMu 保护
+	}
+
+	// journalRecord 日志中的一条记录, 每行一条
+	journalRecord struct {
+		Op        string     `json:"op"`
+		Uploading *Uploading `json:"uploading"`
 	}
 )
 
-// NewUploadingDatabase 初始化未完成上传的数据库, 从库中读取内容
+var (
+	sharedDatabasesMu sync.Mutex
+	sharedDatabases   = map[string]*UploadingDatabase{}
+)
+
+// NewUploadingDatabase 初始化未完成上传的数据库, 从日志中读取内容, 旧版的数据库文件会被迁移.
+// 同一进程中 (例如多个后台任务) 共享同一个数据库, 避免压缩日志时丢失其他实例的修改, 每次调用都需要 Close
 func NewUploadingDatabase() (ud *UploadingDatabase, err error) {
-	file, err := os.OpenFile(filepath.Join(pcsconfig.GetConfigDir(), UploadingFileName), os.O_CREATE|os.O_RDWR, 0777)
-	if err != nil {
-		return nil, err
+	configDir := pcsconfig.GetConfigDir()
+	return openSharedUploadingDatabase(filepath.Join(configDir, UploadingJournalFileName), filepath.Join(configDir, UploadingFileName))
+}
+
+// openSharedUploadingDatabase 返回日志 journalPath 已打开的数据库并增加引用数, 未打开时打开
+func openSharedUploadingDatabase(journalPath, legacyPath string) (ud *UploadingDatabase, err error) {
+	sharedDatabasesMu.Lock()
+	defer sharedDatabasesMu.Unlock()
+	ud, ok := sharedDatabases[journalPath]
+	if !ok {
+		ud, err = openUploadingDatabase(journalPath, legacyPath)
+		if err != nil {
+			return nil, err
+		}
+		sharedDatabases[journalPath] = ud
 	}
+	ud.refs++
+	return ud, nil
+}
 
+func openUploadingDatabase(journalPath, legacyPath string) (ud *UploadingDatabase, err error) {
 	ud = &UploadingDatabase{
-		dataFile: file,
+		path: journalPath,
 	}
-	info, err := file.Stat()
+	ud.dataFile, err = os.OpenFile(journalPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
 	if err != nil {
 		return nil, err
 	}
 
-	if info.Size() <= 0 {
-		return ud, nil
+	err = ud.replay()
+	if err != nil {
+		ud.dataFile.Close()
+		return nil, err
 	}
 
-	err = jsonhelper.UnmarshalData(file, ud)
-	if err != nil {
-		_, err = file.Write([]byte(""))
+	migrated := ud.migrate(legacyPath)
+	// 打开时清除一次失效的条目, Search 时只检查找到的条目
+	ud.clearStale(UploadingMaxAge)
+	if migrated || ud.needCompact() {
+		err = ud.compact()
 		if err != nil {
 			return nil, err
 		}
+		// 压缩后的日志已不含失效的条目
+		ud.pending = nil
+	}
+	if migrated {
+		os.Remove(legacyPath)
 	}
-
 	return ud, nil
 }
 
-// Save 保存内容
+// replay 重放日志, 忽略崩溃时未写完的记录
+func (ud *UploadingDatabase) replay() error {
+	_, err := ud.dataFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	var (
+		dec    = json.NewDecoder(ud.dataFile)
+		offset int64
+	)
+	for {
+		record := &journalRecord{}
+		err = dec.Decode(record)
+		if err != nil {
+			break
+		}
+		offset = dec.InputOffset()
+		ud.records++
+		ud.apply(record)
+	}
+	if err != io.EOF {
+		// 截断末尾损坏的记录, 之后追加的记录才能正确读取
+		pcsUploadVerbose.Warnf("uploading journal: drop broken record at offset %d: %s\n", offset, err)
+		return ud.dataFile.Truncate(offset)
+	}
+	return nil
+}
+
+func (ud *UploadingDatabase) apply(record *journalRecord) {
+	if record.Uploading == nil || record.Uploading.LocalFileMeta == nil {
+		return
+	}
+	k := ud.index(record.Uploading.LocalFileMeta)
+	switch record.Op {
+	case journalOpPut:
+		if k >= 0 {
+			ud.UploadingList[k] = record.Uploading
+		} else {
+			ud.UploadingList = append(ud.UploadingList, record.Uploading)
+		}
+	case journalOpDelete:
+		if k >= 0 {
+			ud.deleteIndex(k)
+		}
+	}
+}
+
+// migrate 读取旧版的 json 数据库, 旧版没有记录开始上传的时间, 以最后保存的时间代替
+func (ud *UploadingDatabase) migrate(legacyPath string) bool {
+	data, err := os.ReadFile(legacyPath)
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	legacy := &UploadingDatabase{}
+	err = jsonhelper.UnmarshalData(bytes.NewReader(data), legacy)
+	if err != nil {
+		return true
+	}
+	for _, uploading := range legacy.UploadingList {
+		if uploading.LocalFileMeta == nil || ud.index(uploading.LocalFileMeta) >= 0 {
+			continue
+		}
+		if uploading.State != nil {
+			uploading.Created = legacy.Timestamp
+		}
+		ud.UploadingList = append(ud.UploadingList, uploading)
+	}
+	return true
+}
+
+// index 查找条目, 优先按路径匹配, 其次按大小和md5匹配, 未找到返回 -1
+func (ud *UploadingDatabase) index(meta *checksum.LocalFileMeta) int {
+	found := -1
+	for k, uploading := range ud.UploadingList {
+		if uploading.LocalFileMeta == nil {
+			continue
+		}
+		if uploading.LocalFileMeta.Path == meta.Path {
+			return k
+		}
+		// 未计算md5时, 只比较大小会匹配到其他文件
+		if found < 0 && len(meta.MD5) > 0 && uploading.LocalFileMeta.EqualLengthMD5(meta) {
+			found = k
+		}
+	}
+	return found
+}
+
+// record 记录一次修改, 调用 Save 时写入日志, 需持有锁
+func (ud *UploadingDatabase) record(op string, uploading *Uploading) {
+	data, err := json.Marshal(&journalRecord{
+		Op:        op,
+		Uploading: uploading,
+	})
+	if err != nil {
+		panic(err)
+	}
+	ud.pending = append(ud.pending, append(data, '\n'))
+}
+
+func (ud *UploadingDatabase) needCompact() bool {
+	return ud.records > len(ud.UploadingList)*compactFactor+compactMinRecords
+}
+
+// Save 将修改追加写入日志并同步到磁盘, 日志过长时压缩
 func (ud *UploadingDatabase) Save() error {
+	ud.lock.Lock()
+	defer ud.lock.Unlock()
 	if ud.dataFile == nil {
 		return errors.New("dataFile is nil")
 	}
+	if len(ud.pending) == 0 {
+		return nil
+	}
 
 	ud.Timestamp = time.Now().Unix()
-
-	var (
-		builder = &strings.Builder{}
-		err     = jsonhelper.MarshalData(builder, ud)
-	)
+	_, err := ud.dataFile.Write(bytes.Join(ud.pending, nil))
 	if err != nil {
-		panic(err)
+		return err
+	}
+	ud.records += len(ud.pending)
+	ud.pending = ud.pending[:0]
+	err = ud.dataFile.Sync()
+	if err != nil {
+		return err
 	}
 
-	err = ud.dataFile.Truncate(int64(builder.Len()))
+	if ud.needCompact() {
+		return ud.compact()
+	}
+	return nil
+}
+
+// compact 将当前的条目写入新的日志, 替换旧的日志, 需持有锁
+func (ud *UploadingDatabase) compact() error {
+	tmpPath := ud.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
+	enc := json.NewEncoder(tmpFile)
+	for _, uploading := range ud.UploadingList {
+		err = enc.Encode(&journalRecord{
+			Op:        journalOpPut,
+			Uploading: uploading,
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if cerr := tmpFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
 
-	str := builder.String()
-	_, err = ud.dataFile.WriteAt(converter.ToBytes(str), 0)
+	// windows 下无法替换已打开的文件, 先关闭
+	ud.dataFile.Close()
+	err = os.Rename(tmpPath, ud.path)
+	if err != nil {
+		os.Remove(tmpPath)
+	} else {
+		ud.records = len(ud.UploadingList)
+		syncDir(filepath.Dir(ud.path))
+	}
+	var oerr error
+	ud.dataFile, oerr = os.OpenFile(ud.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
 	if err != nil {
 		return err
 	}
+	return oerr
+}
 
-	return nil
+// syncDir 同步目录, 确保重命名已写入磁盘, 部分系统不支持
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
 }
 
 // UpdateUploading 更新正在上传
 func (ud *UploadingDatabase) UpdateUploading(meta *checksum.LocalFileMeta, state *uploader.InstanceState) {
-	ud.lock.RLock()
-	defer ud.lock.RUnlock()
+	ud.lock.Lock()
+	defer ud.lock.Unlock()
 	if meta == nil {
 		return
 	}
 	meta.CompleteAbsPath()
-	for k, uploading := range ud.UploadingList {
-		if uploading.LocalFileMeta == nil {
-			continue
-		}
-		if uploading.LocalFileMeta.EqualLengthMD5(meta) || uploading.LocalFileMeta.Path == meta.Path {
-			ud.UploadingList[k].State = state
-			return
-		}
+	k := ud.index(meta)
+	if k < 0 {
+		ud.UploadingList = append(ud.UploadingList, &Uploading{
+			LocalFileMeta: meta,
+		})
+		k = len(ud.UploadingList) - 1
 	}
-
-	ud.UploadingList = append(ud.UploadingList, &Uploading{
-		LocalFileMeta: meta,
-		State:         state,
-	})
+	uploading := ud.UploadingList[k]
+	uploading.State = state
+	if state == nil {
+		uploading.Created = 0
+	} else if uploading.Created == 0 {
+		uploading.Created = time.Now().Unix()
+	}
+	ud.record(journalOpPut, uploading)
 }
 
 func (ud *UploadingDatabase) deleteIndex(k int) {
 	ud.UploadingList = append(ud.UploadingList[:k], ud.UploadingList[k+1:]...)
 }
 
+// removeIndex 删除条目并记录, 需持有锁
+func (ud *UploadingDatabase) removeIndex(k int) {
+	ud.record(journalOpDelete, &Uploading{
+		LocalFileMeta: ud.UploadingList[k].LocalFileMeta,
+	})
+	ud.deleteIndex(k)
+}
+
 // Delete 删除
 func (ud *UploadingDatabase) Delete(meta *checksum.LocalFileMeta) bool {
 	ud.lock.Lock()
@@ -127,50 +348,73 @@ func (ud *UploadingDatabase) Delete(meta *checksum.LocalFileMeta) bool {
 		return false
 	}
 	meta.CompleteAbsPath()
-	for k, uploading := range ud.UploadingList {
-		if uploading.LocalFileMeta == nil {
-			continue
-		}
-		if uploading.LocalFileMeta.EqualLengthMD5(meta) || uploading.LocalFileMeta.Path == meta.Path {
-			ud.deleteIndex(k)
-			return true
-		}
+	k := ud.index(meta)
+	if k < 0 {
+		return false
 	}
-	return false
+	ud.removeIndex(k)
+	return true
 }
 
-// Search 搜索
+// Search 搜索, 找到的断点续传信息已过期或文件已修改时删除
 func (ud *UploadingDatabase) Search(meta *checksum.LocalFileMeta) *uploader.InstanceState {
 	if meta == nil {
 		return nil
 	}
 
 	meta.CompleteAbsPath()
-	ud.clearModTimeChange()
-	for _, uploading := range ud.UploadingList {
-		if uploading.LocalFileMeta == nil {
-			continue
-		}
-		if uploading.LocalFileMeta.EqualLengthMD5(meta) {
-			return uploading.State
-		}
-		if uploading.LocalFileMeta.Path == meta.Path {
-			// 移除旧的信息
-			// 目前只是比较了文件大小
-			if meta.Length != uploading.LocalFileMeta.Length {
-				ud.Delete(meta)
-				return nil
-			}
-
-			meta.MD5 = uploading.LocalFileMeta.MD5
-			meta.SliceMD5 = uploading.LocalFileMeta.SliceMD5
-			return uploading.State
-		}
+
+	ud.lock.Lock()
+	k := ud.index(meta)
+	if k < 0 {
+		ud.lock.Unlock()
+		return nil
 	}
-	return nil
+	uploading := ud.UploadingList[k]
+	if stale, reason := uploading.IsStale(UploadingMaxAge); stale {
+		ud.removeIndex(k)
+		ud.lock.Unlock()
+		pcsUploadVerbose.Infof("clear uploading: %s, reason: %s\n", uploading.LocalFileMeta.Path, reason)
+		return nil
+	}
+	ud.lock.Unlock()
+
+	if uploading.LocalFileMeta.EqualLengthMD5(meta) {
+		return uploading.State
+	}
+	// 移除旧的信息
+	// 目前只是比较了文件大小
+	if meta.Length != uploading.LocalFileMeta.Length {
+		ud.Delete(meta)
+		return nil
+	}
+
+	meta.MD5 = uploading.LocalFileMeta.MD5
+	meta.SliceMD5 = uploading.LocalFileMeta.SliceMD5
+	return uploading.State
+}
+
+// IsStale 断点续传信息是否已过期, 或本地文件已删除或修改, err 为原因
+func (uploading *Uploading) IsStale(maxAge time.Duration) (stale bool, err error) {
+	if uploading.State != nil && uploading.Created > 0 && time.Since(time.Unix(uploading.Created, 0)) > maxAge {
+		return true, errors.New("断点续传信息已过期")
+	}
+	if uploading.ModTime == -1 { // 忽略
+		return false, nil
+	}
+
+	info, err := os.Stat(uploading.LocalFileMeta.Path)
+	if err != nil {
+		return true, err
+	}
+	if uploading.LocalFileMeta.ModTime != info.ModTime().Unix() {
+		return true, errors.New("文件已修改")
+	}
+	return false, nil
 }
 
-func (ud *UploadingDatabase) clearModTimeChange() {
+// clearStale 删除已过期的断点续传信息和已删除或修改的文件, 返回删除的条目
+func (ud *UploadingDatabase) clearStale(maxAge time.Duration) (removed []*Uploading) {
 	ud.lock.Lock()
 	defer ud.lock.Unlock()
 	for i := 0; i < len(ud.UploadingList); i++ {
@@ -179,28 +423,64 @@ func (ud *UploadingDatabase) clearModTimeChange() {
 			continue
 		}
 
-		if uploading.ModTime == -1 { // 忽略
+		stale, reason := uploading.IsStale(maxAge)
+		if !stale {
 			continue
 		}
+		removed = append(removed, uploading)
+		ud.removeIndex(i)
+		i--
+		pcsUploadVerbose.Infof("clear uploading: %s, reason: %s\n", uploading.LocalFileMeta.Path, reason)
+	}
+	return removed
+}
 
-		info, err := os.Stat(uploading.LocalFileMeta.Path)
-		if err != nil {
-			ud.deleteIndex(i)
-			i--
-			pcsUploadVerbose.Warnf("clear invalid file path: %s, err: %s\n", uploading.LocalFileMeta.Path, err)
-			continue
-		}
+// PurgeStale 删除已过期的断点续传信息和已删除或修改的文件, 并保存
+func (ud *UploadingDatabase) PurgeStale(maxAge time.Duration) (removed []*Uploading, err error) {
+	removed = ud.clearStale(maxAge)
+	return removed, ud.Save()
+}
 
-		if uploading.LocalFileMeta.ModTime != info.ModTime().Unix() {
-			ud.deleteIndex(i)
-			i--
-			pcsUploadVerbose.Infof("clear modified file path: %s\n", uploading.LocalFileMeta.Path)
-			continue
+// List 返回所有未完成上传的条目
+func (ud *UploadingDatabase) List() []*Uploading {
+	ud.lock.RLock()
+	defer ud.lock.RUnlock()
+	list := make([]*Uploading, len(ud.UploadingList))
+	copy(list, ud.UploadingList)
+	return list
+}
+
+// Uploaded 返回已上传的数据量
+func (uploading *Uploading) Uploaded() (uploaded int64) {
+	if uploading.State == nil {
+		return 0
+	}
+	for _, block := range uploading.State.BlockList {
+		if block.CheckSum != "" {
+			uploaded += block.Range.Len()
 		}
 	}
+	return uploaded
 }
 
-// Close 关闭数据库
+// Close 写入未保存的修改, 共享的数据库在最后一次 Close 时关闭
 func (ud *UploadingDatabase) Close() error {
-	return ud.dataFile.Close()
+	err := ud.Save()
+	sharedDatabasesMu.Lock()
+	defer sharedDatabasesMu.Unlock()
+	if ud.refs > 1 {
+		ud.refs--
+		return err
+	}
+	ud.refs = 0
+	if sharedDatabases[ud.path] == ud {
+		delete(sharedDatabases, ud.path)
+	}
+
+	ud.lock.Lock()
+	defer ud.lock.Unlock()
+	if cerr := ud.dataFile.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
@@ -0,0 +1,306 @@
+package pcsupload
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/jsonhelper"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestMeta(t *testing.T, dir, name string) *checksum.LocalFileMeta {
+	p := filepath.Join(dir, name)
+	err := os.WriteFile(p, []byte(name), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &checksum.LocalFileMeta{
+		Path:    p,
+		Length:  info.Size(),
+		ModTime: info.ModTime().Unix(),
+	}
+}
+
+func TestUploadingDatabaseJournal(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, UploadingJournalFileName)
+	legacy := filepath.Join(dir, UploadingFileName)
+
+	ud, err := openUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := newTestMeta(t, dir, "a"), newTestMeta(t, dir, "b")
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	ud.UpdateUploading(b, &uploader.InstanceState{})
+	ud.Delete(a)
+	if err = ud.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ud.Close()
+
+	// 模拟崩溃时写了一半的记录
+	f, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"put","uploading":{"pa`)
+	f.Close()
+
+	ud, err = openUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := ud.List()
+	if len(list) != 1 || list[0].Path != b.Path || list[0].Created == 0 {
+		t.Fatalf("unexpected list after replay: %+v", list)
+	}
+
+	// 截断后追加的记录可以正常读取
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	ud.Close()
+	ud, err = openUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ud.List()); n != 2 {
+		t.Fatalf("expect 2 entries, got %d", n)
+	}
+
+	// 过期的断点续传信息被删除
+	ud.UploadingList[0].Created = time.Now().Add(-2 * UploadingMaxAge).Unix()
+	removed, err := ud.PurgeStale(UploadingMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || len(ud.List()) != 1 {
+		t.Fatalf("expect 1 stale entry removed, got %d, left %d", len(removed), len(ud.List()))
+	}
+	ud.Close()
+}
+
+func TestUploadingDatabaseCompact(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, UploadingJournalFileName)
+	ud, err := openUploadingDatabase(journal, filepath.Join(dir, UploadingFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud.Close()
+
+	meta := newTestMeta(t, dir, "a")
+	for i := 0; i < compactMinRecords*2; i++ {
+		ud.UpdateUploading(meta, &uploader.InstanceState{})
+		if err = ud.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ud.records > compactFactor+compactMinRecords {
+		t.Fatalf("journal not compacted, records: %d", ud.records)
+	}
+
+	ud2, err := openUploadingDatabase(journal, filepath.Join(dir, UploadingFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud2.Close()
+	if n := len(ud2.List()); n != 1 {
+		t.Fatalf("expect 1 entry after compaction, got %d", n)
+	}
+}
+
+func TestUploadingDatabaseMigrate(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, UploadingFileName)
+	meta := newTestMeta(t, dir, "a")
+	old := &UploadingDatabase{
+		UploadingList: []*Uploading{{LocalFileMeta: meta, State: &uploader.InstanceState{}}},
+		Timestamp:     1,
+	}
+	f, err := os.Create(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = jsonhelper.MarshalData(f, old)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ud, err := openUploadingDatabase(filepath.Join(dir, UploadingJournalFileName), legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud.Close()
+	if _, err = os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("legacy database not removed: %v", err)
+	}
+	// 旧版的断点续传信息已过期
+	if ud.Search(meta) != nil || len(ud.List()) != 0 {
+		t.Fatalf("expect expired legacy entry to be dropped")
+	}
+}
+
+func TestUploadingStale(t *testing.T) {
+	dir := t.TempDir()
+	ud, err := openUploadingDatabase(filepath.Join(dir, UploadingJournalFileName), filepath.Join(dir, UploadingFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud.Close()
+
+	// Created 为第一次保存断点续传信息的时间, 之后的更新不改变
+	a := newTestMeta(t, dir, "a")
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	uploading := ud.List()[0]
+	uploading.Created -= 10
+	created := uploading.Created
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	if uploading.Created != created {
+		t.Fatalf("Created changed on update: %d -> %d", created, uploading.Created)
+	}
+	if stale, reason := uploading.IsStale(UploadingMaxAge); stale {
+		t.Fatalf("fresh entry is stale: %s", reason)
+	}
+	if stale, _ := uploading.IsStale(5 * time.Second); !stale {
+		t.Fatal("expired entry is not stale")
+	}
+
+	// 只记录了 md5 的条目不会过期
+	ud.UpdateUploading(a, nil)
+	if uploading.Created != 0 {
+		t.Fatalf("Created = %d without state", uploading.Created)
+	}
+	if stale, _ := uploading.IsStale(0); stale {
+		t.Fatal("entry without state expired")
+	}
+
+	// 文件修改或删除后失效
+	b, c := newTestMeta(t, dir, "b"), newTestMeta(t, dir, "c")
+	ud.UpdateUploading(b, &uploader.InstanceState{})
+	ud.UpdateUploading(c, &uploader.InstanceState{})
+	mtime := time.Now().Add(time.Hour)
+	os.Chtimes(b.Path, mtime, mtime)
+	os.Remove(c.Path)
+	for _, uploading := range ud.List()[1:] {
+		if stale, _ := uploading.IsStale(UploadingMaxAge); !stale {
+			t.Errorf("%s is not stale", uploading.Path)
+		}
+	}
+	ignored := &Uploading{LocalFileMeta: &checksum.LocalFileMeta{Path: c.Path, ModTime: -1}}
+	if stale, _ := ignored.IsStale(UploadingMaxAge); stale {
+		t.Error("entry with ModTime -1 is stale")
+	}
+
+	removed, err := ud.PurgeStale(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || len(ud.List()) != 1 || ud.List()[0].Path != a.Path {
+		t.Fatalf("removed %d, left %+v", len(removed), ud.List())
+	}
+
+	// Search 时删除过期的条目
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	ud.List()[0].Created = time.Now().Add(-2 * UploadingMaxAge).Unix()
+	if ud.Search(a) != nil || len(ud.List()) != 0 {
+		t.Fatal("expired entry found by Search")
+	}
+}
+
+func TestUploadingStaleOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	journalPath, legacyPath := filepath.Join(dir, UploadingJournalFileName), filepath.Join(dir, UploadingFileName)
+	ud, err := openUploadingDatabase(journalPath, legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := newTestMeta(t, dir, "a"), newTestMeta(t, dir, "b")
+	ud.UpdateUploading(a, &uploader.InstanceState{})
+	ud.UpdateUploading(b, &uploader.InstanceState{})
+	os.Remove(b.Path)
+
+	// Search 只检查找到的条目
+	if ud.Search(a) == nil || len(ud.List()) != 2 {
+		t.Fatalf("Search: %d entries left", len(ud.List()))
+	}
+	if err = ud.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 打开时清除失效的条目
+	ud, err = openUploadingDatabase(journalPath, legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := ud.List(); len(list) != 1 || list[0].Path != a.Path {
+		t.Fatalf("after open: %+v", list)
+	}
+	if err = ud.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ud, err = openUploadingDatabase(journalPath, legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud.Close()
+	if len(ud.List()) != 1 {
+		t.Fatalf("stale entry not removed from journal: %+v", ud.List())
+	}
+}
+
+func TestUploadingDatabaseShared(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, UploadingJournalFileName)
+	legacy := filepath.Join(dir, UploadingFileName)
+
+	ud1, err := openSharedUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ud2, err := openSharedUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ud1 != ud2 {
+		t.Fatal("database not shared")
+	}
+
+	// 压缩日志不会丢失其他使用者的修改
+	a, b := newTestMeta(t, dir, "a"), newTestMeta(t, dir, "b")
+	ud2.UpdateUploading(b, &uploader.InstanceState{})
+	for i := 0; i < compactMinRecords*2; i++ {
+		ud1.UpdateUploading(a, &uploader.InstanceState{})
+		if err = ud1.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ud1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ud2.UpdateUploading(b, &uploader.InstanceState{})
+	if err = ud2.Save(); err != nil {
+		t.Fatal("database closed while still in use:", err)
+	}
+	if err = ud2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ud, err := openSharedUploadingDatabase(journal, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ud.Close()
+	if ud == ud1 {
+		t.Fatal("closed database reused")
+	}
+	if n := len(ud.List()); n != 2 {
+		t.Fatalf("expect 2 entries, got %d", n)
+	}
+}
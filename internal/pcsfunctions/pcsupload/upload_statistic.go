@@ -2,6 +2,12 @@ package pcsupload
 
 import (
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+)
+
+var (
+	// rapidUploadResults 秒传结果统计, result 为 hit (秒传成功), exists (目标文件已存在) 或 miss (秒传失败)
+	rapidUploadResults = metrics.NewCounterVec("baidupcs_rapid_upload_total", "Number of rapid upload attempts by result.", "result")
 )
 
 type (
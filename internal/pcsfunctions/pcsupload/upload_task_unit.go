@@ -2,6 +2,7 @@ package pcsupload
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -9,12 +10,17 @@ import (
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs"
 	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
 	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsconfig"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions"
+	"github.com/qjfoidnh/BaiduPCS-Go/internal/pcsfunctions/pcshook"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/checksum"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/retry"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"io"
+	"os"
 	"path"
 	"strings"
 	"time"
@@ -37,13 +43,20 @@ type (
 		NoRapidUpload     bool   // 禁用秒传
 		NoSplitFile       bool   // 禁用分片上传
 		Policy            string // 上传重名文件策略
+		Pipeline          bool   // 流水线模式, 大文件在上传分片的同时计算秒传信息, 只读取一次文件
 
 		UploadStatistic *UploadStatistic
+		Checksum        *ChecksumJob       // 后台计算的秒传信息, 可为 nil
+		OnFinish        func(succeed bool) // 任务结束 (不包括重试) 后调用, 可为 nil
 
-		taskInfo *taskframework.TaskInfo
-		panDir   string
-		panFile  string
-		state    *uploader.InstanceState
+		Out     io.Writer                     // 输出, 默认为标准输出
+		Control *pcsfunctions.TransferControl // 暂停和恢复控制
+
+		taskInfo  *taskframework.TaskInfo
+		panDir    string
+		panFile   string
+		state     *uploader.InstanceState
+		startTime time.Time // 第一次执行的时间, 用于钩子
 	}
 )
 
@@ -54,10 +67,15 @@ const (
 	StepUploadRapidUpload
 	// StepUploadUpload 正常上传步骤
 	StepUploadUpload
+	// StepUploadEmpty 上传空文件步骤
+	StepUploadEmpty
+	// StepUploadPipeline 流水线上传步骤
+	StepUploadPipeline
 )
 
 const (
 	StrUploadFailed    = "上传文件失败"
+	StrUploadCanceled  = "上传已取消"
 	DefaultPrintFormat = "\r[%s] ↑ %s/%s %s/s in %s ............"
 	DefaultContentSize = 4 * converter.KB
 )
@@ -66,6 +84,14 @@ func (utu *UploadTaskUnit) SetTaskInfo(taskInfo *taskframework.TaskInfo) {
 	utu.taskInfo = taskInfo
 }
 
+// out 返回输出
+func (utu *UploadTaskUnit) out() io.Writer {
+	if utu.Out == nil {
+		return os.Stdout
+	}
+	return utu.Out
+}
+
 // prepareFile 解析文件阶段
 func (utu *UploadTaskUnit) prepareFile() {
 	// 解析文件保存路径
@@ -75,6 +101,12 @@ func (utu *UploadTaskUnit) prepareFile() {
 	utu.panDir = path.Clean(panDir)
 	utu.panFile = panFile
 
+	// 空文件无需秒传和分片
+	if utu.LocalFileChecksum.Length == 0 {
+		utu.Step = StepUploadEmpty
+		return
+	}
+
 	// 检测断点续传
 	utu.state = utu.UploadingDatabase.Search(&utu.LocalFileChecksum.LocalFileMeta)
 	if utu.state != nil || utu.LocalFileChecksum.LocalFileMeta.MD5 != nil { // 读取到了md5
@@ -88,10 +120,14 @@ func (utu *UploadTaskUnit) prepareFile() {
 	}
 
 	if utu.LocalFileChecksum.Length > baidupcs.MaxRapidUploadSize {
-		fmt.Printf("[%s] 文件超过20GB, 无法使用秒传功能, 跳过秒传...\n", utu.taskInfo.Id())
+		fmt.Fprintf(utu.out(), "[%s] 文件超过20GB, 无法使用秒传功能, 跳过秒传...\n", utu.taskInfo.Id())
 		utu.Step = StepUploadUpload
 		return
 	}
+	if utu.Pipeline && utu.LocalFileChecksum.Length >= PipelineMinSize {
+		utu.Step = StepUploadPipeline
+		return
+	}
 	// 下一步: 秒传
 	utu.Step = StepUploadRapidUpload
 }
@@ -100,39 +136,24 @@ func (utu *UploadTaskUnit) prepareFile() {
 func (utu *UploadTaskUnit) rapidUpload() (isContinue bool, result *taskframework.TaskUnitRunResult) {
 	utu.Step = StepUploadRapidUpload
 
-	// TODO: 建立一个通过百度错误码判断重试的函数
 	result = &taskframework.TaskUnitRunResult{}
 
 	fdl, pcsError := utu.PCS.CacheFilesDirectoriesList(utu.panDir, baidupcs.DefaultOrderOptions)
-	if pcsError != nil {
-		switch pcsError.GetErrType() {
-		case pcserror.ErrTypeRemoteError:
-			switch pcsError.GetRemoteErrCode() {
-			case 31066:
-			// file does not exist
-			// 不缓存文件夹
-			default:
-				// 其他百度服务器错误, 不重试
-				result.ResultMessage = "获取文件列表错误"
-				result.Err = pcsError
-				return
-			}
-		default:
-			// 未知错误, 重试
-			result.ResultMessage = "获取文件列表错误"
-			result.NeedRetry = true
-			result.Err = pcsError
-			return
-		}
+	// 目录不存在时不缓存文件夹, 继续上传
+	if pcsError != nil && !errors.Is(pcsError, pcserror.ErrNotFound) {
+		result.ResultMessage = "获取文件列表错误"
+		result.NeedRetry = pcserror.IsRetryable(pcsError)
+		result.Err = pcsError
+		return
 	}
 
 	// 文件大于128MB, 输出提示信息
 	if utu.LocalFileChecksum.Length >= 128*converter.MB {
-		fmt.Printf("[%s] 检测秒传中, 请稍候...\n", utu.taskInfo.Id())
+		fmt.Fprintf(utu.out(), "[%s] 检测秒传中, 请稍候...\n", utu.taskInfo.Id())
 	}
 
 	// 经测试, 文件的 crc32 值并非秒传文件所必需
-	err := utu.LocalFileChecksum.Sum(checksum.CHECKSUM_MD5 | checksum.CHECKSUM_SLICE_MD5)
+	err := utu.sumRapidUploadInfo()
 	if err != nil {
 		// 不重试
 		result.ResultMessage = "计算文件秒传信息错误"
@@ -147,7 +168,8 @@ func (utu *UploadTaskUnit) rapidUpload() (isContinue bool, result *taskframework
 				// TODO: fd.MD5 有可能是错误的
 				decodedMD5, _ := hex.DecodeString(fd.MD5)
 				if bytes.Compare(decodedMD5, utu.LocalFileChecksum.MD5) == 0 {
-					fmt.Printf("[%s] 目标文件, %s, 已存在, 跳过...\n", utu.taskInfo.Id(), utu.SavePath)
+					fmt.Fprintf(utu.out(), "[%s] 目标文件, %s, 已存在, 跳过...\n", utu.taskInfo.Id(), utu.SavePath)
+					rapidUploadResults.Inc("exists")
 					result.Succeed = true // 成功
 					return
 				}
@@ -159,29 +181,18 @@ func (utu *UploadTaskUnit) rapidUpload() (isContinue bool, result *taskframework
 	if err != nil {
 		result.ResultMessage = "获取用户uk错误, 请确保登录信息包含了STOKEN"
 		result.Err = err
-		fmt.Printf("[%s] 秒传失败, 开始上传文件...\n\n", utu.taskInfo.Id())
+		fmt.Fprintf(utu.out(), "[%s] 秒传失败, 开始上传文件...\n\n", utu.taskInfo.Id())
 		isContinue = true
 		return
 	}
-	currentTime := time.Now().Unix()
-	offset, err := creaetDataOffset(hex.EncodeToString(utu.LocalFileChecksum.MD5), uk, currentTime, utu.LocalFileChecksum.Length, DefaultContentSize)
+	pcsError, result.ResultMessage, err = utu.requestRapidUpload(uk)
 	if err != nil {
-		result.ResultMessage = "计算文件偏移量错误"
 		result.Err = err
 		return
 	}
-	dataContent, dataLength, err := utu.LocalFileChecksum.GetSliceDataContent(offset, DefaultContentSize)
-	if err != nil {
-		result.ResultMessage = "读取随机文件子片段错误"
-		result.Err = err
-		return
-	}
-	b64Content := strings.TrimRight(base64.StdEncoding.EncodeToString(dataContent), "=")
-	pcsError = utu.PCS.RapidUpload(utu.SavePath, hex.EncodeToString(utu.LocalFileChecksum.MD5),
-		hex.EncodeToString(utu.LocalFileChecksum.SliceMD5), b64Content, fmt.Sprint(utu.LocalFileChecksum.CRC32),
-		offset, dataLength, utu.LocalFileChecksum.Length, currentTime)
 	if pcsError == nil {
-		fmt.Printf("[%s] 秒传成功, 保存到网盘路径: %s\n\n", utu.taskInfo.Id(), utu.SavePath)
+		fmt.Fprintf(utu.out(), "[%s] 秒传成功, 保存到网盘路径: %s\n\n", utu.taskInfo.Id(), utu.SavePath)
+		rapidUploadResults.Inc("hit")
 		// 统计
 		utu.UploadStatistic.AddTotalSize(utu.LocalFileChecksum.Length)
 		result.Succeed = true // 成功
@@ -189,16 +200,13 @@ func (utu *UploadTaskUnit) rapidUpload() (isContinue bool, result *taskframework
 	}
 
 	// 判断配额是否已满
-	switch pcsError.GetErrType() {
-	// 远程服务器错误
-	case pcserror.ErrTypeRemoteError:
-		switch pcsError.GetRemoteErrCode() {
-		case 31112: //exceed quota
-			result.ResultMessage = "秒传失败, 超出配额, 网盘容量已满"
-			return
-		}
+	if errors.Is(pcsError, pcserror.ErrQuotaExceeded) {
+		result.ResultMessage = "秒传失败, 超出配额, 网盘容量已满"
+		rapidUploadResults.Inc("miss")
+		return
 	}
-	fmt.Printf("[%s] 秒传失败, 开始上传文件...\n\n", utu.taskInfo.Id())
+	fmt.Fprintf(utu.out(), "[%s] 秒传失败, 开始上传文件...\n\n", utu.taskInfo.Id())
+	rapidUploadResults.Inc("miss")
 
 	// 保存秒传信息
 	utu.UploadingDatabase.UpdateUploading(&utu.LocalFileChecksum.LocalFileMeta, nil)
@@ -207,6 +215,24 @@ func (utu *UploadTaskUnit) rapidUpload() (isContinue bool, result *taskframework
 	return
 }
 
+// requestRapidUpload 使用已计算的 md5 和 slice md5 请求秒传, err 为本地错误, errMessage 为其说明
+func (utu *UploadTaskUnit) requestRapidUpload(uk int64) (pcsError pcserror.Error, errMessage string, err error) {
+	currentTime := time.Now().Unix()
+	offset, err := creaetDataOffset(hex.EncodeToString(utu.LocalFileChecksum.MD5), uk, currentTime, utu.LocalFileChecksum.Length, DefaultContentSize)
+	if err != nil {
+		return nil, "计算文件偏移量错误", err
+	}
+	dataContent, dataLength, err := utu.LocalFileChecksum.GetSliceDataContent(offset, DefaultContentSize)
+	if err != nil {
+		return nil, "读取随机文件子片段错误", err
+	}
+	b64Content := strings.TrimRight(base64.StdEncoding.EncodeToString(dataContent), "=")
+	pcsError = utu.PCS.RapidUpload(utu.SavePath, hex.EncodeToString(utu.LocalFileChecksum.MD5),
+		hex.EncodeToString(utu.LocalFileChecksum.SliceMD5), b64Content, fmt.Sprint(utu.LocalFileChecksum.CRC32),
+		offset, dataLength, utu.LocalFileChecksum.Length, currentTime, utu.fileTime())
+	return pcsError, "", nil
+}
+
 // upload 上传文件
 func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 	utu.Step = StepUploadUpload
@@ -219,10 +245,11 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 		blockSize = getBlockSize(utu.LocalFileChecksum.Length)
 	}
 
-	muer := uploader.NewMultiUploader(NewPCSUpload(utu.PCS, utu.SavePath), rio.NewFileReaderAtLen64(utu.LocalFileChecksum.GetFile()), &uploader.MultiUploaderConfig{
+	muer := uploader.NewMultiUploader(NewPCSUpload(utu.PCS, utu.SavePath, utu.fileTime()), rio.NewFileReaderAtLen64(utu.LocalFileChecksum.GetFile()), &uploader.MultiUploaderConfig{
 		Parallel:  utu.Parallel,
 		BlockSize: blockSize,
 		MaxRate:   pcsconfig.Config.MaxUploadRate,
+		RateLimit: pcsconfig.Config.UploadRateLimit(),
 		Policy:    utu.Policy,
 	})
 
@@ -230,6 +257,11 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 	if utu.state != nil {
 		muer.SetInstanceState(utu.state)
 	}
+	// 取消 (例如 Ctrl-C) 后中断上传
+	stopCancel := context.AfterFunc(utu.PCS.Context(), muer.Cancel)
+	defer stopCancel()
+	removeControl := utu.Control.Add(muer)
+	defer removeControl()
 	muer.OnUploadStatusEvent(func(status uploader.Status, updateChan <-chan struct{}) {
 		select {
 		case <-updateChan:
@@ -238,7 +270,7 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 		default:
 		}
 
-		fmt.Printf(utu.PrintFormat, utu.taskInfo.Id(),
+		fmt.Fprintf(utu.out(), utu.PrintFormat, utu.taskInfo.Id(),
 			converter.ConvertFileSize(status.Uploaded(), 2),
 			converter.ConvertFileSize(status.TotalSize(), 2),
 			converter.ConvertFileSize(status.SpeedsPerSecond(), 2),
@@ -249,14 +281,22 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 	// result
 	result = &taskframework.TaskUnitRunResult{}
 	muer.OnSuccess(func() {
-		fmt.Printf("\n")
-		fmt.Printf("[%s] 上传文件成功, 保存到网盘路径: %s\n", utu.taskInfo.Id(), utu.SavePath)
+		fmt.Fprintf(utu.out(), "\n")
+		fmt.Fprintf(utu.out(), "[%s] 上传文件成功, 保存到网盘路径: %s\n", utu.taskInfo.Id(), utu.SavePath)
 		// 统计
 		utu.UploadStatistic.AddTotalSize(utu.LocalFileChecksum.Length)
 		utu.UploadingDatabase.Delete(&utu.LocalFileChecksum.LocalFileMeta) // 删除
 		utu.UploadingDatabase.Save()
 		result.Succeed = true
 	})
+	muer.OnCancel(func() {
+		fmt.Fprintf(utu.out(), "\n")
+		result.ResultMessage = StrUploadCanceled
+		result.Err = context.Canceled
+		// 保存断点续传信息
+		utu.UploadingDatabase.UpdateUploading(&utu.LocalFileChecksum.LocalFileMeta, muer.InstanceState())
+		utu.UploadingDatabase.Save()
+	})
 	muer.OnError(func(err error) {
 		pcsError, ok := err.(pcserror.Error)
 		if !ok {
@@ -267,14 +307,14 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 			return
 		}
 
-		// 默认需要重试
-		result.NeedRetry = true
+		// 默认按错误分类判断是否重试
+		result.NeedRetry = pcserror.IsRetryable(pcsError)
 
 		switch pcsError.GetErrType() {
 		case pcserror.ErrTypeRemoteError:
 			// 远程百度服务器的错误
-			switch pcsError.GetRemoteErrCode() {
-			case 114514:
+			switch {
+			case pcsError.GetRemoteErrCode() == 114514:
 				// 自定义错误码, 仅在fail和skip策略下出现
 				result.ResultMessage = StrUploadFailed
 				result.Err = pcsError
@@ -285,22 +325,23 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 				}
 				result.NeedRetry = false
 				return
-			case 1919810:
+			case pcsError.GetRemoteErrCode() == 1919810:
 				// 自定义错误码, 仅在rsync策略下出现
 				result.Extra = "skip"
 				result.Err = nil
 				result.ResultMessage = fmt.Sprintf("%s 目标大小未发生改变, 跳过", utu.SavePath)
 				result.NeedRetry = false
 				return
-			case 31363:
+			case pcsError.GetRemoteErrCode() == 31363:
 				// block miss in superfile2, 上传状态过期
 				// 需要重试的
 				utu.UploadingDatabase.Delete(&utu.LocalFileChecksum.LocalFileMeta)
 				utu.UploadingDatabase.Save()
 
 				result.ResultMessage = StrUploadFailed
+				result.NeedRetry = true
 				result.Err = errors.New("上传状态过期, 重新上传")
-			case 31061:
+			case errors.Is(pcsError, pcserror.ErrAlreadyExists):
 				// 已存在重名文件, 不重试
 				result.ResultMessage = StrUploadFailed
 				result.Err = pcsError
@@ -327,7 +368,6 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 			}
 		default:
 			result.ResultMessage = StrUploadFailed
-			result.NeedRetry = false
 			result.Err = pcsError
 		}
 		return
@@ -337,30 +377,148 @@ func (utu *UploadTaskUnit) upload() (result *taskframework.TaskUnitRunResult) {
 	return
 }
 
+// setFailedResult 设置空文件和流水线上传的失败结果, 按同名文件策略跳过时不算失败
+func (utu *UploadTaskUnit) setFailedResult(result *taskframework.TaskUnitRunResult, err error) {
+	result.ResultMessage = StrUploadFailed
+	result.Err = err
+	pcsError, ok := err.(pcserror.Error)
+	if !ok {
+		return
+	}
+	result.NeedRetry = pcserror.IsRetryable(pcsError)
+	switch {
+	case pcsError.GetRemoteErrCode() == 114514, errors.Is(pcsError, pcserror.ErrAlreadyExists):
+		// 已存在重名文件, 不重试
+		result.NeedRetry = false
+		if utu.Policy == "skip" {
+			result.Extra = "skip"
+			result.Err = nil
+			result.ResultMessage = fmt.Sprintf("%s 目标已存在, 跳过", utu.SavePath)
+		}
+	case pcsError.GetRemoteErrCode() == 1919810:
+		// rsync 策略
+		result.Extra = "skip"
+		result.Err = nil
+		result.ResultMessage = fmt.Sprintf("%s 目标大小未发生改变, 跳过", utu.SavePath)
+		result.NeedRetry = false
+	}
+}
+
+// uploadEmpty 上传空文件
+func (utu *UploadTaskUnit) uploadEmpty() (result *taskframework.TaskUnitRunResult) {
+	result = &taskframework.TaskUnitRunResult{}
+
+	pu := NewPCSUpload(utu.PCS, utu.SavePath, utu.fileTime()).(*PCSUpload)
+	// 检查重名文件策略
+	pcsError := pu.Precreate(0, utu.Policy)
+	if pcsError == nil {
+		pcsError = pu.UploadEmpty(utu.Policy)
+	}
+	if pcsError != nil {
+		utu.setFailedResult(result, pcsError)
+		return
+	}
+
+	fmt.Fprintf(utu.out(), "[%s] 上传空文件成功, 保存到网盘路径: %s\n", utu.taskInfo.Id(), utu.SavePath)
+	result.Succeed = true
+	return
+}
+
+// fileTime 返回本地文件的修改时间和创建时间, 获取失败时返回 nil
+func (utu *UploadTaskUnit) fileTime() *baidupcs.LocalFileTime {
+	file := utu.LocalFileChecksum.GetFile()
+	if file == nil {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	return baidupcs.NewLocalFileTime(info.ModTime(), pcsutil.FileCtime(info))
+}
+
 func (utu *UploadTaskUnit) OnRetry(lastRunResult *taskframework.TaskUnitRunResult) {
 	// 输出错误信息
 	if lastRunResult.Err == nil {
 		// result中不包含Err, 忽略输出
-		fmt.Printf("[%s] %s, 重试 %d/%d\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, utu.taskInfo.Retry(), utu.taskInfo.MaxRetry())
+		fmt.Fprintf(utu.out(), "[%s] %s, 重试 %d/%d\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, utu.taskInfo.Retry(), utu.taskInfo.MaxRetry())
 		return
 	}
-	fmt.Printf("[%s] %s, %s, 重试 %d/%d\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err, utu.taskInfo.Retry(), utu.taskInfo.MaxRetry())
+	fmt.Fprintf(utu.out(), "[%s] %s, %s, 重试 %d/%d\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err, utu.taskInfo.Retry(), utu.taskInfo.MaxRetry())
 }
 
 func (utu *UploadTaskUnit) OnSuccess(lastRunResult *taskframework.TaskUnitRunResult) {
+	utu.finish(true, lastRunResult)
 }
 
 func (utu *UploadTaskUnit) OnFailed(lastRunResult *taskframework.TaskUnitRunResult) {
 	// 失败
+	defer utu.finish(false, lastRunResult)
 	if lastRunResult.Err == nil {
 		// result中不包含Err, 忽略输出
-		fmt.Printf("[%s] %s\n", utu.taskInfo.Id(), lastRunResult.ResultMessage)
+		fmt.Fprintf(utu.out(), "[%s] %s\n", utu.taskInfo.Id(), lastRunResult.ResultMessage)
 		return
 	}
-	fmt.Printf("[%s] %s, %s\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err)
+	fmt.Fprintf(utu.out(), "[%s] %s, %s\n", utu.taskInfo.Id(), lastRunResult.ResultMessage, lastRunResult.Err)
 }
 
 func (utu *UploadTaskUnit) OnComplete(lastRunResult *taskframework.TaskUnitRunResult) {
+	// 文件不可读时没有结果, 不会调用 OnSuccess 和 OnFailed
+	if lastRunResult == nil {
+		utu.finish(false, nil)
+	}
+}
+
+func (utu *UploadTaskUnit) finish(succeed bool, result *taskframework.TaskUnitRunResult) {
+	utu.fireHook(succeed, result)
+	if utu.OnFinish != nil {
+		utu.OnFinish(succeed)
+	}
+}
+
+// fireHook 执行配置的钩子, 钩子失败不影响上传结果
+func (utu *UploadTaskUnit) fireHook(succeed bool, result *taskframework.TaskUnitRunResult) {
+	status := pcshook.StatusFailed
+	switch {
+	case succeed:
+		status = pcshook.StatusSuccess
+	case result != nil && result.Extra == "skip":
+		status = pcshook.StatusSkipped
+	}
+	hook := pcshook.FromConfig(pcsconfig.Config)
+	if !hook.Enabled(pcshook.KindUpload + "." + status) {
+		return
+	}
+
+	var err error
+	switch {
+	case result == nil:
+		err = errors.New("文件不可读")
+	case result.Err != nil:
+		err = fmt.Errorf("%s, %w", result.ResultMessage, result.Err)
+	case !succeed && result.ResultMessage != "":
+		err = errors.New(result.ResultMessage)
+	}
+	payload := pcshook.NewPayload(pcshook.KindUpload, status, utu.startTime, err)
+	payload.LocalPath = utu.LocalFileChecksum.Path
+	payload.RemotePath = utu.SavePath
+	payload.Size = utu.LocalFileChecksum.Length
+	if utu.LocalFileChecksum.MD5 != nil {
+		payload.MD5 = hex.EncodeToString(utu.LocalFileChecksum.MD5)
+	}
+	if succeed {
+		fd, pcsError := utu.PCS.FilesDirectoriesMeta(utu.SavePath)
+		if pcsError == nil {
+			payload.FsID = fd.FsID
+			if payload.MD5 == "" {
+				payload.MD5 = fd.MD5
+			}
+		}
+	}
+	err = hook.Fire(utu.PCS.Context(), payload)
+	if err != nil {
+		fmt.Fprintf(utu.out(), "[%s] 执行钩子错误: %s\n", utu.taskInfo.Id(), err)
+	}
 }
 
 func (utu *UploadTaskUnit) RetryWait() time.Duration {
@@ -368,11 +526,30 @@ func (utu *UploadTaskUnit) RetryWait() time.Duration {
 }
 
 func (utu *UploadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
-	fmt.Printf("[%s] 准备上传: %s\n", utu.taskInfo.Id(), utu.LocalFileChecksum.Path)
+	// 已取消 (例如 Ctrl-C), 中断的任务不再重试
+	ctx := utu.PCS.Context()
+	if ctx.Err() != nil {
+		return &taskframework.TaskUnitRunResult{
+			ResultMessage: StrUploadCanceled,
+			Err:           ctx.Err(),
+		}
+	}
+	defer func() {
+		if ctx.Err() != nil && result != nil && !result.Succeed {
+			result.ResultMessage = StrUploadCanceled
+			result.Err = ctx.Err()
+			result.NeedRetry = false
+		}
+	}()
+
+	if utu.startTime.IsZero() {
+		utu.startTime = time.Now()
+	}
+	fmt.Fprintf(utu.out(), "[%s] 准备上传: %s\n", utu.taskInfo.Id(), utu.LocalFileChecksum.Path)
 
 	err := utu.LocalFileChecksum.OpenPath()
 	if err != nil {
-		fmt.Printf("[%s] 文件不可读, 错误信息: %s, 跳过...\n", utu.taskInfo.Id(), err)
+		fmt.Fprintf(utu.out(), "[%s] 文件不可读, 错误信息: %s, 跳过...\n", utu.taskInfo.Id(), err)
 		return
 	}
 	defer utu.LocalFileChecksum.Close() // 关闭文件
@@ -381,6 +558,10 @@ func (utu *UploadTaskUnit) Run() (result *taskframework.TaskUnitRunResult) {
 	utu.prepareFile()
 
 	switch utu.Step {
+	case StepUploadEmpty:
+		return utu.uploadEmpty()
+	case StepUploadPipeline:
+		return utu.pipelineUpload()
 	case StepUploadRapidUpload:
 		goto stepUploadRapidUpload
 	case StepUploadUpload:
@@ -106,7 +106,7 @@ func main() {
 	appInstance.CliApp.Version = Version
 
 	// Run the CLI application
-	err = appInstance.CliApp.Run(os.Args)
+	err = appInstance.Run(os.Args)
 	if err != nil {
 		// Handle potential errors from app run, though cli usually handles exits.
 		// Use fmt.Fprintf to stderr for errors after initialization.
@@ -0,0 +1,241 @@
+// Package dirwatch 监视本地目录中的文件变化, 文件写入完成 (大小和修改时间在一段时间内不变) 后通知.
+// Linux 下使用 inotify, 其他系统或 inotify 不可用时使用轮询
+package dirwatch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultSettle 默认的文件稳定时间
+	DefaultSettle = 3 * time.Second
+	// DefaultPollInterval 默认的轮询间隔
+	DefaultPollInterval = 2 * time.Second
+	// NotifyRescanInterval 使用 inotify 时, 为防止遗漏事件, 定期完整扫描的间隔
+	NotifyRescanInterval = time.Minute
+)
+
+type (
+	// StableFunc 文件写入完成后调用, 返回 false 表示暂时无法处理, 稍后再次通知
+	StableFunc func(path string, fi os.FileInfo) bool
+
+	// FilterFunc 过滤函数, 返回 false 时忽略该文件或目录
+	FilterFunc func(path string, fi os.FileInfo) bool
+
+	// Watcher 目录监视
+	Watcher struct {
+		Root          string
+		Settle        time.Duration // 文件大小和修改时间在此时间内不变才认为写入完成
+		PollInterval  time.Duration // 轮询间隔
+		Filter        FilterFunc
+		NoNotify      bool            // 不使用 inotify, 只使用轮询
+		OnNotifyError func(err error) // 事件通知出错时调用, 之后改为轮询
+
+		pending map[string]*pendingFile
+		known   map[string]fileState // 已通知的文件状态
+	}
+
+	fileState struct {
+		size  int64
+		mtime int64
+	}
+
+	pendingFile struct {
+		state fileState
+		since time.Time // 状态最后一次改变的时间
+	}
+
+	// notifier 文件系统事件通知, Events 返回发生变化的文件或目录的路径,
+	// 出错时 Events 被关闭, Err 返回原因
+	notifier interface {
+		Events() <-chan string
+		Err() error
+		Close() error
+	}
+)
+
+var errNotDir = errors.New("not a directory")
+
+// NewWatcher 初始化 Watcher
+func NewWatcher(root string) *Watcher {
+	return &Watcher{
+		Root:         root,
+		Settle:       DefaultSettle,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Run 开始监视, 目录中已有的文件也会通知, 直到 ctx 取消
+func (w *Watcher) Run(ctx context.Context, onStable StableFunc) error {
+	fi, err := os.Stat(w.Root)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return &os.PathError{Op: "watch", Path: w.Root, Err: errNotDir}
+	}
+	if w.Settle <= 0 {
+		w.Settle = DefaultSettle
+	}
+	if w.PollInterval <= 0 {
+		w.PollInterval = DefaultPollInterval
+	}
+	w.pending = map[string]*pendingFile{}
+	w.known = map[string]fileState{}
+
+	var (
+		n              notifier
+		events         <-chan string
+		rescanInterval = w.PollInterval
+	)
+	if !w.NoNotify {
+		n, err = newNotifier(w.Root, w.Filter)
+		if err == nil {
+			defer n.Close()
+			events = n.Events()
+			rescanInterval = NotifyRescanInterval
+		}
+	}
+
+	checkInterval := w.Settle / 3
+	if checkInterval > time.Second {
+		checkInterval = time.Second
+	}
+	check := time.NewTicker(checkInterval)
+	defer check.Stop()
+	rescan := time.NewTicker(rescanInterval)
+	defer rescan.Stop()
+
+	w.scan(w.Root)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case name, ok := <-events:
+			if !ok {
+				// 事件通知出错, 改为轮询
+				events = nil
+				rescan.Reset(w.PollInterval)
+				if w.OnNotifyError != nil {
+					w.OnNotifyError(n.Err())
+				}
+				w.scan(w.Root)
+				continue
+			}
+			w.touch(name)
+		case <-rescan.C:
+			w.scan(w.Root)
+			w.forget()
+		case <-check.C:
+			w.check(onStable)
+		}
+	}
+}
+
+// touch 文件或目录发生变化
+func (w *Watcher) touch(name string) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		// 已删除或移走
+		delete(w.pending, name)
+		delete(w.known, name)
+		return
+	}
+	if fi.IsDir() {
+		w.scan(name)
+		return
+	}
+	if w.Filter != nil && !w.Filter(name, fi) {
+		return
+	}
+	w.mark(name, fi, true)
+}
+
+// scan 扫描目录, 将新增和改变的文件加入待处理列表
+func (w *Watcher) scan(dir string) {
+	filepath.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if w.Filter != nil && name != w.Root && !w.Filter(name, fi) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Mode().IsRegular() {
+			w.mark(name, fi, false)
+		}
+		return nil
+	})
+}
+
+// mark 将文件加入待处理列表, force 为 true 时, 即使状态与已通知的相同也重新等待稳定
+func (w *Watcher) mark(name string, fi os.FileInfo, force bool) {
+	state := fileState{
+		size:  fi.Size(),
+		mtime: fi.ModTime().UnixNano(),
+	}
+	if known, ok := w.known[name]; ok && known == state && !force {
+		return
+	}
+	if p, ok := w.pending[name]; ok {
+		if p.state != state || force {
+			p.state = state
+			p.since = time.Now()
+		}
+		return
+	}
+	w.pending[name] = &pendingFile{
+		state: state,
+		since: time.Now(),
+	}
+}
+
+// check 检查待处理的文件, 状态在 Settle 时间内不变的文件通知写入完成
+func (w *Watcher) check(onStable StableFunc) {
+	now := time.Now()
+	for name, p := range w.pending {
+		fi, err := os.Stat(name)
+		if err != nil || !fi.Mode().IsRegular() {
+			delete(w.pending, name)
+			continue
+		}
+		state := fileState{
+			size:  fi.Size(),
+			mtime: fi.ModTime().UnixNano(),
+		}
+		if state != p.state {
+			p.state = state
+			p.since = now
+			continue
+		}
+		if now.Sub(p.since) < w.Settle {
+			continue
+		}
+		// 内容未改变 (例如只修改了权限) 的文件不重复通知
+		if known, ok := w.known[name]; ok && known == state {
+			delete(w.pending, name)
+			continue
+		}
+		if !onStable(name, fi) {
+			p.since = now
+			continue
+		}
+		w.known[name] = state
+		delete(w.pending, name)
+	}
+}
+
+// forget 清除已删除文件的记录
+func (w *Watcher) forget() {
+	for name := range w.known {
+		if _, err := os.Stat(name); err != nil {
+			delete(w.known, name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package dirwatch_test
+
+import (
+	"context"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/dirwatch"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testWatcher(t *testing.T, noNotify bool) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "exist.txt"), []byte("exist"), 0644)
+	os.Mkdir(filepath.Join(dir, "skip"), 0777)
+
+	var (
+		mu      sync.Mutex
+		stable  = map[string]int64{}
+		refused = true
+	)
+	w := dirwatch.NewWatcher(dir)
+	w.Settle = 300 * time.Millisecond
+	w.PollInterval = 100 * time.Millisecond
+	w.NoNotify = noNotify
+	w.Filter = func(path string, fi os.FileInfo) bool {
+		return fi.Name() != "skip"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, func(path string, fi os.FileInfo) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		// 第一次拒绝, 稍后应再次通知
+		if refused && filepath.Base(path) == "exist.txt" {
+			refused = false
+			return false
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if _, ok := stable[rel]; ok {
+			t.Errorf("%s notified twice", rel)
+		}
+		stable[rel] = fi.Size()
+		return true
+	})
+
+	// 分多次写入, 写入过程中不应通知
+	time.Sleep(200 * time.Millisecond)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0777)
+	f, err := os.Create(filepath.Join(dir, "sub", "new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		f.Write([]byte("data"))
+		time.Sleep(100 * time.Millisecond)
+	}
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "skip", "ignored.txt"), []byte("x"), 0644)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(stable)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int64{
+		"exist.txt":                     5,
+		filepath.Join("sub", "new.txt"): 16,
+	}
+	if len(stable) != len(want) {
+		t.Fatalf("stable = %v, want %v", stable, want)
+	}
+	for k, v := range want {
+		if stable[k] != v {
+			t.Errorf("%s size = %d, want %d", k, stable[k], v)
+		}
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	testWatcher(t, true)
+}
+
+func TestWatcherNotify(t *testing.T) {
+	testWatcher(t, false)
+}
@@ -0,0 +1,170 @@
+//go:build linux
+// +build linux
+
+package dirwatch
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+const (
+	inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_DELETE
+	// 等待事件的超时时间, 毫秒, 用于检查是否已关闭
+	inotifyPollTimeout = 500
+)
+
+// inotify 使用 inotify 监视目录及其所有子目录
+type inotify struct {
+	fd      int
+	root    string
+	filter  FilterFunc
+	mu      sync.Mutex
+	watches map[int]string // watch descriptor 对应的目录
+	events  chan string
+	done    chan struct{}
+	wg      sync.WaitGroup
+	err     error // 读取事件出错的原因, events 关闭后可读取
+}
+
+func newNotifier(root string, filter FilterFunc) (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	in := &inotify{
+		fd:      fd,
+		root:    root,
+		filter:  filter,
+		watches: map[int]string{},
+		events:  make(chan string, 64),
+		done:    make(chan struct{}),
+	}
+	err = in.addRecursive(root)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	in.wg.Add(1)
+	go in.readEvents()
+	return in, nil
+}
+
+// addRecursive 监视目录 dir 及其子目录
+func (in *inotify) addRecursive(dir string) error {
+	return filepath.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if in.filter != nil && name != in.root && !in.filter(name, fi) {
+			return filepath.SkipDir
+		}
+		wd, err := unix.InotifyAddWatch(in.fd, name, inotifyMask)
+		if err != nil {
+			// 根目录无法监视时使用轮询, 子目录的变化由定期扫描发现
+			if name == in.root {
+				return err
+			}
+			return nil
+		}
+		in.mu.Lock()
+		in.watches[wd] = name
+		in.mu.Unlock()
+		return nil
+	})
+}
+
+// readEvents 读取事件, 出错时记录错误并关闭 events
+func (in *inotify) readEvents() {
+	defer in.wg.Done()
+	defer close(in.events)
+
+	var (
+		buf = make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		fds = []unix.PollFd{{Fd: int32(in.fd), Events: unix.POLLIN}}
+	)
+	for {
+		select {
+		case <-in.done:
+			return
+		default:
+		}
+
+		n, err := unix.Poll(fds, inotifyPollTimeout)
+		if err != nil && err != unix.EINTR {
+			in.err = &os.SyscallError{Syscall: "poll", Err: err}
+			return
+		}
+		if n <= 0 {
+			continue
+		}
+		n, err = unix.Read(in.fd, buf)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EINTR {
+				continue
+			}
+			in.err = &os.SyscallError{Syscall: "read", Err: err}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(raw.Len)]), "\x00")
+			offset = nameStart + int(raw.Len)
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// 事件溢出, 重新扫描整个目录
+				in.send(in.root)
+				continue
+			}
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				in.mu.Lock()
+				delete(in.watches, int(raw.Wd))
+				in.mu.Unlock()
+				continue
+			}
+			in.mu.Lock()
+			dir, ok := in.watches[int(raw.Wd)]
+			in.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+
+			fullPath := filepath.Join(dir, name)
+			if raw.Mask&unix.IN_ISDIR != 0 {
+				if raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) == 0 {
+					continue
+				}
+				// 新建的目录, 加入监视
+				in.addRecursive(fullPath)
+			}
+			in.send(fullPath)
+		}
+	}
+}
+
+func (in *inotify) send(name string) {
+	select {
+	case in.events <- name:
+	case <-in.done:
+	}
+}
+
+func (in *inotify) Events() <-chan string {
+	return in.events
+}
+
+func (in *inotify) Err() error {
+	return in.err
+}
+
+func (in *inotify) Close() error {
+	close(in.done)
+	in.wg.Wait()
+	return unix.Close(in.fd)
+}
@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package dirwatch
+
+import (
+	"golang.org/x/sys/unix"
+	"testing"
+	"time"
+)
+
+func TestInotifyReadError(t *testing.T) {
+	n, err := newNotifier(t.TempDir(), nil)
+	if err != nil {
+		t.Skip(err)
+	}
+	in := n.(*inotify)
+	// 关闭 inotify 后读取事件出错, events 应被关闭
+	unix.Close(in.fd)
+	select {
+	case _, ok := <-in.Events():
+		if ok {
+			t.Fatal("unexpected event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events not closed after read error")
+	}
+	if in.Err() == nil {
+		t.Error("Err() = nil after read error")
+	}
+	in.Close()
+}
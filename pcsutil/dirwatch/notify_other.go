@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package dirwatch
+
+import (
+	"errors"
+)
+
+// newNotifier 当前系统不支持文件系统事件通知, 使用轮询
+func newNotifier(root string, filter FilterFunc) (notifier, error) {
+	return nil, errors.New("file system notification not supported")
+}
@@ -114,6 +114,76 @@ func WalkDir(dirPth, suffix string) (files []string, err error) {
 	return files, err
 }
 
+// WalkDirFunc 获取指定目录及所有子目录下的所有文件 (包括空文件) 和空目录, filterFunc 对每个目录和文件调用,
+// 返回 false 时跳过该文件或整个目录, filterFunc 为 nil 时不过滤.
+// 经过滤后不含任何文件和子目录的目录视为空目录
+func WalkDirFunc(dirPth string, filterFunc func(filename string, fi os.FileInfo) bool) (files, emptyDirs []string, err error) {
+	files = make([]string, 0, 32)
+
+	var (
+		dirs     []string
+		children = map[string]int{}
+		walkFunc fs.WalkDirFunc
+	)
+	// addEntry 记录文件或目录, 其上级目录不再是空目录
+	addEntry := func(filename string) {
+		children[filepath.Dir(filepath.Clean(filename))]++
+	}
+	walkFunc = func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if fi.Mode()&os.ModeSymlink != 0 { // 读取 symbol link
+			targetFileInfo, statErr := os.Stat(filename)
+			if statErr != nil {
+				return nil
+			}
+			if targetFileInfo.IsDir() {
+				if filterFunc != nil && !filterFunc(filename, targetFileInfo) {
+					return nil
+				}
+				addEntry(filename)
+				return filepath.WalkDir(filename+string(os.PathSeparator), walkFunc)
+			}
+			fi = targetFileInfo
+		}
+
+		if fi.IsDir() {
+			isRoot := filepath.Clean(filename) == filepath.Clean(dirPth)
+			// 根目录不过滤
+			if filterFunc != nil && !isRoot && !filterFunc(filename, fi) {
+				return filepath.SkipDir
+			}
+			if _, ok := children[filepath.Clean(filename)]; !ok {
+				children[filepath.Clean(filename)] = 0
+				dirs = append(dirs, filename)
+			}
+			if !isRoot {
+				addEntry(filename)
+			}
+			return nil
+		}
+		if filterFunc != nil && !filterFunc(filename, fi) {
+			return nil
+		}
+		addEntry(filename)
+		files = append(files, path.Clean(filename))
+		return nil
+	}
+
+	err = filepath.WalkDir(dirPth, walkFunc)
+	for _, dir := range dirs {
+		if children[filepath.Clean(dir)] == 0 {
+			emptyDirs = append(emptyDirs, path.Clean(dir))
+		}
+	}
+	return files, emptyDirs, err
+}
+
 // ConvertToUnixPathSeparator 将 windows 目录分隔符转换为 Unix 的
 func ConvertToUnixPathSeparator(p string) string {
 	return strings.Replace(p, "\\", "/", -1)
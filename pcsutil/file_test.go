@@ -3,6 +3,10 @@ package pcsutil_test
 import (
 	"fmt"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -15,3 +19,23 @@ func TestWalkDir(t *testing.T) {
 		fmt.Println(file)
 	}
 }
+
+func TestWalkDirFuncEmpty(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "src")
+	os.MkdirAll(filepath.Join(root, "empty", "nested"), 0755)
+	os.MkdirAll(filepath.Join(root, "data"), 0755)
+	os.WriteFile(filepath.Join(root, "data", "a.txt"), []byte("data"), 0644)
+	os.WriteFile(filepath.Join(root, "zero"), nil, 0644)
+
+	files, emptyDirs, err := pcsutil.WalkDirFunc(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	if want := []string{filepath.Join(root, "data", "a.txt"), filepath.Join(root, "zero")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+	if want := []string{filepath.Join(root, "empty", "nested")}; !reflect.DeepEqual(emptyDirs, want) {
+		t.Errorf("emptyDirs = %q, want %q", emptyDirs, want)
+	}
+}
@@ -0,0 +1,273 @@
+// Package filefilter 上传和下载遍历目录时的文件过滤规则
+package filefilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Filter 文件过滤规则, 同时适用于本地路径和网盘路径,
+	// 路径均以 / 分隔, 且相对于遍历的根目录
+	Filter struct {
+		Includes      []string  // 只包含匹配的文件, 为空则包含所有文件
+		Excludes      []string  // 排除匹配的文件和目录
+		MinSize       int64     // 文件大小下限, 0 为不限制
+		MaxSize       int64     // 文件大小上限, 0 为不限制
+		NewerThan     time.Time // 只包含修改时间晚于此时间的文件
+		OlderThan     time.Time // 只包含修改时间早于此时间的文件
+		ExcludeHidden bool      // 排除隐藏的文件和目录
+
+		ignoreRules []ignoreRule
+	}
+
+	// ignoreRule gitignore 语法的规则
+	ignoreRule struct {
+		pattern  []string
+		negate   bool // 以 ! 开头, 重新包含
+		dirOnly  bool // 以 / 结尾, 只匹配目录
+		anchored bool // 包含 /, 相对于根目录匹配
+	}
+)
+
+var (
+	durationRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)([wd])$`)
+	timeLayouts    = []string{
+		"2006-01-02",
+		"2006-01-02 15:04",
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+	}
+)
+
+// LoadIgnoreFile 从文件读取 gitignore 语法的排除规则
+func (f *Filter) LoadIgnoreFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.AddIgnoreRules(file)
+}
+
+// AddIgnoreRules 读取 gitignore 语法的排除规则, 后面的规则优先
+func (f *Filter) AddIgnoreRules(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = strings.Split(line, "/")
+		f.ignoreRules = append(f.ignoreRules, rule)
+	}
+	return scanner.Err()
+}
+
+// IsEmpty 是否没有设置任何规则
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Includes) == 0 && len(f.Excludes) == 0 && len(f.ignoreRules) == 0 &&
+		f.MinSize <= 0 && f.MaxSize <= 0 && f.NewerThan.IsZero() && f.OlderThan.IsZero() && !f.ExcludeHidden)
+}
+
+// SkipDir 目录是否被排除, rel 为相对于遍历根目录的路径, 不检查上级目录
+func (f *Filter) SkipDir(rel string) bool {
+	if f == nil {
+		return false
+	}
+	return f.excluded(cleanRel(rel), true)
+}
+
+// MatchFile 文件是否符合规则, rel 为相对于遍历根目录的路径, 不检查上级目录
+func (f *Filter) MatchFile(rel string, size int64, mtime time.Time) bool {
+	if f == nil {
+		return true
+	}
+	rel = cleanRel(rel)
+	if f.excluded(rel, false) {
+		return false
+	}
+
+	if len(f.Includes) > 0 {
+		included := false
+		for _, pattern := range f.Includes {
+			if matchGlob(pattern, rel) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	switch {
+	case f.MinSize > 0 && size < f.MinSize,
+		f.MaxSize > 0 && size > f.MaxSize,
+		!f.NewerThan.IsZero() && !mtime.After(f.NewerThan),
+		!f.OlderThan.IsZero() && !mtime.Before(f.OlderThan):
+		return false
+	}
+	return true
+}
+
+// Match 文件及其所有上级目录是否符合规则, 用于无法跳过整个目录的遍历
+func (f *Filter) Match(rel string, size int64, mtime time.Time) bool {
+	if f == nil {
+		return true
+	}
+	rel = cleanRel(rel)
+	return !f.parentExcluded(rel) && f.MatchFile(rel, size, mtime)
+}
+
+// MatchDir 目录及其所有上级目录是否未被排除
+func (f *Filter) MatchDir(rel string) bool {
+	if f == nil {
+		return true
+	}
+	rel = cleanRel(rel)
+	return !f.parentExcluded(rel) && !f.excluded(rel, true)
+}
+
+// parentExcluded 上级目录是否被排除
+func (f *Filter) parentExcluded(rel string) bool {
+	for i := strings.Index(rel, "/"); i >= 0; i = nextSlash(rel, i) {
+		if f.excluded(rel[:i], true) {
+			return true
+		}
+	}
+	return false
+}
+
+func nextSlash(s string, i int) int {
+	j := strings.Index(s[i+1:], "/")
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// excluded 文件或目录本身是否被排除
+func (f *Filter) excluded(rel string, isDir bool) bool {
+	if rel == "" {
+		return false
+	}
+	base := path.Base(rel)
+	if f.ExcludeHidden && strings.HasPrefix(base, ".") {
+		return true
+	}
+	for _, pattern := range f.Excludes {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+
+	ignored := false
+	for _, rule := range f.ignoreRules {
+		if rule.match(rel, base, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (rule *ignoreRule) match(rel, base string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	if rule.anchored {
+		return matchSegments(rule.pattern, strings.Split(rel, "/"))
+	}
+	ok, _ := path.Match(rule.pattern[0], base)
+	return ok
+}
+
+// matchGlob 不含 / 的模式匹配文件名, 否则匹配完整的相对路径, ** 匹配任意层目录
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(name) > 0
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func cleanRel(rel string) string {
+	rel = strings.Trim(path.Clean("/"+strings.ReplaceAll(rel, "\\", "/")), "/")
+	return rel
+}
+
+// ParseTime 解析时间, 支持日期 (如 2006-01-02) 和相对于 now 的时长 (如 12h, 7d, 2w)
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if m := durationRegexp.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := 24 * time.Hour
+		if m[2] == "w" {
+			unit *= 7
+		}
+		return now.Add(-time.Duration(n * float64(unit))), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无法解析时间: %s, 支持日期 (如 2006-01-02) 或时长 (如 12h, 7d, 2w)", s)
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,82 @@
+package filefilter_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/filefilter"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	f := &filefilter.Filter{
+		Excludes:      []string{"node_modules", "*.swp", "docs/**/*.tmp"},
+		Includes:      []string{"*.go", "*.md", "docs/**"},
+		MaxSize:       1024,
+		NewerThan:     now.Add(-48 * time.Hour),
+		ExcludeHidden: true,
+	}
+	err := f.AddIgnoreRules(strings.NewReader("# comment\n/build/\nvendor/\n*.log\n!keep.log\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		rel  string
+		size int64
+		want bool
+	}{
+		{"main.go", 10, true},
+		{"main.c", 10, false},
+		{"src/node_modules/x/a.go", 10, false},
+		{".git/config.go", 10, false},
+		{"src/.main.go.swp", 10, false},
+		{"docs/a/b/c.txt", 10, true},
+		{"docs/a/b/c.tmp", 10, false},
+		{"build/out.go", 10, false},
+		{"src/build/out.go", 10, true},
+		{"a/vendor/x.go", 10, false},
+		{"debug.log", 10, false},
+		{"big.go", 2048, false},
+	}
+	for _, c := range cases {
+		if got := f.Match(c.rel, c.size, now); got != c.want {
+			t.Errorf("Match(%s) = %v, want %v", c.rel, got, c.want)
+		}
+	}
+
+	if f.Match("old.go", 10, now.Add(-72*time.Hour)) {
+		t.Errorf("old file should be excluded")
+	}
+	if !f.SkipDir("node_modules") || f.SkipDir("src") || f.MatchDir("src/.git/objects") {
+		t.Errorf("SkipDir mismatch")
+	}
+
+	g := &filefilter.Filter{}
+	g.AddIgnoreRules(strings.NewReader("*.log\n!keep.log\n"))
+	if g.Match("a/x.log", 1, now) || !g.Match("a/keep.log", 1, now) {
+		t.Errorf("negate rule mismatch")
+	}
+	if !(*filefilter.Filter)(nil).Match("x", 0, now) || !(*filefilter.Filter)(nil).IsEmpty() {
+		t.Errorf("nil filter should match everything")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	cases := map[string]time.Time{
+		"7d":         now.Add(-7 * 24 * time.Hour),
+		"2w":         now.Add(-14 * 24 * time.Hour),
+		"90m":        now.Add(-90 * time.Minute),
+		"2024-01-02": time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local),
+	}
+	for s, want := range cases {
+		got, err := filefilter.ParseTime(s, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTime(%s) = %s, %v, want %s", s, got, err, want)
+		}
+	}
+	if _, err := filefilter.ParseTime("yesterday", now); err == nil {
+		t.Errorf("invalid time should fail")
+	}
+}
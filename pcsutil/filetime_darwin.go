@@ -0,0 +1,18 @@
+//go:build darwin
+// +build darwin
+
+package pcsutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileCtime 获取文件的创建时间
+func FileCtime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Birthtimespec.Unix())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,18 @@
+//go:build linux
+// +build linux
+
+package pcsutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileCtime 获取文件的创建时间, linux 下没有创建时间, 使用 inode 的修改时间代替
+func FileCtime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package pcsutil
+
+import (
+	"os"
+	"time"
+)
+
+// FileCtime 获取文件的创建时间, 不支持的平台使用修改时间代替
+func FileCtime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package pcsutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileCtime 获取文件的创建时间
+func FileCtime(fi os.FileInfo) time.Time {
+	if attr, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attr.CreationTime.Nanoseconds())
+	}
+	return fi.ModTime()
+}
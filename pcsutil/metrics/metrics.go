@@ -0,0 +1,292 @@
+// Package metrics 进程内的指标统计, 以 Prometheus 文本格式输出, 不依赖外部服务
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var (
+	// DefaultRegistry 默认的指标注册表
+	DefaultRegistry = NewRegistry()
+
+	// DefaultLatencyBuckets 默认的延迟分布区间, 单位为秒
+	DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+type (
+	// Registry 指标注册表
+	Registry struct {
+		mu       sync.RWMutex
+		families map[string]*family
+	}
+
+	family struct {
+		mu         sync.Mutex
+		name       string
+		help       string
+		typ        string
+		labelNames []string
+		buckets    []float64
+		series     map[string]*series
+		valueFunc  func() float64
+	}
+
+	series struct {
+		labelValues []string
+		value       float64
+		count       uint64
+		buckets     []uint64
+	}
+
+	// CounterVec 带标签的计数器, 只增不减
+	CounterVec struct {
+		f *family
+	}
+
+	// GaugeVec 带标签的仪表, 可增可减
+	GaugeVec struct {
+		f *family
+	}
+
+	// HistogramVec 带标签的分布统计
+	HistogramVec struct {
+		f *family
+	}
+)
+
+// NewRegistry 初始化指标注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		families: map[string]*family{},
+	}
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.families[f.name]; ok {
+		if old.typ != f.typ {
+			panic("metrics: " + f.name + " registered with different type")
+		}
+		return old
+	}
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+	return f
+}
+
+// NewCounterVec 在默认注册表中注册计数器
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return DefaultRegistry.NewCounterVec(name, help, labelNames...)
+}
+
+// NewGaugeVec 在默认注册表中注册仪表
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return DefaultRegistry.NewGaugeVec(name, help, labelNames...)
+}
+
+// NewGaugeFunc 在默认注册表中注册仪表, 输出时调用 f 获取值
+func NewGaugeFunc(name, help string, f func() float64) {
+	DefaultRegistry.NewGaugeFunc(name, help, f)
+}
+
+// NewHistogramVec 在默认注册表中注册分布统计
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return DefaultRegistry.NewHistogramVec(name, help, buckets, labelNames...)
+}
+
+// NewCounterVec 注册计数器
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		f: r.register(&family{name: name, help: help, typ: typeCounter, labelNames: labelNames}),
+	}
+}
+
+// NewGaugeVec 注册仪表
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{
+		f: r.register(&family{name: name, help: help, typ: typeGauge, labelNames: labelNames}),
+	}
+}
+
+// NewGaugeFunc 注册仪表, 输出时调用 f 获取值
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.register(&family{name: name, help: help, typ: typeGauge, valueFunc: f})
+}
+
+// NewHistogramVec 注册分布统计, buckets 为各个区间的上限, 需从小到大排列
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+	return &HistogramVec{
+		f: r.register(&family{name: name, help: help, typ: typeHistogram, labelNames: labelNames, buckets: buckets}),
+	}
+}
+
+// get 获取标签对应的数据, 调用前需加锁
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labelValues: append([]string(nil), labelValues...),
+		}
+		if f.typ == typeHistogram {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Inc 计数加1
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+// Add 计数增加 v, v 不能为负数
+func (cv *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	cv.f.mu.Lock()
+	defer cv.f.mu.Unlock()
+	cv.f.get(labelValues).value += v
+}
+
+// Set 设置值
+func (gv *GaugeVec) Set(v float64, labelValues ...string) {
+	gv.f.mu.Lock()
+	defer gv.f.mu.Unlock()
+	gv.f.get(labelValues).value = v
+}
+
+// Add 增加 v, v 可以为负数
+func (gv *GaugeVec) Add(v float64, labelValues ...string) {
+	gv.f.mu.Lock()
+	defer gv.f.mu.Unlock()
+	gv.f.get(labelValues).value += v
+}
+
+// Observe 记录一次观测值
+func (hv *HistogramVec) Observe(v float64, labelValues ...string) {
+	hv.f.mu.Lock()
+	defer hv.f.mu.Unlock()
+	s := hv.f.get(labelValues)
+	s.value += v
+	s.count++
+	for k, upper := range hv.f.buckets {
+		if v <= upper {
+			s.buckets[k]++
+		}
+	}
+}
+
+// WriteTo 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := r.families
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	builder := &strings.Builder{}
+	for _, name := range names {
+		families[name].writeTo(builder)
+	}
+	nn, err := io.WriteString(w, builder.String())
+	return int64(nn), err
+}
+
+func (f *family) writeTo(builder *strings.Builder) {
+	fmt.Fprintf(builder, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(builder, "# TYPE %s %s\n", f.name, f.typ)
+
+	if f.valueFunc != nil {
+		fmt.Fprintf(builder, "%s %s\n", f.name, formatFloat(f.valueFunc()))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		labels := formatLabels(f.labelNames, s.labelValues)
+		if f.typ != typeHistogram {
+			fmt.Fprintf(builder, "%s%s %s\n", f.name, wrapLabels(labels), formatFloat(s.value))
+			continue
+		}
+
+		for k, upper := range f.buckets {
+			fmt.Fprintf(builder, "%s_bucket%s %d\n", f.name, wrapLabels(joinLabels(labels, `le="`+formatFloat(upper)+`"`)), s.buckets[k])
+		}
+		fmt.Fprintf(builder, "%s_bucket%s %d\n", f.name, wrapLabels(joinLabels(labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(builder, "%s_sum%s %s\n", f.name, wrapLabels(labels), formatFloat(s.value))
+		fmt.Fprintf(builder, "%s_count%s %d\n", f.name, wrapLabels(labels), s.count)
+	}
+}
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for k := range names {
+		pairs[k] = names[k] + `="` + escapeLabelValue(values[k]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.NewCounterVec("requests_total", "requests", "op", "code")
+	c.Inc("list", "0")
+	c.Add(2, "list", "0")
+	h := r.NewHistogramVec("latency_seconds", "latency", []float64{0.1, 1}, "op")
+	h.Observe(0.5, "list")
+	r.NewGaugeFunc("speed_bytes", "speed", func() float64 { return 3 })
+
+	builder := &strings.Builder{}
+	r.WriteTo(builder)
+	out := builder.String()
+	for _, want := range []string{
+		"# TYPE requests_total counter\n",
+		`requests_total{op="list",code="0"} 3` + "\n",
+		`latency_seconds_bucket{op="list",le="0.1"} 0` + "\n",
+		`latency_seconds_bucket{op="list",le="+Inf"} 1` + "\n",
+		`latency_seconds_sum{op="list"} 0.5` + "\n",
+		"speed_bytes 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
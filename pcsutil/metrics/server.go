@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+const (
+	// EnvMetricsAddr 指标服务监听地址的环境变量
+	EnvMetricsAddr = "BAIDUPCS_GO_METRICS_ADDR"
+)
+
+var (
+	serveOnce sync.Once
+	serveErr  error
+)
+
+// Handler 返回输出默认注册表中所有指标的 http.Handler
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		DefaultRegistry.WriteTo(w)
+	})
+}
+
+// ListenAndServe 在 addr 上启动指标服务, 路径为 /metrics, 多次调用只启动一次.
+// 服务在后台运行, 仅返回监听地址时遇到的错误
+func ListenAndServe(addr string) error {
+	serveOnce.Do(func() {
+		var l net.Listener
+		l, serveErr = net.Listen("tcp", addr)
+		if serveErr != nil {
+			return
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", Handler())
+		go http.Serve(l, mux)
+	})
+	return serveErr
+}
@@ -0,0 +1,226 @@
+// Package renamer 批量重命名时的名称变换规则
+package renamer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	// Substitution sed 风格的正则替换, 即 s/正则/替换/标志
+	Substitution struct {
+		re     *regexp.Regexp
+		repl   string // 已转换为 regexp 语法的替换内容
+		global bool   // g 标志, 替换所有匹配
+	}
+
+	// Rules 名称变换规则, 依次应用 Subs, Template, Ext, Case
+	Rules struct {
+		Subs     []*Substitution
+		Template string // 名称模板, 支持 {name}, {ext}, {n}, {n:宽度}
+		SetExt   bool   // 是否修改扩展名
+		Ext      string // 新的扩展名, 为空则去掉扩展名
+		Case     string // 大小写转换: lower, upper, title
+	}
+)
+
+var (
+	templateRegexp = regexp.MustCompile(`\{(name|ext|n)(?::(\d+))?\}`)
+
+	// ErrEmptyRules 没有任何变换规则
+	ErrEmptyRules = errors.New("未指定任何重命名规则")
+)
+
+// ParseSubstitution 解析 sed 风格的替换表达式, 例如 s/IMG_(\d+)/photo-\1/g,
+// 分隔符可以是 s 后的任意字符, 标志支持 g (全部替换) 和 i (忽略大小写)
+func ParseSubstitution(expr string) (*Substitution, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return nil, fmt.Errorf("替换表达式 %s 格式错误, 应为 s/正则/替换/标志", expr)
+	}
+	delim := expr[1]
+	if delim == '\\' || delim == '\n' {
+		return nil, fmt.Errorf("替换表达式 %s 的分隔符无效", expr)
+	}
+
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	for i := 2; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\' && i+1 < len(expr) && expr[i+1] == delim:
+			cur.WriteByte(delim)
+			i++
+		case expr[i] == '\\' && i+1 < len(expr):
+			cur.WriteString(expr[i : i+2])
+			i++
+		case expr[i] == delim:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(expr[i])
+		}
+	}
+	parts = append(parts, cur.String())
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("替换表达式 %s 格式错误, 应为 s/正则/替换/标志", expr)
+	}
+
+	sub := &Substitution{
+		repl: convertReplacement(parts[1]),
+	}
+	pattern := parts[0]
+	for _, flag := range parts[2] {
+		switch flag {
+		case 'g':
+			sub.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, fmt.Errorf("替换表达式 %s 的标志 %c 无效, 支持 g 和 i", expr, flag)
+		}
+	}
+
+	var err error
+	sub.re, err = regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("替换表达式 %s 的正则错误: %s", expr, err)
+	}
+	return sub, nil
+}
+
+// convertReplacement 将 sed 的替换语法 (\1, &, \&) 转换为 regexp 的语法
+func convertReplacement(repl string) string {
+	var b strings.Builder
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		switch {
+		case c == '\\' && i+1 < len(repl):
+			i++
+			next := repl[i]
+			if next >= '0' && next <= '9' {
+				b.WriteString("${" + string(next) + "}")
+			} else if next == '$' {
+				b.WriteString("$$")
+			} else {
+				b.WriteByte(next)
+			}
+		case c == '&':
+			b.WriteString("${0}")
+		case c == '$':
+			b.WriteString("$$")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Apply 对 name 执行替换, 未指定 g 标志时只替换第一个匹配
+func (s *Substitution) Apply(name string) string {
+	if s.global {
+		return s.re.ReplaceAllString(name, s.repl)
+	}
+	loc := s.re.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return name
+	}
+	dst := s.re.ExpandString(nil, s.repl, name, loc)
+	return name[:loc[0]] + string(dst) + name[loc[1]:]
+}
+
+// Check 检查规则是否有效
+func (r *Rules) Check() error {
+	switch r.Case {
+	case "", "lower", "upper", "title":
+	default:
+		return fmt.Errorf("未知的大小写转换: %s, 可选: lower, upper, title", r.Case)
+	}
+	if len(r.Subs) == 0 && r.Template == "" && !r.SetExt && r.Case == "" {
+		return ErrEmptyRules
+	}
+	if strings.Contains(r.Ext, "/") {
+		return fmt.Errorf("扩展名 %s 无效", r.Ext)
+	}
+	return nil
+}
+
+// Apply 返回 name 变换后的名称, n 为序号, 用于模板中的 {n}.
+// 目录没有扩展名, 不修改扩展名
+func (r *Rules) Apply(name string, isDir bool, n int) string {
+	for _, sub := range r.Subs {
+		name = sub.Apply(name)
+	}
+
+	if r.Template != "" {
+		base, ext := SplitExt(name, isDir)
+		name = templateRegexp.ReplaceAllStringFunc(r.Template, func(s string) string {
+			m := templateRegexp.FindStringSubmatch(s)
+			switch m[1] {
+			case "name":
+				return base
+			case "ext":
+				return ext
+			}
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, n)
+		})
+	}
+
+	if r.SetExt && !isDir {
+		base, _ := SplitExt(name, false)
+		name = base
+		if ext := strings.TrimPrefix(r.Ext, "."); ext != "" {
+			name += "." + ext
+		}
+	}
+
+	switch r.Case {
+	case "lower":
+		name = strings.ToLower(name)
+	case "upper":
+		name = strings.ToUpper(name)
+	case "title":
+		// 扩展名不变
+		base, ext := SplitExt(name, isDir)
+		name = toTitle(base) + ext
+	}
+	return name
+}
+
+// SplitExt 分割名称和扩展名, 扩展名包含 ".", 目录和以 . 开头且没有其他 . 的名称 (例如 .bashrc) 没有扩展名
+func SplitExt(name string, isDir bool) (base, ext string) {
+	if isDir {
+		return name, ""
+	}
+	ext = path.Ext(name)
+	base = strings.TrimSuffix(name, ext)
+	if base == "" {
+		return name, ""
+	}
+	return base, ext
+}
+
+// toTitle 将每个单词的首字母转为大写, 其余转为小写
+func toTitle(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' {
+			if start {
+				runes[i] = unicode.ToUpper(r)
+			} else {
+				runes[i] = unicode.ToLower(r)
+			}
+			start = false
+			continue
+		}
+		start = true
+	}
+	return string(runes)
+}
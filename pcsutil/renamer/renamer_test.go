@@ -0,0 +1,79 @@
+package renamer_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/renamer"
+	"testing"
+)
+
+func TestSubstitution(t *testing.T) {
+	cases := []struct {
+		expr, name, want string
+	}{
+		{`s/IMG_(\d+)/photo-\1/`, "IMG_0012.JPG", "photo-0012.JPG"},
+		{`s/a/b/`, "aaa.txt", "baa.txt"},
+		{`s/a/b/g`, "aaa.txt", "bbb.txt"},
+		{`s/A/b/gi`, "aAa.txt", "bbb.txt"},
+		{`s|/|-|g`, "a/b", "a-b"},
+		{`s/\//-/`, "a/b", "a-b"},
+		{`s/ +/_/g`, "my  holiday pic.jpg", "my_holiday_pic.jpg"},
+		{`s/^/[&]/`, "x", "[]x"},
+		{`s/x/[&]/`, "axb", "a[x]b"},
+		{`s/x/\&$1/`, "axb", "a&$1b"},
+		{`s/nomatch/y/`, "abc", "abc"},
+	}
+	for _, c := range cases {
+		sub, err := renamer.ParseSubstitution(c.expr)
+		if err != nil {
+			t.Errorf("%s: %s", c.expr, err)
+			continue
+		}
+		if got := sub.Apply(c.name); got != c.want {
+			t.Errorf("%s on %s: got %s, want %s", c.expr, c.name, got, c.want)
+		}
+	}
+
+	for _, expr := range []string{"", "s", "y/a/b/", "s/a/b", "s/a/b/x", "s/(/b/"} {
+		if _, err := renamer.ParseSubstitution(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	sub, _ := renamer.ParseSubstitution(`s/-/ /g`)
+	cases := []struct {
+		rules renamer.Rules
+		name  string
+		isDir bool
+		n     int
+		want  string
+	}{
+		{renamer.Rules{Template: "trip_{n:3}{ext}"}, "IMG_1.jpg", false, 7, "trip_007.jpg"},
+		{renamer.Rules{Template: "{n}-{name}{ext}"}, "a.tar", false, 12, "12-a.tar"},
+		{renamer.Rules{Template: "{name}_{n:2}{ext}"}, "photos.2024", true, 1, "photos.2024_01"},
+		{renamer.Rules{SetExt: true, Ext: "jpeg"}, "a.JPG", false, 0, "a.jpeg"},
+		{renamer.Rules{SetExt: true, Ext: ".txt"}, "README", false, 0, "README.txt"},
+		{renamer.Rules{SetExt: true}, "a.bak", false, 0, "a"},
+		{renamer.Rules{SetExt: true, Ext: "txt"}, "dir.d", true, 0, "dir.d"},
+		{renamer.Rules{Case: "lower"}, "IMG.JPG", false, 0, "img.jpg"},
+		{renamer.Rules{Case: "upper"}, "readme.md", false, 0, "README.MD"},
+		{renamer.Rules{Subs: []*renamer.Substitution{sub}, Case: "title"}, "my-holiday-PIC.jpg", false, 0, "My Holiday Pic.jpg"},
+		{renamer.Rules{Case: "title"}, ".bashrc", false, 0, ".Bashrc"},
+	}
+	for _, c := range cases {
+		if err := c.rules.Check(); err != nil {
+			t.Errorf("%+v: %s", c.rules, err)
+			continue
+		}
+		if got := c.rules.Apply(c.name, c.isDir, c.n); got != c.want {
+			t.Errorf("%+v on %s: got %s, want %s", c.rules, c.name, got, c.want)
+		}
+	}
+
+	if err := (&renamer.Rules{}).Check(); err != renamer.ErrEmptyRules {
+		t.Errorf("empty rules: got %v", err)
+	}
+	if err := (&renamer.Rules{Case: "camel"}).Check(); err == nil {
+		t.Errorf("unknown case: expected error")
+	}
+}
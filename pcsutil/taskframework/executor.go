@@ -5,6 +5,7 @@ import (
 	"github.com/oleiade/lane"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/waitgroup"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,9 @@ type (
 		// 是否统计失败队列
 		IsFailedDeque bool
 		failedDeque   *lane.Deque
+
+		initOnce sync.Once
+		notify   chan struct{} // 有新任务加入队列, 用于 Serve
 	}
 )
 
@@ -25,18 +29,21 @@ func NewTaskExecutor() *TaskExecutor {
 }
 
 func (te *TaskExecutor) lazyInit() {
-	if te.deque == nil {
-		te.deque = lane.NewDeque()
-	}
-	if te.incr == nil {
-		te.incr = &incremental.Int{}
-	}
+	te.initOnce.Do(func() {
+		if te.deque == nil {
+			te.deque = lane.NewDeque()
+		}
+		if te.incr == nil {
+			te.incr = &incremental.Int{}
+		}
+		if te.IsFailedDeque {
+			te.failedDeque = lane.NewDeque()
+		}
+		te.notify = make(chan struct{}, 1)
+	})
 	if te.parallel < 1 {
 		te.parallel = 1
 	}
-	if te.IsFailedDeque {
-		te.failedDeque = lane.NewDeque()
-	}
 }
 
 // 设置任务的最大并发量
@@ -52,13 +59,23 @@ func (te *TaskExecutor) Append(unit TaskUnit, maxRetry int) *TaskInfo {
 		maxRetry: maxRetry,
 	}
 	unit.SetTaskInfo(taskInfo)
+	tasksQueued.Add(1, unitName(unit))
 	te.deque.Append(&TaskInfoItem{
 		Info: taskInfo,
 		Unit: unit,
 	})
+	te.wakeup()
 	return taskInfo
 }
 
+// wakeup 通知 Serve 有新的任务
+func (te *TaskExecutor) wakeup() {
+	select {
+	case te.notify <- struct{}{}:
+	default:
+	}
+}
+
 //AppendNoRetry 将任务加到任务队列末尾, 不重试
 func (te *TaskExecutor) AppendNoRetry(unit TaskUnit) {
 	te.Append(unit, 0)
@@ -90,60 +107,110 @@ func (te *TaskExecutor) Execute() {
 
 			go func(task *TaskInfoItem) {
 				defer wg.Done()
+				te.run(task)
+			}(task)
+		}
 
-				result := task.Unit.Run()
+		wg.Wait()
 
-				// 返回结果为空
-				if result == nil {
-					task.Unit.OnComplete(result)
-					return
-				}
+		// 没有任务了
+		if te.deque.Size() == 0 {
+			break
+		}
+	}
+}
 
-				if result.Succeed {
-					task.Unit.OnSuccess(result)
-					task.Unit.OnComplete(result)
-					return
-				}
+// Serve 持续执行任务, 队列为空时等待新加入的任务, done 关闭后等待进行中的任务 (包括重试) 结束后返回
+func (te *TaskExecutor) Serve(done <-chan struct{}) {
+	te.lazyInit()
 
-				// 需要进行重试
-				if result.NeedRetry {
-					// 重试次数超出限制
-					// 执行失败
-					if task.Info.IsExceedRetry() {
-						task.Unit.OnFailed(result)
-						if te.IsFailedDeque {
-							// 加入失败队列
-							te.failedDeque.Append(task)
-						}
-						task.Unit.OnComplete(result)
-						return
-					}
-					task.Info.retry++         // 增加重试次数
-					task.Unit.OnRetry(result) // 调用重试
-					task.Unit.OnComplete(result)
-
-					time.Sleep(task.Unit.RetryWait()) // 等待
-					te.deque.Append(task)             // 重新加入队列末尾
+	wg := waitgroup.NewWaitGroup(te.parallel)
+	for {
+		e := te.deque.Shift()
+		if e == nil {
+			select {
+			case <-te.notify:
+			case <-done:
+				wg.Wait()
+				// 重试的任务会重新加入队列
+				if te.deque.Size() == 0 {
 					return
 				}
-
-				// 执行失败
-				task.Unit.OnFailed(result)
-				if te.IsFailedDeque && result.Extra != "skip" {
-					// 加入失败队列
-					te.failedDeque.Append(task)
-				}
-				task.Unit.OnComplete(result)
-			}(task)
+				wg = waitgroup.NewWaitGroup(te.parallel)
+			}
+			continue
 		}
 
-		wg.Wait()
+		task := e.(*TaskInfoItem)
+		wg.AddDelta()
+		go func(task *TaskInfoItem) {
+			defer wg.Done()
+			te.run(task)
+		}(task)
+	}
+}
 
-		// 没有任务了
-		if te.deque.Size() == 0 {
-			break
+// run 执行单个任务, 需要重试时重新加入队列末尾
+func (te *TaskExecutor) run(task *TaskInfoItem) {
+	name := unitName(task.Unit)
+	tasksQueued.Add(-1, name)
+	tasksRunning.Add(1, name)
+	result := task.Unit.Run()
+	tasksRunning.Add(-1, name)
+
+	// 返回结果为空
+	if result == nil {
+		tasksFinished.Inc(name, "unknown")
+		task.Unit.OnComplete(result)
+		return
+	}
+
+	if result.Succeed {
+		tasksFinished.Inc(name, "succeeded")
+		task.Unit.OnSuccess(result)
+		task.Unit.OnComplete(result)
+		return
+	}
+
+	// 需要进行重试
+	if result.NeedRetry {
+		// 重试次数超出限制
+		// 执行失败
+		if task.Info.IsExceedRetry() {
+			tasksFinished.Inc(name, "failed")
+			task.Unit.OnFailed(result)
+			if te.IsFailedDeque {
+				// 加入失败队列
+				te.failedDeque.Append(task)
+			}
+			task.Unit.OnComplete(result)
+			return
 		}
+		tasksFinished.Inc(name, "retry")
+		tasksRetries.Inc(name)
+		tasksQueued.Add(1, name)
+		task.Info.retry++         // 增加重试次数
+		task.Unit.OnRetry(result) // 调用重试
+		task.Unit.OnComplete(result)
+
+		time.Sleep(task.Unit.RetryWait()) // 等待
+		te.deque.Append(task)             // 重新加入队列末尾
+		te.wakeup()
+		return
+	}
+
+	// 执行失败
+	if result.Extra == "skip" {
+		tasksFinished.Inc(name, "skipped")
+	} else {
+		tasksFinished.Inc(name, "failed")
+	}
+	task.Unit.OnFailed(result)
+	if te.IsFailedDeque && result.Extra != "skip" {
+		// 加入失败队列
+		te.failedDeque.Append(task)
 	}
+	task.Unit.OnComplete(result)
 }
 
 //FailedDeque 获取失败队列
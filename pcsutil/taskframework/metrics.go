@@ -0,0 +1,23 @@
+package taskframework
+
+import (
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"strings"
+)
+
+var (
+	tasksQueued   = metrics.NewGaugeVec("baidupcs_tasks_pending", "Number of tasks waiting in the queue.", "unit")
+	tasksRunning  = metrics.NewGaugeVec("baidupcs_tasks_running", "Number of tasks currently running.", "unit")
+	tasksFinished = metrics.NewCounterVec("baidupcs_tasks_total", "Number of finished task runs by result.", "unit", "state")
+	tasksRetries  = metrics.NewCounterVec("baidupcs_task_retries_total", "Number of task retries.", "unit")
+)
+
+// unitName 返回任务单元的类型名, 用作指标的标签
+func unitName(unit TaskUnit) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", unit), "*")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
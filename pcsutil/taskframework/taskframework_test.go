@@ -3,6 +3,7 @@ package taskframework_test
 import (
 	"fmt"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/taskframework"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -57,3 +58,68 @@ func TestTaskExecutor(t *testing.T) {
 	}
 	te.Execute()
 }
+
+type countUnit struct {
+	taskInfo *taskframework.TaskInfo
+	fails    int32
+	done     chan string
+}
+
+func (cu *countUnit) SetTaskInfo(taskInfo *taskframework.TaskInfo) {
+	cu.taskInfo = taskInfo
+}
+
+func (cu *countUnit) OnFailed(lastRunResult *taskframework.TaskUnitRunResult) {}
+
+func (cu *countUnit) OnSuccess(lastRunResult *taskframework.TaskUnitRunResult) {
+	cu.done <- cu.taskInfo.Id()
+}
+
+func (cu *countUnit) OnComplete(lastRunResult *taskframework.TaskUnitRunResult) {}
+
+func (cu *countUnit) Run() (result *taskframework.TaskUnitRunResult) {
+	if atomic.AddInt32(&cu.fails, -1) >= 0 {
+		return &taskframework.TaskUnitRunResult{NeedRetry: true}
+	}
+	return &taskframework.TaskUnitRunResult{Succeed: true}
+}
+
+func (cu *countUnit) OnRetry(lastRunResult *taskframework.TaskUnitRunResult) {}
+
+func (cu *countUnit) RetryWait() time.Duration {
+	return 10 * time.Millisecond
+}
+
+func TestTaskExecutorServe(t *testing.T) {
+	te := taskframework.NewTaskExecutor()
+	te.SetParallel(2)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		te.Serve(done)
+		close(finished)
+	}()
+
+	results := make(chan string, 10)
+	// 队列为空时加入的任务也会执行
+	te.Append(&countUnit{done: results}, 0)
+	select {
+	case <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task appended to idle executor not executed")
+	}
+
+	// done 关闭后等待重试的任务结束
+	for i := 0; i < 3; i++ {
+		te.Append(&countUnit{done: results, fails: 1}, 2)
+	}
+	close(done)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve not returned")
+	}
+	if len(results) != 3 {
+		t.Errorf("succeeded = %d, want 3", len(results))
+	}
+}
@@ -0,0 +1,125 @@
+// Package tokenbucket 令牌桶限速器, 支持在服务器限流时自适应降速
+package tokenbucket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// minRateDivisor 自适应降速的下限为设定速率的 1/minRateDivisor
+	minRateDivisor = 16
+	// recoverInterval 未再被限流时, 每隔 recoverInterval 恢复一次速率
+	recoverInterval = 30 * time.Second
+)
+
+type (
+	// Bucket 令牌桶, rate 为每秒产生的令牌数, burst 为桶容量. rate <= 0 代表不限制
+	Bucket struct {
+		mu        sync.Mutex
+		rate      float64   // 设定的速率
+		current   float64   // 当前速率, 被限流后降低
+		burst     float64   // 桶容量
+		tokens    float64   // 当前令牌数
+		last      time.Time // 上次更新令牌的时间
+		penalized time.Time // 上次降速或恢复的时间
+	}
+)
+
+// New 初始化令牌桶, burst < 1 时使用 1
+func New(rate float64, burst int) *Bucket {
+	b := &Bucket{}
+	b.SetRate(rate, burst)
+	return b
+}
+
+// SetRate 修改速率和桶容量, 同时重置自适应降速
+func (b *Bucket) SetRate(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rate = rate
+	b.current = rate
+	b.burst = float64(burst)
+	b.tokens = b.burst
+	b.last = time.Now()
+}
+
+// Rate 返回设定的速率和当前速率
+func (b *Bucket) Rate() (rate, current float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate, b.current
+}
+
+// reserve 取出一个令牌, 返回需要等待的时间
+func (b *Bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return 0
+	}
+
+	// 一段时间未被限流, 逐步恢复速率
+	if b.current < b.rate && now.Sub(b.penalized) >= recoverInterval {
+		b.current *= 2
+		if b.current > b.rate {
+			b.current = b.rate
+		}
+		b.penalized = now
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.current
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.current * float64(time.Second))
+}
+
+// Wait 等待取得一个令牌, 返回等待的时间. ctx 取消时返回 ctx.Err(), 已取出的令牌不退还
+func (b *Bucket) Wait(ctx context.Context) (waited time.Duration, err error) {
+	if b == nil {
+		return 0, nil
+	}
+	wait := b.reserve(time.Now())
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return wait, ctx.Err()
+	}
+}
+
+// Penalize 服务器返回了限流错误, 将当前速率减半, 并清空令牌, 不低于设定速率的 1/16
+func (b *Bucket) Penalize() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return
+	}
+	b.current /= 2
+	if min := b.rate / minRateDivisor; b.current < min {
+		b.current = min
+	}
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+	b.penalized = time.Now()
+}
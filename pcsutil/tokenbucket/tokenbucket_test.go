@@ -0,0 +1,40 @@
+package tokenbucket_test
+
+import (
+	"context"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/tokenbucket"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := tokenbucket.New(100, 2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if waited, _ := b.Wait(ctx); waited != 0 {
+			t.Fatalf("burst token %d waited %s", i, waited)
+		}
+	}
+	waited, err := b.Wait(ctx)
+	if err != nil || waited <= 0 || waited > 20*time.Millisecond {
+		t.Fatalf("third token waited %s, err %v", waited, err)
+	}
+
+	b.Penalize()
+	if rate, current := b.Rate(); rate != 100 || current != 50 {
+		t.Fatalf("rate after penalize: %v %v", rate, current)
+	}
+	for i := 0; i < 10; i++ {
+		b.Penalize()
+	}
+	if _, current := b.Rate(); current != 100.0/16 {
+		t.Fatalf("rate floor: %v", current)
+	}
+
+	unlimited := tokenbucket.New(0, 1)
+	for i := 0; i < 100; i++ {
+		if waited, _ := unlimited.Wait(ctx); waited != 0 {
+			t.Fatal("unlimited bucket should not wait")
+		}
+	}
+}
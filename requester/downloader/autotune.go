@@ -0,0 +1,230 @@
+package downloader
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/jsonhelper"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// AutoParallelInit 没有历史记录时, 自动调整并发量的初始值
+	AutoParallelInit = 2
+
+	autoTuneSamples          = 5                // 每调整一次并发量需要的速度采样次数, 每秒采样一次
+	autoTuneProbeRounds      = 6                // 速度稳定后, 每隔多少轮尝试增加一个连接
+	autoTuneThrottleCooldown = 10 * time.Second // 遇到限流后, 重新建立连接的等待时间
+	autoTuneCeilingRecover   = 12               // 遇到限流后, 连续多少轮没有限流则提高一次并发量上限
+)
+
+type (
+	// ParallelTuner 根据实际下载速度自动调整并发量,
+	// 速度持续提升时增加连接, 增加连接后速度没有提升或遇到限流时减少连接
+	ParallelTuner struct {
+		mu           sync.Mutex
+		limit        int // 当前允许的并发量
+		ceiling      int // 并发量上限, 遇到限流后降低, 一段时间没有限流后逐步恢复
+		maxCeiling   int // 并发量上限的最大值
+		bestLimit    int
+		bestSpeeds   int64
+		sumSpeeds    int64
+		samples      int
+		stableRounds int
+		calmRounds   int // 距离上次限流的轮数
+		throttled    bool
+		lastThrottle time.Time
+	}
+
+	// ParallelRecord 主机的最佳并发量记录
+	ParallelRecord struct {
+		Parallel  int   `json:"parallel"`
+		Speeds    int64 `json:"speeds"`
+		UpdatedAt int64 `json:"updated_at"`
+	}
+
+	// ParallelHistory 记录各个主机的最佳并发量, 下次下载时以此作为初始并发量
+	ParallelHistory struct {
+		mu       sync.Mutex
+		Hosts    map[string]*ParallelRecord `json:"hosts"`
+		filePath string
+	}
+)
+
+// NewParallelTuner 初始化自动调整并发量, initial 为初始并发量, max 为并发量上限
+func NewParallelTuner(initial, max int) *ParallelTuner {
+	if max < 1 {
+		max = 1
+	}
+	if initial < 1 {
+		initial = 1
+	}
+	if initial > max {
+		initial = max
+	}
+	return &ParallelTuner{
+		limit:      initial,
+		ceiling:    max,
+		maxCeiling: max,
+		bestLimit:  initial,
+	}
+}
+
+// Limit 返回当前允许的并发量
+func (pt *ParallelTuner) Limit() int {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.limit
+}
+
+// Best 返回速度最快时的并发量和速度, 速度为 0 表示还没有足够的采样
+func (pt *ParallelTuner) Best() (parallel int, speeds int64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.bestLimit, pt.bestSpeeds
+}
+
+// Throttle 记录一次限流 (403/429), 下一轮调整时减少连接
+func (pt *ParallelTuner) Throttle() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.throttled = true
+	pt.lastThrottle = time.Now()
+}
+
+// InCooldown 是否处于限流后的等待期
+func (pt *ParallelTuner) InCooldown() bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return time.Since(pt.lastThrottle) < autoTuneThrottleCooldown
+}
+
+// Sample 记录一次速度采样, 每 autoTuneSamples 次采样调整一次并发量
+func (pt *ParallelTuner) Sample(speeds int64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.sumSpeeds += speeds
+	pt.samples++
+	if pt.samples < autoTuneSamples {
+		return
+	}
+
+	avg := pt.sumSpeeds / int64(pt.samples)
+	pt.sumSpeeds, pt.samples = 0, 0
+	oldLimit := pt.limit
+	if !pt.throttled && pt.ceiling < pt.maxCeiling {
+		pt.calmRounds++
+	}
+
+	switch {
+	case pt.throttled:
+		// 连接数过多, 降低上限并减少连接
+		pt.throttled = false
+		pt.ceiling = pt.limit - 1
+		if pt.ceiling < 1 {
+			pt.ceiling = 1
+		}
+		pt.limit = pt.limit * 3 / 4
+		if pt.limit >= oldLimit {
+			pt.limit = oldLimit - 1
+		}
+		if pt.limit < 1 {
+			pt.limit = 1
+		}
+		// 重新统计最快速度
+		pt.bestLimit, pt.bestSpeeds = pt.limit, 0
+		pt.stableRounds, pt.calmRounds = 0, 0
+	case pt.ceiling < pt.maxCeiling && pt.calmRounds >= autoTuneCeilingRecover:
+		// 一段时间没有限流, 提高上限, 并尝试增加一个连接
+		pt.calmRounds = 0
+		pt.ceiling++
+		pt.limit++
+		pt.bestSpeeds = (pt.bestSpeeds*3 + avg) / 4
+		pt.stableRounds = 0
+	case avg > pt.bestSpeeds+pt.bestSpeeds/20:
+		// 速度仍在提升, 继续增加连接
+		pt.bestLimit, pt.bestSpeeds = pt.limit, avg
+		pt.stableRounds = 0
+		step := pt.limit / 2
+		if step < 1 {
+			step = 1
+		}
+		pt.limit += step
+		if pt.limit > pt.ceiling {
+			pt.limit = pt.ceiling
+		}
+	case pt.limit > pt.bestLimit:
+		// 增加连接后速度没有提升, 回退
+		pt.limit = pt.bestLimit
+		pt.stableRounds = 0
+	default:
+		// 速度稳定, 跟随当前速度, 并定期尝试增加一个连接
+		pt.bestSpeeds = (pt.bestSpeeds*3 + avg) / 4
+		pt.stableRounds++
+		if pt.stableRounds >= autoTuneProbeRounds && pt.limit < pt.ceiling {
+			pt.limit++
+			pt.stableRounds = 0
+		}
+	}
+
+	if pt.limit != oldLimit {
+		pcsverbose.Verbosef("MONITOR: auto parallel: %d -> %d, speeds: %s/s\n", oldLimit, pt.limit, converter.ConvertFileSize(avg, 2))
+	}
+}
+
+// NewParallelHistory 初始化并发量记录, 从 filePath 中读取内容
+func NewParallelHistory(filePath string) *ParallelHistory {
+	ph := &ParallelHistory{
+		Hosts:    map[string]*ParallelRecord{},
+		filePath: filePath,
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			pcsverbose.Verbosef("DEBUG: open parallel history error: %s\n", err)
+		}
+		return ph
+	}
+	defer file.Close()
+
+	err = jsonhelper.UnmarshalData(file, ph)
+	if err != nil {
+		pcsverbose.Verbosef("DEBUG: parse parallel history error: %s\n", err)
+	}
+	if ph.Hosts == nil {
+		ph.Hosts = map[string]*ParallelRecord{}
+	}
+	return ph
+}
+
+// Get 获取主机的最佳并发量, 没有记录则返回 0
+func (ph *ParallelHistory) Get(host string) int {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	record, ok := ph.Hosts[host]
+	if !ok {
+		return 0
+	}
+	return record.Parallel
+}
+
+// Put 记录主机的最佳并发量, 并保存到文件
+func (ph *ParallelHistory) Put(host string, parallel int, speeds int64) error {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.Hosts[host] = &ParallelRecord{
+		Parallel:  parallel,
+		Speeds:    speeds,
+		UpdatedAt: time.Now().Unix(),
+	}
+
+	file, err := os.OpenFile(ph.filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return jsonhelper.MarshalData(file, ph)
+}
@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"testing"
+)
+
+// tuneStep 一轮调整: 先记录 throttles 次限流, 再以 speeds 采样 autoTuneSamples 次
+type tuneStep struct {
+	speeds      int64
+	throttles   int
+	wantLimit   int
+	wantCeiling int
+}
+
+func TestParallelTunerSample(t *testing.T) {
+	cases := []struct {
+		name         string
+		initial, max int
+		steps        []tuneStep
+	}{
+		{
+			name: "ramp up", initial: 2, max: 8,
+			steps: []tuneStep{
+				{speeds: 100, wantLimit: 3, wantCeiling: 8},
+				{speeds: 200, wantLimit: 4, wantCeiling: 8},
+				{speeds: 400, wantLimit: 6, wantCeiling: 8},
+				{speeds: 800, wantLimit: 8, wantCeiling: 8},
+				{speeds: 1600, wantLimit: 8, wantCeiling: 8},
+			},
+		},
+		{
+			name: "no improvement rolls back", initial: 2, max: 8,
+			steps: []tuneStep{
+				{speeds: 100, wantLimit: 3, wantCeiling: 8},
+				{speeds: 102, wantLimit: 2, wantCeiling: 8},
+				{speeds: 100, wantLimit: 2, wantCeiling: 8},
+			},
+		},
+		{
+			name: "throttle lowers limit and ceiling", initial: 8, max: 8,
+			steps: []tuneStep{
+				{speeds: 100, throttles: 1, wantLimit: 6, wantCeiling: 7},
+				{speeds: 100, throttles: 3, wantLimit: 4, wantCeiling: 5},
+				{speeds: 100, throttles: 1, wantLimit: 3, wantCeiling: 3},
+				{speeds: 100, throttles: 1, wantLimit: 2, wantCeiling: 2},
+				{speeds: 100, throttles: 1, wantLimit: 1, wantCeiling: 1},
+				{speeds: 100, throttles: 1, wantLimit: 1, wantCeiling: 1},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		pt := NewParallelTuner(c.initial, c.max)
+		for i, step := range c.steps {
+			runTuneStep(pt, step)
+			if pt.Limit() != step.wantLimit || pt.ceiling != step.wantCeiling {
+				t.Errorf("%s: step %d: got limit %d, ceiling %d, want limit %d, ceiling %d", c.name, i, pt.Limit(), pt.ceiling, step.wantLimit, step.wantCeiling)
+				break
+			}
+		}
+	}
+}
+
+func TestParallelTunerRecover(t *testing.T) {
+	pt := NewParallelTuner(4, 8)
+	runTuneStep(pt, tuneStep{speeds: 400, throttles: 1})
+	if pt.Limit() != 3 || pt.ceiling != 3 {
+		t.Fatalf("after throttle: got limit %d, ceiling %d", pt.Limit(), pt.ceiling)
+	}
+
+	// 没有限流的轮数不足时, 上限不变
+	for i := 1; i < autoTuneCeilingRecover; i++ {
+		runTuneStep(pt, tuneStep{speeds: 400})
+		if pt.ceiling != 3 || pt.Limit() > 3 {
+			t.Fatalf("round %d: got limit %d, ceiling %d", i, pt.Limit(), pt.ceiling)
+		}
+	}
+	// 上限提高一次, 并尝试增加一个连接
+	runTuneStep(pt, tuneStep{speeds: 400})
+	if pt.Limit() != 4 || pt.ceiling != 4 {
+		t.Fatalf("recovered: got limit %d, ceiling %d", pt.Limit(), pt.ceiling)
+	}
+	// 速度提升, 保留新的连接
+	runTuneStep(pt, tuneStep{speeds: 600})
+	if pt.Limit() != 4 {
+		t.Fatalf("after recover: got limit %d", pt.Limit())
+	}
+
+	// 再次限流, 重新计算
+	runTuneStep(pt, tuneStep{speeds: 600, throttles: 1})
+	if pt.Limit() != 3 || pt.ceiling != 3 || pt.calmRounds != 0 {
+		t.Fatalf("throttled again: got limit %d, ceiling %d, calm rounds %d", pt.Limit(), pt.ceiling, pt.calmRounds)
+	}
+
+	// 恢复到最大值后不再提高
+	for i := 0; i < autoTuneCeilingRecover*10; i++ {
+		runTuneStep(pt, tuneStep{speeds: 600})
+	}
+	if pt.ceiling != 8 || pt.Limit() > 8 {
+		t.Fatalf("fully recovered: got limit %d, ceiling %d", pt.Limit(), pt.ceiling)
+	}
+}
+
+func runTuneStep(pt *ParallelTuner, step tuneStep) {
+	for i := 0; i < step.throttles; i++ {
+		pt.Throttle()
+	}
+	for i := 0; i < autoTuneSamples; i++ {
+		pt.Sample(step.speeds)
+	}
+}
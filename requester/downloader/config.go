@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio/speeds"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/transfer"
 )
 
@@ -22,10 +23,14 @@ type Config struct {
 	CacheSize                  int                        // 下载缓冲
 	BlockSize                  int64                      // 每个Range区块的大小, RangeGenMode 为 RangeGenMode2 时才有效
 	MaxRate                    int64                      // 限制最大下载速度
+	RateLimit                  *speeds.RateLimit          // 多个下载共享的限速器, 设置后忽略 MaxRate
 	InstanceStateStorageFormat InstanceStateStorageFormat // 断点续传储存类型
 	InstanceStatePath          string                     // 断点续传信息路径
 	IsTest                     bool                       // 是否测试下载
 	TryHTTP                    bool                       // 是否尝试使用 http 连接
+	AutoParallel               bool                       // 根据下载速度自动调整并发量, MaxParallel 为上限
+	ParallelHistory            *ParallelHistory           // 各个主机的最佳并发量记录, 自动调整并发量时使用
+	MirrorHealth               *MirrorHealth              // 各个下载服务器的健康状况, 用于分配 worker
 }
 
 //NewConfig 返回默认配置
@@ -38,6 +38,7 @@ type (
 		onDownloadStatusEvent DownloadStatusFunc //状态处理事件
 
 		monitorCancelFunc context.CancelFunc
+		ctx               context.Context
 
 		firstInfo               *DownloadFirstInfo      // 初始信息
 		loadBalancerCompareFunc LoadBalancerCompareFunc // 负载均衡检测函数
@@ -47,6 +48,7 @@ type (
 		durl                    string
 		loadBalansers           []string
 		writer                  io.WriterAt
+		readRange               *transfer.Range // 只下载的数据范围, 为 nil 时下载整个文件
 		client                  *requester.HTTPClient
 		config                  *Config
 		monitor                 *Monitor
@@ -70,6 +72,11 @@ func NewDownloader(durl string, writer io.WriterAt, config *Config) (der *Downlo
 	return
 }
 
+// SetContext 设置 context, ctx 取消后中断下载, 保留断点续传信息
+func (der *Downloader) SetContext(ctx context.Context) {
+	der.ctx = ctx
+}
+
 // SetFirstInfo 设置初始信息
 // 如果设置了此值, 将忽略检测url
 func (der *Downloader) SetFirstInfo(i *DownloadFirstInfo) {
@@ -94,6 +101,24 @@ func (der *Downloader) SetFileContentLength(length int64) {
 		}
 	}
 }
+
+// SetRange 只下载文件中 [begin, end) 范围的数据, 写入 writer 的偏移量相对于 begin,
+// 需要同时使用 SetFirstInfo 或 SetFileContentLength 设置整个文件的大小
+func (der *Downloader) SetRange(begin, end int64) {
+	der.readRange = &transfer.Range{
+		Begin: begin,
+		End:   end,
+	}
+}
+
+// rangeBounds 返回需要下载的数据范围
+func (der *Downloader) rangeBounds(status *transfer.DownloadStatus) (begin, end int64) {
+	if der.readRange != nil {
+		return der.readRange.Begin, der.readRange.End
+	}
+	return 0, status.TotalSize()
+}
+
 // SetLoadBalancerCompareFunc 设置负载均衡检测函数
 func (der *Downloader) SetLoadBalancerCompareFunc(f LoadBalancerCompareFunc) {
 	der.loadBalancerCompareFunc = f
@@ -143,6 +168,35 @@ func (der *Downloader) SelectParallel(single bool, maxParallel int, totalSize in
 	return
 }
 
+// SelectAutoParallel 获取自动调整并发量的初始值, 优先使用该主机的历史记录
+func (der *Downloader) SelectAutoParallel(parallel int) (initial int) {
+	if der.config.ParallelHistory != nil {
+		initial = der.config.ParallelHistory.Get(URLHost(der.durl))
+	}
+	if initial < 1 {
+		initial = AutoParallelInit
+	}
+	if initial > parallel {
+		initial = parallel
+	}
+	return
+}
+
+// saveAutoParallel 记录该主机的最佳并发量
+func (der *Downloader) saveAutoParallel(tuner *ParallelTuner) {
+	if tuner == nil || der.config.ParallelHistory == nil {
+		return
+	}
+	parallel, speeds := tuner.Best()
+	if speeds <= 0 { // 下载时间太短, 没有足够的采样
+		return
+	}
+	err := der.config.ParallelHistory.Put(URLHost(der.durl), parallel, speeds)
+	if err != nil {
+		pcsverbose.Verbosef("DEBUG: save parallel history error: %s\n", err)
+	}
+}
+
 // SelectBlockSizeAndInitRangeGen 获取合适的 BlockSize, 和初始化 RangeGen
 func (der *Downloader) SelectBlockSizeAndInitRangeGen(single bool, status *transfer.DownloadStatus, parallel int) (blockSize int64, initErr error) {
 	// Range 生成器
@@ -152,9 +206,10 @@ func (der *Downloader) SelectBlockSizeAndInitRangeGen(single bool, status *trans
 	}
 	gen := status.RangeListGen()
 	if gen == nil {
+		begin, end := der.rangeBounds(status)
 		switch der.config.Mode {
 		case transfer.RangeGenMode_Default:
-			gen = transfer.NewRangeListGenDefault(status.TotalSize(), 0, 0, parallel)
+			gen = transfer.NewRangeListGenDefault(end, begin, 0, parallel)
 			blockSize = gen.LoadBlockSize()
 		case transfer.RangeGenMode_BlockSize:
 			//b2 := status.TotalSize()/int64(parallel) + 1
@@ -173,7 +228,7 @@ func (der *Downloader) SelectBlockSizeAndInitRangeGen(single bool, status *trans
 			} else {
 				blockSize = BlockSizeList[4]
 			}
-			gen = transfer.NewRangeListGenBlockSize(totalSize, 0, blockSize)
+			gen = transfer.NewRangeListGenBlockSize(end, begin, blockSize)
 		default:
 			initErr = transfer.ErrUnknownRangeGenMode
 			return
@@ -239,19 +294,31 @@ func (der *Downloader) checkLoadBalancers() *LoadBalancerResponseList {
 	wg := waitgroup.NewWaitGroup(4)
 	privTimeout := der.client.Client.Timeout
 	der.client.SetTimeout(5 * time.Second)
-	for _, loadBalanser := range der.loadBalansers { // 这里服务器列表数量为0，所以逻辑实际未生效
+	for _, loadBalanser := range der.loadBalansers { // 只有定位下载返回多个下载服务器时才生效
+		if der.config.MirrorHealth != nil && der.config.MirrorHealth.IsBlocked(URLHost(loadBalanser)) {
+			pcsverbose.Verbosef("DEBUG: loadBalanser blocked: %s\n", loadBalanser)
+			continue
+		}
+
 		wg.AddDelta()
 		go func(loadBalanser string) {
 			defer wg.Done()
 
+			startTime := time.Now()
 			subContentLength, subResp, subErr := der.durlCheckFunc(der.client, loadBalanser)
 			if subResp != nil {
 				subResp.Body.Close() // 不读Body, 马上关闭连接
 			}
 			if subErr != nil {
+				if der.config.MirrorHealth != nil {
+					der.config.MirrorHealth.ObserveError(URLHost(loadBalanser))
+				}
 				pcsverbose.Verbosef("DEBUG: loadBalanser Error: %s\n", subErr)
 				return
 			}
+			if der.config.MirrorHealth != nil && subResp.StatusCode/100 == 2 {
+				der.config.MirrorHealth.ObserveResponse(URLHost(loadBalanser), time.Since(startTime))
+			}
 
 			// 检测状态码
 			switch subResp.StatusCode / 100 {
@@ -342,6 +409,9 @@ func (der *Downloader) Execute() error {
 		single                   = der.firstInfo.AcceptRanges == ""
 		bii                      *transfer.DownloadInstanceInfo
 	)
+	if single && der.readRange != nil {
+		return ErrRangeNotSupported
+	}
 
 	if !single {
 		//load breakpoint
@@ -368,10 +438,16 @@ func (der *Downloader) Execute() error {
 		// 新建状态
 		status = transfer.NewDownloadStatus()
 		status.SetTotalSize(der.firstInfo.ContentLength)
+		if der.readRange != nil {
+			status.SetTotalSize(der.readRange.End - der.readRange.Begin)
+		}
 	}
 
 	// 设置限速
-	if der.config.MaxRate > 0 {
+	if der.config.RateLimit != nil {
+		// 共享的限速器, 由外部管理, 不在这里停止
+		status.SetRateLimit(der.config.RateLimit)
+	} else if der.config.MaxRate > 0 {
 		rl := speeds.NewRateLimit(der.config.MaxRate)
 		status.SetRateLimit(rl)
 		defer rl.Stop()
@@ -389,6 +465,13 @@ func (der *Downloader) Execute() error {
 
 	pcsverbose.Verbosef("DEBUG: download task CREATED: parallel: %d, cache size: %d\n", parallel, cacheSize)
 
+	// 自动调整并发量, parallel 作为上限
+	var tuner *ParallelTuner
+	if der.config.AutoParallel && !single && parallel > 1 {
+		tuner = NewParallelTuner(der.SelectAutoParallel(parallel), parallel)
+		pcsverbose.Verbosef("DEBUG: auto parallel enabled, initial: %d, max: %d\n", tuner.Limit(), parallel)
+	}
+
 	der.monitor.InitMonitorCapacity(parallel)
 
 	var writer Writer
@@ -401,6 +484,12 @@ func (der *Downloader) Execute() error {
 			}
 		}
 		writer = der.writer // 非测试模式, 赋值writer
+		if der.readRange != nil {
+			writer = &offsetWriterAt{
+				WriterAt: der.writer,
+				offset:   der.readRange.Begin,
+			}
+		}
 	}
 
 	// 数据平均分配给各个线程
@@ -413,13 +502,25 @@ func (der *Downloader) Execute() error {
 			bii.Ranges = append(bii.Ranges, &transfer.Range{})
 		} else {
 			gen := status.RangeListGen()
-			for i := 0; i < cap(bii.Ranges); i++ {
+			initial := cap(bii.Ranges)
+			if tuner != nil {
+				initial = tuner.Limit()
+			}
+			for i := 0; i < initial; i++ {
 				_, r := gen.GenRange()
 				if r == nil { // 没有了（不正常）
 					break
 				}
 				bii.Ranges = append(bii.Ranges, r)
 			}
+			// 其余的 worker 先空闲, 由 monitor 按照自动调整的并发量分配 range
+			_, end := der.rangeBounds(status)
+			for tuner != nil && len(bii.Ranges) < cap(bii.Ranges) {
+				bii.Ranges = append(bii.Ranges, &transfer.Range{
+					Begin: end,
+					End:   end,
+				})
+			}
 		}
 	}
 
@@ -427,7 +528,13 @@ func (der *Downloader) Execute() error {
 		writeMu = &sync.Mutex{}
 	)
 	for k, r := range bii.Ranges {
-		loadBalancer := loadBalancerResponseList.SequentialGet()
+		var loadBalancer *LoadBalancerResponse
+		if der.config.MirrorHealth != nil {
+			loadBalancer = der.config.MirrorHealth.Pick(loadBalancerResponseList)
+		}
+		if loadBalancer == nil {
+			loadBalancer = loadBalancerResponseList.SequentialGet()
+		}
 		if loadBalancer == nil {
 			continue
 		}
@@ -437,6 +544,7 @@ func (der *Downloader) Execute() error {
 		worker.SetWriteMutex(writeMu)
 		worker.SetReferer(loadBalancer.Referer)
 		worker.SetTotalSize(der.firstInfo.ContentLength)
+		worker.SetMirrorHealth(der.config.MirrorHealth)
 
 		// 使用第一个连接
 		// 断点续传时不使用
@@ -450,11 +558,17 @@ func (der *Downloader) Execute() error {
 	}
 
 	der.monitor.SetStatus(status)
+	der.monitor.SetParallelTuner(tuner)
+	der.monitor.SetMirrors(loadBalancerResponseList, der.config.MirrorHealth)
 
 	// 服务器不支持断点续传, 或者单线程下载, 都不重载worker
 	der.monitor.SetReloadWorker(parallel > 1)
 
-	moniterCtx, moniterCancelFunc := context.WithCancel(context.Background())
+	parentCtx := der.ctx
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	moniterCtx, moniterCancelFunc := context.WithCancel(parentCtx)
 	der.monitorCancelFunc = moniterCancelFunc
 
 	der.monitor.SetInstanceState(der.instanceState)
@@ -463,7 +577,9 @@ func (der *Downloader) Execute() error {
 	der.executeTime = time.Now()
 	pcsutil.Trigger(der.onExecuteEvent)
 	der.downloadStatusEvent() // 启动执行状态处理事件
+	untrack := trackStatus(status)
 	der.monitor.Execute(moniterCtx)
+	untrack()
 
 	// 检查错误
 	err = der.monitor.Err()
@@ -472,6 +588,7 @@ func (der *Downloader) Execute() error {
 		if !single {
 			der.removeInstanceState() // 移除断点续传文件
 		}
+		der.saveAutoParallel(tuner)
 	}
 
 	// 执行结束
@@ -0,0 +1,43 @@
+package downloader
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/transfer"
+	"sync"
+)
+
+var (
+	downloadedBytes = metrics.NewCounterVec("baidupcs_download_bytes_total", "Number of bytes downloaded.")
+
+	activeStatuses   = map[*transfer.DownloadStatus]struct{}{}
+	activeStatusesMu sync.Mutex
+)
+
+func init() {
+	metrics.NewGaugeFunc("baidupcs_download_speed_bytes", "Current download speed in bytes per second, summed over running downloads.", func() float64 {
+		activeStatusesMu.Lock()
+		defer activeStatusesMu.Unlock()
+		var total int64
+		for status := range activeStatuses {
+			total += status.SpeedsPerSecond()
+		}
+		return float64(total)
+	})
+	metrics.NewGaugeFunc("baidupcs_downloads_active", "Number of running downloads.", func() float64 {
+		activeStatusesMu.Lock()
+		defer activeStatusesMu.Unlock()
+		return float64(len(activeStatuses))
+	})
+}
+
+// trackStatus 记录正在下载的状态, 用于统计总速度, 返回取消记录的函数
+func trackStatus(status *transfer.DownloadStatus) (untrack func()) {
+	activeStatusesMu.Lock()
+	activeStatuses[status] = struct{}{}
+	activeStatusesMu.Unlock()
+	return func() {
+		activeStatusesMu.Lock()
+		delete(activeStatuses, status)
+		activeStatusesMu.Unlock()
+	}
+}
@@ -0,0 +1,249 @@
+package downloader
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mirrorEvictConsecutiveErrors = 3                      // 连续失败多少次后剔除主机
+	mirrorEvictMinRequests       = 5                      // 按失败率剔除主机前, 至少需要的请求次数
+	mirrorEvictErrorRate         = 0.5                    // 失败率超过此值则剔除主机
+	mirrorLatencyBase            = 500 * time.Millisecond // 延迟评分的基准值
+)
+
+type (
+	// MirrorStat 下载服务器 (镜像) 的统计信息
+	MirrorStat struct {
+		Host        string
+		Requests    int           // 请求次数
+		Errors      int           // 失败次数
+		Latency     time.Duration // 平均响应时间
+		Speeds      int64         // 单个连接的平均速度
+		Downloaded  int64         // 已下载的数据量
+		Score       float64       // 健康评分, 0 ~ 1
+		Evicted     bool          // 是否已被剔除
+		consecutive int           // 连续失败次数
+	}
+
+	// MirrorHealth 记录各个下载服务器的延迟, 速度和失败率, 并据此分配 worker
+	MirrorHealth struct {
+		mu        sync.Mutex
+		mirrors   map[string]*MirrorStat
+		blocklist []string
+	}
+)
+
+// NewMirrorHealth 初始化 MirrorHealth, blocklist 为禁止使用的主机,
+// 以 . 或 *. 开头的表示该域名及其所有子域名
+func NewMirrorHealth(blocklist []string) *MirrorHealth {
+	mh := &MirrorHealth{
+		mirrors: map[string]*MirrorStat{},
+	}
+	for _, host := range blocklist {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" {
+			continue
+		}
+		mh.blocklist = append(mh.blocklist, strings.TrimPrefix(host, "*"))
+	}
+	return mh
+}
+
+// URLHost 返回下载地址的主机名
+func URLHost(durl string) string {
+	u, err := url.Parse(durl)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// IsBlocked 主机是否在禁止列表中
+func (mh *MirrorHealth) IsBlocked(host string) bool {
+	host = strings.ToLower(host)
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i] // 去掉端口
+	}
+	for _, b := range mh.blocklist {
+		if host == b || (strings.HasPrefix(b, ".") && (strings.HasSuffix(host, b) || host == b[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mh *MirrorHealth) stat(host string) *MirrorStat {
+	ms, ok := mh.mirrors[host]
+	if !ok {
+		ms = &MirrorStat{
+			Host: host,
+		}
+		mh.mirrors[host] = ms
+	}
+	return ms
+}
+
+// ObserveResponse 记录一次成功的响应和响应时间
+func (mh *MirrorHealth) ObserveResponse(host string, latency time.Duration) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	ms := mh.stat(host)
+	ms.Requests++
+	ms.consecutive = 0
+	if ms.Latency == 0 {
+		ms.Latency = latency
+	} else {
+		ms.Latency = (ms.Latency*3 + latency) / 4
+	}
+}
+
+// ObserveError 记录一次失败, 失败过多的主机会被剔除
+func (mh *MirrorHealth) ObserveError(host string) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	ms := mh.stat(host)
+	ms.Requests++
+	ms.Errors++
+	ms.consecutive++
+	if ms.Evicted {
+		return
+	}
+	if ms.consecutive >= mirrorEvictConsecutiveErrors ||
+		(ms.Requests >= mirrorEvictMinRequests && float64(ms.Errors)/float64(ms.Requests) > mirrorEvictErrorRate) {
+		ms.Evicted = true
+		pcsverbose.Verbosef("DEBUG: mirror evicted: %s, requests: %d, errors: %d\n", host, ms.Requests, ms.Errors)
+	}
+}
+
+// ObserveTransfer 记录一个连接传输的数据量和耗时
+func (mh *MirrorHealth) ObserveTransfer(host string, size int64, elapsed time.Duration) {
+	if size <= 0 || elapsed <= 0 {
+		return
+	}
+	speeds := int64(float64(size) / elapsed.Seconds())
+
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	ms := mh.stat(host)
+	ms.Downloaded += size
+	if ms.Speeds == 0 {
+		ms.Speeds = speeds
+	} else {
+		ms.Speeds = (ms.Speeds*3 + speeds) / 4
+	}
+}
+
+// Evicted 主机是否已被剔除
+func (mh *MirrorHealth) Evicted(host string) bool {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	ms, ok := mh.mirrors[host]
+	return ok && ms.Evicted
+}
+
+// score 计算健康评分, 调用前需加锁
+func (mh *MirrorHealth) score(host string, maxSpeeds int64) float64 {
+	ms, ok := mh.mirrors[host]
+	if !ok {
+		return 1 // 没有统计信息, 给予最高分以便尝试
+	}
+
+	speedsFactor := 1.0
+	if ms.Speeds > 0 && maxSpeeds > 0 {
+		speedsFactor = 0.2 + 0.8*float64(ms.Speeds)/float64(maxSpeeds)
+	}
+	errorFactor := 1.0
+	if ms.Requests > 0 {
+		errorFactor = 1 - float64(ms.Errors)/float64(ms.Requests)
+		errorFactor *= errorFactor
+	}
+	latencyFactor := 1 / (1 + float64(ms.Latency)/float64(mirrorLatencyBase))
+	return speedsFactor * errorFactor * latencyFactor
+}
+
+func (mh *MirrorHealth) maxSpeeds() (max int64) {
+	for _, ms := range mh.mirrors {
+		if !ms.Evicted && ms.Speeds > max {
+			max = ms.Speeds
+		}
+	}
+	return
+}
+
+// Pick 按照健康评分加权随机选择一个下载服务器, 跳过被禁止和被剔除的主机,
+// 如果全部被剔除, 则返回评分最高的
+func (mh *MirrorHealth) Pick(lbrl *LoadBalancerResponseList) *LoadBalancerResponse {
+	if lbrl == nil || len(lbrl.lbr) == 0 {
+		return nil
+	}
+
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	var (
+		maxSpeeds = mh.maxSpeeds()
+		scores    = make([]float64, len(lbrl.lbr))
+		total     float64
+		best      = -1
+	)
+	for k, lbr := range lbrl.lbr {
+		host := URLHost(lbr.URL)
+		if mh.IsBlocked(host) {
+			scores[k] = -1
+			continue
+		}
+		scores[k] = mh.score(host, maxSpeeds)
+		if best < 0 || scores[k] > scores[best] {
+			best = k
+		}
+		if ms, ok := mh.mirrors[host]; ok && ms.Evicted {
+			continue
+		}
+		total += scores[k]
+	}
+
+	if best < 0 {
+		return nil
+	}
+	if total <= 0 {
+		return lbrl.lbr[best]
+	}
+
+	r := ran.Float64() * total
+	for k, lbr := range lbrl.lbr {
+		if scores[k] < 0 {
+			continue
+		}
+		if ms, ok := mh.mirrors[URLHost(lbr.URL)]; ok && ms.Evicted {
+			continue
+		}
+		r -= scores[k]
+		if r <= 0 {
+			return lbr
+		}
+	}
+	return lbrl.lbr[best]
+}
+
+// Stats 返回所有下载服务器的统计信息, 按评分从高到低排列
+func (mh *MirrorHealth) Stats() []MirrorStat {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	maxSpeeds := mh.maxSpeeds()
+	stats := make([]MirrorStat, 0, len(mh.mirrors))
+	for host, ms := range mh.mirrors {
+		stat := *ms
+		stat.Score = mh.score(host, maxSpeeds)
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Score > stats[j].Score
+	})
+	return stats
+}
@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMirrorHealthIsBlocked(t *testing.T) {
+	mh := NewMirrorHealth([]string{"bad.example.com", " *.cdn.example.com ", ".Other.com", ""})
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{"bad.example.com", true},
+		{"BAD.example.com:443", true},
+		{"good.example.com", false},
+		{"a.cdn.example.com", true},
+		{"cdn.example.com", true},
+		{"xcdn.example.com", false},
+		{"x.other.com", true},
+		{"other.com:80", true},
+		{"another.com", false},
+		{"[::1]", false},
+	}
+	for _, c := range cases {
+		if got := mh.IsBlocked(c.host); got != c.want {
+			t.Errorf("IsBlocked(%s): got %v, want %v", c.host, got, c.want)
+		}
+	}
+}
+
+func TestMirrorHealthScore(t *testing.T) {
+	mh := NewMirrorHealth(nil)
+	if score := mh.score("unknown", 0); score != 1 {
+		t.Errorf("unknown host: got %f, want 1", score)
+	}
+
+	mh.ObserveResponse("fast", 100*time.Millisecond)
+	mh.ObserveTransfer("fast", 10<<20, time.Second)
+	mh.ObserveResponse("slow", 100*time.Millisecond)
+	mh.ObserveTransfer("slow", 1<<20, time.Second)
+	mh.ObserveResponse("flaky", 100*time.Millisecond)
+	mh.ObserveTransfer("flaky", 10<<20, time.Second)
+	mh.ObserveError("flaky")
+	mh.ObserveResponse("laggy", 2*time.Second)
+	mh.ObserveTransfer("laggy", 10<<20, time.Second)
+
+	max := mh.maxSpeeds()
+	fast, slow, flaky, laggy := mh.score("fast", max), mh.score("slow", max), mh.score("flaky", max), mh.score("laggy", max)
+	if !(fast > slow && fast > flaky && fast > laggy) {
+		t.Errorf("fast should score highest: fast %f, slow %f, flaky %f, laggy %f", fast, slow, flaky, laggy)
+	}
+	if fast <= 0 || fast > 1 || slow <= 0 || flaky <= 0 || laggy <= 0 {
+		t.Errorf("scores out of range: fast %f, slow %f, flaky %f, laggy %f", fast, slow, flaky, laggy)
+	}
+}
+
+func TestMirrorHealthEvict(t *testing.T) {
+	mh := NewMirrorHealth(nil)
+	for i := 0; i < mirrorEvictConsecutiveErrors-1; i++ {
+		mh.ObserveError("a")
+	}
+	mh.ObserveResponse("a", time.Millisecond)
+	mh.ObserveError("a")
+	if mh.Evicted("a") {
+		t.Errorf("consecutive errors reset by a response")
+	}
+	for i := 0; i < mirrorEvictConsecutiveErrors; i++ {
+		mh.ObserveError("b")
+	}
+	if !mh.Evicted("b") {
+		t.Errorf("b should be evicted after %d consecutive errors", mirrorEvictConsecutiveErrors)
+	}
+}
+
+func TestMirrorHealthPick(t *testing.T) {
+	lbrl := NewLoadBalancerResponseList([]*LoadBalancerResponse{
+		{URL: "https://blocked.example.com/file"},
+		{URL: "https://evicted.example.com/file"},
+		{URL: "https://good.example.com/file"},
+	})
+
+	mh := NewMirrorHealth([]string{"blocked.example.com"})
+	for i := 0; i < mirrorEvictConsecutiveErrors; i++ {
+		mh.ObserveError("evicted.example.com")
+	}
+	for i := 0; i < 100; i++ {
+		lb := mh.Pick(lbrl)
+		if lb == nil || URLHost(lb.URL) != "good.example.com" {
+			t.Fatalf("got %v, want good.example.com", lb)
+		}
+	}
+
+	// 全部被剔除时, 返回评分最高的未被禁止的主机
+	for i := 0; i < mirrorEvictConsecutiveErrors*2; i++ {
+		mh.ObserveError("good.example.com")
+	}
+	if lb := mh.Pick(lbrl); lb == nil || URLHost(lb.URL) == "blocked.example.com" {
+		t.Errorf("all evicted: got %v", lb)
+	}
+
+	// 全部被禁止
+	mh = NewMirrorHealth([]string{".example.com"})
+	if lb := mh.Pick(lbrl); lb != nil {
+		t.Errorf("all blocked: got %v, want nil", lb)
+	}
+	if lb := mh.Pick(nil); lb != nil {
+		t.Errorf("nil list: got %v, want nil", lb)
+	}
+
+	// 加权随机, 评分高的主机被选中的次数更多
+	lbrl = NewLoadBalancerResponseList([]*LoadBalancerResponse{
+		{URL: "https://fast.example.com/file"},
+		{URL: "https://slow.example.com/file"},
+	})
+	mh = NewMirrorHealth(nil)
+	mh.ObserveResponse("fast.example.com", 50*time.Millisecond)
+	mh.ObserveTransfer("fast.example.com", 10<<20, time.Second)
+	mh.ObserveResponse("slow.example.com", 2*time.Second)
+	mh.ObserveTransfer("slow.example.com", 1<<20, time.Second)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[URLHost(mh.Pick(lbrl).URL)]++
+	}
+	if counts["fast.example.com"] <= counts["slow.example.com"] || counts["slow.example.com"] == 0 {
+		t.Errorf("weighted pick: got %v", counts)
+	}
+}
@@ -3,15 +3,22 @@ package downloader
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcstable"
 	"github.com/qjfoidnh/BaiduPCS-Go/pcsverbose"
 	"github.com/qjfoidnh/BaiduPCS-Go/requester/transfer"
+	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var (
 	//ErrNoWokers no workers
 	ErrNoWokers = errors.New("no workers")
+	// ErrRangeNotSupported 服务器不支持 Range 请求, 无法只下载部分数据
+	ErrRangeNotSupported = errors.New("server does not support range requests")
 )
 
 type (
@@ -23,10 +30,14 @@ type (
 		completed       chan struct{}
 		err             error
 		resetController *ResetController
-		isReloadWorker  bool //是否重载worker, 单线程模式不重载
+		isReloadWorker  bool           //是否重载worker, 单线程模式不重载
+		tuner           *ParallelTuner // 自动调整并发量, 为空则不限制
+		loadBalancers   *LoadBalancerResponseList
+		mirrorHealth    *MirrorHealth // 下载服务器的健康状况, 为空则不切换服务器
 
 		// 临时变量
 		lastAvaliableIndex int
+		throttledWorkers   map[*Worker]struct{} // 已统计过的限流 worker
 	}
 
 	// RangeWorkerFunc 遍历workers的函数
@@ -79,6 +90,17 @@ func (mt *Monitor) SetInstanceState(instanceState *InstanceState) {
 	mt.instanceState = instanceState
 }
 
+//SetParallelTuner 设置自动调整并发量
+func (mt *Monitor) SetParallelTuner(tuner *ParallelTuner) {
+	mt.tuner = tuner
+}
+
+//SetMirrors 设置可用的下载服务器和健康状况统计
+func (mt *Monitor) SetMirrors(lbrl *LoadBalancerResponseList, mh *MirrorHealth) {
+	mt.loadBalancers = lbrl
+	mt.mirrorHealth = mh
+}
+
 //Status 返回DownloadStatus
 func (mt *Monitor) Status() *transfer.DownloadStatus {
 	return mt.status
@@ -127,6 +149,75 @@ func (mt *Monitor) NumLeftWorkers() (num int) {
 	return
 }
 
+//NumActiveWorkers 占用连接的worker数量, 不包括已完成和被限流的
+func (mt *Monitor) NumActiveWorkers() (num int) {
+	for _, worker := range mt.workers {
+		if worker.Completed() {
+			continue
+		}
+		switch worker.GetStatus().StatusCode() {
+		case StatusCodeTooManyConnections, StatusCodeInternalError:
+			continue
+		}
+		num++
+	}
+	return
+}
+
+// numDownloadingWorkers 正在下载的worker数量
+func (mt *Monitor) numDownloadingWorkers() (num int) {
+	for _, worker := range mt.workers {
+		switch worker.GetStatus().StatusCode() {
+		case StatusCodeDownloading, StatusCodeWaitToWrite:
+			num++
+		}
+	}
+	return
+}
+
+// canAddWorker 自动调整并发量时, 是否可以再占用一个连接
+func (mt *Monitor) canAddWorker() bool {
+	return mt.tuner == nil || mt.NumActiveWorkers() < mt.tuner.Limit()
+}
+
+// pickMirror 为 worker 选择健康状况较好的下载服务器
+func (mt *Monitor) pickMirror(worker *Worker) {
+	if mt.mirrorHealth == nil {
+		return
+	}
+	lb := mt.mirrorHealth.Pick(mt.loadBalancers)
+	if lb == nil || lb.URL == worker.URL() {
+		return
+	}
+	pcsverbose.Verbosef("MONITOR: worker[%d] switch mirror: %s -> %s\n", worker.ID(), URLHost(worker.URL()), URLHost(lb.URL))
+	worker.SetURL(lb.URL)
+	worker.SetReferer(lb.Referer)
+}
+
+// switchMirror worker 出错后, 切换到其他未被剔除的下载服务器重试
+func (mt *Monitor) switchMirror(worker *Worker) bool {
+	if mt.mirrorHealth == nil || !mt.resetController.CanReset() {
+		return false
+	}
+	host := URLHost(worker.URL())
+	lb := mt.mirrorHealth.Pick(mt.loadBalancers)
+	if lb == nil {
+		return false
+	}
+	lbHost := URLHost(lb.URL)
+	if lbHost == host || mt.mirrorHealth.Evicted(lbHost) {
+		return false
+	}
+
+	pcsverbose.Verbosef("MONITOR: worker[%d] %s, switch mirror: %s -> %s\n", worker.ID(), worker.Err(), host, lbHost)
+	worker.SetURL(lb.URL)
+	worker.SetReferer(lb.Referer)
+	worker.ClearStatus()
+	mt.resetController.AddResetNum()
+	go worker.Execute()
+	return true
+}
+
 //SetReloadWorker 是否重载worker
 func (mt *Monitor) SetReloadWorker(b bool) {
 	mt.isReloadWorker = b
@@ -164,6 +255,19 @@ func (mt *Monitor) registerAllCompleted() {
 			for _, worker := range mt.workers {
 				switch worker.GetStatus().StatusCode() {
 				case StatusCodeInternalError:
+					// 自动调整并发量时, 其他连接仍在下载, 403 视为连接数过多
+					if mt.tuner != nil && worker.RespStatusCode() == http.StatusForbidden && mt.numDownloadingWorkers() > 0 {
+						worker.status.SetStatusCode(StatusCodeTooManyConnections)
+						continue
+					}
+					// 服务器拒绝访问, 切换到其他下载服务器重试.
+					// 本地写入错误, 404, 416 等与服务器无关, 切换也无法恢复
+					if worker.RespStatusCode() == http.StatusForbidden && mt.mirrorHealth != nil {
+						mt.mirrorHealth.ObserveError(URLHost(worker.URL()))
+						if mt.switchMirror(worker) {
+							continue
+						}
+					}
 					// 检测到内部错误
 					// 马上停止执行
 					mt.err = worker.Err()
@@ -203,6 +307,9 @@ func (mt *Monitor) ResetFailedAndNetErrorWorkers() {
 		}
 
 	reset:
+		if mt.mirrorHealth != nil && mt.mirrorHealth.Evicted(URLHost(mt.workers[k].URL())) {
+			mt.pickMirror(mt.workers[k])
+		}
 		mt.workers[k].Reset()
 		mt.resetController.AddResetNum()
 	}
@@ -245,6 +352,10 @@ func (mt *Monitor) TryAddNewWork() {
 		return
 	}
 
+	if !mt.canAddWorker() { // 达到自动调整的并发量
+		return
+	}
+
 	availableWorker := mt.GetAvailableWorker()
 	if availableWorker == nil {
 		return
@@ -259,6 +370,7 @@ func (mt *Monitor) TryAddNewWork() {
 
 	availableWorker.SetRange(r)
 	availableWorker.ClearStatus()
+	mt.pickMirror(availableWorker)
 
 	mt.resetController.AddResetNum()
 	pcsverbose.Verbosef("MONITOR: worker[%d] add new range: %s\n", availableWorker.ID(), r.ShowDetails())
@@ -278,6 +390,10 @@ func (mt *Monitor) DynamicSplitWorker(worker *Worker) {
 		return
 	}
 
+	if !mt.canAddWorker() {
+		return
+	}
+
 	// 筛选空闲的Worker
 	availableWorker := mt.GetAvailableWorker()
 	if availableWorker == nil || worker == availableWorker { // 没有空的
@@ -298,6 +414,7 @@ func (mt *Monitor) DynamicSplitWorker(worker *Worker) {
 	availableWorkerRange.StoreBegin(middle) // middle不能加1
 	availableWorkerRange.StoreEnd(end)
 	availableWorker.ClearStatus()
+	mt.pickMirror(availableWorker)
 
 	workerRange.StoreEnd(middle)
 
@@ -341,6 +458,85 @@ func (mt *Monitor) ResetWorker(worker *Worker) {
 	worker.Reset()
 }
 
+// tuneParallel 自动调整并发量, 统计限流的 worker, 并在等待期过后重新建立连接
+func (mt *Monitor) tuneParallel() {
+	if mt.throttledWorkers == nil {
+		mt.throttledWorkers = map[*Worker]struct{}{}
+	}
+
+	for _, worker := range mt.workers {
+		if worker.GetStatus().StatusCode() != StatusCodeTooManyConnections {
+			continue
+		}
+		if _, ok := mt.throttledWorkers[worker]; !ok {
+			pcsverbose.Verbosef("MONITOR: worker[%d] throttled: %s\n", worker.ID(), worker.Err())
+			mt.throttledWorkers[worker] = struct{}{}
+			mt.tuner.Throttle()
+		}
+	}
+
+	mt.tuner.Sample(mt.status.SpeedsPerSecond())
+	mt.shedWorkers()
+
+	if mt.tuner.InCooldown() {
+		return
+	}
+	for worker := range mt.throttledWorkers {
+		if !mt.canAddWorker() || !mt.resetController.CanReset() {
+			return
+		}
+		delete(mt.throttledWorkers, worker)
+		if worker.GetStatus().StatusCode() != StatusCodeTooManyConnections {
+			continue
+		}
+		mt.resetController.AddResetNum()
+		pcsverbose.Verbosef("MONITOR: worker[%d] retry after throttled\n", worker.ID())
+		worker.Reset()
+	}
+}
+
+// shedWorkers 并发量降低后, 停止多出的连接, 优先停止速度最慢的.
+// 停止的 worker 状态为连接数太多, 可以增加连接时再重设
+func (mt *Monitor) shedWorkers() {
+	excess := mt.NumActiveWorkers() - mt.tuner.Limit()
+	if excess <= 0 {
+		return
+	}
+
+	connected := make(WorkerList, 0, len(mt.workers))
+	for _, worker := range mt.workers {
+		switch worker.GetStatus().StatusCode() {
+		case StatusCodePending, StatusCodeDownloading, StatusCodeWaitToWrite:
+			connected = append(connected, worker)
+		}
+	}
+	sort.Slice(connected, func(i, j int) bool {
+		return connected[i].GetSpeedsPerSecond() < connected[j].GetSpeedsPerSecond()
+	})
+	for _, worker := range connected[:min(excess, len(connected))] {
+		pcsverbose.Verbosef("MONITOR: worker[%d] shed, limit: %d\n", worker.ID(), mt.tuner.Limit())
+		// 已统计, 不视为限流
+		mt.throttledWorkers[worker] = struct{}{}
+		worker.Shed()
+	}
+}
+
+//ShowWorkers 返回所有worker的状态
+func (mt *Monitor) ShowWorkers() string {
+	var (
+		builder = &strings.Builder{}
+		tb      = pcstable.NewTable(builder)
+	)
+	tb.SetHeader([]string{"#", "status", "range", "left", "speeds", "error"})
+	mt.RangeWorker(func(key int, worker *Worker) bool {
+		wrange := worker.GetRange()
+		tb.Append([]string{fmt.Sprint(worker.ID()), worker.GetStatus().StatusText(), wrange.ShowDetails(), strconv.FormatInt(wrange.Len(), 10), strconv.FormatInt(worker.GetSpeedsPerSecond(), 10), fmt.Sprint(worker.Err())})
+		return true
+	})
+	tb.Render()
+	return "\n" + builder.String()
+}
+
 //Execute 执行任务
 func (mt *Monitor) Execute(cancelCtx context.Context) {
 	if len(mt.workers) == 0 {
@@ -370,6 +566,7 @@ func (mt *Monitor) Execute(cancelCtx context.Context) {
 					pcsverbose.Verbosef("DEBUG: cancel failed, worker id: %d, err: %s\n", worker.ID(), err)
 				}
 			}
+			mt.err = cancelCtx.Err()
 			return
 		case <-mt.completed:
 			return
@@ -379,6 +576,10 @@ func (mt *Monitor) Execute(cancelCtx context.Context) {
 
 			mt.status.UpdateSpeeds() // 更新速度
 
+			if mt.tuner != nil {
+				mt.tuneParallel()
+			}
+
 			// 保存断点信息到文件
 			if mt.instanceState != nil {
 				mt.instanceState.Put(&transfer.DownloadInstanceInfo{
@@ -12,6 +12,12 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errShed = errors.New("connection shed by auto parallel")
 )
 
 type (
@@ -35,8 +41,11 @@ type (
 		resetFunc              context.CancelFunc
 		readRespBodyCancelFunc func()
 		err                    error //错误信息
+		respStatusCode         int32 //最近一次响应的状态码, 由监控器读取
+		shed                   int32 //是否被监控器停止, 停止后状态为连接数太多
 		status                 WorkerStatus
 		downloadStatus         *transfer.DownloadStatus //总的下载状态
+		mirrorHealth           *MirrorHealth            //下载服务器的健康状况统计
 	}
 
 	// WorkerList worker列表
@@ -109,6 +118,21 @@ func (wer *Worker) SetRange(r *transfer.Range) {
 	wer.wrange.StoreEnd(r.LoadEnd())
 }
 
+//URL 返回下载地址
+func (wer *Worker) URL() string {
+	return wer.url
+}
+
+//SetURL 设置下载地址, 用于切换下载服务器, 下次执行时生效
+func (wer *Worker) SetURL(durl string) {
+	wer.url = durl
+}
+
+//SetMirrorHealth 设置下载服务器的健康状况统计
+func (wer *Worker) SetMirrorHealth(mh *MirrorHealth) {
+	wer.mirrorHealth = mh
+}
+
 //SetReferer 设置来源
 func (wer *Worker) SetReferer(referer string) {
 	wer.referer = referer
@@ -189,6 +213,23 @@ func (wer *Worker) Reset() {
 	go wer.Execute()
 }
 
+// Shed 停止正在进行的连接, 不重新执行, 状态变为连接数太多, 剩余的范围之后由监控器重设继续下载
+func (wer *Worker) Shed() {
+	if wer.resetFunc == nil || wer.acceptRanges == "" { // 单线程模式无法继续下载
+		return
+	}
+	atomic.StoreInt32(&wer.shed, 1)
+	wer.resetFunc()
+	if wer.readRespBodyCancelFunc != nil {
+		wer.readRespBodyCancelFunc()
+	}
+}
+
+// RespStatusCode 返回最近一次响应的状态码
+func (wer *Worker) RespStatusCode() int {
+	return int(atomic.LoadInt32(&wer.respStatusCode))
+}
+
 // Canceled 是否已经取消
 func (wer *Worker) Canceled() bool {
 	return wer.status.statusCode == StatusCodeCanceled
@@ -224,6 +265,12 @@ func (wer *Worker) Err() error {
 	return wer.err
 }
 
+func (wer *Worker) observeError(host string) {
+	if wer.mirrorHealth != nil {
+		wer.mirrorHealth.ObserveError(host)
+	}
+}
+
 //Execute 执行任务
 func (wer *Worker) Execute() {
 	wer.lazyInit()
@@ -231,6 +278,15 @@ func (wer *Worker) Execute() {
 	wer.execMu.Lock()
 	defer wer.execMu.Unlock()
 
+	atomic.StoreInt32(&wer.shed, 0)
+	defer func() {
+		// 被监控器停止, 未完成的部分等待重设
+		if atomic.CompareAndSwapInt32(&wer.shed, 1, 0) && wer.status.statusCode != StatusCodeSuccessed {
+			wer.status.SetStatusCode(StatusCodeTooManyConnections)
+			wer.err = errShed
+		}
+	}()
+
 	wer.status.statusCode = StatusCodeInit
 	single := wer.acceptRanges == ""
 
@@ -266,7 +322,11 @@ func (wer *Worker) Execute() {
 
 	wer.status.statusCode = StatusCodePending
 
-	var resp *http.Response
+	var (
+		resp      *http.Response
+		host      = URLHost(wer.url)
+		startTime = time.Now()
+	)
 	if wer.firstResp != nil {
 		resp = wer.firstResp // 使用第一个连接
 	} else {
@@ -283,16 +343,28 @@ func (wer *Worker) Execute() {
 	}
 	if wer.err != nil {
 		wer.status.statusCode = StatusCodeNetError
+		wer.observeError(host)
 		return
 	}
 
 	// 判断响应状态
+	atomic.StoreInt32(&wer.respStatusCode, int32(resp.StatusCode))
 	switch resp.StatusCode {
 	case 200, 206:
-		// do nothing, continue
+		// 统计下载服务器的响应时间和速度
+		if wer.mirrorHealth != nil {
+			wer.mirrorHealth.ObserveResponse(host, time.Since(startTime))
+			respTime, begin := time.Now(), wer.wrange.LoadBegin()
+			defer func() {
+				wer.mirrorHealth.ObserveTransfer(host, wer.wrange.LoadBegin()-begin, time.Since(respTime))
+				if wer.status.statusCode == StatusCodeFailed {
+					wer.mirrorHealth.ObserveError(host)
+				}
+			}()
+		}
 	case 416: //Requested Range Not Satisfiable
 		fallthrough
-	case 403: // Forbidden
+	case 403: // Forbidden, 可能是限流, 由监控器判断后统计
 		fallthrough
 	case 404: // file block not exists
 		wer.status.statusCode = StatusCodeInternalError
@@ -301,14 +373,16 @@ func (wer *Worker) Execute() {
 	case 406: // Not Acceptable
 		wer.status.statusCode = StatusCodeNetError
 		wer.err = errors.New(resp.Status)
+		wer.observeError(host)
 		return
-	case 429, 509: // Too Many Requests
+	case 429, 509: // Too Many Requests, 限流不计入服务器的失败
 		wer.status.SetStatusCode(StatusCodeTooManyConnections)
 		wer.err = errors.New(resp.Status)
 		return
 	default:
 		wer.status.statusCode = StatusCodeNetError
 		wer.err = fmt.Errorf("unexpected http status code, %d, %s", resp.StatusCode, resp.Status)
+		wer.observeError(host)
 		return
 	}
 
@@ -424,6 +498,7 @@ func (wer *Worker) Execute() {
 			wer.wrange.AddBegin(n64)
 			if wer.downloadStatus != nil {
 				wer.downloadStatus.AddDownloaded(n64)
+				downloadedBytes.Add(float64(n64))
 				if single {
 					wer.downloadStatus.AddTotalSize(n64)
 				}
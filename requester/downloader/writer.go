@@ -15,8 +15,18 @@ type (
 	Writer interface {
 		io.WriterAt
 	}
+
+	// offsetWriterAt 写入时将偏移量减去 offset, 用于只下载部分数据
+	offsetWriterAt struct {
+		io.WriterAt
+		offset int64
+	}
 )
 
+func (ow *offsetWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return ow.WriterAt.WriteAt(p, off-ow.offset)
+}
+
 // NewDownloaderWriterByFilename 创建下载器数据输出接口, 类似于os.OpenFile
 func NewDownloaderWriterByFilename(name string, flag int, perm os.FileMode) (writer Writer, file *os.File, err error) {
 	file, err = os.OpenFile(name, flag, perm)
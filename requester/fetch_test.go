@@ -0,0 +1,49 @@
+package requester_test
+
+import (
+	"context"
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReqWithContextCancel(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不响应, 直到请求被取消
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	client := requester.NewHTTPClient()
+	start := time.Now()
+	resp, err := client.ReqWithContext(ctx, http.MethodGet, srv.URL, nil, nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request not interrupted in time: %s", elapsed)
+	}
+
+	// 已取消的 context, 不发起请求
+	resp, err = client.ReqWithContext(ctx, http.MethodGet, srv.URL, nil, nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: got err %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package harlog 将 http 请求和响应记录为 HAR 文件, 并可以回放记录的响应, 用于离线复现接口变化导致的问题.
+// 记录时会自动隐藏 Cookie, BDUSS, STOKEN 和 access_token 等敏感信息
+package harlog
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+const (
+	// HARVersion HAR 格式版本
+	HARVersion = "1.2"
+)
+
+type (
+	// HAR HAR 文件
+	HAR struct {
+		Log Log `json:"log"`
+	}
+
+	// Log HAR 日志
+	Log struct {
+		Version string   `json:"version"`
+		Creator Creator  `json:"creator"`
+		Entries []*Entry `json:"entries"`
+	}
+
+	// Creator 创建 HAR 文件的程序
+	Creator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	// Entry 一次请求和响应
+	Entry struct {
+		StartedDateTime time.Time `json:"startedDateTime"`
+		Time            float64   `json:"time"` // 总耗时, 毫秒
+		Request         Request   `json:"request"`
+		Response        Response  `json:"response"`
+		Cache           struct{}  `json:"cache"`
+		Timings         Timings   `json:"timings"`
+		Comment         string    `json:"comment,omitempty"`
+	}
+
+	// Request 请求
+	Request struct {
+		Method      string      `json:"method"`
+		URL         string      `json:"url"`
+		HTTPVersion string      `json:"httpVersion"`
+		Cookies     []NameValue `json:"cookies"`
+		Headers     []NameValue `json:"headers"`
+		QueryString []NameValue `json:"queryString"`
+		PostData    *PostData   `json:"postData,omitempty"`
+		HeadersSize int64       `json:"headersSize"`
+		BodySize    int64       `json:"bodySize"`
+	}
+
+	// Response 响应
+	Response struct {
+		Status      int         `json:"status"`
+		StatusText  string      `json:"statusText"`
+		HTTPVersion string      `json:"httpVersion"`
+		Cookies     []NameValue `json:"cookies"`
+		Headers     []NameValue `json:"headers"`
+		Content     Content     `json:"content"`
+		RedirectURL string      `json:"redirectURL"`
+		HeadersSize int64       `json:"headersSize"`
+		BodySize    int64       `json:"bodySize"`
+	}
+
+	// NameValue 名称和值, 用于 header, cookie 和 query
+	NameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// PostData 请求体
+	PostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	// Content 响应体
+	Content struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	// Timings 各阶段耗时, 毫秒, -1 表示不适用
+	Timings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// Load 读取 HAR 文件
+func Load(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	har := &HAR{}
+	err = json.Unmarshal(data, har)
+	if err != nil {
+		return nil, err
+	}
+	return har, nil
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,153 @@
+package harlog_test
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/harlog"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "STOKEN", Value: "secret-stoken"})
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errno":0,"access_token":"secret-token","path":"`+r.URL.Query().Get("path")+`"}`)
+	}))
+	defer server.Close()
+
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+	rec, err := harlog.NewRecorder(harPath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: rec.Transport(nil)}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/rest/2.0/file?method=list&path=/a&access_token=secret-token&t=1", nil)
+	req.AddCookie(&http.Cookie{Name: "BDUSS", Value: "secret-bduss"})
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("secrets not redacted:\n%s", data)
+	}
+	if len(rec.Entries()) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(rec.Entries()))
+	}
+
+	replayer, err := harlog.LoadReplayer(harPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayClient := &http.Client{Transport: replayer}
+	resp, err = replayClient.Get(server.URL + "/rest/2.0/file?method=list&path=/a&access_token=other&t=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	want := strings.Replace(string(original), "secret-token", harlog.Redacted, 1)
+	if string(replayed) != want {
+		t.Fatalf("replayed body:\n%s\nwant:\n%s", replayed, want)
+	}
+
+	if _, err = replayClient.Get(server.URL + "/rest/2.0/file?method=list&path=/b"); err == nil {
+		t.Fatal("expected no recorded response for another path")
+	}
+}
+
+func TestRecorderAppend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errno":0}`)
+	}))
+	defer server.Close()
+
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+	rec, err := harlog.NewRecorder(harPath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Entries()); n != 0 {
+		t.Fatalf("new recorder: got %d entries", n)
+	}
+
+	client := &http.Client{Transport: rec.Transport(nil)}
+	for i := 1; i <= 20; i++ {
+		resp, err := client.Get(server.URL + "/api?i=" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		// 每条记录写入后, 文件都是完整的 HAR
+		entries := rec.Entries()
+		if len(entries) != i {
+			t.Fatalf("after %d requests: got %d entries", i, len(entries))
+		}
+		if got := entries[i-1].Request.URL; !strings.HasSuffix(got, "i="+strconv.Itoa(i)) {
+			t.Fatalf("entry %d: got url %s", i, got)
+		}
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedactRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/file?fid=1&sign=secret-sign&token=secret-token")
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		w.WriteHeader(http.StatusFound)
+		io.WriteString(w, "fid=1&sign=secret-sign&token=secret-token")
+	}))
+	defer server.Close()
+
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+	rec, err := harlog.NewRecorder(harPath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: rec.Transport(nil),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(server.URL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("secrets not redacted:\n%s", data)
+	}
+	if got := rec.Entries()[0].Response.RedirectURL; !strings.Contains(got, "fid=1") {
+		t.Fatalf("redirect url: %s", got)
+	}
+}
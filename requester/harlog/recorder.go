@@ -0,0 +1,333 @@
+package harlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBodyLimit 默认记录的请求体和响应体的最大长度
+	DefaultBodyLimit = 64 * 1024
+	// multipartBodyLimit multipart 请求体 (通常是上传) 记录的最大长度
+	multipartBodyLimit = 1024
+	// harTrailer 写在最后一条记录之后, 使文件始终是完整的 HAR
+	harTrailer = "\n]}}\n"
+)
+
+type (
+	// Recorder 将请求和响应记录到 HAR 文件, 实现了 requester.TraceRecorder
+	Recorder struct {
+		BodyLimit int // 记录的请求体和响应体的最大长度, 二进制内容 (例如下载的文件) 不记录
+
+		path   string
+		mu     sync.Mutex
+		file   *os.File
+		offset int64 // 下一条记录的写入位置, 即结尾之前
+		count  int   // 已写入的记录数
+		err    error // 写入记录时的错误, 由 Save 返回
+	}
+
+	recorderTransport struct {
+		recorder *Recorder
+		next     http.RoundTripper
+	}
+
+	// bodyCapture 记录读取过的数据, 最多记录 limit 字节
+	bodyCapture struct {
+		io.ReadCloser
+		limit  int
+		mu     sync.Mutex
+		buf    bytes.Buffer
+		total  int64
+		once   sync.Once
+		onDone func(bc *bodyCapture)
+	}
+)
+
+// NewRecorder 初始化记录到 path 的 Recorder, 会立即创建文件, 以检查是否可写.
+// version 为写入 HAR 文件的程序版本. 记录不保存在内存中, 每条记录追加写入文件
+func NewRecorder(path, version string) (*Recorder, error) {
+	creator, err := json.Marshal(&Creator{
+		Name:    "BaiduPCS-Go",
+		Version: version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := `{"log":{"version":"` + HARVersion + `","creator":` + string(creator) + `,"entries":[`
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.WriteString(header + harTrailer)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Recorder{
+		BodyLimit: DefaultBodyLimit,
+		path:      path,
+		file:      file,
+		offset:    int64(len(header)),
+	}, nil
+}
+
+// Path 返回 HAR 文件路径
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Transport 返回通过 r 记录请求的 http.RoundTripper, next 为 nil 则使用 http.DefaultTransport
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recorderTransport{
+		recorder: r,
+		next:     next,
+	}
+}
+
+func (rt *recorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.recorder.RoundTrip(rt.next, req)
+}
+
+// Entries 从文件读取已记录的请求
+func (r *Recorder) Entries() []*Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil
+	}
+	var har HAR
+	err = json.Unmarshal(data, &har)
+	if err != nil {
+		return nil
+	}
+	return har.Log.Entries
+}
+
+// RoundTrip 通过 next 发送请求, 并记录请求和响应. 响应体读取完毕或关闭后才会写入记录
+func (r *Recorder) RoundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	entry := &Entry{
+		StartedDateTime: start,
+		Request: Request{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			HTTPVersion: req.Proto,
+			Cookies:     redactCookies(req.Cookies()),
+			Headers:     redactHeaders(req.Header),
+			QueryString: nameValues(redactQuery(req.URL.Query())),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+
+	var (
+		reqMime = req.Header.Get("Content-Type")
+		reqBody *bodyCapture
+	)
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = &bodyCapture{
+			ReadCloser: req.Body,
+			limit:      r.bodyLimit(reqMime),
+		}
+		req = req.Clone(req.Context())
+		req.Body = reqBody
+	}
+	finishRequest := func() {
+		if reqBody == nil {
+			return
+		}
+		text, comment := reqBody.text()
+		entry.Request.BodySize = reqBody.size()
+		entry.Request.PostData = &PostData{
+			MimeType: reqMime,
+			Text:     text,
+			Comment:  comment,
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	wait := time.Since(start)
+	entry.Timings.Wait = millis(wait)
+	if err != nil {
+		finishRequest()
+		entry.Time = millis(wait)
+		entry.Comment = "error: " + err.Error()
+		entry.Response = Response{
+			Cookies:     []NameValue{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		r.add(entry)
+		return nil, err
+	}
+
+	respMime := resp.Header.Get("Content-Type")
+	entry.Response = Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Cookies:     redactCookies(resp.Cookies()),
+		Headers:     redactHeaders(resp.Header),
+		RedirectURL: redactLocation(resp.Header.Get("Location")),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	resp.Body = &bodyCapture{
+		ReadCloser: resp.Body,
+		limit:      r.bodyLimit(respMime),
+		onDone: func(bc *bodyCapture) {
+			elapsed := time.Since(start)
+			finishRequest()
+			text, comment := bc.text()
+			entry.Time = millis(elapsed)
+			entry.Timings.Receive = millis(elapsed - wait)
+			entry.Response.BodySize = bc.size()
+			entry.Response.Content = Content{
+				Size:     bc.size(),
+				MimeType: respMime,
+				Text:     text,
+				Comment:  comment,
+			}
+			r.add(entry)
+		},
+	}
+	return resp, nil
+}
+
+// bodyLimit 根据内容类型返回记录的最大长度
+func (r *Recorder) bodyLimit(mimeType string) int {
+	limit := r.BodyLimit
+	mimeType = strings.ToLower(mimeType)
+	switch {
+	case strings.HasPrefix(mimeType, "multipart/"):
+		if limit > multipartBodyLimit {
+			limit = multipartBodyLimit
+		}
+		return limit
+	case mimeType == "", strings.HasPrefix(mimeType, "text/"), strings.Contains(mimeType, "json"),
+		strings.Contains(mimeType, "javascript"), strings.Contains(mimeType, "xml"), strings.Contains(mimeType, "x-www-form-urlencoded"):
+		return limit
+	}
+	// 二进制内容, 例如下载的文件
+	return 0
+}
+
+// add 在结尾之前写入一条记录, 并重新写入结尾, 不重写已有的记录
+func (r *Recorder) add(entry *Entry) {
+	data, err := json.Marshal(entry)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.err = err
+		return
+	}
+	if r.err != nil {
+		return
+	}
+
+	sep := "\n"
+	if r.count > 0 {
+		sep = ",\n"
+	}
+	record := sep + string(data)
+	_, err = r.file.WriteAt([]byte(record+harTrailer), r.offset)
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.offset += int64(len(record))
+	r.count++
+}
+
+// Save 将已写入的记录同步到磁盘, 返回写入记录时的错误
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return r.file.Sync()
+}
+
+func (bc *bodyCapture) Read(p []byte) (n int, err error) {
+	n, err = bc.ReadCloser.Read(p)
+	bc.mu.Lock()
+	bc.total += int64(n)
+	if remain := bc.limit - bc.buf.Len(); remain > 0 {
+		if remain > n {
+			remain = n
+		}
+		bc.buf.Write(p[:remain])
+	}
+	bc.mu.Unlock()
+	if err == io.EOF {
+		bc.done()
+	}
+	return
+}
+
+func (bc *bodyCapture) Close() error {
+	err := bc.ReadCloser.Close()
+	bc.done()
+	return err
+}
+
+func (bc *bodyCapture) done() {
+	if bc.onDone == nil {
+		return
+	}
+	bc.once.Do(func() {
+		bc.onDone(bc)
+	})
+}
+
+func (bc *bodyCapture) size() int64 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.total
+}
+
+// text 返回隐藏了敏感信息的内容和说明
+func (bc *bodyCapture) text() (text, comment string) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	switch {
+	case bc.limit <= 0 && bc.total > 0:
+		return "", fmt.Sprintf("二进制内容未记录, 共 %d 字节", bc.total)
+	case int64(bc.buf.Len()) < bc.total:
+		comment = fmt.Sprintf("内容已截断, 只记录了前 %d 字节, 共 %d 字节", bc.buf.Len(), bc.total)
+	}
+	return redactBody(bc.buf.String()), comment
+}
+
+func nameValues(values url.Values) []NameValue {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	nvs := make([]NameValue, 0, len(values))
+	for _, k := range keys {
+		for _, v := range values[k] {
+			nvs = append(nvs, NameValue{Name: k, Value: v})
+		}
+	}
+	return nvs
+}
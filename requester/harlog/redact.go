@@ -0,0 +1,128 @@
+package harlog
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Redacted 隐藏敏感信息后的值
+	Redacted = "REDACTED"
+)
+
+var (
+	// sensitiveParams 需要隐藏的参数名, 小写
+	sensitiveParams = map[string]bool{
+		"bduss":         true,
+		"stoken":        true,
+		"ptoken":        true,
+		"sboxtkn":       true,
+		"access_token":  true,
+		"refresh_token": true,
+		"bdstoken":      true,
+		"token":         true,
+		"sign":          true,
+	}
+
+	// sensitiveHeaders 需要隐藏值的请求头, 小写
+	sensitiveHeaders = map[string]bool{
+		"authorization":       true,
+		"proxy-authorization": true,
+	}
+
+	// sensitiveBodyRegexp 请求体和响应体中的敏感信息, 包括表单和 json 格式
+	sensitiveBodyRegexp = regexp.MustCompile(`(?i)("?\b(?:bduss|stoken|ptoken|sboxtkn|access_token|refresh_token|bdstoken|token|sign)\b"?\s*[:=]\s*"?)([^"&;,\s}]+)`)
+)
+
+// redactQuery 隐藏 query 中的敏感参数
+func redactQuery(query url.Values) url.Values {
+	redacted := make(url.Values, len(query))
+	for k, vs := range query {
+		if sensitiveParams[strings.ToLower(k)] {
+			redacted[k] = []string{Redacted}
+			continue
+		}
+		redacted[k] = vs
+	}
+	return redacted
+}
+
+// redactURL 隐藏 url 中的敏感参数
+func redactURL(u *url.URL) string {
+	u2 := *u
+	u2.User = nil
+	if u2.RawQuery != "" {
+		u2.RawQuery = redactQuery(u2.Query()).Encode()
+	}
+	return u2.String()
+}
+
+// redactLocation 隐藏重定向地址中的敏感参数, 无法解析时隐藏整个地址
+func redactLocation(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Redacted
+	}
+	return redactURL(u)
+}
+
+// redactBody 隐藏请求体和响应体中的敏感信息
+func redactBody(text string) string {
+	return sensitiveBodyRegexp.ReplaceAllString(text, "${1}"+Redacted)
+}
+
+// redactHeaders 转换 header, 隐藏 Cookie, Set-Cookie, 认证信息和重定向地址中的敏感参数
+func redactHeaders(header http.Header) (headers []NameValue) {
+	headers = make([]NameValue, 0, len(header))
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		for _, value := range values {
+			switch {
+			case lower == "cookie":
+				value = redactCookieHeader(value)
+			case lower == "set-cookie":
+				value = redactSetCookie(value)
+			case lower == "location" || lower == "content-location":
+				value = redactLocation(value)
+			case sensitiveHeaders[lower]:
+				value = Redacted
+			}
+			headers = append(headers, NameValue{Name: name, Value: value})
+		}
+	}
+	return
+}
+
+// redactCookieHeader 隐藏 Cookie 请求头中所有 cookie 的值
+func redactCookieHeader(value string) string {
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		if k := strings.Index(part, "="); k >= 0 {
+			parts[i] = part[:k+1] + Redacted
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// redactSetCookie 隐藏 Set-Cookie 响应头中 cookie 的值, 保留其他属性
+func redactSetCookie(value string) string {
+	end := strings.Index(value, ";")
+	if end < 0 {
+		end = len(value)
+	}
+	if k := strings.Index(value[:end], "="); k >= 0 {
+		return value[:k+1] + Redacted + value[end:]
+	}
+	return value
+}
+
+// redactCookies 转换 cookie, 隐藏所有值
+func redactCookies(cookies []*http.Cookie) []NameValue {
+	nvs := make([]NameValue, 0, len(cookies))
+	for _, cookie := range cookies {
+		nvs = append(nvs, NameValue{Name: cookie.Name, Value: Redacted})
+	}
+	return nvs
+}
@@ -0,0 +1,176 @@
+package harlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNoRecordedResponse 没有与请求匹配的记录
+	ErrNoRecordedResponse = errors.New("no recorded response")
+
+	// volatileParams 每次请求都会变化的参数, 匹配请求时忽略
+	volatileParams = map[string]bool{
+		"t":         true,
+		"time":      true,
+		"timestamp": true,
+		"rand":      true,
+		"logid":     true,
+		"_":         true,
+	}
+
+	// skipReplayHeaders 回放时不写入的响应头, 记录的响应体已经解压
+	skipReplayHeaders = map[string]bool{
+		"content-length":    true,
+		"content-encoding":  true,
+		"transfer-encoding": true,
+	}
+)
+
+type (
+	// Replayer 回放 HAR 文件中记录的响应, 可作为 http.RoundTripper 替换 http 客户端的 Transport,
+	// 也可作为 http.Handler 启动本地服务器, 用于离线复现问题.
+	// 请求按照方法, 路径和记录中的参数匹配 (忽略已隐藏和每次变化的参数),
+	// 同一请求有多条记录时按顺序回放, 全部回放后重复最后一条
+	Replayer struct {
+		mu      sync.Mutex
+		entries []*replayEntry
+	}
+
+	replayEntry struct {
+		*Entry
+		u      *url.URL
+		served int
+	}
+)
+
+// NewReplayer 初始化回放 har 的 Replayer
+func NewReplayer(har *HAR) *Replayer {
+	rp := &Replayer{}
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || entry.Response.Status == 0 {
+			// 无法解析的地址, 或请求出错的记录, 不回放
+			continue
+		}
+		rp.entries = append(rp.entries, &replayEntry{
+			Entry: entry,
+			u:     u,
+		})
+	}
+	return rp
+}
+
+// LoadReplayer 读取 HAR 文件, 返回 Replayer
+func LoadReplayer(path string) (*Replayer, error) {
+	har, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(har), nil
+}
+
+// Len 返回可回放的记录数
+func (rp *Replayer) Len() int {
+	return len(rp.entries)
+}
+
+// match 查找与请求匹配的记录, matchHost 为 false 时不比较主机
+func (rp *Replayer) match(method string, u *url.URL, matchHost bool) *Entry {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	var last *replayEntry
+	query := u.Query()
+	for _, re := range rp.entries {
+		if re.Request.Method != method || re.u.Path != u.Path || (matchHost && re.u.Host != u.Host) {
+			continue
+		}
+		if !queryMatch(re.u.Query(), query) {
+			continue
+		}
+		if re.served == 0 {
+			re.served++
+			return re.Entry
+		}
+		last = re
+	}
+	if last == nil {
+		return nil
+	}
+	last.served++
+	return last.Entry
+}
+
+// queryMatch 请求是否包含记录中的所有参数, 忽略已隐藏和每次变化的参数
+func queryMatch(recorded, query url.Values) bool {
+	for k, vs := range recorded {
+		if volatileParams[strings.ToLower(k)] || sensitiveParams[strings.ToLower(k)] {
+			continue
+		}
+		if len(vs) > 0 && vs[0] == Redacted {
+			continue
+		}
+		if query.Get(k) != vs[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	entry := rp.match(req.Method, req.URL, true)
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoRecordedResponse, req.Method, redactURL(req.URL))
+	}
+
+	text := entry.Response.Content.Text
+	header := http.Header{}
+	for _, h := range entry.Response.Headers {
+		if skipReplayHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(text)))
+	return &http.Response{
+		Status:        strconv.Itoa(entry.Response.Status) + " " + entry.Response.StatusText,
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(text)),
+		ContentLength: int64(len(text)),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP 实现 http.Handler, 不比较主机
+func (rp *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	entry := rp.match(r.Method, r.URL, false)
+	if entry == nil {
+		http.Error(w, ErrNoRecordedResponse.Error(), http.StatusNotFound)
+		return
+	}
+	for _, h := range entry.Response.Headers {
+		if skipReplayHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		w.Header().Add(h.Name, h.Value)
+	}
+	w.WriteHeader(entry.Response.Status)
+	io.WriteString(w, entry.Response.Content.Text)
+}
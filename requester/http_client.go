@@ -46,7 +46,7 @@ func (h *HTTPClient) lazyInit() {
 			ResponseHeaderTimeout: 60 * time.Second, // Increased timeout
 			ExpectContinueTimeout: 10 * time.Second,
 		}
-		h.Client.Transport = h.transport
+		h.Client.Transport = &traceTransport{next: h.transport}
 	}
 }
 
@@ -131,6 +131,42 @@ func (mr *MultipartReader) Read(p []byte) (n int, err error) {
 	return n, err
 }
 
+// Rewind 重置读取位置, 以便重新发送请求, 所有表单内容都需要实现 io.Seeker
+func (mr *MultipartReader) Rewind() error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if !mr.closed {
+		return errors.New("multipartreader not closed")
+	}
+
+	readers := make([]io.Reader, 0, 2+2*len(mr.parts)+2*len(mr.part64s))
+	readers = append(readers, strings.NewReader(mr.formBody))
+	for k := range mr.parts {
+		if err := seekStart(mr.parts[k].readerlen); err != nil {
+			return err
+		}
+		readers = append(readers, strings.NewReader(mr.parts[k].form), mr.parts[k].readerlen)
+	}
+	for k := range mr.part64s {
+		if err := seekStart(mr.part64s[k].readerlen64); err != nil {
+			return err
+		}
+		readers = append(readers, strings.NewReader(mr.part64s[k].form), mr.part64s[k].readerlen64)
+	}
+	readers = append(readers, strings.NewReader(mr.formClose))
+	mr.multiReader = io.MultiReader(readers...)
+	return nil
+}
+
+func seekStart(r io.Reader) error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return errors.New("multipartreader: part is not seekable")
+	}
+	_, err := seeker.Seek(0, io.SeekStart)
+	return err
+}
+
 // Len 返回表单内容总长度
 func (mr *MultipartReader) Len() int64 {
 	return atomic.LoadInt64(&mr.length)
@@ -0,0 +1,125 @@
+package requester
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// RetryPolicy 请求级别的重试策略
+	RetryPolicy struct {
+		MaxRetry   int           // 最大重试次数, 0 为不重试
+		BaseDelay  time.Duration // 第一次重试前的基础等待时间
+		MaxDelay   time.Duration // 单次等待时间上限
+		MaxElapsed time.Duration // 包括等待在内的总耗时上限, 超过则不再重试, 0 为不限制
+	}
+
+	// Rewinder 可重置读取位置的请求体, 用于重新发送请求
+	Rewinder interface {
+		Rewind() error
+	}
+)
+
+var (
+	// DefaultRetryPolicy 默认重试策略
+	DefaultRetryPolicy = RetryPolicy{
+		MaxRetry:   3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+		MaxElapsed: 2 * time.Minute,
+	}
+
+	// ErrNotReplayable 请求体不能重复发送
+	ErrNotReplayable = errors.New("request body is not replayable")
+)
+
+// Backoff 返回第 retry 次重试 (从 1 开始) 前的等待时间, 指数退避, 并在 [d/2, d] 范围内随机抖动
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+	d := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// RetryAfter 解析响应头 Retry-After, 支持秒数和 HTTP 日期两种格式
+func RetryAfter(resp *http.Response) (d time.Duration, ok bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	d = time.Until(t)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// IsRequestNotSent 判断请求是否在发送之前就失败了 (例如 DNS 解析失败, 连接被拒绝),
+// 这种情况下即使是非幂等的请求, 重试也是安全的
+func IsRequestNotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// IsTransientStatus 服务器临时性错误的 http 状态码, 可以重试
+func IsTransientStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// RewindPost 重置 post 数据, 以便重新发送请求.
+// map, string, []byte 每次请求都会重新构造请求体, 不需要处理;
+// 其他 io.Reader 需要实现 Rewinder 或 io.Seeker, 否则返回 ErrNotReplayable
+func RewindPost(post interface{}) error {
+	switch value := post.(type) {
+	case nil, string, []byte, map[string]string, map[string]interface{}, map[interface{}]interface{}:
+		return nil
+	case Rewinder:
+		return value.Rewind()
+	case *bytes.Reader:
+		_, err := value.Seek(0, io.SeekStart)
+		return err
+	case *strings.Reader:
+		_, err := value.Seek(0, io.SeekStart)
+		return err
+	}
+	return ErrNotReplayable
+}
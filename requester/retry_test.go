@@ -0,0 +1,60 @@
+package requester_test
+
+import (
+	"bytes"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/multipartreader"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := requester.RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+	// 第 1 ~ 6 次重试的等待上限
+	for i, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		retry := i + 1
+		max *= time.Millisecond
+		d := policy.Backoff(retry)
+		if d < max/2 || d > max {
+			t.Errorf("retry %d: backoff %s not in [%s, %s]", retry, d, max/2, max)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if _, ok := requester.RetryAfter(resp); ok {
+		t.Fatal("empty Retry-After")
+	}
+	resp.Header.Set("Retry-After", "5")
+	if d, ok := requester.RetryAfter(resp); !ok || d != 5*time.Second {
+		t.Fatalf("Retry-After seconds: %s %v", d, ok)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if d, ok := requester.RetryAfter(resp); !ok || d < 59*time.Minute {
+		t.Fatalf("Retry-After date: %s %v", d, ok)
+	}
+}
+
+func TestRewindPost(t *testing.T) {
+	mr := multipartreader.NewMultipartReader()
+	mr.AddFormFeild("param", bytes.NewReader([]byte("data")))
+	mr.CloseMultipart()
+	first, _ := io.ReadAll(mr)
+	if err := requester.RewindPost(mr); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := io.ReadAll(mr)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("rewound body differs:\n%q\n%q", first, second)
+	}
+
+	if err := requester.RewindPost(io.MultiReader()); err != requester.ErrNotReplayable {
+		t.Fatalf("expected ErrNotReplayable, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type (
+	// RateLimit 限速器, 可被多个任务共享, MaxRate <= 0 代表不限制
 	RateLimit struct {
 		MaxRate int64
 
@@ -14,6 +15,7 @@ type (
 		interval        time.Duration
 		ticker          *time.Ticker
 		muChan          chan struct{}
+		muChanLock      sync.RWMutex
 		closeChan       chan struct{}
 		backServiceOnce sync.Once
 	}
@@ -27,6 +29,16 @@ func NewRateLimit(maxRate int64) *RateLimit {
 	}
 }
 
+// SetMaxRate 修改最大速度, 可在限速生效期间调用, maxRate <= 0 代表不限制
+func (rl *RateLimit) SetMaxRate(maxRate int64) {
+	atomic.StoreInt64(&rl.MaxRate, maxRate)
+}
+
+// LoadMaxRate 返回当前的最大速度
+func (rl *RateLimit) LoadMaxRate() int64 {
+	return atomic.LoadInt64(&rl.MaxRate)
+}
+
 func (rl *RateLimit) SetInterval(i time.Duration) {
 	if i <= 0 {
 		i = 1 * time.Second
@@ -49,12 +61,20 @@ func (rl *RateLimit) Stop() {
 }
 
 func (rl *RateLimit) resetChan() {
+	rl.muChanLock.Lock()
+	defer rl.muChanLock.Unlock()
 	if rl.muChan != nil {
 		close(rl.muChan)
 	}
 	rl.muChan = make(chan struct{})
 }
 
+func (rl *RateLimit) loadChan() chan struct{} {
+	rl.muChanLock.RLock()
+	defer rl.muChanLock.RUnlock()
+	return rl.muChan
+}
+
 func (rl *RateLimit) backService() {
 	if rl.interval <= 0 {
 		rl.interval = 1 * time.Second
@@ -75,12 +95,18 @@ func (rl *RateLimit) backService() {
 	}()
 }
 
+// Add 增加数据量, 超出限额时阻塞直到下一个周期
 func (rl *RateLimit) Add(count int64) {
 	rl.backServiceOnce.Do(rl.backService)
 	for {
-		if atomic.LoadInt64(&rl.count) >= rl.MaxRate { // 超出最大限额
+		maxRate := rl.LoadMaxRate()
+		if maxRate <= 0 { // 不限制
+			atomic.AddInt64(&rl.count, count)
+			break
+		}
+		if atomic.LoadInt64(&rl.count) >= maxRate { // 超出最大限额
 			// 阻塞
-			<-rl.muChan
+			<-rl.loadChan()
 			continue
 		}
 		atomic.AddInt64(&rl.count, count)
@@ -0,0 +1,44 @@
+package requester
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+type (
+	// TraceRecorder 记录经过 HTTPClient 的请求和响应, 由 next 发送实际的请求
+	TraceRecorder interface {
+		RoundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error)
+	}
+
+	traceRecorderHolder struct {
+		recorder TraceRecorder
+	}
+
+	// traceTransport 设置了 TraceRecorder 时, 通过 TraceRecorder 发送请求
+	traceTransport struct {
+		next http.RoundTripper
+	}
+)
+
+var (
+	globalTraceRecorder atomic.Value
+)
+
+// SetTraceRecorder 设置全局的请求记录器, 为 nil 则不记录
+func SetTraceRecorder(recorder TraceRecorder) {
+	globalTraceRecorder.Store(traceRecorderHolder{recorder: recorder})
+}
+
+func loadTraceRecorder() TraceRecorder {
+	holder, _ := globalTraceRecorder.Load().(traceRecorderHolder)
+	return holder.recorder
+}
+
+func (tt *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := loadTraceRecorder()
+	if recorder == nil {
+		return tt.next.RoundTrip(req)
+	}
+	return recorder.RoundTrip(tt.next, req)
+}
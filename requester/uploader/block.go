@@ -91,6 +91,7 @@ func (fb *fileBlock) Read(b []byte) (n int, err error) {
 	if fb.speedsStatRef != nil {
 		fb.speedsStatRef.Add(n64)
 	}
+	uploadedBytes.Add(float64(n64))
 	return
 }
 
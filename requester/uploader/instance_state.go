@@ -20,12 +20,17 @@ type (
 
 func (muer *MultiUploader) getWorkerListByInstanceState(is *InstanceState) workerList {
 	workers := make(workerList, 0, len(is.BlockList))
+	readerAt := &pausableReaderAt{
+		ReaderAt: muer.file,
+		gate:     &muer.gate,
+		canceled: muer.canceled,
+	}
 	for _, blockState := range is.BlockList {
 		if blockState.CheckSum == "" {
 			workers = append(workers, &worker{
 				id:         blockState.ID,
 				partOffset: blockState.Range.Begin,
-				splitUnit:  NewBufioSplitUnit(muer.file, blockState.Range, muer.speedsStat, muer.rateLimit),
+				splitUnit:  NewBufioSplitUnit(readerAt, blockState.Range, muer.speedsStat, muer.rateLimit),
 				checksum:   blockState.CheckSum,
 			})
 		} else {
@@ -0,0 +1,43 @@
+package uploader
+
+import (
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/metrics"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	uploadedBytes = metrics.NewCounterVec("baidupcs_upload_bytes_total", "Number of bytes uploaded, including retransmissions.")
+
+	activeUploaders   = map[*MultiUploader]struct{}{}
+	activeUploadersMu sync.Mutex
+)
+
+func init() {
+	metrics.NewGaugeFunc("baidupcs_upload_speed_bytes", "Current upload speed in bytes per second, summed over running uploads.", func() float64 {
+		activeUploadersMu.Lock()
+		defer activeUploadersMu.Unlock()
+		var total int64
+		for muer := range activeUploaders {
+			total += atomic.LoadInt64(&muer.tmpSpeeds)
+		}
+		return float64(total)
+	})
+	metrics.NewGaugeFunc("baidupcs_uploads_active", "Number of running uploads.", func() float64 {
+		activeUploadersMu.Lock()
+		defer activeUploadersMu.Unlock()
+		return float64(len(activeUploaders))
+	})
+}
+
+// trackUploader 记录正在上传的任务, 用于统计总速度, 返回取消记录的函数
+func trackUploader(muer *MultiUploader) (untrack func()) {
+	activeUploadersMu.Lock()
+	activeUploaders[muer] = struct{}{}
+	activeUploadersMu.Unlock()
+	return func() {
+		activeUploadersMu.Lock()
+		delete(activeUploaders, muer)
+		activeUploadersMu.Unlock()
+	}
+}
@@ -22,6 +22,8 @@ type (
 
 	// MultiUploader 多线程上传
 	MultiUploader struct {
+		tmpSpeeds int64 // 最近一次统计的速度 (注意对齐)
+
 		onExecuteEvent      requester.Event        //开始上传事件
 		onSuccessEvent      requester.Event        //成功上传事件
 		onFinishEvent       requester.Event        //结束上传事件
@@ -37,6 +39,7 @@ type (
 		workers     workerList
 		speedsStat  *speeds.Speeds
 		rateLimit   *speeds.RateLimit
+		gate        pauseGate
 
 		executeTime             time.Time
 		finished                chan struct{}
@@ -47,10 +50,11 @@ type (
 
 	// MultiUploaderConfig 多线程上传配置
 	MultiUploaderConfig struct {
-		Parallel  int   // 上传并发量
-		BlockSize int64 // 上传分块
-		MaxRate   int64 // 限制最大上传速度
-		Policy    string  // 文件重名策略
+		Parallel  int               // 上传并发量
+		BlockSize int64             // 上传分块
+		MaxRate   int64             // 限制最大上传速度
+		RateLimit *speeds.RateLimit // 多个上传共享的限速器, 设置后忽略 MaxRate
+		Policy    string            // 文件重名策略
 	}
 )
 
@@ -60,6 +64,7 @@ func NewMultiUploader(multiUpload MultiUpload, file rio.ReaderAtLen64, config *M
 		multiUpload: multiUpload,
 		file:        file,
 		config:      config,
+		canceled:    make(chan struct{}),
 	}
 }
 
@@ -106,7 +111,10 @@ func (muer *MultiUploader) Execute() {
 	muer.check()
 	muer.lazyInit()
 	// 初始化限速
-	if muer.config.MaxRate > 0 {
+	if muer.config.RateLimit != nil {
+		// 共享的限速器, 由外部管理, 不在这里停止
+		muer.rateLimit = muer.config.RateLimit
+	} else if muer.config.MaxRate > 0 {
 		muer.rateLimit = speeds.NewRateLimit(muer.config.MaxRate)
 		defer muer.rateLimit.Stop()
 	}
@@ -129,7 +137,9 @@ func (muer *MultiUploader) Execute() {
 
 	muer.uploadStatusEvent()
 
+	untrack := trackUploader(muer)
 	err := muer.upload()
+	untrack()
 
 	// 完成
 	muer.finished <- struct{}{}
@@ -162,9 +172,11 @@ func (muer *MultiUploader) InstanceState() *InstanceState {
 	}
 }
 
-// Cancel 取消上传
+// Cancel 取消上传, 可多次调用
 func (muer *MultiUploader) Cancel() {
-	close(muer.canceled)
+	muer.closeCanceledOnce.Do(func() {
+		close(muer.canceled)
+	})
 }
 
 //OnExecute 设置开始上传事件
@@ -0,0 +1,78 @@
+package uploader
+
+import (
+	"io"
+	"sync"
+)
+
+type (
+	// pauseGate 暂停控制, 暂停时阻塞数据读取
+	pauseGate struct {
+		mu      sync.Mutex
+		resumed chan struct{} // 非 nil 时表示已暂停, 恢复时关闭
+	}
+
+	// pausableReaderAt 读取前检查是否已暂停
+	pausableReaderAt struct {
+		io.ReaderAt
+		gate     *pauseGate
+		canceled <-chan struct{}
+	}
+)
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// wait 暂停时阻塞, 直到恢复或取消
+func (g *pauseGate) wait(canceled <-chan struct{}) {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return
+	}
+	select {
+	case <-resumed:
+	case <-canceled:
+	}
+}
+
+func (pra *pausableReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	pra.gate.wait(pra.canceled)
+	return pra.ReaderAt.ReadAt(p, off)
+}
+
+// Pause 暂停上传, 正在上传的分片会在读取下一段数据前阻塞
+func (muer *MultiUploader) Pause() {
+	muer.gate.pause()
+}
+
+// Resume 恢复上传
+func (muer *MultiUploader) Resume() {
+	muer.gate.resume()
+}
+
+// Paused 返回上传是否已暂停
+func (muer *MultiUploader) Paused() bool {
+	return muer.gate.paused()
+}
@@ -1,6 +1,7 @@
 package uploader
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -93,10 +94,12 @@ func (muer *MultiUploader) uploadStatusEvent() {
 				return
 			case <-ticker.C:
 				readed := muer.workers.Readed()
+				speedsPerSecond := muer.speedsStat.GetSpeeds()
+				atomic.StoreInt64(&muer.tmpSpeeds, speedsPerSecond)
 				muer.onUploadStatusEvent(&UploadStatus{
 					totalSize:       muer.file.Len(),
 					uploaded:        readed,
-					speedsPerSecond: muer.speedsStat.GetSpeeds(),
+					speedsPerSecond: speedsPerSecond,
 					timeElapsed:     time.Since(muer.executeTime) / 1e8 * 1e8,
 				}, muer.updateInstanceStateChan)
 			}
@@ -0,0 +1,365 @@
+package uploader
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"github.com/qjfoidnh/BaiduPCS-Go/pcsutil/converter"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio/speeds"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/transfer"
+	"hash"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultStreamBlockSize 数据流上传的默认分片大小, 总长度未知, 按分片数上限 1024 计最大支持 32GB
+	DefaultStreamBlockSize = 32 * converter.MB
+	// StreamMaxRetry 数据流上传单个分片的最大重试次数, 分片只保存在内存中, 失败后无法断点续传
+	StreamMaxRetry = 10
+	// MaxStreamBlockNum 数据流上传的最大分片数
+	MaxStreamBlockNum = 1024
+)
+
+type (
+	// StreamUploader 上传长度未知且不可 Seek 的数据流, 例如标准输入.
+	// 按分片大小读取数据, 读满一个分片即上传, 同时在内存中的分片数不超过并发量,
+	// 读取时计算整个文件的 md5, 读取结束后合并分片
+	StreamUploader struct {
+		multiUpload MultiUpload
+		reader      io.Reader
+		config      *MultiUploaderConfig
+
+		fileSize   int64 // 数据流的总大小, 未知时为 -1
+		md5        hash.Hash
+		size       int64 // 已读取的数据量
+		uploaded   int64 // 已上传完成的分片的数据量
+		checksums  []string
+		active     map[int]SplitUnit // 正在上传的分片
+		mu         sync.Mutex
+		speedsStat *speeds.Speeds
+		rateLimit  *speeds.RateLimit
+		gate       pauseGate
+
+		onUploadStatusEvent     UploadStatusFunc
+		beforeCreate            func() (done bool, err error)
+		executeTime             time.Time
+		canceled                chan struct{}
+		closeCanceledOnce       sync.Once
+		updateInstanceStateChan chan struct{}
+	}
+)
+
+// ErrStreamTooManyBlocks 数据流分片数超过上限
+var ErrStreamTooManyBlocks = errors.New("数据流过大, 分片数超过上限, 请增大分片大小")
+
+// NewStreamUploader 初始化数据流上传, config 中的 Parallel, BlockSize, MaxRate, RateLimit, Policy 有效
+func NewStreamUploader(multiUpload MultiUpload, reader io.Reader, config *MultiUploaderConfig) *StreamUploader {
+	if config == nil {
+		config = &MultiUploaderConfig{}
+	}
+	if config.Parallel <= 0 {
+		config.Parallel = 4
+	}
+	if config.BlockSize <= 0 {
+		config.BlockSize = DefaultStreamBlockSize
+	}
+	return &StreamUploader{
+		multiUpload: multiUpload,
+		reader:      reader,
+		config:      config,
+		fileSize:    -1,
+		md5:         md5.New(),
+		active:      map[int]SplitUnit{},
+		speedsStat:  &speeds.Speeds{},
+		canceled:    make(chan struct{}),
+
+		updateInstanceStateChan: make(chan struct{}, 1),
+	}
+}
+
+// OnUploadStatusEvent 设置上传状态事件, 未设置总大小时, 总大小为当前已读取的数据量.
+// 有分片上传完成时, 事件的 updateChan 可读, 用于保存断点续传信息
+func (su *StreamUploader) OnUploadStatusEvent(f UploadStatusFunc) {
+	su.onUploadStatusEvent = f
+}
+
+// SetFileSize 设置数据流的总大小, 用于同名文件策略的判断和上传状态
+func (su *StreamUploader) SetFileSize(fileSize int64) {
+	su.fileSize = fileSize
+}
+
+// OnBeforeCreateSuperFile 设置所有分片上传完成后, 合并分片前的事件, 返回 done 为 true 时不再合并分片
+func (su *StreamUploader) OnBeforeCreateSuperFile(f func() (done bool, err error)) {
+	su.beforeCreate = f
+}
+
+// Execute 执行上传, 读取到 EOF 后合并分片
+func (su *StreamUploader) Execute() (err error) {
+	if su.config.RateLimit != nil {
+		su.rateLimit = su.config.RateLimit
+	} else if su.config.MaxRate > 0 {
+		su.rateLimit = speeds.NewRateLimit(su.config.MaxRate)
+		defer su.rateLimit.Stop()
+	}
+
+	// 长度未知时, 无法按大小判断 rsync 策略
+	err = su.multiUpload.Precreate(su.fileSize, su.config.Policy)
+	if err != nil {
+		return err
+	}
+
+	su.executeTime = time.Now()
+	finished := make(chan struct{})
+	defer close(finished)
+	su.uploadStatusEvent(finished)
+
+	var (
+		wg        sync.WaitGroup
+		slots     = make(chan []byte, su.config.Parallel) // 分片缓冲区, 限制内存占用
+		errOnce   sync.Once
+		uploadErr error
+	)
+	for i := 0; i < su.config.Parallel; i++ {
+		slots <- nil
+	}
+	setErr := func(e error) {
+		errOnce.Do(func() {
+			uploadErr = e
+			su.Cancel()
+		})
+	}
+
+	for id := 0; ; id++ {
+		var buf []byte
+		select {
+		case buf = <-slots:
+		case <-su.canceled:
+		}
+		if su.isCanceled() {
+			break
+		}
+		if buf == nil {
+			buf = make([]byte, su.config.BlockSize)
+		}
+
+		su.gate.wait(su.canceled)
+		n, rerr := io.ReadFull(su.reader, buf)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			setErr(rerr)
+			break
+		}
+		// 空数据流也上传一个空分片
+		if n == 0 && id > 0 {
+			break
+		}
+		if id >= MaxStreamBlockNum {
+			setErr(ErrStreamTooManyBlocks)
+			break
+		}
+		su.md5.Write(buf[:n])
+		atomic.AddInt64(&su.size, int64(n))
+
+		su.mu.Lock()
+		su.checksums = append(su.checksums, "")
+		su.mu.Unlock()
+
+		wg.Add(1)
+		go func(id int, data []byte) {
+			defer wg.Done()
+			defer func() { slots <- buf }()
+			checksum, terr := su.uploadBlock(id, data)
+			if terr != nil {
+				setErr(terr)
+				return
+			}
+			su.mu.Lock()
+			su.checksums[id] = checksum
+			su.mu.Unlock()
+			select {
+			case su.updateInstanceStateChan <- struct{}{}:
+			default:
+			}
+		}(id, buf[:n])
+
+		if rerr != nil { // EOF
+			break
+		}
+	}
+	wg.Wait()
+
+	if uploadErr != nil {
+		return uploadErr
+	}
+	if su.isCanceled() {
+		return context.Canceled
+	}
+	if su.beforeCreate != nil {
+		done, err := su.beforeCreate()
+		if done || err != nil {
+			return err
+		}
+	}
+	return su.multiUpload.CreateSuperFile(su.config.Policy, su.checksums...)
+}
+
+// uploadBlock 上传一个分片, 失败时重试
+func (su *StreamUploader) uploadBlock(id int, data []byte) (string, error) {
+	unit := NewBufioSplitUnit(&pausableReaderAt{
+		ReaderAt: bytesReaderAt(data),
+		gate:     &su.gate,
+		canceled: su.canceled,
+	}, transfer.Range{Begin: 0, End: int64(len(data))}, su.speedsStat, su.rateLimit)
+	su.mu.Lock()
+	su.active[id] = unit
+	su.mu.Unlock()
+	defer func() {
+		su.mu.Lock()
+		delete(su.active, id)
+		su.mu.Unlock()
+	}()
+
+	partOffset := int64(id) * su.config.BlockSize
+	for retry := 0; ; retry++ {
+		var (
+			ctx, cancel = context.WithCancel(context.Background())
+			doneChan    = make(chan struct{})
+			checksum    string
+			err         error
+		)
+		go func() {
+			checksum, err = su.multiUpload.TmpFile(ctx, id, partOffset, unit)
+			close(doneChan)
+		}()
+		select {
+		case <-su.canceled:
+			cancel()
+			return "", context.Canceled
+		case <-doneChan:
+		}
+		cancel()
+		if err == nil {
+			atomic.AddInt64(&su.uploaded, int64(len(data)))
+			return checksum, nil
+		}
+
+		if me, ok := err.(*MultiError); ok && me.Terminated {
+			return "", me.Err
+		}
+		if retry >= StreamMaxRetry {
+			return "", err
+		}
+		uploaderVerbose.Warnf("upload stream err: %s, id: %d, retry: %d\n", err, id, retry+1)
+		unit.Seek(0, io.SeekStart)
+	}
+}
+
+// Cancel 取消上传, 可多次调用
+func (su *StreamUploader) Cancel() {
+	su.closeCanceledOnce.Do(func() {
+		close(su.canceled)
+	})
+}
+
+func (su *StreamUploader) isCanceled() bool {
+	select {
+	case <-su.canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pause 暂停上传
+func (su *StreamUploader) Pause() {
+	su.gate.pause()
+}
+
+// Resume 恢复上传
+func (su *StreamUploader) Resume() {
+	su.gate.resume()
+}
+
+// Size 返回已读取的数据量, 上传成功后即为文件大小
+func (su *StreamUploader) Size() int64 {
+	return atomic.LoadInt64(&su.size)
+}
+
+// MD5 返回已读取数据的 md5, 上传成功后即为文件的 md5
+func (su *StreamUploader) MD5() []byte {
+	return su.md5.Sum(nil)
+}
+
+// InstanceState 返回断点续传信息, 可由 MultiUploader 继续上传, 未设置总大小时返回 nil.
+// 数据流需从头读取, 分片的划分与 MultiUploader 相同
+func (su *StreamUploader) InstanceState() *InstanceState {
+	if su.fileSize < 0 {
+		return nil
+	}
+	blockList := SplitBlock(su.fileSize, su.config.BlockSize)
+	su.mu.Lock()
+	for _, blockState := range blockList {
+		if blockState.ID < len(su.checksums) {
+			blockState.CheckSum = su.checksums[blockState.ID]
+		}
+	}
+	su.mu.Unlock()
+	return &InstanceState{
+		BlockList: blockList,
+	}
+}
+
+// Uploaded 返回已上传的数据量
+func (su *StreamUploader) Uploaded() int64 {
+	uploaded := atomic.LoadInt64(&su.uploaded)
+	su.mu.Lock()
+	for _, unit := range su.active {
+		uploaded += unit.Readed()
+	}
+	su.mu.Unlock()
+	return uploaded
+}
+
+func (su *StreamUploader) uploadStatusEvent(finished <-chan struct{}) {
+	if su.onUploadStatusEvent == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(3 * time.Second) // 每3秒统计
+		defer ticker.Stop()
+		for {
+			select {
+			case <-finished:
+				return
+			case <-ticker.C:
+				totalSize := su.fileSize
+				if totalSize < 0 {
+					totalSize = su.Size()
+				}
+				su.onUploadStatusEvent(&UploadStatus{
+					totalSize:       totalSize,
+					uploaded:        su.Uploaded(),
+					speedsPerSecond: su.speedsStat.GetSpeeds(),
+					timeElapsed:     time.Since(su.executeTime) / 1e8 * 1e8,
+				}, su.updateInstanceStateChan)
+			}
+		}
+	}()
+}
+
+// bytesReaderAt 内存中的分片数据
+type bytesReaderAt []byte
+
+func (b bytesReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,179 @@
+package uploader_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/qjfoidnh/BaiduPCS-Go/baidupcs/pcserror"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/rio"
+	"github.com/qjfoidnh/BaiduPCS-Go/requester/uploader"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type memUpload struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	fails     int
+	failParts map[int]bool // 这些分片上传失败, 且不重试
+	blockList []string
+}
+
+// memFile 内存中的文件
+type memFile []byte
+
+func (f memFile) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(f).ReadAt(p, off)
+}
+
+func (f memFile) Len() int64 {
+	return int64(len(f))
+}
+
+func (mu *memUpload) Precreate(fileSize int64, policy string) pcserror.Error {
+	return nil
+}
+
+func (mu *memUpload) TmpFile(ctx context.Context, partseq int, partOffset int64, r rio.ReaderLen64) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	mu.mu.Lock()
+	defer mu.mu.Unlock()
+	if mu.failParts[partseq] {
+		return "", &uploader.MultiError{Err: io.ErrClosedPipe, Terminated: true}
+	}
+	if mu.fails > 0 {
+		mu.fails--
+		return "", io.ErrUnexpectedEOF
+	}
+	mu.parts[partseq] = data
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (mu *memUpload) CreateSuperFile(policy string, checksumList ...string) error {
+	mu.blockList = checksumList
+	return nil
+}
+
+func TestStreamUploader(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	mu := &memUpload{parts: map[int][]byte{}, fails: 2}
+	su := uploader.NewStreamUploader(mu, io.MultiReader(bytes.NewReader(data)), &uploader.MultiUploaderConfig{
+		Parallel:  3,
+		BlockSize: 4096,
+	})
+	if err := su.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mu.blockList) != 3 {
+		t.Fatalf("block list = %d, want 3", len(mu.blockList))
+	}
+	var joined []byte
+	for i, checksum := range mu.blockList {
+		sum := md5.Sum(mu.parts[i])
+		if checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("block %d checksum mismatch", i)
+		}
+		joined = append(joined, mu.parts[i]...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Errorf("uploaded data mismatch")
+	}
+	if sum := md5.Sum(data); !reflect.DeepEqual(su.MD5(), sum[:]) || su.Size() != int64(len(data)) {
+		t.Errorf("md5 or size mismatch")
+	}
+}
+
+func TestStreamUploaderEmpty(t *testing.T) {
+	mu := &memUpload{parts: map[int][]byte{}}
+	su := uploader.NewStreamUploader(mu, bytes.NewReader(nil), nil)
+	if err := su.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mu.blockList) != 1 || len(mu.parts[0]) != 0 {
+		t.Errorf("empty stream should upload one empty block, got %d", len(mu.blockList))
+	}
+}
+
+func TestStreamUploaderBeforeCreate(t *testing.T) {
+	data := []byte("pipeline")
+	mu := &memUpload{parts: map[int][]byte{}}
+	su := uploader.NewStreamUploader(mu, bytes.NewReader(data), nil)
+	su.SetFileSize(int64(len(data)))
+	var gotMD5 []byte
+	su.OnBeforeCreateSuperFile(func() (bool, error) {
+		gotMD5 = su.MD5()
+		return true, nil
+	})
+	if err := su.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if sum := md5.Sum(data); !bytes.Equal(gotMD5, sum[:]) {
+		t.Errorf("md5 before create mismatch")
+	}
+	if mu.blockList != nil {
+		t.Errorf("CreateSuperFile should be skipped")
+	}
+}
+
+func TestStreamUploaderInstanceState(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	mu := &memUpload{parts: map[int][]byte{}, failParts: map[int]bool{1: true}}
+	su := uploader.NewStreamUploader(mu, bytes.NewReader(data), &uploader.MultiUploaderConfig{
+		Parallel:  1,
+		BlockSize: 4096,
+	})
+	if su.InstanceState() != nil {
+		t.Errorf("instance state without file size should be nil")
+	}
+	su.SetFileSize(int64(len(data)))
+	if err := su.Execute(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// 与 MultiUploader 的分片相同, 只有第一个分片已上传
+	state := su.InstanceState()
+	want := uploader.SplitBlock(int64(len(data)), 4096)
+	if len(state.BlockList) != len(want) {
+		t.Fatalf("block list = %d, want %d", len(state.BlockList), len(want))
+	}
+	for i, blockState := range state.BlockList {
+		got, wantRange := blockState.Range, want[i].Range
+		if blockState.ID != want[i].ID || got.Begin != wantRange.Begin || got.End != wantRange.End {
+			t.Errorf("block %d: got %d [%d, %d), want %d [%d, %d)", i, blockState.ID, got.Begin, got.End, want[i].ID, wantRange.Begin, wantRange.End)
+		}
+		if uploaded := blockState.CheckSum != ""; uploaded != (i == 0) {
+			t.Errorf("block %d: checksum %q", i, blockState.CheckSum)
+		}
+	}
+
+	// 由 MultiUploader 继续上传
+	mu.failParts = nil
+	muer := uploader.NewMultiUploader(mu, memFile(data), &uploader.MultiUploaderConfig{
+		Parallel:  2,
+		BlockSize: 4096,
+	})
+	muer.SetInstanceState(state)
+	var resumeErr error
+	muer.OnError(func(err error) {
+		resumeErr = err
+	})
+	muer.Execute()
+	if resumeErr != nil {
+		t.Fatal(resumeErr)
+	}
+	var joined []byte
+	for i := range mu.blockList {
+		joined = append(joined, mu.parts[i]...)
+	}
+	if len(mu.blockList) != len(want) || !bytes.Equal(joined, data) {
+		t.Errorf("resumed upload mismatch: %d blocks", len(mu.blockList))
+	}
+}